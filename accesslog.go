@@ -0,0 +1,264 @@
+package infrared
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// AccessLogFormatJSON logs every field as a JSON object. A list of fields
+	// can follow after a colon, e.g. "json:timestamp,remote_ip,player".
+	AccessLogFormatJSON = "json"
+
+	// DefaultAccessLogFormat is the format of the access log if none is set
+	DefaultAccessLogFormat = "$timestamp $remote_ip $host $server_id $player $protocol $result $bytes_sent $bytes_received $duration"
+)
+
+const (
+	// accessResultProxied is the result of connections that were relayed
+	// to a server
+	accessResultProxied = "proxied"
+	// accessResultHandled is the result of connections that Infrared
+	// answered or rejected by itself
+	accessResultHandled = "handled"
+	// accessResultError is the result of connections that failed
+	accessResultError = "error"
+)
+
+// accessLogFields are the fields of an access log entry in their default order
+var accessLogFields = []string{
+	"timestamp",
+	"remote_ip",
+	"host",
+	"server_id",
+	"player",
+	"protocol",
+	"result",
+	"bytes_sent",
+	"bytes_received",
+	"duration",
+}
+
+// accessEntry is the record of one completed connection
+type accessEntry struct {
+	Timestamp     time.Time
+	RemoteIP      string
+	Host          string
+	ServerID      string
+	Player        string
+	Protocol      int
+	Result        string
+	BytesSent     int64
+	BytesReceived int64
+	Duration      time.Duration
+	proxied       bool
+}
+
+// value returns the field with the given name; numbers are returned as int64
+func (entry *accessEntry) value(field string) interface{} {
+	switch field {
+	case "timestamp":
+		return entry.Timestamp.Format(time.RFC3339)
+	case "remote_ip":
+		return entry.RemoteIP
+	case "host":
+		return entry.Host
+	case "server_id":
+		return entry.ServerID
+	case "player":
+		return entry.Player
+	case "protocol":
+		return int64(entry.Protocol)
+	case "result":
+		return entry.Result
+	case "bytes_sent":
+		return entry.BytesSent
+	case "bytes_received":
+		return entry.BytesReceived
+	case "duration":
+		return entry.Duration.Milliseconds()
+	}
+	return nil
+}
+
+// accessLogPart is either literal text of a template or the field that
+// replaces a variable
+type accessLogPart struct {
+	text  string
+	field string
+}
+
+// accessLogger writes an access log entry per completed connection. It is
+// safe for concurrent use.
+type accessLogger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	json   bool
+	fields []string
+	parts  []accessLogPart
+}
+
+// newAccessLogger parses the format, which is either AccessLogFormatJSON or
+// a text template with nginx like variables, e.g. "$remote_ip $player"
+func newAccessLogger(w io.Writer, format string) (*accessLogger, error) {
+	if format == "" {
+		format = DefaultAccessLogFormat
+	}
+
+	logger := &accessLogger{w: w}
+	if format == AccessLogFormatJSON || strings.HasPrefix(format, AccessLogFormatJSON+":") {
+		logger.json = true
+		logger.fields = accessLogFields
+		if list := strings.TrimPrefix(format, AccessLogFormatJSON); list != "" {
+			logger.fields = nil
+			for _, field := range strings.Split(list[1:], ",") {
+				field = strings.TrimSpace(field)
+				if !isAccessLogField(field) {
+					return nil, fmt.Errorf("unknown access log field %q", field)
+				}
+				logger.fields = append(logger.fields, field)
+			}
+		}
+		return logger, nil
+	}
+
+	parts, err := parseAccessLogTemplate(format)
+	if err != nil {
+		return nil, err
+	}
+	logger.parts = parts
+	return logger, nil
+}
+
+func parseAccessLogTemplate(format string) ([]accessLogPart, error) {
+	var parts []accessLogPart
+	for len(format) > 0 {
+		i := strings.IndexByte(format, '$')
+		if i < 0 {
+			parts = append(parts, accessLogPart{text: format})
+			break
+		}
+		if i > 0 {
+			parts = append(parts, accessLogPart{text: format[:i]})
+		}
+
+		format = format[i+1:]
+		n := 0
+		for n < len(format) && (format[n] == '_' || 'a' <= format[n] && format[n] <= 'z') {
+			n++
+		}
+		field := format[:n]
+		if !isAccessLogField(field) {
+			return nil, fmt.Errorf("unknown access log variable $%s", field)
+		}
+		parts = append(parts, accessLogPart{field: field})
+		format = format[n:]
+	}
+	return parts, nil
+}
+
+func isAccessLogField(field string) bool {
+	for _, f := range accessLogFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// format returns the log line of the entry without the line break
+func (logger *accessLogger) format(entry *accessEntry) []byte {
+	var buf bytes.Buffer
+	if logger.json {
+		// The fields are written one by one to keep their configured order
+		buf.WriteByte('{')
+		for i, field := range logger.fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(field)
+			value, _ := json.Marshal(entry.value(field))
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+		return buf.Bytes()
+	}
+
+	for _, part := range logger.parts {
+		if part.field == "" {
+			buf.WriteString(part.text)
+			continue
+		}
+
+		switch v := entry.value(part.field).(type) {
+		case int64:
+			buf.WriteString(strconv.FormatInt(v, 10))
+		case string:
+			if v == "" {
+				// Like nginx, empty values are logged as a dash
+				v = "-"
+			}
+			buf.WriteString(v)
+		}
+	}
+	return buf.Bytes()
+}
+
+func (logger *accessLogger) log(entry *accessEntry) {
+	line := append(logger.format(entry), '\n')
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	// A failed write must not affect the connections
+	_, _ = logger.w.Write(line)
+}
+
+// recordAccess lets fn fill in the access log entry of c if it has one
+func recordAccess(c Conn, fn func(entry *accessEntry)) {
+	cc, ok := c.(*conn)
+	if !ok || cc.access == nil {
+		return
+	}
+	fn(cc.access)
+}
+
+// trackAccess attaches a new access log entry to c and returns it, or nil
+// if c can't carry one
+func trackAccess(c Conn, start time.Time) *accessEntry {
+	cc, ok := c.(*conn)
+	if !ok {
+		return nil
+	}
+
+	cc.access = &accessEntry{
+		Timestamp: start,
+		RemoteIP:  remoteIP(c.RemoteAddr()),
+		Result:    accessResultHandled,
+	}
+	return cc.access
+}
+
+// logAccess completes the access log entry of c and writes it
+func (gateway *Gateway) logAccess(c Conn, entry *accessEntry, err error) {
+	if gateway.accessLog == nil || entry == nil {
+		return
+	}
+
+	entry.Duration = time.Since(entry.Timestamp)
+	entry.BytesReceived, entry.BytesSent = relayedBytes(c)
+	switch {
+	case err != nil:
+		entry.Result = accessResultError
+	case entry.proxied:
+		entry.Result = accessResultProxied
+	}
+	gateway.accessLog.log(entry)
+}
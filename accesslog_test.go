@@ -0,0 +1,122 @@
+package infrared
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAccessLogger_Format(t *testing.T) {
+	entry := &accessEntry{
+		Timestamp:     time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+		RemoteIP:      "203.0.113.7",
+		Host:          "mc.example.com",
+		ServerID:      "mc.example.com@:25565",
+		Protocol:      754,
+		Result:        accessResultProxied,
+		BytesSent:     1234,
+		BytesReceived: 567,
+		Duration:      1500 * time.Millisecond,
+	}
+
+	tt := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{
+			name: "Default",
+			want: "2021-03-04T05:06:07Z 203.0.113.7 mc.example.com mc.example.com@:25565 - 754 proxied 1234 567 1500",
+		},
+		{
+			name:   "Template",
+			format: `[$timestamp] $remote_ip "$player" $result/$duration`,
+			want:   `[2021-03-04T05:06:07Z] 203.0.113.7 "-" proxied/1500`,
+		},
+		{
+			name:   "JSON",
+			format: "json",
+			want:   `{"timestamp":"2021-03-04T05:06:07Z","remote_ip":"203.0.113.7","host":"mc.example.com","server_id":"mc.example.com@:25565","player":"","protocol":754,"result":"proxied","bytes_sent":1234,"bytes_received":567,"duration":1500}`,
+		},
+		{
+			name:   "JSONFields",
+			format: "json:result, remote_ip",
+			want:   `{"result":"proxied","remote_ip":"203.0.113.7"}`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			logger, err := newAccessLogger(nil, tc.format)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := string(logger.format(entry)); got != tc.want {
+				t.Errorf("got: %s; want: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewAccessLogger_UnknownField(t *testing.T) {
+	for _, format := range []string{"$remote_ip $user", "json:remote_ip,user"} {
+		if _, err := newAccessLogger(nil, format); err == nil {
+			t.Errorf("got: no error for %q; want: error", format)
+		}
+	}
+}
+
+func TestGateway_LogAccess(t *testing.T) {
+	tt := []struct {
+		name    string
+		proxied bool
+		err     error
+		want    string
+	}{
+		{
+			name: "Handled",
+			want: "handled",
+		},
+		{
+			name:    "Proxied",
+			proxied: true,
+			want:    "proxied",
+		},
+		{
+			name:    "Error",
+			proxied: true,
+			err:     errors.New("broken pipe"),
+			want:    "error",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := newAccessLogger(&buf, "$player $result")
+			if err != nil {
+				t.Fatal(err)
+			}
+			gateway := &Gateway{accessLog: logger}
+
+			c, s := net.Pipe()
+			defer c.Close()
+			defer s.Close()
+
+			conn := wrapConn(c)
+			entry := trackAccess(conn, time.Now())
+			recordAccess(conn, func(entry *accessEntry) {
+				entry.Player = "Steve"
+				entry.proxied = tc.proxied
+			})
+			gateway.logAccess(conn, entry, tc.err)
+
+			if got, want := buf.String(), "Steve "+tc.want+"\n"; got != want {
+				t.Errorf("got: %q; want: %q", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package infrared
+
+import (
+	"log"
+	"net"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+const (
+	defaultBanMessage       = "You are banned from this server."
+	defaultWhitelistMessage = "You are not whitelisted on this server."
+)
+
+func (proxy *Proxy) Whitelist() bool {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.Whitelist
+}
+
+func (proxy *Proxy) BanMessage() string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	if proxy.Config.BanMessage == "" {
+		return defaultBanMessage
+	}
+	return proxy.Config.BanMessage
+}
+
+func (proxy *Proxy) WhitelistMessage() string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	if proxy.Config.WhitelistMessage == "" {
+		return defaultWhitelistMessage
+	}
+	return proxy.Config.WhitelistMessage
+}
+
+// rejectByACL checks the client against the ACL store of the gateway and
+// disconnects it if it is banned or not whitelisted. It reports whether the
+// client was rejected.
+func (proxy *Proxy) rejectByACL(conn Conn, hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr, username string) (bool, error) {
+	if proxy.gateway == nil || proxy.gateway.ACLStore == nil {
+		return false, nil
+	}
+	store := proxy.gateway.ACLStore
+	ip := remoteIP(connRemoteAddr)
+
+	banned, err := store.IsBanned(ip, username)
+	if err != nil {
+		return proxy.aclLookupFailed(connRemoteAddr, err)
+	}
+	if banned {
+		logWith(connFields("reject", connRemoteAddr, proxy.UID()), "[i] Rejecting banned %s on %s", connRemoteAddr, proxy.UID())
+		return true, proxy.rejectLogin(conn, hs, proxy.BanMessage())
+	}
+
+	if !proxy.Whitelist() {
+		return false, nil
+	}
+
+	whitelisted, err := store.IsWhitelisted(ip, username)
+	if err != nil {
+		return proxy.aclLookupFailed(connRemoteAddr, err)
+	}
+	if !whitelisted {
+		logWith(connFields("reject", connRemoteAddr, proxy.UID()), "[i] Rejecting not whitelisted %s on %s", connRemoteAddr, proxy.UID())
+		return true, proxy.rejectLogin(conn, hs, proxy.WhitelistMessage())
+	}
+
+	return false, nil
+}
+
+// aclLookupFailed decides over a client whose lookup in the ACL store failed.
+// It is let through if the gateway fails open and rejected otherwise.
+func (proxy *Proxy) aclLookupFailed(connRemoteAddr net.Addr, err error) (bool, error) {
+	if !proxy.gateway.ACLFailOpen {
+		return true, err
+	}
+	log.Printf("[w] Failed looking up %s in the acl store; letting it through; error: %s", connRemoteAddr, err)
+	return false, nil
+}
+
+// rejectLogin disconnects logins with the message.
+// Status requests are answered by closing the connection.
+func (proxy *Proxy) rejectLogin(conn Conn, hs handshaking.ServerBoundHandshake, message string) error {
+	if !hs.IsLoginRequest() {
+		return nil
+	}
+	return conn.WritePacket(disconnectPacket(message))
+}
@@ -0,0 +1,50 @@
+package acl
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Store holds the ban list and the whitelist of IPs and usernames.
+// Implementations have to be safe for concurrent use.
+type Store interface {
+	// IsBanned reports whether the IP or the username is banned.
+	// An empty username is never banned.
+	IsBanned(ip, username string) (bool, error)
+	// IsWhitelisted reports whether the IP or the username is whitelisted.
+	IsWhitelisted(ip, username string) (bool, error)
+}
+
+// NewStore creates a Store from a URI. Supported are:
+//
+//	memory://                               an empty in-memory store
+//	file:///path/to/acl.json                a JSON file that is reloaded on change
+//	redis://[:password@]host:port[?prefix=] sets in a Redis server that are shared by all instances
+func NewStore(uri string) (Store, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "memory":
+		return NewMemory(), nil
+	case "file", "":
+		path := u.Path
+		if u.Host != "" {
+			path = u.Host + path
+		}
+		return NewFile(path)
+	case "redis":
+		password, _ := u.User.Password()
+		return NewRedis(u.Host, password, u.Query().Get("prefix")), nil
+	default:
+		return nil, fmt.Errorf("unsupported acl store %q", u.Scheme)
+	}
+}
+
+// normalizeUsername makes usernames case-insensitive like Minecraft does
+func normalizeUsername(username string) string {
+	return strings.ToLower(username)
+}
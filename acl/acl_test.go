@@ -0,0 +1,190 @@
+package acl
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	store := NewMemory()
+	store.Set(Lists{
+		BannedIPs:            []string{"1.2.3.4"},
+		BannedUsernames:      []string{"Griefer"},
+		WhitelistedUsernames: []string{"Notch"},
+	})
+
+	tt := []struct {
+		ip          string
+		username    string
+		banned      bool
+		whitelisted bool
+	}{
+		{
+			ip:     "1.2.3.4",
+			banned: true,
+		},
+		{
+			ip:       "5.6.7.8",
+			username: "griefer",
+			banned:   true,
+		},
+		{
+			ip:          "5.6.7.8",
+			username:    "notch",
+			whitelisted: true,
+		},
+		{
+			ip: "5.6.7.8",
+		},
+	}
+
+	for _, tc := range tt {
+		banned, _ := store.IsBanned(tc.ip, tc.username)
+		if banned != tc.banned {
+			t.Errorf("%s/%s banned; got: %v; want: %v", tc.ip, tc.username, banned, tc.banned)
+		}
+
+		whitelisted, _ := store.IsWhitelisted(tc.ip, tc.username)
+		if whitelisted != tc.whitelisted {
+			t.Errorf("%s/%s whitelisted; got: %v; want: %v", tc.ip, tc.username, whitelisted, tc.whitelisted)
+		}
+	}
+}
+
+// fakeRedis answers SISMEMBER commands with the members of sets. Replies
+// about the member slow are delayed.
+func fakeRedis(t *testing.T, sets map[string][]string, slow string) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveFakeRedis(conn, sets, slow)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func serveFakeRedis(conn net.Conn, sets map[string][]string, slow string) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	for {
+		var args []string
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		n := 0
+		for _, c := range strings.TrimSpace(header[1:]) {
+			n = n*10 + int(c-'0')
+		}
+		for i := 0; i < n*2; i++ {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if i%2 == 1 {
+				args = append(args, strings.TrimSpace(line))
+			}
+		}
+
+		if args[2] == slow {
+			time.Sleep(500 * time.Millisecond)
+		}
+		reply := ":0\r\n"
+		for _, member := range sets[args[1]] {
+			if member == args[2] {
+				reply = ":1\r\n"
+			}
+		}
+		conn.Write([]byte(reply))
+	}
+}
+
+func TestRedis(t *testing.T) {
+	addr := fakeRedis(t, map[string][]string{
+		"infrared:banned_ips":       {"1.2.3.4"},
+		"infrared:banned_usernames": {"griefer"},
+	}, "")
+
+	store, err := NewStore("redis://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		ip       string
+		username string
+		banned   bool
+	}{
+		{
+			ip:     "1.2.3.4",
+			banned: true,
+		},
+		{
+			ip:       "5.6.7.8",
+			username: "Griefer",
+			banned:   true,
+		},
+		{
+			ip:       "5.6.7.8",
+			username: "Notch",
+			banned:   false,
+		},
+	}
+
+	for _, tc := range tt {
+		banned, err := store.IsBanned(tc.ip, tc.username)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if banned != tc.banned {
+			t.Errorf("%s/%s; got: %v; want: %v", tc.ip, tc.username, banned, tc.banned)
+		}
+	}
+}
+
+func TestRedis_SlowReply(t *testing.T) {
+	addr := fakeRedis(t, map[string][]string{
+		"infrared:banned_ips": {"1.2.3.4"},
+	}, "9.9.9.9")
+
+	store, err := NewStore("redis://" + addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	slowDone := make(chan struct{})
+	go func() {
+		_, _ = store.IsBanned("9.9.9.9", "")
+		close(slowDone)
+	}()
+	// Let the slow lookup take the first connection
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	banned, err := store.IsBanned("1.2.3.4", "Notch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !banned {
+		t.Error("got: not banned; want: banned")
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Errorf("got: %v; want: no wait for the slow lookup", elapsed)
+	}
+	<-slowDone
+}
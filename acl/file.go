@@ -0,0 +1,94 @@
+package acl
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// File is a Store that is loaded from a JSON file and reloaded
+// whenever the file changes
+type File struct {
+	*Memory
+	path string
+}
+
+// NewFile loads the lists from the JSON file on path and starts watching it
+func NewFile(path string) (*File, error) {
+	f := &File{
+		Memory: NewMemory(),
+		path:   path,
+	}
+
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := watcher.Add(path); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	go func() {
+		defer watcher.Close()
+		f.watch(watcher, time.Millisecond*50)
+	}()
+
+	return f, nil
+}
+
+func (f *File) load() error {
+	bb, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+
+	var lists Lists
+	if err := json.Unmarshal(bb, &lists); err != nil {
+		return err
+	}
+
+	f.Set(lists)
+	return nil
+}
+
+func (f *File) watch(watcher *fsnotify.Watcher, interval time.Duration) {
+	// The interval protects the watcher from write event spams
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	changed := false
+
+	for {
+		select {
+		case <-tick.C:
+			if !changed {
+				continue
+			}
+			changed = false
+			log.Println("Updating acl", f.path)
+			if err := f.load(); err != nil {
+				log.Printf("Failed updating acl %s; error %s", f.path, err)
+			}
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&fsnotify.Write == fsnotify.Write {
+				changed = true
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Failed watching acl %s; error %s", f.path, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package acl
+
+import "sync"
+
+// Lists is the data representation of the ban list and the whitelist
+type Lists struct {
+	BannedIPs            []string `json:"bannedIps"`
+	BannedUsernames      []string `json:"bannedUsernames"`
+	WhitelistedIPs       []string `json:"whitelistedIps"`
+	WhitelistedUsernames []string `json:"whitelistedUsernames"`
+}
+
+// Memory is a Store that keeps all lists in memory
+type Memory struct {
+	mu                   sync.RWMutex
+	bannedIPs            map[string]bool
+	bannedUsernames      map[string]bool
+	whitelistedIPs       map[string]bool
+	whitelistedUsernames map[string]bool
+}
+
+func NewMemory() *Memory {
+	m := &Memory{}
+	m.Set(Lists{})
+	return m
+}
+
+// Set replaces all lists of the store
+func (m *Memory) Set(lists Lists) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bannedIPs = toSet(lists.BannedIPs, false)
+	m.bannedUsernames = toSet(lists.BannedUsernames, true)
+	m.whitelistedIPs = toSet(lists.WhitelistedIPs, false)
+	m.whitelistedUsernames = toSet(lists.WhitelistedUsernames, true)
+}
+
+func (m *Memory) BanIP(ip string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bannedIPs[ip] = true
+}
+
+func (m *Memory) BanUsername(username string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bannedUsernames[normalizeUsername(username)] = true
+}
+
+func (m *Memory) WhitelistIP(ip string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.whitelistedIPs[ip] = true
+}
+
+func (m *Memory) WhitelistUsername(username string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.whitelistedUsernames[normalizeUsername(username)] = true
+}
+
+func (m *Memory) IsBanned(ip, username string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.bannedIPs[ip] || (username != "" && m.bannedUsernames[normalizeUsername(username)]), nil
+}
+
+func (m *Memory) IsWhitelisted(ip, username string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.whitelistedIPs[ip] || (username != "" && m.whitelistedUsernames[normalizeUsername(username)]), nil
+}
+
+func toSet(values []string, isUsername bool) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		if isUsername {
+			value = normalizeUsername(value)
+		}
+		set[value] = true
+	}
+	return set
+}
@@ -0,0 +1,204 @@
+package acl
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultRedisPrefix = "infrared"
+	redisTimeout       = 2 * time.Second
+	// redisMaxConns is the maximum number of open connections to the
+	// server. Lookups wait up to redisTimeout for a free one.
+	redisMaxConns = 16
+)
+
+var errRedisBusy = errors.New("no free redis connection")
+
+// Redis is a Store that looks up the lists in sets of a Redis server, so
+// that all Infrared instances that use the same server share their lists.
+// The sets are named <prefix>:banned_ips, <prefix>:banned_usernames,
+// <prefix>:whitelisted_ips and <prefix>:whitelisted_usernames.
+//
+// Lookups run concurrently on a pool of connections and every lookup is a
+// single round trip, so that a slow reply does not hold up other logins.
+type Redis struct {
+	addr     string
+	password string
+	prefix   string
+
+	// conns limits the open connections and idle holds the ones that
+	// can be reused
+	conns chan struct{}
+	idle  chan *redisConn
+}
+
+func NewRedis(addr, password, prefix string) *Redis {
+	if prefix == "" {
+		prefix = defaultRedisPrefix
+	}
+
+	return &Redis{
+		addr:     addr,
+		password: password,
+		prefix:   prefix,
+		conns:    make(chan struct{}, redisMaxConns),
+		idle:     make(chan *redisConn, redisMaxConns),
+	}
+}
+
+func (r *Redis) IsBanned(ip, username string) (bool, error) {
+	return r.isMemberOfAny("banned", ip, username)
+}
+
+func (r *Redis) IsWhitelisted(ip, username string) (bool, error) {
+	return r.isMemberOfAny("whitelisted", ip, username)
+}
+
+// isMemberOfAny looks up the IP and the username in one pipeline
+func (r *Redis) isMemberOfAny(list, ip, username string) (bool, error) {
+	cmds := [][]string{
+		{"SISMEMBER", fmt.Sprintf("%s:%s_ips", r.prefix, list), ip},
+	}
+	if username != "" {
+		cmds = append(cmds, []string{"SISMEMBER", fmt.Sprintf("%s:%s_usernames", r.prefix, list), normalizeUsername(username)})
+	}
+
+	replies, err := r.do(cmds...)
+	if err != nil {
+		return false, err
+	}
+
+	for _, reply := range replies {
+		if reply == 1 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// do sends the commands in one pipeline on a pooled connection and returns
+// their integer replies
+func (r *Redis) do(cmds ...[]string) ([]int64, error) {
+	timer := time.NewTimer(redisTimeout)
+	defer timer.Stop()
+	select {
+	case r.conns <- struct{}{}:
+	case <-timer.C:
+		return nil, errRedisBusy
+	}
+	defer func() { <-r.conns }()
+
+	var c *redisConn
+	select {
+	case c = <-r.idle:
+	default:
+		var err error
+		c, err = dialRedis(r.addr, r.password)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	replies, err := c.do(cmds...)
+	if err != nil {
+		// The replies of the connection may be out of sync now
+		c.conn.Close()
+		return nil, err
+	}
+
+	select {
+	case r.idle <- c:
+	default:
+		c.conn.Close()
+	}
+	return replies, nil
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func dialRedis(addr, password string) (*redisConn, error) {
+	conn, err := net.DialTimeout("tcp", addr, redisTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{
+		conn: conn,
+		r:    bufio.NewReader(conn),
+	}
+
+	if password == "" {
+		return c, nil
+	}
+
+	if _, err := c.do([]string{"AUTH", password}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("could not authenticate; %s", err)
+	}
+	return c, nil
+}
+
+// do writes all commands at once and then reads their replies
+func (c *redisConn) do(cmds ...[]string) ([]int64, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(redisTimeout)); err != nil {
+		return nil, err
+	}
+
+	var bb []byte
+	for _, args := range cmds {
+		bb = append(bb, encodeRedisCommand(args...)...)
+	}
+	if _, err := c.conn.Write(bb); err != nil {
+		return nil, err
+	}
+
+	replies := make([]int64, len(cmds))
+	for i := range replies {
+		reply, err := readRedisReply(c.r)
+		if err != nil {
+			return nil, err
+		}
+		replies[i] = reply
+	}
+	return replies, nil
+}
+
+func encodeRedisCommand(args ...string) []byte {
+	cmd := []byte(fmt.Sprintf("*%d\r\n", len(args)))
+	for _, arg := range args {
+		cmd = append(cmd, fmt.Sprintf("$%d\r\n%s\r\n", len(arg), arg)...)
+	}
+	return cmd
+}
+
+// readRedisReply reads a reply and returns its value if it is an integer.
+// Simple string replies like +OK are returned as 0.
+func readRedisReply(r *bufio.Reader) (int64, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, err
+	}
+
+	if len(line) < 3 {
+		return 0, errors.New("invalid redis reply")
+	}
+	line = line[:len(line)-2]
+
+	switch line[0] {
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '+':
+		return 0, nil
+	case '-':
+		return 0, errors.New(line[1:])
+	default:
+		return 0, fmt.Errorf("unexpected redis reply %q", line)
+	}
+}
@@ -0,0 +1,87 @@
+package infrared
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/haveachin/infrared/acl"
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+// failingStore is an acl.Store whose server does not answer
+type failingStore struct{}
+
+func (failingStore) IsBanned(string, string) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func (failingStore) IsWhitelisted(string, string) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestProxy_RejectByACL(t *testing.T) {
+	banned := acl.NewMemory()
+	banned.Set(acl.Lists{BannedUsernames: []string{"Griefer"}})
+
+	tt := []struct {
+		name     string
+		store    acl.Store
+		failOpen bool
+		username string
+		rejected bool
+		err      bool
+	}{
+		{
+			name:     "NotBanned",
+			store:    banned,
+			username: "Steve",
+		},
+		{
+			name:     "Banned",
+			store:    banned,
+			username: "Griefer",
+			rejected: true,
+		},
+		{
+			name:     "FailClosed",
+			store:    failingStore{},
+			username: "Steve",
+			rejected: true,
+			err:      true,
+		},
+		{
+			name:     "FailOpen",
+			store:    failingStore{},
+			failOpen: true,
+			username: "Steve",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{
+				Config: &ProxyConfig{DomainName: "mc.example.com"},
+				gateway: &Gateway{
+					ACLStore:    tc.store,
+					ACLFailOpen: tc.failOpen,
+				},
+			}
+
+			c1, c2 := net.Pipe()
+			defer c1.Close()
+			defer c2.Close()
+			go func() { _, _ = wrapConn(c1).ReadPacket() }()
+
+			hs := handshaking.ServerBoundHandshake{NextState: handshaking.ServerBoundHandshakeLoginState}
+			addr := &net.TCPAddr{IP: net.IPv4(203, 0, 113, 1), Port: 50000}
+			rejected, err := proxy.rejectByACL(wrapConn(c2), hs, addr, tc.username)
+			if rejected != tc.rejected {
+				t.Errorf("got: %v; want: %v", rejected, tc.rejected)
+			}
+			if (err != nil) != tc.err {
+				t.Errorf("got: %v; want error: %v", err, tc.err)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"github.com/go-chi/chi/v5"
@@ -9,23 +10,56 @@ import (
 	"io/ioutil"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"sort"
+	"strings"
 )
 
-// ListenAndServe StartWebserver Start Webserver if environment variable "api-enable" is set to true
-func ListenAndServe(configPath string, apiBind string) {
+// ListenAndServe StartWebserver Start Webserver if environment variable "api-enable" is set to true.
+// If token is not empty, every request needs it as its bearer token.
+func ListenAndServe(gateway *infrared.Gateway, configPath string, apiBind string, token string) {
 	fmt.Println("Starting WebAPI on " + apiBind)
+
+	err := http.ListenAndServe(apiBind, newRouter(gateway, configPath, token))
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+}
+
+func newRouter(gateway *infrared.Gateway, configPath string, token string) http.Handler {
 	router := chi.NewRouter()
 	router.Use(middleware.Logger)
+	if token != "" {
+		router.Use(bearerAuth(token))
+	}
 
 	router.Post("/proxies", addProxy(configPath))
 	router.Post("/proxies/{fileName}", addProxyWithName(configPath))
 	router.Delete("/proxies/{fileName}", removeProxy(configPath))
+	router.Get("/gateway/proxies", listProxies(gateway))
+	router.Get("/gateway/proxies/{proxyUID}", getProxy(gateway))
+	router.Delete("/gateway/proxies/{proxyUID}", closeProxy(gateway))
+	router.Patch("/gateway/proxies/{proxyUID}/forwarding", setForwarding(gateway))
+	router.Patch("/gateway/proxies/{proxyUID}/maintenance", setMaintenance(gateway))
+	return router
+}
 
-	err := http.ListenAndServe(apiBind, router)
-	if err != nil {
-		log.Fatal(err)
-		return
+// bearerAuth rejects every request that does not have token as its bearer
+// token with 401 Unauthorized
+func bearerAuth(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth := r.Header.Get("Authorization")
+			if !strings.HasPrefix(auth, "Bearer ") ||
+				subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
 	}
 }
 
@@ -85,6 +119,149 @@ func removeProxy(configPath string) http.HandlerFunc {
 	}
 }
 
+// proxyResponse is the state of a running proxy
+type proxyResponse struct {
+	UID         string              `json:"uid"`
+	DomainName  string              `json:"domainName"`
+	ListenTo    string              `json:"listenTo"`
+	ProxyTo     string              `json:"proxyTo"`
+	Players     int                 `json:"players"`
+	Forwarding  infrared.Forwarding `json:"forwarding"`
+	Maintenance bool                `json:"maintenance"`
+}
+
+// forwardingRequest changes the forwarding flags that are set
+type forwardingRequest struct {
+	RealIP        *bool `json:"realIp"`
+	ProxyProtocol *bool `json:"proxyProtocol"`
+	BungeeCord    *bool `json:"bungeeCord"`
+}
+
+// maintenanceRequest starts or ends the maintenance
+type maintenanceRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+func listProxies(gateway *infrared.Gateway) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		proxies := []proxyResponse{}
+		gateway.Proxies.Range(func(_, v interface{}) bool {
+			proxies = append(proxies, newProxyResponse(v.(*infrared.Proxy)))
+			return true
+		})
+		sort.Slice(proxies, func(i, j int) bool {
+			return proxies[i].UID < proxies[j].UID
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(proxies)
+	}
+}
+
+func getProxy(gateway *infrared.Gateway) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		proxy, ok := lookupProxy(gateway, r)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		writeProxy(w, proxy)
+	}
+}
+
+func closeProxy(gateway *infrared.Gateway) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		proxy, ok := lookupProxy(gateway, r)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		gateway.CloseProxy(proxy.UID())
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func setForwarding(gateway *infrared.Gateway) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		proxy, ok := lookupProxy(gateway, r)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var req forwardingRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		forwarding := proxy.Forwarding()
+		if req.RealIP != nil {
+			forwarding.RealIP = *req.RealIP
+		}
+		if req.ProxyProtocol != nil {
+			forwarding.ProxyProtocol = *req.ProxyProtocol
+		}
+		if req.BungeeCord != nil {
+			forwarding.BungeeCord = *req.BungeeCord
+		}
+		if err := forwarding.Validate(); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		proxy.SetForwarding(forwarding)
+		log.Printf("[i] Set forwarding of %s to real IP %t, proxy protocol %t and BungeeCord %t", proxy.UID(), forwarding.RealIP, forwarding.ProxyProtocol, forwarding.BungeeCord)
+
+		writeProxy(w, proxy)
+	}
+}
+
+func setMaintenance(gateway *infrared.Gateway) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		proxy, ok := lookupProxy(gateway, r)
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		var req maintenanceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		proxy.SetMaintenance(*req.Enabled)
+
+		writeProxy(w, proxy)
+	}
+}
+
+func lookupProxy(gateway *infrared.Gateway, r *http.Request) (*infrared.Proxy, bool) {
+	proxyUID, err := url.PathUnescape(chi.URLParam(r, "proxyUID"))
+	if err != nil {
+		return nil, false
+	}
+	return gateway.Proxy(proxyUID)
+}
+
+func writeProxy(w http.ResponseWriter, proxy *infrared.Proxy) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(newProxyResponse(proxy))
+}
+
+func newProxyResponse(proxy *infrared.Proxy) proxyResponse {
+	return proxyResponse{
+		UID:         proxy.UID(),
+		DomainName:  proxy.DomainName(),
+		ListenTo:    proxy.ListenTo(),
+		ProxyTo:     proxy.ProxyTo(),
+		Players:     len(proxy.Sessions()),
+		Forwarding:  proxy.Forwarding(),
+		Maintenance: proxy.Maintenance().Enabled,
+	}
+}
+
 // Helper method to check for domainName and proxyTo in a given JSON array
 // If the filename is empty the domain will be used as the filename - files with the same name will be overwritten
 func checkJSONAndRegister(rawData []byte, filename string, configPath string) (successful bool) {
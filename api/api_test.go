@@ -0,0 +1,187 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/haveachin/infrared"
+)
+
+func testGateway(domains ...string) *infrared.Gateway {
+	gateway := &infrared.Gateway{}
+	for _, domain := range domains {
+		proxy := &infrared.Proxy{Config: &infrared.ProxyConfig{
+			DomainName: domain,
+			ListenTo:   ":25565",
+			ProxyTo:    ":25566",
+		}}
+		gateway.Proxies.Store(proxy.UID(), proxy)
+	}
+	return gateway
+}
+
+func TestBearerAuth(t *testing.T) {
+	router := newRouter(testGateway(), "", "secret")
+
+	tt := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{
+			name: "NoToken",
+			want: http.StatusUnauthorized,
+		},
+		{
+			name:   "WrongToken",
+			header: "Bearer wrong",
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "NotBearer",
+			header: "Basic secret",
+			want:   http.StatusUnauthorized,
+		},
+		{
+			name:   "Token",
+			header: "Bearer secret",
+			want:   http.StatusOK,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/gateway/proxies", nil)
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tc.want {
+				t.Errorf("got: %v; want: %v", rec.Code, tc.want)
+			}
+		})
+	}
+}
+
+func TestListProxies(t *testing.T) {
+	router := newRouter(testGateway("b.example.com", "a.example.com"), "", "")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/gateway/proxies", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got: %v; want: %v", rec.Code, http.StatusOK)
+	}
+
+	var proxies []proxyResponse
+	if err := json.NewDecoder(rec.Body).Decode(&proxies); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"a.example.com@:25565", "b.example.com@:25565"}
+	if len(proxies) != len(want) {
+		t.Fatalf("got: %v proxies; want: %v", len(proxies), len(want))
+	}
+	for i, proxy := range proxies {
+		if proxy.UID != want[i] {
+			t.Errorf("got: %v; want: %v", proxy.UID, want[i])
+		}
+	}
+}
+
+func TestCloseProxy(t *testing.T) {
+	gateway := testGateway("mc.example.com")
+	router := newRouter(gateway, "", "")
+	path := "/gateway/proxies/" + url.PathEscape("mc.example.com@:25565")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, path, nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("got: %v; want: %v", rec.Code, http.StatusNoContent)
+	}
+	if _, ok := gateway.Proxy("mc.example.com@:25565"); ok {
+		t.Error("got: proxy; want: no proxy")
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, path, nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("got: %v; want: %v", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestSetMaintenance(t *testing.T) {
+	gateway := testGateway("mc.example.com")
+	router := newRouter(gateway, "", "")
+	path := "/gateway/proxies/" + url.PathEscape("mc.example.com@:25565") + "/maintenance"
+
+	tt := []struct {
+		name        string
+		path        string
+		body        string
+		want        int
+		maintenance bool
+	}{
+		{
+			name:        "Start",
+			path:        path,
+			body:        `{"enabled": true}`,
+			want:        http.StatusOK,
+			maintenance: true,
+		},
+		{
+			name:        "MissingEnabled",
+			path:        path,
+			body:        `{}`,
+			want:        http.StatusBadRequest,
+			maintenance: true,
+		},
+		{
+			name:        "End",
+			path:        path,
+			body:        `{"enabled": false}`,
+			want:        http.StatusOK,
+			maintenance: false,
+		},
+		{
+			name: "UnknownProxy",
+			path: "/gateway/proxies/" + url.PathEscape("other.example.com@:25565") + "/maintenance",
+			body: `{"enabled": true}`,
+			want: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPatch, tc.path, strings.NewReader(tc.body)))
+			if rec.Code != tc.want {
+				t.Fatalf("got: %v; want: %v", rec.Code, tc.want)
+			}
+			if rec.Code == http.StatusNotFound {
+				return
+			}
+
+			proxy, _ := gateway.Proxy("mc.example.com@:25565")
+			if enabled := proxy.Maintenance().Enabled; enabled != tc.maintenance {
+				t.Errorf("got: %v; want: %v", enabled, tc.maintenance)
+			}
+
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var resp proxyResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Maintenance != tc.maintenance {
+				t.Errorf("got: %v; want: %v", resp.Maintenance, tc.maintenance)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package infrared
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/haveachin/infrared/callback"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+const (
+	defaultAuthHookTimeout = 5 * time.Second
+	defaultAuthHookMessage = "You are not allowed to join this server."
+)
+
+// authHookRequest is the body that is posted to the auth hook for a login
+type authHookRequest struct {
+	Username      string `json:"username"`
+	PlayerUUID    string `json:"playerUuid,omitempty"`
+	RemoteAddress string `json:"remoteAddress"`
+	ProxyUID      string `json:"proxyUid"`
+}
+
+func (proxy *Proxy) AuthHook() AuthHookConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.AuthHook
+}
+
+func (cfg AuthHookConfig) timeout() time.Duration {
+	if cfg.Timeout <= 0 {
+		return defaultAuthHookTimeout
+	}
+	return time.Millisecond * time.Duration(cfg.Timeout)
+}
+
+func (cfg AuthHookConfig) message() string {
+	if cfg.Message == "" {
+		return defaultAuthHookMessage
+	}
+	return cfg.Message
+}
+
+// allows posts the login to the auth hook and reports whether it answered
+// with 200. Every other answer rejects the login.
+func (cfg AuthHookConfig) allows(request authHookRequest) (bool, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if cfg.Secret != "" {
+		req.Header.Set(callback.SignatureHeader, callback.Sign(cfg.Secret, body))
+	}
+
+	client := http.Client{Timeout: cfg.timeout()}
+	response, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer response.Body.Close()
+
+	return response.StatusCode == http.StatusOK, nil
+}
+
+// rejectByAuthHook asks the auth hook whether the login may be proxied and
+// disconnects it otherwise. Logins are also rejected if the auth hook
+// fails to answer, so that an outage does not let everyone in. It reports
+// whether the client was rejected.
+func (proxy *Proxy) rejectByAuthHook(conn Conn, hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr, loginStart login.ServerLoginStart) (bool, error) {
+	cfg := proxy.AuthHook()
+	if !cfg.IsEnabled() || !hs.IsLoginRequest() {
+		return false, nil
+	}
+
+	allowed, err := cfg.allows(authHookRequest{
+		Username:      string(loginStart.Name),
+		PlayerUUID:    playerUUID(loginStart),
+		RemoteAddress: connRemoteAddr.String(),
+		ProxyUID:      proxy.UID(),
+	})
+	if err != nil {
+		if err := proxy.rejectLogin(conn, hs, cfg.message()); err != nil {
+			return true, err
+		}
+		return true, fmt.Errorf("auth hook failed: %w", err)
+	}
+
+	if !allowed {
+		logWith(connFields("reject", connRemoteAddr, proxy.UID()), "[i] Rejecting %s on %s by the auth hook", connRemoteAddr, proxy.UID())
+		return true, proxy.rejectLogin(conn, hs, cfg.message())
+	}
+
+	return false, nil
+}
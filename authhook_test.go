@@ -0,0 +1,127 @@
+package infrared
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/haveachin/infrared/callback"
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+func TestProxy_RejectByAuthHook(t *testing.T) {
+	var mu sync.Mutex
+	var request authHookRequest
+	var signature string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		signature = r.Header.Get(callback.SignatureHeader)
+		err := json.NewDecoder(r.Body).Decode(&request)
+		username := request.Username
+		mu.Unlock()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch username {
+		case "Steve":
+			w.WriteHeader(http.StatusOK)
+		case "Slow":
+			time.Sleep(200 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	tt := []struct {
+		name     string
+		url      string
+		username string
+		rejected bool
+		wantErr  bool
+	}{
+		{
+			name:     "Allowed",
+			url:      server.URL,
+			username: "Steve",
+		},
+		{
+			name:     "Rejected",
+			url:      server.URL,
+			username: "Alex",
+			rejected: true,
+		},
+		{
+			name:     "Timeout",
+			url:      server.URL,
+			username: "Slow",
+			rejected: true,
+			wantErr:  true,
+		},
+		{
+			name:     "Unreachable",
+			url:      "http://" + serverAddr(831),
+			username: "Steve",
+			rejected: true,
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{
+				Config: &ProxyConfig{
+					DomainName: "mc.example.com",
+					ListenTo:   ":25565",
+					AuthHook: AuthHookConfig{
+						URL:     tc.url,
+						Timeout: 50,
+						Secret:  "secret",
+					},
+				},
+			}
+
+			c1, c2 := net.Pipe()
+			defer c1.Close()
+			defer c2.Close()
+			disconnected := make(chan bool, 1)
+			go func() {
+				_, err := wrapConn(c1).ReadPacket()
+				disconnected <- err == nil
+			}()
+
+			hs := handshaking.ServerBoundHandshake{NextState: handshaking.ServerBoundHandshakeLoginState}
+			loginStart := login.ServerLoginStart{Name: protocol.String(tc.username)}
+			remoteAddr := &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 50000}
+			rejected, err := proxy.rejectByAuthHook(wrapConn(c2), hs, remoteAddr, loginStart)
+			if rejected != tc.rejected {
+				t.Errorf("got: %v; want: %v", rejected, tc.rejected)
+			}
+			if (err != nil) != tc.wantErr {
+				t.Errorf("got: %v; want error: %v", err, tc.wantErr)
+			}
+
+			c2.Close()
+			if got := <-disconnected; got != tc.rejected {
+				t.Errorf("got disconnect: %v; want: %v", got, tc.rejected)
+			}
+		})
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if request.RemoteAddress != "203.0.113.7:50000" || request.ProxyUID != "mc.example.com@:25565" {
+		t.Errorf("got: %+v; want: the address of the player and the proxy UID", request)
+	}
+	if signature == "" {
+		t.Error("got: no signature; want: signature")
+	}
+}
@@ -0,0 +1,389 @@
+package infrared
+
+import (
+	"hash/fnv"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+// The strategies that select the backend of a connection
+const (
+	// BackendSelectionFailover always uses the first backend that is up in
+	// the configured order. The others are only used while it is down.
+	BackendSelectionFailover = "failover"
+	// BackendSelectionRoundRobin starts every connection at the backend
+	// after the one of the previous connection, so that they are spread
+	// evenly. Backends that are down are skipped.
+	BackendSelectionRoundRobin = "round_robin"
+	// BackendSelectionWeightedRoundRobin starts the connections at the
+	// backends in proportion to their weights. Backends that are down are
+	// skipped.
+	BackendSelectionWeightedRoundRobin = "weighted_round_robin"
+	// BackendSelectionSticky starts the connections of a player at the same
+	// backend as long as it is up. Players are identified by their UUID and
+	// status requests by their IP.
+	BackendSelectionSticky = "sticky"
+)
+
+// defaultBackendWeight is the weight of backends without a configured one
+const defaultBackendWeight = 1
+
+// backendRetryInterval is the time that a backend which failed to be dialed
+// is treated as down. After that it is tried in its turn again.
+const backendRetryInterval = 10 * time.Second
+
+// defaultDialRetryDelay is the delay between dial retries if none is configured
+const defaultDialRetryDelay = 500 * time.Millisecond
+
+// BackendSelector orders the backends of a proxy for a new connection.
+// The connection is proxied to the first one of them that can be dialed.
+type BackendSelector interface {
+	Select(backends []string, isUp func(addr string) bool) []string
+}
+
+// backendSelector returns the selector of the backend selection strategy
+// of the proxy. The sticky selection uses stickyKey to identify the client.
+func (proxy *Proxy) backendSelector(stickyKey string) BackendSelector {
+	switch proxy.BackendSelection() {
+	case BackendSelectionRoundRobin:
+		return roundRobinSelector{next: &proxy.nextBackend}
+	case BackendSelectionWeightedRoundRobin:
+		return weightedSelector{
+			balancer: &proxy.weightedBackends,
+			weights:  proxy.BackendWeights(),
+		}
+	case BackendSelectionSticky:
+		return stickySelector{key: stickyKey}
+	default:
+		return failoverSelector{}
+	}
+}
+
+type failoverSelector struct{}
+
+func (failoverSelector) Select(backends []string, isUp func(addr string) bool) []string {
+	selected := make([]string, 0, len(backends))
+	var down []string
+	for _, addr := range backends {
+		if isUp(addr) {
+			selected = append(selected, addr)
+			continue
+		}
+		down = append(down, addr)
+	}
+
+	// Backends that are down are the last resort; they might be up again
+	return append(selected, down...)
+}
+
+// roundRobinSelector rotates the backends by one for every connection.
+// next is shared by all connections of a proxy.
+type roundRobinSelector struct {
+	next *uint32
+}
+
+func (s roundRobinSelector) Select(backends []string, isUp func(addr string) bool) []string {
+	if len(backends) == 0 {
+		return backends
+	}
+
+	start := int((atomic.AddUint32(s.next, 1) - 1) % uint32(len(backends)))
+	rotated := make([]string, 0, len(backends))
+	rotated = append(rotated, backends[start:]...)
+	rotated = append(rotated, backends[:start]...)
+	return failoverSelector{}.Select(rotated, isUp)
+}
+
+// weightedBalancer implements the smooth weighted round robin of nginx.
+// Every pick adds the weights of the candidates to their current weight and
+// picks the candidate with the highest one, whose current weight is then
+// reduced by the total. That interleaves the backends, so a:3 and b:1
+// results in a, a, b, a instead of a, a, a, b. It is safe for concurrent
+// use.
+type weightedBalancer struct {
+	mu      sync.Mutex
+	current map[string]int
+}
+
+func (b *weightedBalancer) pick(candidates []string, weight func(addr string) int) string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.current == nil {
+		b.current = map[string]int{}
+	}
+
+	var best string
+	total := 0
+	for _, addr := range candidates {
+		w := weight(addr)
+		b.current[addr] += w
+		total += w
+		if best == "" || b.current[addr] > b.current[best] {
+			best = addr
+		}
+	}
+	b.current[best] -= total
+	return best
+}
+
+// weightedSelector starts every connection at a backend that is picked in
+// proportion to its weight out of the backends that are up. Backends with a
+// weight of zero or less are only used while the others are down.
+type weightedSelector struct {
+	balancer *weightedBalancer
+	weights  map[string]int
+}
+
+func (s weightedSelector) weight(addr string) int {
+	w, ok := s.weights[addr]
+	if !ok {
+		return defaultBackendWeight
+	}
+	return w
+}
+
+func (s weightedSelector) Select(backends []string, isUp func(addr string) bool) []string {
+	var candidates []string
+	for _, addr := range backends {
+		if isUp(addr) && s.weight(addr) > 0 {
+			candidates = append(candidates, addr)
+		}
+	}
+	if len(candidates) == 0 {
+		return failoverSelector{}.Select(backends, isUp)
+	}
+
+	first := s.balancer.pick(candidates, s.weight)
+	rest := make([]string, 0, len(backends)-1)
+	for _, addr := range backends {
+		if addr != first {
+			rest = append(rest, addr)
+		}
+	}
+	return append([]string{first}, failoverSelector{}.Select(rest, isUp)...)
+}
+
+// stickySelector orders the backends by rendezvous hashing of the key and
+// their address, so that a client always gets the same order. Adding or
+// removing a backend only moves the clients that it took over or had.
+type stickySelector struct {
+	key string
+}
+
+func (s stickySelector) score(addr string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(addr))
+	_, _ = h.Write([]byte{0})
+	_, _ = h.Write([]byte(s.key))
+
+	// FNV barely mixes the last bytes into the high bits, which decide the
+	// order, so similar keys would mostly get the same backend. The
+	// finalizer of SplitMix64 spreads them.
+	x := h.Sum64()
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
+
+func (s stickySelector) Select(backends []string, isUp func(addr string) bool) []string {
+	ordered := make([]string, len(backends))
+	copy(ordered, backends)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return s.score(ordered[i]) > s.score(ordered[j])
+	})
+	return failoverSelector{}.Select(ordered, isUp)
+}
+
+// stickyKey identifies the client for the sticky selection. Logins are
+// identified by the UUID of the player, which is derived from its name for
+// clients that do not send it, and all other requests by the IP.
+func stickyKey(connRemoteAddr net.Addr, loginStart login.ServerLoginStart) string {
+	if loginStart.HasPlayerUUID {
+		return loginStart.PlayerUUID.String()
+	}
+	if loginStart.Name != "" {
+		return offlinePlayerUUID(string(loginStart.Name)).String()
+	}
+	if connRemoteAddr == nil {
+		return ""
+	}
+	return remoteIP(connRemoteAddr)
+}
+
+// backendHealth tracks which backends are up. A backend is down for a
+// while after it failed and while it fails its health checks. It is safe
+// for concurrent use.
+type backendHealth struct {
+	mu        sync.Mutex
+	downUntil map[string]time.Time
+	unhealthy map[string]bool
+}
+
+func (h *backendHealth) IsUp(addr string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return !h.unhealthy[addr] && !time.Now().Before(h.downUntil[addr])
+}
+
+// IsHealthy reports whether the backend passes its health checks
+func (h *backendHealth) IsHealthy(addr string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return !h.unhealthy[addr]
+}
+
+func (h *backendHealth) SetHealthy(addr string, healthy bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if healthy {
+		delete(h.unhealthy, addr)
+		return
+	}
+	if h.unhealthy == nil {
+		h.unhealthy = map[string]bool{}
+	}
+	h.unhealthy[addr] = true
+}
+
+func (h *backendHealth) MarkUp(addr string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.downUntil, addr)
+}
+
+func (h *backendHealth) MarkDown(addr string, retry time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.downUntil == nil {
+		h.downUntil = map[string]time.Time{}
+	}
+	h.downUntil[addr] = time.Now().Add(retry)
+}
+
+// Backends returns the addresses of the servers that the proxy proxies to.
+// Without configured backends it is the one of ProxyTo.
+func (proxy *Proxy) Backends() []string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	if len(proxy.Config.Backends) == 0 {
+		return []string{proxy.Config.ProxyTo}
+	}
+
+	backends := make([]string, len(proxy.Config.Backends))
+	copy(backends, proxy.Config.Backends)
+	return backends
+}
+
+// DialRetries returns how often the backends are dialed again after all of
+// them failed, e.g. while the server restarts and its port is not bound yet
+func (proxy *Proxy) DialRetries() int {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.DialRetries
+}
+
+func (proxy *Proxy) DialRetryDelay() time.Duration {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	if proxy.Config.DialRetryDelay <= 0 {
+		return defaultDialRetryDelay
+	}
+	return time.Millisecond * time.Duration(proxy.Config.DialRetryDelay)
+}
+
+// BackendWeights returns the weights of the backends for the weighted
+// round robin selection
+func (proxy *Proxy) BackendWeights() map[string]int {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	weights := make(map[string]int, len(proxy.Config.BackendWeights))
+	for addr, weight := range proxy.Config.BackendWeights {
+		weights[addr] = weight
+	}
+	return weights
+}
+
+func (proxy *Proxy) BackendSelection() string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	if proxy.Config.BackendSelection == "" {
+		return BackendSelectionFailover
+	}
+	return proxy.Config.BackendSelection
+}
+
+// backendsFor returns the backends in the order in which they should be
+// dialed for the client and the subdomain or version route that was used,
+// if any. Status requests go to the StatusProxyTo if it is set.
+// loginStart is only set for login requests and connRemoteAddr is nil for
+// requests of Infrared itself.
+func (proxy *Proxy) backendsFor(hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr, loginStart login.ServerLoginStart) ([]string, string) {
+	if addr := proxy.StatusProxyTo(); addr != "" && hs.IsStatusRequest() {
+		return []string{addr}, ""
+	}
+
+	if addr, route := proxy.routeTo(hs); route != "" {
+		return []string{addr}, route
+	}
+
+	if addr, route := proxy.versionRouteTo(hs); route != "" {
+		return []string{addr}, route
+	}
+
+	selector := proxy.backendSelector(stickyKey(connRemoteAddr, loginStart))
+	if canary := proxy.Canary(); hs.IsLoginRequest() && canary.isCanary(loginStart) {
+		selector = canarySelector{
+			BackendSelector: selector,
+			canary:          canary.Backend,
+		}
+	}
+	return selector.Select(proxy.Backends(), proxy.backendHealth.IsUp), ""
+}
+
+// dialBackend dials the backends in order until one of them answers and
+// returns its connection and address. The ones that fail are marked as down.
+func (proxy *Proxy) dialBackend(backends []string) (Conn, string, error) {
+	dialer, err := proxy.Dialer()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var addr string
+	for i := range backends {
+		addr = backends[i]
+		var rconn Conn
+		rconn, err = dialer.Dial(addr)
+		if err == nil {
+			proxy.backendHealth.MarkUp(addr)
+			proxy.setTCPOptions(rconn)
+			return rconn, addr, nil
+		}
+
+		proxy.backendHealth.MarkDown(addr, backendRetryInterval)
+		if i < len(backends)-1 {
+			log.Printf("[i] %s did not respond; failing over to %s", addr, backends[i+1])
+		}
+	}
+	return nil, addr, err
+}
+
+// dialBackendWithRetries dials the backends like dialBackend and dials all
+// of them again after the retry delay until one answers or the retries run out
+func (proxy *Proxy) dialBackendWithRetries(backends []string) (Conn, string, error) {
+	rconn, addr, err := proxy.dialBackend(backends)
+	retries := proxy.DialRetries()
+	for retry := 1; err != nil && retry <= retries; retry++ {
+		delay := proxy.DialRetryDelay()
+		log.Printf("[i] %s did not respond; retrying in %s (%d/%d)", addr, delay, retry, retries)
+		time.Sleep(delay)
+		rconn, addr, err = proxy.dialBackend(backends)
+	}
+	return rconn, addr, err
+}
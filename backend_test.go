@@ -0,0 +1,485 @@
+package infrared
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+func TestFailoverSelector_Select(t *testing.T) {
+	backends := []string{"a:25565", "b:25565", "c:25565"}
+
+	tt := []struct {
+		name string
+		down []string
+		want []string
+	}{
+		{
+			name: "AllUp",
+			want: []string{"a:25565", "b:25565", "c:25565"},
+		},
+		{
+			name: "PrimaryDown",
+			down: []string{"a:25565"},
+			want: []string{"b:25565", "c:25565", "a:25565"},
+		},
+		{
+			name: "PrimaryAndSecondaryDown",
+			down: []string{"a:25565", "b:25565"},
+			want: []string{"c:25565", "a:25565", "b:25565"},
+		},
+		{
+			name: "AllDown",
+			down: []string{"a:25565", "b:25565", "c:25565"},
+			want: []string{"a:25565", "b:25565", "c:25565"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var health backendHealth
+			for _, addr := range tc.down {
+				health.MarkDown(addr, time.Minute)
+			}
+
+			got := failoverSelector{}.Select(backends, health.IsUp)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRoundRobinSelector_Select(t *testing.T) {
+	backends := []string{"a:25565", "b:25565", "c:25565"}
+
+	tt := []struct {
+		name string
+		down []string
+		want [][]string
+	}{
+		{
+			name: "AllUp",
+			want: [][]string{
+				{"a:25565", "b:25565", "c:25565"},
+				{"b:25565", "c:25565", "a:25565"},
+				{"c:25565", "a:25565", "b:25565"},
+				{"a:25565", "b:25565", "c:25565"},
+			},
+		},
+		{
+			name: "SecondDown",
+			down: []string{"b:25565"},
+			want: [][]string{
+				{"a:25565", "c:25565", "b:25565"},
+				{"c:25565", "a:25565", "b:25565"},
+				{"c:25565", "a:25565", "b:25565"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var health backendHealth
+			for _, addr := range tc.down {
+				health.MarkDown(addr, time.Minute)
+			}
+
+			var next uint32
+			selector := roundRobinSelector{next: &next}
+			for i, want := range tc.want {
+				got := selector.Select(backends, health.IsUp)
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("connection %d: got: %v; want: %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWeightedSelector_Select(t *testing.T) {
+	backends := []string{"a:25565", "b:25565", "c:25565"}
+
+	tt := []struct {
+		name    string
+		weights map[string]int
+		down    []string
+		want    map[string]int
+	}{
+		{
+			name:    "Weighted",
+			weights: map[string]int{"a:25565": 3, "b:25565": 1, "c:25565": 0},
+			want:    map[string]int{"a:25565": 3000, "b:25565": 1000},
+		},
+		{
+			name:    "DefaultWeight",
+			weights: map[string]int{"a:25565": 2},
+			want:    map[string]int{"a:25565": 2000, "b:25565": 1000, "c:25565": 1000},
+		},
+		{
+			name:    "HeaviestDown",
+			weights: map[string]int{"a:25565": 3, "b:25565": 1, "c:25565": 1},
+			down:    []string{"a:25565"},
+			want:    map[string]int{"b:25565": 2000, "c:25565": 2000},
+		},
+		{
+			name:    "WeightedDown",
+			weights: map[string]int{"a:25565": 3, "b:25565": 0, "c:25565": 0},
+			down:    []string{"a:25565"},
+			want:    map[string]int{"b:25565": 4000},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var health backendHealth
+			for _, addr := range tc.down {
+				health.MarkDown(addr, time.Minute)
+			}
+
+			selector := weightedSelector{
+				balancer: &weightedBalancer{},
+				weights:  tc.weights,
+			}
+			got := map[string]int{}
+			for i := 0; i < 4000; i++ {
+				selected := selector.Select(backends, health.IsUp)
+				if len(selected) != len(backends) {
+					t.Fatalf("got: %v; want all backends", selected)
+				}
+				got[selected[0]]++
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProxyConfig_LoadFromPath_BackendWeights(t *testing.T) {
+	dir, err := ioutil.TempDir("", "infrared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "proxy.json")
+	if err := ioutil.WriteFile(path, []byte(`{"backendWeights": {"a:25565": 3, "b:25565": 1}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg ProxyConfig
+	if err := cfg.LoadFromPath(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"backendWeights": {"a:25565": 2}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.LoadFromPath(path); err != nil {
+		t.Fatal(err)
+	}
+
+	proxy := &Proxy{Config: &cfg}
+	want := map[string]int{"a:25565": 2}
+	if got := proxy.BackendWeights(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v; want: %v", got, want)
+	}
+}
+
+func TestWeightedBalancer_Interleaves(t *testing.T) {
+	weights := map[string]int{"a": 3, "b": 1}
+	var balancer weightedBalancer
+
+	var got []string
+	for i := 0; i < 8; i++ {
+		got = append(got, balancer.pick([]string{"a", "b"}, func(addr string) int { return weights[addr] }))
+	}
+
+	want := []string{"a", "a", "b", "a", "a", "a", "b", "a"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v; want: %v", got, want)
+	}
+}
+
+func TestStickySelector_Select(t *testing.T) {
+	backends := []string{"a:25565", "b:25565", "c:25565"}
+	var health backendHealth
+
+	counts := map[string]int{}
+	for i := 0; i < 3000; i++ {
+		selector := stickySelector{key: fmt.Sprintf("player-%d", i)}
+		first := selector.Select(backends, health.IsUp)
+		if again := selector.Select(backends, health.IsUp); !reflect.DeepEqual(again, first) {
+			t.Fatalf("got: %v; want: %v", again, first)
+		}
+		counts[first[0]]++
+	}
+
+	// Every backend should get roughly a third of the players
+	for _, addr := range backends {
+		if counts[addr] < 800 || counts[addr] > 1200 {
+			t.Errorf("got: %d players on %s; want about 1000", counts[addr], addr)
+		}
+	}
+}
+
+func TestStickySelector_SelectDown(t *testing.T) {
+	backends := []string{"a:25565", "b:25565", "c:25565"}
+	selector := stickySelector{key: "player"}
+
+	var health backendHealth
+	order := selector.Select(backends, health.IsUp)
+
+	health.MarkDown(order[0], time.Minute)
+	want := []string{order[1], order[2], order[0]}
+	if got := selector.Select(backends, health.IsUp); !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v; want: %v", got, want)
+	}
+}
+
+func TestStickySelector_AddBackend(t *testing.T) {
+	backends := []string{"a:25565", "b:25565", "c:25565"}
+	added := append(backends, "d:25565")
+	var health backendHealth
+
+	moved := 0
+	const players = 4000
+	for i := 0; i < players; i++ {
+		selector := stickySelector{key: fmt.Sprintf("player-%d", i)}
+		before := selector.Select(backends, health.IsUp)[0]
+		after := selector.Select(added, health.IsUp)[0]
+		if before == after {
+			continue
+		}
+		if after != "d:25565" {
+			t.Fatalf("got: player moved from %s to %s; want: only moves to the new backend", before, after)
+		}
+		moved++
+	}
+
+	// The new backend should take over roughly a quarter of the players
+	if moved < players/5 || moved > players*3/10 {
+		t.Errorf("got: %d moved players; want about %d", moved, players/4)
+	}
+}
+
+func TestStickyKey(t *testing.T) {
+	remoteAddr := &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 50000}
+	uuid := protocol.UUID{0x01, 0x02}
+
+	tt := []struct {
+		name       string
+		loginStart login.ServerLoginStart
+		want       string
+	}{
+		{
+			name:       "PlayerUUID",
+			loginStart: login.ServerLoginStart{Name: "Steve", HasPlayerUUID: true, PlayerUUID: uuid},
+			want:       uuid.String(),
+		},
+		{
+			name:       "OfflinePlayerUUID",
+			loginStart: login.ServerLoginStart{Name: "Steve"},
+			want:       offlinePlayerUUID("Steve").String(),
+		},
+		{
+			name: "IP",
+			want: "203.0.113.7",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := stickyKey(remoteAddr, tc.loginStart); got != tc.want {
+				t.Errorf("got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestBackendHealth(t *testing.T) {
+	var health backendHealth
+	if !health.IsUp("a") {
+		t.Error("got: unknown backend down; want: up")
+	}
+
+	health.MarkDown("a", 10*time.Millisecond)
+	if health.IsUp("a") {
+		t.Error("got: up after failure; want: down")
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if !health.IsUp("a") {
+		t.Error("got: down after retry interval; want: up")
+	}
+
+	health.MarkDown("a", time.Minute)
+	health.MarkUp("a")
+	if !health.IsUp("a") {
+		t.Error("got: down after success; want: up")
+	}
+}
+
+func TestProxy_DialBackend_Failover(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	dead, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := dead.Addr().String()
+	dead.Close()
+
+	secondary := listener.Addr().String()
+	proxy := &Proxy{
+		Config: &ProxyConfig{
+			Backends: []string{deadAddr, secondary},
+			Timeout:  1000,
+		},
+	}
+
+	backends, _ := proxy.backendsFor(handshaking.ServerBoundHandshake{ServerAddress: "infrared"}, nil, login.ServerLoginStart{})
+	rconn, addr, err := proxy.dialBackend(backends)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rconn.Close()
+
+	if addr != secondary {
+		t.Errorf("got: %v; want: %v", addr, secondary)
+	}
+
+	// The dead primary is only tried after the secondary now
+	backends, _ = proxy.backendsFor(handshaking.ServerBoundHandshake{ServerAddress: "infrared"}, nil, login.ServerLoginStart{})
+	want := []string{secondary, deadAddr}
+	if !reflect.DeepEqual(backends, want) {
+		t.Errorf("got: %v; want: %v", backends, want)
+	}
+}
+
+func TestProxy_DialBackendWithRetries(t *testing.T) {
+	tt := []struct {
+		name      string
+		retries   int
+		connected bool
+	}{
+		{
+			name:      "Retries",
+			retries:   10,
+			connected: true,
+		},
+		{
+			name:      "NoRetries",
+			retries:   0,
+			connected: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reserve an address that is not bound yet, like the one of a restarting server
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr := l.Addr().String()
+			l.Close()
+
+			started := make(chan net.Listener, 1)
+			go func() {
+				time.Sleep(50 * time.Millisecond)
+				l, err := net.Listen("tcp", addr)
+				if err != nil {
+					started <- nil
+					return
+				}
+				started <- l
+			}()
+			defer func() {
+				if l := <-started; l != nil {
+					l.Close()
+				}
+			}()
+
+			proxy := &Proxy{
+				Config: &ProxyConfig{
+					DialRetries:    tc.retries,
+					DialRetryDelay: 20,
+				},
+			}
+			rconn, _, err := proxy.dialBackendWithRetries([]string{addr})
+			if connected := err == nil; connected != tc.connected {
+				t.Fatalf("got: %v; want connected: %v", err, tc.connected)
+			}
+			if rconn != nil {
+				rconn.Close()
+			}
+		})
+	}
+}
+
+func TestProxy_BackendsFor_StatusProxyTo(t *testing.T) {
+	tt := []struct {
+		name          string
+		statusProxyTo string
+		nextState     protocol.Byte
+		want          []string
+	}{
+		{
+			name:          "Status",
+			statusProxyTo: "localhost:25570",
+			nextState:     handshaking.ServerBoundHandshakeStatusState,
+			want:          []string{"localhost:25570"},
+		},
+		{
+			name:          "Login",
+			statusProxyTo: "localhost:25570",
+			nextState:     handshaking.ServerBoundHandshakeLoginState,
+			want:          []string{"localhost:25566"},
+		},
+		{
+			name:      "NoStatusProxyTo",
+			nextState: handshaking.ServerBoundHandshakeStatusState,
+			want:      []string{"localhost:25566"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{
+				Config: &ProxyConfig{
+					ProxyTo:       "localhost:25566",
+					StatusProxyTo: tc.statusProxyTo,
+				},
+			}
+
+			hs := handshaking.ServerBoundHandshake{ServerAddress: "infrared", NextState: tc.nextState}
+			if got, _ := proxy.backendsFor(hs, nil, login.ServerLoginStart{}); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
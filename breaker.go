@@ -0,0 +1,119 @@
+package infrared
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+const defaultBreakerCooldown = 30000
+
+// circuitBreaker counts consecutive failures and opens after a threshold
+// of them. While it is open, callers skip the failing operation until the
+// cooldown is over. The first failure after the cooldown opens it again.
+// It is safe for concurrent use.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// Allow reports whether the operation should be tried
+func (b *circuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !time.Now().Before(b.openUntil)
+}
+
+// Success closes the breaker and reports whether it was open before
+func (b *circuitBreaker) Success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasOpen := !b.openUntil.IsZero()
+	b.failures = 0
+	b.openUntil = time.Time{}
+	return wasOpen
+}
+
+// Failure counts a failure and reports whether it opened the breaker
+func (b *circuitBreaker) Failure(threshold int, cooldown time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if threshold <= 0 || b.failures < threshold {
+		return false
+	}
+	b.openUntil = time.Now().Add(cooldown)
+	return true
+}
+
+func (proxy *Proxy) StatusBreaker() BreakerConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.StatusBreaker
+}
+
+// allowStatusDial reports whether a status request should be passed through
+// to the server or answered with the offline status right away
+func (proxy *Proxy) allowStatusDial() bool {
+	if !proxy.StatusBreaker().IsEnabled() {
+		return true
+	}
+	return proxy.statusBreaker.Allow()
+}
+
+// recordDial reports the result of a dial to the server to the status breaker
+func (proxy *Proxy) recordDial(err error) {
+	cfg := proxy.StatusBreaker()
+	if !cfg.IsEnabled() {
+		return
+	}
+
+	if err == nil {
+		if proxy.statusBreaker.Success() {
+			metrics.SetStatusBreakerOpen(proxy.DomainName(), false)
+		}
+		return
+	}
+
+	if proxy.statusBreaker.Failure(cfg.Threshold, cfg.CooldownDuration()) {
+		metrics.SetStatusBreakerOpen(proxy.DomainName(), true)
+	}
+}
+
+func (proxy *Proxy) LoginBreaker() BreakerConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.LoginBreaker
+}
+
+// allowLoginDial reports whether a login should be passed through to the
+// server or fail right away. After the cooldown the logins are let through
+// again until one of them fails.
+func (proxy *Proxy) allowLoginDial() bool {
+	if !proxy.LoginBreaker().IsEnabled() {
+		return true
+	}
+	return proxy.loginBreaker.Allow()
+}
+
+// recordLoginDial reports the result of a login dial to the login breaker
+func (proxy *Proxy) recordLoginDial(err error) {
+	cfg := proxy.LoginBreaker()
+	if !cfg.IsEnabled() {
+		return
+	}
+
+	if err == nil {
+		if proxy.loginBreaker.Success() {
+			log.Printf("[i] Closed the login breaker of %s", proxy.UID())
+			metrics.SetLoginBreakerOpen(proxy.DomainName(), false)
+		}
+		return
+	}
+
+	if proxy.loginBreaker.Failure(cfg.Threshold, cfg.CooldownDuration()) {
+		log.Printf("[i] Opened the login breaker of %s for %s", proxy.UID(), cfg.CooldownDuration())
+		metrics.SetLoginBreakerOpen(proxy.DomainName(), true)
+	}
+}
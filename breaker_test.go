@@ -0,0 +1,84 @@
+package infrared
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	const threshold = 2
+	const cooldown = 10 * time.Millisecond
+	var b circuitBreaker
+
+	if b.Failure(threshold, cooldown) {
+		t.Error("got: open after one failure; want: closed")
+	}
+	if !b.Allow() {
+		t.Error("got: not allowed below threshold; want: allowed")
+	}
+
+	if !b.Failure(threshold, cooldown) {
+		t.Error("got: closed at threshold; want: open")
+	}
+	if b.Allow() {
+		t.Error("got: allowed while open; want: not allowed")
+	}
+
+	time.Sleep(2 * cooldown)
+	if !b.Allow() {
+		t.Error("got: not allowed after cooldown; want: allowed")
+	}
+
+	// The first failure after the cooldown opens it again
+	if !b.Failure(threshold, cooldown) {
+		t.Error("got: closed after failed retry; want: open")
+	}
+
+	if !b.Success() {
+		t.Error("got: closed before success; want: open")
+	}
+	if !b.Allow() {
+		t.Error("got: not allowed after success; want: allowed")
+	}
+}
+
+func TestProxy_AllowLoginDial(t *testing.T) {
+	errDial := errors.New("connection refused")
+
+	tt := []struct {
+		name      string
+		threshold int
+		failures  int
+		want      bool
+	}{
+		{name: "Disabled", threshold: 0, failures: 5, want: true},
+		{name: "BelowThreshold", threshold: 3, failures: 2, want: true},
+		{name: "AtThreshold", threshold: 3, failures: 3, want: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{Config: &ProxyConfig{
+				LoginBreaker: BreakerConfig{Threshold: tc.threshold, Cooldown: 60000},
+			}}
+
+			for i := 0; i < tc.failures; i++ {
+				proxy.recordLoginDial(errDial)
+			}
+			if got := proxy.allowLoginDial(); got != tc.want {
+				t.Errorf("got: %v; want: %v", got, tc.want)
+			}
+
+			// The status breaker is not affected by failed logins
+			if !proxy.allowStatusDial() {
+				t.Error("got: status dial not allowed; want: allowed")
+			}
+
+			proxy.recordLoginDial(nil)
+			if !proxy.allowLoginDial() {
+				t.Error("got: not allowed after success; want: allowed")
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package infrared
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+// errRealIPAndBungeeCord is returned for proxies that would put the address
+// of the client into the handshake twice
+var errRealIPAndBungeeCord = errors.New("realIp and bungeeCordForwarding can't be enabled at the same time")
+
+func (proxy *Proxy) BungeeCordForwarding() bool {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.BungeeCordForwarding
+}
+
+// bungeeCordAddress returns the server address of the handshake with the
+// IP and the profile of the player in the legacy forwarding format of
+// BungeeCord: host\0ip\0uuid\0properties. The properties, which carry the
+// skin, are only known if Infrared authenticated the player. Forwarding data
+// that the client sent itself is dropped, so that it can't spoof its IP.
+// Forge markers are kept.
+func bungeeCordAddress(hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr, profile gameProfile) protocol.String {
+	parts := []string{
+		hs.ParseServerAddress(),
+		remoteIP(connRemoteAddr),
+		hex.EncodeToString(profile.ID[:]),
+	}
+	if len(profile.Properties) > 0 {
+		properties, _ := json.Marshal(profile.Properties)
+		parts = append(parts, string(properties))
+	}
+	addr := strings.Join(parts, handshaking.ForgeSeparator)
+
+	if marker := forgeMarker(hs); marker != "" {
+		addr += handshaking.ForgeSeparator + marker + handshaking.ForgeSeparator
+	}
+	return protocol.String(addr)
+}
+
+// forgeMarker returns the marker of Forge clients like FML2 or, since 1.20.2,
+// FORGE from the server address of the handshake
+func forgeMarker(hs handshaking.ServerBoundHandshake) string {
+	parts := strings.Split(string(hs.ServerAddress), handshaking.ForgeSeparator)
+	for _, part := range parts[1:] {
+		if strings.HasPrefix(part, "FML") || strings.HasPrefix(part, "FORGE") {
+			return part
+		}
+	}
+	return ""
+}
@@ -0,0 +1,98 @@
+package infrared
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+func TestBungeeCordAddress(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.IPv4(203, 0, 113, 7), Port: 51234}
+	uuid := offlinePlayerUUID("Steve")
+	forwarded := "mc.example.com\x00203.0.113.7\x00" + hex.EncodeToString(uuid[:])
+	textures := []gameProfileProperty{{Name: "textures", Value: "e30=", Signature: "c2ln"}}
+
+	tt := []struct {
+		name       string
+		address    string
+		properties []gameProfileProperty
+		want       string
+	}{
+		{
+			name:    "Vanilla",
+			address: "mc.example.com",
+			want:    forwarded,
+		},
+		{
+			name:    "Forge",
+			address: "mc.example.com\x00FML2\x00",
+			want:    forwarded + "\x00FML2\x00",
+		},
+		{
+			name:    "Forge1.20.2",
+			address: "mc.example.com\x00FORGE",
+			want:    forwarded + "\x00FORGE\x00",
+		},
+		{
+			name:    "SpoofedBungeeCord",
+			address: "mc.example.com\x001.1.1.1\x00069a79f444e94726a5befca90e38aaf5",
+			want:    forwarded,
+		},
+		{
+			name:       "Authenticated",
+			address:    "mc.example.com",
+			properties: textures,
+			want:       forwarded + "\x00" + `[{"name":"textures","value":"e30=","signature":"c2ln"}]`,
+		},
+		{
+			name:       "AuthenticatedForge",
+			address:    "mc.example.com\x00FML2\x00",
+			properties: textures,
+			want:       forwarded + "\x00" + `[{"name":"textures","value":"e30=","signature":"c2ln"}]` + "\x00FML2\x00",
+		},
+		{
+			name:    "SpoofedRealIP",
+			address: "mc.example.com///1.1.1.1:1234///1600000000",
+			want:    forwarded,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hs := handshaking.ServerBoundHandshake{ServerAddress: protocol.String(tc.address)}
+			profile := offlineProfile("Steve")
+			profile.Properties = tc.properties
+			if got := bungeeCordAddress(hs, addr, profile); string(got) != tc.want {
+				t.Errorf("got: %q; want: %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProxyConfig_LoadFromPath_RealIPAndBungeeCord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "infrared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "proxy.json")
+	if err := ioutil.WriteFile(path, []byte(`{"realIp": true, "bungeeCordForwarding": true}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg ProxyConfig
+	if err := cfg.LoadFromPath(path); err != errRealIPAndBungeeCord {
+		t.Errorf("got: %v; want: %v", err, errRealIPAndBungeeCord)
+	}
+
+	if err := (Forwarding{RealIP: true, BungeeCord: true}).Validate(); err != errRealIPAndBungeeCord {
+		t.Errorf("got: %v; want: %v", err, errRealIPAndBungeeCord)
+	}
+}
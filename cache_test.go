@@ -0,0 +1,62 @@
+package infrared
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTTLCache_Get(t *testing.T) {
+	tt := []struct {
+		name   string
+		ttl    time.Duration
+		wait   time.Duration
+		result bool
+	}{
+		{
+			name:   "Fresh",
+			ttl:    time.Minute,
+			result: true,
+		},
+		{
+			name:   "Expired",
+			ttl:    time.Millisecond,
+			wait:   5 * time.Millisecond,
+			result: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cache := newTTLCache(10)
+			cache.Set("key", true, tc.ttl)
+			time.Sleep(tc.wait)
+
+			if _, ok := cache.Get("key"); ok != tc.result {
+				t.Errorf("got: %v; want: %v", ok, tc.result)
+			}
+		})
+	}
+}
+
+func TestTTLCache_Eviction(t *testing.T) {
+	cache := newTTLCache(2)
+	cache.Set("a", 1, time.Minute)
+	cache.Set("b", 2, time.Minute)
+	// Touch "a" so that "b" becomes the least recently used entry
+	cache.Get("a")
+	cache.Set("c", 3, time.Minute)
+
+	if cache.Len() != 2 {
+		t.Errorf("got: %d entries; want: 2", cache.Len())
+	}
+
+	if _, ok := cache.Get("b"); ok {
+		t.Error("least recently used entry was not evicted")
+	}
+
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("entry %s was evicted", key)
+		}
+	}
+}
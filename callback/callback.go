@@ -2,11 +2,31 @@ package callback
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
 	"time"
 )
 
+const (
+	// DefaultTimeout is used for every event that has no timeout configured
+	DefaultTimeout = 5 * time.Second
+	// SignatureHeader is the header that holds the signature of the body
+	SignatureHeader = "X-Infrared-Signature"
+)
+
+var (
+	// mustDeliverRetries is the number of retries of a failed must deliver event
+	mustDeliverRetries = 3
+	// mustDeliverRetryDelay is the delay before the first retry; it doubles for each further retry
+	mustDeliverRetryDelay = time.Second
+)
+
 // HTTPClient represents an interface for the Logger to log events with.
 type HTTPClient interface {
 	Do(req *http.Request) (*http.Response, error)
@@ -33,6 +53,24 @@ type Logger struct {
 
 	URL    string
 	Events []string
+	// Timeout is the time a request may take; zero means DefaultTimeout
+	Timeout time.Duration
+	// EventTimeouts overrides the Timeout for specific event types
+	EventTimeouts map[string]time.Duration
+	// MustDeliver contains the event types that are retried in the
+	// background on failure. Every other event is dropped on its
+	// first failure (fire and forget).
+	MustDeliver []string
+	// MaxRetries is the number of retries of every failed event; zero
+	// only retries the MustDeliver events
+	MaxRetries int
+	// RetryBackoff contains the delay before each retry. Its last delay is
+	// used for all further retries. If it is empty, the delay starts at one
+	// second and doubles for each retry.
+	RetryBackoff []time.Duration
+	// Secret signs the body of every request with HMAC-SHA256 if it is set.
+	// The signature is sent in the SignatureHeader as sha256=<hex>.
+	Secret string
 }
 
 func (logger Logger) isValid() bool {
@@ -51,8 +89,57 @@ func (logger Logger) hasEvent(event Event) bool {
 	return hasEvent
 }
 
+// timeout returns the time that a request for the given event may take
+func (logger Logger) timeout(event Event) time.Duration {
+	if timeout, ok := logger.EventTimeouts[event.EventType()]; ok && timeout > 0 {
+		return timeout
+	}
+
+	if logger.Timeout > 0 {
+		return logger.Timeout
+	}
+
+	return DefaultTimeout
+}
+
+// isMustDeliver checks if Logger.MustDeliver contains the given event's type.
+func (logger Logger) isMustDeliver(event Event) bool {
+	for _, e := range logger.MustDeliver {
+		if e == event.EventType() {
+			return true
+		}
+	}
+	return false
+}
+
+// retries returns the number of times a failed request for the given event is retried
+func (logger Logger) retries(event Event) int {
+	if logger.MaxRetries > 0 {
+		return logger.MaxRetries
+	}
+
+	if logger.isMustDeliver(event) {
+		return mustDeliverRetries
+	}
+
+	return 0
+}
+
+// retryDelay returns the delay before the retry with the given index
+func (logger Logger) retryDelay(retry int) time.Duration {
+	if len(logger.RetryBackoff) == 0 {
+		return mustDeliverRetryDelay << uint(retry)
+	}
+
+	if retry >= len(logger.RetryBackoff) {
+		retry = len(logger.RetryBackoff) - 1
+	}
+	return logger.RetryBackoff[retry]
+}
+
 // LogEvent posts the given event to an http endpoint if the Logger
 // holds a valid URL and the Logger.Events contains given event's type.
+// If the request fails and the event has retries, it is retried in the background.
 func (logger Logger) LogEvent(event Event) (*EventLog, error) {
 	if logger.client == nil {
 		logger.client = http.DefaultClient
@@ -72,15 +159,64 @@ func (logger Logger) LogEvent(event Event) (*EventLog, error) {
 		return nil, err
 	}
 
-	request, err := http.NewRequest(http.MethodPost, logger.URL, bytes.NewReader(bb))
-	if err != nil {
+	timeout := logger.timeout(event)
+	if err := logger.post(bb, timeout); err != nil {
+		if retries := logger.retries(event); retries > 0 {
+			go logger.retry(event.EventType(), bb, timeout, retries)
+		}
 		return nil, err
 	}
 
-	_, err = logger.client.Do(request)
+	return &eventLog, nil
+}
+
+// post sends the body to the Logger.URL and fails if the request takes longer than timeout
+func (logger Logger) post(body []byte, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, logger.URL, bytes.NewReader(body))
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &eventLog, nil
+	if logger.Secret != "" {
+		request.Header.Set(SignatureHeader, Sign(logger.Secret, body))
+	}
+
+	response, err := logger.client.Do(request)
+	if err != nil {
+		return err
+	}
+
+	if response == nil {
+		return nil
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("callback server responded with %s", response.Status)
+	}
+
+	return nil
+}
+
+// Sign returns the HMAC-SHA256 of the body with the secret in the format of
+// the SignatureHeader
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// retry posts the body again until it succeeds or runs out of retries
+func (logger Logger) retry(eventType string, body []byte, timeout time.Duration, retries int) {
+	var err error
+	for i := 0; i < retries; i++ {
+		time.Sleep(logger.retryDelay(i))
+		if err = logger.post(body, timeout); err == nil {
+			return
+		}
+	}
+	log.Printf("[x] Dropping %s event after %d failed retries; error: %s", eventType, retries, err)
 }
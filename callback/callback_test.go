@@ -2,9 +2,16 @@ package callback
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"io/ioutil"
 	"net/http"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestLogger_IsValid(t *testing.T) {
@@ -157,3 +164,278 @@ func TestLogger_LogEvent(t *testing.T) {
 		}
 	}
 }
+
+func TestLogger_Timeout(t *testing.T) {
+	tt := []struct {
+		logger  Logger
+		event   Event
+		timeout time.Duration
+	}{
+		{
+			logger:  Logger{},
+			event:   ErrorEvent{},
+			timeout: DefaultTimeout,
+		},
+		{
+			logger: Logger{
+				Timeout: time.Second,
+			},
+			event:   ErrorEvent{},
+			timeout: time.Second,
+		},
+		{
+			logger: Logger{
+				Timeout:       time.Second,
+				EventTimeouts: map[string]time.Duration{EventTypeError: time.Minute},
+			},
+			event:   ErrorEvent{},
+			timeout: time.Minute,
+		},
+		{
+			logger: Logger{
+				Timeout:       time.Second,
+				EventTimeouts: map[string]time.Duration{EventTypeError: time.Minute},
+			},
+			event:   PlayerJoinEvent{},
+			timeout: time.Second,
+		},
+	}
+
+	for _, tc := range tt {
+		if timeout := tc.logger.timeout(tc.event); timeout != tc.timeout {
+			t.Errorf("got: %v; want: %v", timeout, tc.timeout)
+		}
+	}
+}
+
+type failingHTTPClient struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	done     chan bool
+}
+
+func (mock *failingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	mock.attempts++
+	if mock.attempts <= mock.failures {
+		return nil, errors.New("callback server is down")
+	}
+	mock.done <- true
+	return nil, nil
+}
+
+func TestLogger_LogEvent_MustDeliver(t *testing.T) {
+	mustDeliverRetryDelay = time.Millisecond
+
+	tt := []struct {
+		name        string
+		mustDeliver []string
+		maxRetries  int
+		delivered   bool
+	}{
+		{
+			name:        "MustDeliver",
+			mustDeliver: []string{EventTypeError},
+			delivered:   true,
+		},
+		{
+			name:      "FireAndForget",
+			delivered: false,
+		},
+		{
+			name:       "MaxRetries",
+			maxRetries: 2,
+			delivered:  true,
+		},
+		{
+			name:       "TooFewRetries",
+			maxRetries: 1,
+			delivered:  false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &failingHTTPClient{
+				failures: 2,
+				done:     make(chan bool, 1),
+			}
+			logger := Logger{
+				client:      client,
+				URL:         "https://example.com",
+				Events:      []string{EventTypeError},
+				MustDeliver: tc.mustDeliver,
+				MaxRetries:  tc.maxRetries,
+			}
+
+			if _, err := logger.LogEvent(ErrorEvent{}); err == nil {
+				t.Error("first attempt should fail")
+			}
+
+			select {
+			case <-client.done:
+				if !tc.delivered {
+					t.Error("fire and forget event was retried")
+				}
+			case <-time.After(100 * time.Millisecond):
+				if tc.delivered {
+					t.Error("must deliver event was not retried")
+				}
+			}
+		})
+	}
+}
+
+func TestLogger_Retries(t *testing.T) {
+	tt := []struct {
+		logger  Logger
+		event   Event
+		retries int
+	}{
+		{
+			logger:  Logger{},
+			event:   ErrorEvent{},
+			retries: 0,
+		},
+		{
+			logger: Logger{
+				MustDeliver: []string{EventTypeError},
+			},
+			event:   ErrorEvent{},
+			retries: mustDeliverRetries,
+		},
+		{
+			logger: Logger{
+				MustDeliver: []string{EventTypeError},
+				MaxRetries:  5,
+			},
+			event:   ErrorEvent{},
+			retries: 5,
+		},
+		{
+			logger: Logger{
+				MaxRetries: 5,
+			},
+			event:   PlayerJoinEvent{},
+			retries: 5,
+		},
+	}
+
+	for _, tc := range tt {
+		if retries := tc.logger.retries(tc.event); retries != tc.retries {
+			t.Errorf("got: %v; want: %v", retries, tc.retries)
+		}
+	}
+}
+
+func TestLogger_RetryDelay(t *testing.T) {
+	mustDeliverRetryDelay = time.Second
+
+	tt := []struct {
+		logger Logger
+		retry  int
+		delay  time.Duration
+	}{
+		{
+			logger: Logger{},
+			retry:  0,
+			delay:  time.Second,
+		},
+		{
+			logger: Logger{},
+			retry:  2,
+			delay:  4 * time.Second,
+		},
+		{
+			logger: Logger{
+				RetryBackoff: []time.Duration{time.Second, 5 * time.Second},
+			},
+			retry: 1,
+			delay: 5 * time.Second,
+		},
+		{
+			logger: Logger{
+				RetryBackoff: []time.Duration{time.Second, 5 * time.Second},
+			},
+			retry: 4,
+			delay: 5 * time.Second,
+		},
+	}
+
+	for _, tc := range tt {
+		if delay := tc.logger.retryDelay(tc.retry); delay != tc.delay {
+			t.Errorf("got: %v; want: %v", delay, tc.delay)
+		}
+	}
+}
+
+type headerHTTPClient struct {
+	header http.Header
+	body   []byte
+}
+
+func (mock *headerHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	mock.header = req.Header
+	body, err := ioutil.ReadAll(req.Body)
+	mock.body = body
+	return nil, err
+}
+
+func TestLogger_LogEvent_Signature(t *testing.T) {
+	tt := []struct {
+		name   string
+		secret string
+		signed bool
+	}{
+		{
+			name:   "Secret",
+			secret: "my secret",
+			signed: true,
+		},
+		{
+			name:   "NoSecret",
+			signed: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &headerHTTPClient{}
+			logger := Logger{
+				client: client,
+				URL:    "https://example.com",
+				Events: []string{EventTypeError},
+				Secret: tc.secret,
+			}
+
+			if _, err := logger.LogEvent(ErrorEvent{Error: "my error message"}); err != nil {
+				t.Fatal(err)
+			}
+
+			signature := client.header.Get(SignatureHeader)
+			if !tc.signed {
+				if signature != "" {
+					t.Errorf("got: %v; want no signature", signature)
+				}
+				return
+			}
+
+			mac := hmac.New(sha256.New, []byte(tc.secret))
+			mac.Write(client.body)
+			want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+			if signature != want {
+				t.Errorf("got: %v; want: %v", signature, want)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	// Computed with: printf 'body' | openssl dgst -sha256 -hmac secret
+	want := "sha256=dc46983557fea127b43af721467eb9b3fde2338fe3e14f51952aa8478c13d355"
+	if got := Sign("secret", []byte("body")); got != want {
+		t.Errorf("got: %v; want: %v", got, want)
+	}
+}
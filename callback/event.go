@@ -6,6 +6,10 @@ const (
 	EventTypePlayerLeave    string = "PlayerLeave"
 	EventTypeContainerStart string = "ContainerStart"
 	EventTypeContainerStop  string = "ContainerStop"
+	EventTypeBackendOnline  string = "BackendOnline"
+	EventTypeBackendOffline string = "BackendOffline"
+
+	EventTypeProxyProtocolError string = "ProxyProtocolError"
 )
 
 type Event interface {
@@ -26,6 +30,14 @@ type PlayerJoinEvent struct {
 	RemoteAddress string `json:"remoteAddress"`
 	TargetAddress string `json:"targetAddress"`
 	ProxyUID      string `json:"proxyUid"`
+	// PlayerUUID is only set for clients since 1.20.2, which send it
+	PlayerUUID string `json:"playerUuid,omitempty"`
+	// Locale and Brand are only set if the proxy captures the client info
+	Locale string `json:"locale,omitempty"`
+	Brand  string `json:"brand,omitempty"`
+	// ProxyProtocolTLVs are the TLVs of the PROXY protocol header that the
+	// connection was received with
+	ProxyProtocolTLVs map[string]string `json:"proxyProtocolTlvs,omitempty"`
 }
 
 func (event PlayerJoinEvent) EventType() string {
@@ -37,6 +49,16 @@ type PlayerLeaveEvent struct {
 	RemoteAddress string `json:"remoteAddress"`
 	TargetAddress string `json:"targetAddress"`
 	ProxyUID      string `json:"proxyUid"`
+	// PlayerUUID is only set for clients since 1.20.2, which send it
+	PlayerUUID string `json:"playerUuid,omitempty"`
+	// BytesSent and BytesReceived are seen from the player
+	BytesSent     int64 `json:"bytesSent"`
+	BytesReceived int64 `json:"bytesReceived"`
+	// Duration is the time in milliseconds that the player was connected
+	Duration int64 `json:"duration"`
+	// ProxyProtocolTLVs are the TLVs of the PROXY protocol header that the
+	// connection was received with
+	ProxyProtocolTLVs map[string]string `json:"proxyProtocolTlvs,omitempty"`
 }
 
 func (event PlayerLeaveEvent) EventType() string {
@@ -58,3 +80,34 @@ type ContainerStopEvent struct {
 func (event ContainerStopEvent) EventType() string {
 	return EventTypeContainerStop
 }
+
+// BackendOnlineEvent is sent when a backend passes its health check again
+type BackendOnlineEvent struct {
+	Backend  string `json:"backend"`
+	ProxyUID string `json:"proxyUid"`
+}
+
+func (event BackendOnlineEvent) EventType() string {
+	return EventTypeBackendOnline
+}
+
+// BackendOfflineEvent is sent when a backend became unhealthy
+type BackendOfflineEvent struct {
+	Backend  string `json:"backend"`
+	ProxyUID string `json:"proxyUid"`
+}
+
+func (event BackendOfflineEvent) EventType() string {
+	return EventTypeBackendOffline
+}
+
+type ProxyProtocolErrorEvent struct {
+	Error         string `json:"error"`
+	Reason        string `json:"reason"`
+	RemoteAddress string `json:"remoteAddress"`
+	ListenTo      string `json:"listenTo"`
+}
+
+func (event ProxyProtocolErrorEvent) EventType() string {
+	return EventTypeProxyProtocolError
+}
@@ -27,6 +27,14 @@ func TestErrorEvent_EventType(t *testing.T) {
 			event:     ContainerStopEvent{},
 			eventType: EventTypeContainerStop,
 		},
+		{
+			event:     BackendOnlineEvent{},
+			eventType: EventTypeBackendOnline,
+		},
+		{
+			event:     BackendOfflineEvent{},
+			eventType: EventTypeBackendOffline,
+		},
 	}
 
 	for _, tc := range tt {
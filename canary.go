@@ -0,0 +1,47 @@
+package infrared
+
+import (
+	"hash/fnv"
+	"math/rand"
+
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+func (proxy *Proxy) Canary() CanaryConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.Canary
+}
+
+// canarySelector puts the canary in front of the backends of another
+// selector, so that they are only used while the canary is down
+type canarySelector struct {
+	BackendSelector
+	canary string
+}
+
+func (s canarySelector) Select(backends []string, isUp func(addr string) bool) []string {
+	return append([]string{s.canary}, s.BackendSelector.Select(backends, isUp)...)
+}
+
+// isCanary reports whether the player of loginStart is routed to the
+// canary. Unless the canary is random, a player always gets the same
+// variant, since the decision is made by a hash of their UUID.
+func (cfg CanaryConfig) isCanary(loginStart login.ServerLoginStart) bool {
+	if !cfg.IsEnabled() {
+		return false
+	}
+
+	if cfg.Random {
+		return rand.Intn(100) < cfg.Percentage
+	}
+
+	playerUUID := loginStart.PlayerUUID
+	if !loginStart.HasPlayerUUID {
+		playerUUID = offlinePlayerUUID(string(loginStart.Name))
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write(playerUUID[:])
+	return int(h.Sum32()%100) < cfg.Percentage
+}
@@ -0,0 +1,86 @@
+package infrared
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+func TestCanaryConfig_IsCanary(t *testing.T) {
+	const players = 10000
+
+	for _, percentage := range []int{0, 10, 50, 100} {
+		t.Run(fmt.Sprintf("Percentage%d", percentage), func(t *testing.T) {
+			cfg := CanaryConfig{
+				Backend:    "canary:25565",
+				Percentage: percentage,
+			}
+
+			var canaries int
+			for i := 0; i < players; i++ {
+				loginStart := login.ServerLoginStart{
+					Name: protocol.String(fmt.Sprintf("Player%d", i)),
+				}
+
+				isCanary := cfg.isCanary(loginStart)
+				if isCanary != cfg.isCanary(loginStart) {
+					t.Fatalf("got: different variants for %s; want: the same", loginStart.Name)
+				}
+				if isCanary {
+					canaries++
+				}
+			}
+
+			// Allow the hash to deviate by two percent
+			want := players * percentage / 100
+			if canaries < want-players/50 || canaries > want+players/50 {
+				t.Errorf("got: %d canaries; want: about %d", canaries, want)
+			}
+		})
+	}
+}
+
+func TestProxy_BackendsFor_Canary(t *testing.T) {
+	proxy := &Proxy{
+		Config: &ProxyConfig{
+			Backends: []string{"stable:25565"},
+			Canary: CanaryConfig{
+				Backend:    "canary:25565",
+				Percentage: 100,
+			},
+		},
+	}
+
+	tt := []struct {
+		name  string
+		state protocol.Byte
+		want  []string
+	}{
+		{
+			name:  "Login",
+			state: handshaking.ServerBoundHandshakeLoginState,
+			want:  []string{"canary:25565", "stable:25565"},
+		},
+		{
+			name:  "Status",
+			state: handshaking.ServerBoundHandshakeStatusState,
+			want:  []string{"stable:25565"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hs := handshaking.ServerBoundHandshake{NextState: tc.state}
+			loginStart := login.ServerLoginStart{Name: "Steve"}
+
+			got, _ := proxy.backendsFor(hs, nil, loginStart)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
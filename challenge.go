@@ -0,0 +1,85 @@
+package infrared
+
+import (
+	"net"
+	"time"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+const (
+	defaultChallengeWindow     = 30000
+	defaultChallengeMaxEntries = 10000
+	defaultChallengeMessage    = "Please reconnect to join the server."
+)
+
+func (proxy *Proxy) Challenge() ChallengeConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.Challenge
+}
+
+// isChallenged reports whether the client has to be challenged before its
+// connection is proxied. The first attempt of an unseen IP is challenged and
+// every further attempt within the challenge window passes. Status requests
+// and logins are tracked apart, so that pinging does not pass the challenge
+// of a login and a ping flood does not evict the IPs of players.
+func (proxy *Proxy) isChallenged(hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr) bool {
+	cfg := proxy.Challenge()
+	if hs.IsStatusRequest() && !cfg.Status {
+		return false
+	}
+	if hs.IsLoginRequest() && !cfg.Login {
+		return false
+	}
+
+	window := time.Millisecond * time.Duration(cfg.Window)
+	if window <= 0 {
+		window = time.Millisecond * defaultChallengeWindow
+	}
+
+	cache := proxy.challengeCache(hs, cfg.MaxEntries)
+	ip := remoteIP(connRemoteAddr)
+	_, seen := cache.Get(ip)
+	cache.Set(ip, true, window)
+	return !seen
+}
+
+// challengeCache returns the seen IPs of the next state of hs
+func (proxy *Proxy) challengeCache(hs handshaking.ServerBoundHandshake, maxEntries int) *ttlCache {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	cache := &proxy.challengedLogin
+	if hs.IsStatusRequest() {
+		cache = &proxy.challengedStatus
+	}
+	if *cache == nil {
+		if maxEntries <= 0 {
+			maxEntries = defaultChallengeMaxEntries
+		}
+		*cache = newTTLCache(maxEntries)
+	}
+	return *cache
+}
+
+func (proxy *Proxy) handleChallenge(conn Conn, hs handshaking.ServerBoundHandshake) error {
+	if !hs.IsLoginRequest() {
+		// Status requests are answered by closing the connection
+		return nil
+	}
+
+	message := proxy.Challenge().Message
+	if message == "" {
+		message = defaultChallengeMessage
+	}
+	return conn.WritePacket(disconnectPacket(message))
+}
+
+// remoteIP returns the IP of addr without the port
+func remoteIP(addr net.Addr) string {
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
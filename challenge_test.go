@@ -0,0 +1,170 @@
+package infrared
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+func TestProxy_IsChallenged(t *testing.T) {
+	loginHs := handshaking.ServerBoundHandshake{NextState: handshaking.ServerBoundHandshakeLoginState}
+	statusHs := handshaking.ServerBoundHandshake{NextState: handshaking.ServerBoundHandshakeStatusState}
+	addr := &net.TCPAddr{IP: net.IPv4(203, 0, 113, 1), Port: 50000}
+	rejoinAddr := &net.TCPAddr{IP: net.IPv4(203, 0, 113, 1), Port: 50001}
+	otherAddr := &net.TCPAddr{IP: net.IPv4(203, 0, 113, 2), Port: 50000}
+
+	type attempt struct {
+		hs         handshaking.ServerBoundHandshake
+		addr       net.Addr
+		wait       time.Duration
+		challenged bool
+	}
+
+	tt := []struct {
+		name      string
+		challenge ChallengeConfig
+		attempts  []attempt
+	}{
+		{
+			name:      "FirstJoin",
+			challenge: ChallengeConfig{Login: true, Window: 1000},
+			attempts: []attempt{
+				{hs: loginHs, addr: addr, challenged: true},
+			},
+		},
+		{
+			name:      "RejoinWithinWindow",
+			challenge: ChallengeConfig{Login: true, Window: 1000},
+			attempts: []attempt{
+				{hs: loginHs, addr: addr, challenged: true},
+				{hs: loginHs, addr: rejoinAddr, challenged: false},
+				{hs: loginHs, addr: addr, challenged: false},
+			},
+		},
+		{
+			name:      "RejoinAfterWindow",
+			challenge: ChallengeConfig{Login: true, Window: 10},
+			attempts: []attempt{
+				{hs: loginHs, addr: addr, challenged: true},
+				{hs: loginHs, addr: addr, wait: 30 * time.Millisecond, challenged: true},
+			},
+		},
+		{
+			name:      "OtherIP",
+			challenge: ChallengeConfig{Login: true, Window: 1000},
+			attempts: []attempt{
+				{hs: loginHs, addr: addr, challenged: true},
+				{hs: loginHs, addr: otherAddr, challenged: true},
+			},
+		},
+		{
+			name:      "StatusNotChallenged",
+			challenge: ChallengeConfig{Login: true, Window: 1000},
+			attempts: []attempt{
+				{hs: statusHs, addr: addr, challenged: false},
+				{hs: loginHs, addr: addr, challenged: true},
+			},
+		},
+		{
+			name:      "StatusDoesNotPassLogin",
+			challenge: ChallengeConfig{Status: true, Login: true, Window: 1000},
+			attempts: []attempt{
+				{hs: statusHs, addr: addr, challenged: true},
+				{hs: statusHs, addr: addr, challenged: false},
+				{hs: loginHs, addr: addr, challenged: true},
+				{hs: loginHs, addr: addr, challenged: false},
+			},
+		},
+		{
+			name:      "StatusFloodKeepsLogins",
+			challenge: ChallengeConfig{Status: true, Login: true, Window: 1000, MaxEntries: 2},
+			attempts: []attempt{
+				{hs: loginHs, addr: addr, challenged: true},
+				{hs: statusHs, addr: otherAddr, challenged: true},
+				{hs: statusHs, addr: &net.TCPAddr{IP: net.IPv4(203, 0, 113, 3)}, challenged: true},
+				{hs: statusHs, addr: &net.TCPAddr{IP: net.IPv4(203, 0, 113, 4)}, challenged: true},
+				{hs: loginHs, addr: addr, challenged: false},
+			},
+		},
+		{
+			name:      "StatusChallenged",
+			challenge: ChallengeConfig{Status: true, Window: 1000},
+			attempts: []attempt{
+				{hs: statusHs, addr: addr, challenged: true},
+				{hs: statusHs, addr: addr, challenged: false},
+				{hs: loginHs, addr: otherAddr, challenged: false},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{
+				Config: &ProxyConfig{
+					DomainName: "mc.example.com",
+					Challenge:  tc.challenge,
+				},
+			}
+
+			for i, a := range tc.attempts {
+				time.Sleep(a.wait)
+				if challenged := proxy.isChallenged(a.hs, a.addr); challenged != a.challenged {
+					t.Errorf("attempt %d: got: %v; want: %v", i, challenged, a.challenged)
+				}
+			}
+		})
+	}
+}
+
+func TestProxy_HandleChallenge(t *testing.T) {
+	tt := []struct {
+		name    string
+		message string
+		want    string
+	}{
+		{
+			name: "DefaultMessage",
+			want: defaultChallengeMessage,
+		},
+		{
+			name:    "CustomMessage",
+			message: "Reconnect to verify",
+			want:    "Reconnect to verify",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{
+				Config: &ProxyConfig{
+					DomainName: "mc.example.com",
+					Challenge:  ChallengeConfig{Login: true, Message: tc.message},
+				},
+			}
+
+			c1, c2 := net.Pipe()
+			defer c1.Close()
+			defer c2.Close()
+			received := make(chan protocol.Packet, 1)
+			go func() {
+				pk, _ := wrapConn(c1).ReadPacket()
+				received <- pk
+			}()
+
+			hs := handshaking.ServerBoundHandshake{NextState: handshaking.ServerBoundHandshakeLoginState}
+			if err := proxy.handleChallenge(wrapConn(c2), hs); err != nil {
+				t.Fatal(err)
+			}
+
+			pk := <-received
+			want := disconnectPacket(tc.want)
+			if pk.ID != want.ID || !bytes.Equal(pk.Data, want.Data) {
+				t.Errorf("got: %v; want: %v", pk, want)
+			}
+		})
+	}
+}
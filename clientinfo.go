@@ -0,0 +1,178 @@
+package infrared
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strings"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/configuration"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+const (
+	// maxClientInfoPackets is the number of client packets that are
+	// inspected before the capture gives up
+	maxClientInfoPackets = 16
+	// maxClientInfoPacketSize is the biggest decompressed packet that
+	// is inspected
+	maxClientInfoPacketSize = 1 << 15
+	maxBrandLength          = 64
+	otherLocale             = "other"
+)
+
+// clientInfo is what a client tells about itself in the configuration state
+type clientInfo struct {
+	Locale string
+	Brand  string
+}
+
+func (info clientInfo) isComplete() bool {
+	return info.Locale != "" && info.Brand != ""
+}
+
+func (proxy *Proxy) CaptureClientInfo() bool {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.CaptureClientInfo
+}
+
+// capturesClientInfo reports whether the client info of the login is
+// captured. Only clients since 1.20.2 send it before the play state.
+func (proxy *Proxy) capturesClientInfo(hs handshaking.ServerBoundHandshake) bool {
+	return proxy.CaptureClientInfo() &&
+		hs.IsLoginRequest() &&
+		hs.ProtocolVersion >= configuration.ProtocolVersion
+}
+
+// captureClientInfo relays the first packets of the client to the server
+// untouched and reads the locale and the brand of the client out of them.
+// It returns the number of relayed bytes. The capture gives up as soon as
+// the login is encrypted, which is the case for online mode servers.
+func captureClientInfo(conn, rconn Conn, protocolVersion protocol.VarInt) (clientInfo, int64, error) {
+	var info clientInfo
+	var relayed int64
+	compressed := false
+
+	for i := 0; i < maxClientInfoPackets && !info.isComplete(); i++ {
+		data, err := protocol.ReadPacketBytes(conn.Reader())
+		if err != nil {
+			return info, relayed, err
+		}
+
+		n, err := rconn.Write(append(protocol.VarInt(len(data)).Encode(), data...))
+		relayed += int64(n)
+		countRelayed(conn, rconn, int64(n))
+		if err != nil {
+			return info, relayed, err
+		}
+
+		if i == 0 {
+			// The login acknowledgement tells if the server enabled the
+			// compression. Everything else, like an encryption response,
+			// makes the following packets unreadable.
+			switch {
+			case len(data) == 1 && data[0] == login.ServerBoundLoginAcknowledgedPacketID:
+			case len(data) == 2 && data[0] == 0x00 && data[1] == login.ServerBoundLoginAcknowledgedPacketID:
+				compressed = true
+			default:
+				return info, relayed, nil
+			}
+			continue
+		}
+
+		pk, ok := decodeClientPacket(data, compressed)
+		if !ok {
+			continue
+		}
+
+		switch pk.ID {
+		case configuration.ServerBoundClientInformationPacketID:
+			settings, err := configuration.UnmarshalServerBoundClientInformation(pk)
+			if err == nil {
+				info.Locale = strings.ToLower(string(settings.Locale))
+			}
+		case configuration.ServerBoundPluginMessagePacketID(protocolVersion):
+			msg, err := configuration.UnmarshalServerBoundPluginMessage(pk, protocolVersion)
+			if err != nil || msg.Channel != configuration.BrandChannel {
+				continue
+			}
+			if brand, err := msg.Brand(); err == nil {
+				info.Brand = truncateBrand(brand)
+			}
+		case configuration.ServerBoundAcknowledgeFinishConfigurationPacketID(protocolVersion):
+			// The client is in the play state now
+			return info, relayed, nil
+		}
+	}
+
+	return info, relayed, nil
+}
+
+// decodeClientPacket decodes the packet of a frame that was read from the
+// client. Compressed packets that are too big are not decoded.
+func decodeClientPacket(data []byte, compressed bool) (protocol.Packet, bool) {
+	if compressed {
+		r := bytes.NewReader(data)
+		var dataLength protocol.VarInt
+		if err := dataLength.Decode(r); err != nil {
+			return protocol.Packet{}, false
+		}
+		data = data[len(data)-r.Len():]
+
+		if dataLength != 0 {
+			if dataLength > maxClientInfoPacketSize {
+				return protocol.Packet{}, false
+			}
+
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return protocol.Packet{}, false
+			}
+			defer zr.Close()
+
+			data = make([]byte, dataLength)
+			if _, err := io.ReadFull(zr, data); err != nil {
+				return protocol.Packet{}, false
+			}
+		}
+	}
+
+	if len(data) < 1 {
+		return protocol.Packet{}, false
+	}
+
+	return protocol.Packet{
+		ID:   data[0],
+		Data: data[1:],
+	}, true
+}
+
+func truncateBrand(brand string) string {
+	runes := []rune(brand)
+	if len(runes) > maxBrandLength {
+		return string(runes[:maxBrandLength])
+	}
+	return brand
+}
+
+// localeLabel returns the language of locale as a metrics label, e.g. "en"
+// for "en_us", so that the label has a small number of values
+func localeLabel(locale string) string {
+	language := strings.ToLower(locale)
+	if i := strings.IndexByte(language, '_'); i >= 0 {
+		language = language[:i]
+	}
+
+	if len(language) < 2 || len(language) > 3 {
+		return otherLocale
+	}
+	for _, r := range language {
+		if r < 'a' || r > 'z' {
+			return otherLocale
+		}
+	}
+	return language
+}
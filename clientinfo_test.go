@@ -0,0 +1,148 @@
+package infrared
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+// compressedFrame encodes pk in the compressed packet format; with
+// deflate its data is compressed, otherwise it is sent below the threshold
+func compressedFrame(t *testing.T, pk protocol.Packet, deflate bool) []byte {
+	payload := append([]byte{pk.ID}, pk.Data...)
+	data := []byte{0x00}
+	if deflate {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		if _, err := zw.Write(payload); err != nil {
+			t.Fatal(err)
+		}
+		zw.Close()
+		data = append(protocol.VarInt(len(payload)).Encode(), buf.Bytes()...)
+	} else {
+		data = append(data, payload...)
+	}
+	return append(protocol.VarInt(len(data)).Encode(), data...)
+}
+
+func uncompressedFrame(pk protocol.Packet) []byte {
+	b, _ := pk.Marshal()
+	return b
+}
+
+func TestCaptureClientInfo(t *testing.T) {
+	const protocolVersion = 767 // 1.21
+
+	loginAck := protocol.Packet{ID: 0x03}
+	clientInformation := protocol.MarshalPacket(0x00, protocol.String("de_DE"), protocol.Byte(12))
+	brand := protocol.MarshalPacket(0x02, protocol.String("minecraft:brand"), protocol.String("fabric"))
+	finishAck := protocol.Packet{ID: 0x03}
+	encryptionResponse := protocol.Packet{ID: 0x01, Data: []byte{0x01, 0x02}}
+
+	tt := []struct {
+		name   string
+		frames [][]byte
+		want   clientInfo
+	}{
+		{
+			name: "Uncompressed",
+			frames: [][]byte{
+				uncompressedFrame(loginAck),
+				uncompressedFrame(clientInformation),
+				uncompressedFrame(brand),
+			},
+			want: clientInfo{Locale: "de_de", Brand: "fabric"},
+		},
+		{
+			name: "Compressed",
+			frames: [][]byte{
+				compressedFrame(t, loginAck, false),
+				compressedFrame(t, clientInformation, true),
+				compressedFrame(t, brand, false),
+			},
+			want: clientInfo{Locale: "de_de", Brand: "fabric"},
+		},
+		{
+			name: "FinishedWithoutBrand",
+			frames: [][]byte{
+				uncompressedFrame(loginAck),
+				uncompressedFrame(clientInformation),
+				uncompressedFrame(finishAck),
+			},
+			want: clientInfo{Locale: "de_de"},
+		},
+		{
+			name: "Encrypted",
+			frames: [][]byte{
+				uncompressedFrame(encryptionResponse),
+			},
+			want: clientInfo{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			client, c := net.Pipe()
+			server, rc := net.Pipe()
+			defer client.Close()
+			defer server.Close()
+			defer c.Close()
+			defer rc.Close()
+
+			var sent []byte
+			for _, frame := range tc.frames {
+				sent = append(sent, frame...)
+			}
+			go func() {
+				_, _ = client.Write(sent)
+			}()
+
+			relayedCh := make(chan []byte, 1)
+			go func() {
+				b, _ := ioutil.ReadAll(io.LimitReader(server, int64(len(sent))))
+				relayedCh <- b
+			}()
+
+			info, n, err := captureClientInfo(wrapConn(c), wrapConn(rc), protocolVersion)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if info != tc.want {
+				t.Errorf("got: %v; want: %v", info, tc.want)
+			}
+			if n != int64(len(sent)) {
+				t.Errorf("got: %d; want: %d", n, len(sent))
+			}
+			if relayed := <-relayedCh; !bytes.Equal(relayed, sent) {
+				t.Errorf("got: %v; want: %v", relayed, sent)
+			}
+		})
+	}
+}
+
+func TestLocaleLabel(t *testing.T) {
+	tt := []struct {
+		locale string
+		want   string
+	}{
+		{locale: "en_us", want: "en"},
+		{locale: "DE_de", want: "de"},
+		{locale: "fil_ph", want: "fil"},
+		{locale: "", want: "other"},
+		{locale: "lol_us_x", want: "lol"},
+		{locale: "../../", want: "other"},
+		{locale: "abcdef", want: "other"},
+	}
+
+	for _, tc := range tt {
+		if got := localeLabel(tc.locale); got != tc.want {
+			t.Errorf("got: %v; want: %v", got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package infrared
+
+import "time"
+
+// ClientTimeout returns the time that clients of the proxy get to finish
+// their handshake and login. It falls back to the timeout of the gateway.
+func (proxy *Proxy) ClientTimeout() time.Duration {
+	proxy.Config.RLock()
+	timeout := proxy.Config.ClientTimeout
+	proxy.Config.RUnlock()
+	if timeout > 0 {
+		return time.Millisecond * time.Duration(timeout)
+	}
+
+	if proxy.gateway == nil {
+		return 0
+	}
+	return proxy.gateway.ClientTimeout
+}
+
+// setClientDeadline sets the deadline of conn to timeout from now.
+// A timeout of zero keeps the current deadline.
+func setClientDeadline(conn Conn, timeout time.Duration) error {
+	if timeout <= 0 {
+		return nil
+	}
+	return conn.SetDeadline(time.Now().Add(timeout))
+}
@@ -0,0 +1,78 @@
+package infrared
+
+import (
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestProxy_ClientTimeout(t *testing.T) {
+	tt := []struct {
+		name          string
+		gateway       *Gateway
+		clientTimeout int
+		want          time.Duration
+	}{
+		{
+			name:    "GatewayDefault",
+			gateway: &Gateway{ClientTimeout: 5 * time.Second},
+			want:    5 * time.Second,
+		},
+		{
+			name:          "ProxyOverride",
+			gateway:       &Gateway{ClientTimeout: 5 * time.Second},
+			clientTimeout: 2000,
+			want:          2 * time.Second,
+		},
+		{
+			name:          "ProxyWithoutGateway",
+			clientTimeout: 2000,
+			want:          2 * time.Second,
+		},
+		{
+			name: "Disabled",
+			want: 0,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{
+				Config:  &ProxyConfig{ClientTimeout: tc.clientTimeout},
+				gateway: tc.gateway,
+			}
+
+			if got := proxy.ClientTimeout(); got != tc.want {
+				t.Errorf("got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProxy_HandleConn_ClientTimeout(t *testing.T) {
+	proxy := &Proxy{
+		Config:  &ProxyConfig{ClientTimeout: 50},
+		gateway: &Gateway{ClientTimeout: time.Hour},
+	}
+
+	c, client := net.Pipe()
+	defer c.Close()
+	defer client.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		// The client never sends its handshake
+		errCh <- proxy.handleConn(wrapConn(c), client.LocalAddr())
+	}()
+
+	select {
+	case err := <-errCh:
+		if !errors.Is(err, os.ErrDeadlineExceeded) {
+			t.Errorf("got: %v; want: %v", err, os.ErrDeadlineExceeded)
+		}
+	case <-time.After(time.Second):
+		t.Error("the timeout of the proxy was not applied")
+	}
+}
@@ -0,0 +1,30 @@
+//go:build grpc
+// +build grpc
+
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/haveachin/infrared"
+	"github.com/haveachin/infrared/grpcapi"
+)
+
+const clfGRPCBind = "grpc-bind"
+
+var grpcBind = flag.String(clfGRPCBind, "", "bind address and/or port for the gRPC API; empty disables it")
+
+func init() {
+	startGRPC = func(gateway *infrared.Gateway) {
+		if *grpcBind == "" {
+			return
+		}
+
+		go func() {
+			if err := grpcapi.ListenAndServe(gateway, configPath, *grpcBind, apiToken); err != nil {
+				log.Println("Failed serving gRPC API; error:", err)
+			}
+		}()
+	}
+}
@@ -1,13 +1,24 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"github.com/haveachin/infrared/api"
 	"log"
 	"os"
+	"os/signal"
 	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	// Embeds the time zones for the open hours of proxies on systems
+	// without a time zone database
+	_ "time/tzdata"
 
 	"github.com/haveachin/infrared"
+	"github.com/haveachin/infrared/acl"
+	"github.com/haveachin/infrared/geoip"
+	"github.com/haveachin/infrared/protocol"
 )
 
 const (
@@ -16,24 +27,108 @@ const (
 	envReceiveProxyProtocol = envPrefix + "RECEIVE_PROXY_PROTOCOL"
 	envApiEnabled           = envPrefix + "API_ENABLED"
 	envApiBind              = envPrefix + "API_BIND"
+	envApiToken             = envPrefix + "API_TOKEN"
 )
 
 const (
 	clfConfigPath           = "config-path"
 	clfReceiveProxyProtocol = "receive-proxy-protocol"
+	clfProxyProtocolVersion = "require-proxy-protocol-version"
+	clfProxyProtocolTrusted = "proxy-protocol-trusted-ips"
 	clfPrometheusEnabled    = "enable-prometheus"
 	clfPrometheusBind       = "prometheus-bind"
+	clfHealthPath           = "health-path"
+	clfReadyPath            = "ready-path"
+	clfStatsDAddr           = "statsd-addr"
+	clfStatsDPrefix         = "statsd-prefix"
+	clfDogStatsD            = "dogstatsd"
+	clfRelayBufferSize      = "relay-buffer-size"
+	clfMaxPacketSize        = "max-packet-size"
+	clfZeroCopy             = "zero-copy"
+	clfTCPKeepAlive         = "tcp-keepalive"
+	clfTCPNoDelay           = "tcp-nodelay"
+	clfTLSCert              = "tls-cert"
+	clfTLSKey               = "tls-key"
+	clfACLStore             = "acl-store"
+	clfACLFailOpen          = "acl-fail-open"
+	clfGeoIPDB              = "geoip-db"
+	clfAcceptLogInterval    = "accept-log-interval"
+	clfUnmatchedAction      = "unmatched-action"
+	clfDefaultServer        = "default-server"
+	clfUnmatchedMOTD        = "unmatched-motd"
+	clfUnmatchedMessage     = "unmatched-message"
+	clfHTTPProbeStatus      = "http-probe-status"
+	clfHTTPProbeBody        = "http-probe-body"
+	clfLogSessionStats      = "log-session-stats"
+	clfAccessLog            = "access-log"
+	clfConnRateLimit        = "rate-limit"
+	clfConnRateBurst        = "rate-limit-burst"
+	clfAccessLogFormat      = "access-log-format"
+	clfRaiseFileLimit       = "raise-file-limit"
+	clfProcessConcurrency   = "process-concurrency"
+	clfClientTimeout        = "client-timeout"
+	clfEventSocket          = "event-socket"
+	clfOverloadGoroutines   = "overload-max-goroutines"
+	clfOverloadConnections  = "overload-max-connections"
+	clfLogCompression       = "log-compression"
+	clfLogFormat            = "log-format"
+	clfLogLevel             = "log-level"
+	clfShutdownTimeout      = "shutdown-timeout"
 )
 
 var (
 	configPath           = "./configs"
 	receiveProxyProtocol = false
+	proxyProtocolVersion = 0
+	proxyProtocolTrusted = ""
 	prometheusEnabled    = false
 	prometheusBind       = ":9100"
+	healthPath           = infrared.DefaultHealthPath
+	readyPath            = infrared.DefaultReadyPath
 	apiEnabled           = false
 	apiBind              = "127.0.0.1:8080"
+	apiToken             = ""
+	statsDAddr           = ""
+	statsDPrefix         = "infrared"
+	dogStatsD            = false
+	relayBufferSize      = 0xffff
+	maxPacketSize        = protocol.MaxPacketSize
+	zeroCopy             = true
+	tcpKeepAlive         = time.Duration(0)
+	tcpNoDelay           = true
+	tlsCert              = ""
+	tlsKey               = ""
+	aclStore             = ""
+	aclFailOpen          = false
+	geoIPDB              = ""
+	acceptLogInterval    = time.Duration(0)
+	unmatchedAction      = ""
+	defaultServer        = ""
+	unmatchedMOTD        = ""
+	unmatchedMessage     = ""
+	httpProbeStatus      = 0
+	httpProbeBody        = ""
+	logSessionStats      = false
+	accessLog            = ""
+	connRateLimit        = 0.0
+	connRateBurst        = 0
+	accessLogFormat      = infrared.DefaultAccessLogFormat
+	raiseFileLimit       = false
+	processConcurrency   = 0
+	clientTimeout        = time.Duration(0)
+	eventSocket          = ""
+	overloadGoroutines   = 0
+	overloadConnections  = 0
+	logCompression       = false
+	logFormat            = infrared.LogFormatText
+	logLevel             = infrared.LogLevelDebug
+	shutdownTimeout      = 25 * time.Second
 )
 
+// startGRPC starts the gRPC API. It is only set if Infrared is built
+// with the grpc build tag.
+var startGRPC func(gateway *infrared.Gateway)
+
 func envBool(name string, value bool) bool {
 	envString := os.Getenv(name)
 	if envString == "" {
@@ -57,18 +152,93 @@ func envString(name string, value string) string {
 	return envString
 }
 
+// splitList splits a comma separated flag into its trimmed values
+func splitList(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
 func initEnv() {
 	configPath = envString(envConfigPath, configPath)
 	receiveProxyProtocol = envBool(envReceiveProxyProtocol, receiveProxyProtocol)
 	apiEnabled = envBool(envApiEnabled, apiEnabled)
 	apiBind = envString(envApiBind, apiBind)
+	apiToken = envString(envApiToken, apiToken)
+}
+
+// flagEnvName returns the environment variable of the flag, e.g.
+// INFRARED_LOG_LEVEL for -log-level
+func flagEnvName(name string) string {
+	return envPrefix + strings.ToUpper(strings.Replace(name, "-", "_", -1))
+}
+
+// initFlagEnv sets every flag whose environment variable is set, so that
+// each flag can be passed as one, e.g. in containers. Flags on the command
+// line still override them.
+func initFlagEnv() {
+	flag.VisitAll(func(f *flag.Flag) {
+		name := flagEnvName(f.Name)
+		value := os.Getenv(name)
+		if value == "" {
+			return
+		}
+
+		if err := flag.Set(f.Name, value); err != nil {
+			log.Printf("[w] Ignoring %s; error: %s", name, err)
+		}
+	})
 }
 
 func initFlags() {
 	flag.StringVar(&configPath, clfConfigPath, configPath, "path of all proxy configs")
 	flag.BoolVar(&receiveProxyProtocol, clfReceiveProxyProtocol, receiveProxyProtocol, "should accept proxy protocol")
+	flag.IntVar(&proxyProtocolVersion, clfProxyProtocolVersion, proxyProtocolVersion, "the only accepted version of received proxy protocol headers; 0 accepts both")
+	flag.StringVar(&proxyProtocolTrusted, clfProxyProtocolTrusted, proxyProtocolTrusted, "comma separated IPs and CIDR ranges whose proxy protocol headers are read; empty trusts everyone")
 	flag.BoolVar(&prometheusEnabled, clfPrometheusEnabled, prometheusEnabled, "should run prometheus client exposing metrics")
 	flag.StringVar(&prometheusBind, clfPrometheusBind, prometheusBind, "bind address and/or port for prometheus")
+	flag.StringVar(&healthPath, clfHealthPath, healthPath, "path of the liveness probe next to the prometheus metrics; - disables it")
+	flag.StringVar(&readyPath, clfReadyPath, readyPath, "path of the readiness probe next to the prometheus metrics; - disables it")
+	flag.StringVar(&statsDAddr, clfStatsDAddr, statsDAddr, "address of the StatsD server that metrics are sent to")
+	flag.StringVar(&statsDPrefix, clfStatsDPrefix, statsDPrefix, "prefix of all StatsD metric names")
+	flag.BoolVar(&dogStatsD, clfDogStatsD, dogStatsD, "should send labels as DogStatsD tags")
+	flag.IntVar(&relayBufferSize, clfRelayBufferSize, relayBufferSize, "size in bytes of the buffers that relay the traffic; only used without zero-copy or for TLS, Unix socket and online mode intercepted connections")
+	flag.IntVar(&maxPacketSize, clfMaxPacketSize, maxPacketSize, "length in bytes of the longest packet that is read from clients before they are proxied")
+	flag.BoolVar(&zeroCopy, clfZeroCopy, zeroCopy, "should let the kernel relay plain TCP connections without copying them")
+	flag.DurationVar(&tcpKeepAlive, clfTCPKeepAlive, tcpKeepAlive, "idle time after which TCP keep-alive probes are sent; 0 uses the default of Go and a negative value disables them")
+	flag.BoolVar(&tcpNoDelay, clfTCPNoDelay, tcpNoDelay, "should send small writes right away instead of coalescing them")
+	flag.StringVar(&tlsCert, clfTLSCert, tlsCert, "PEM file of the certificate that terminates TLS on every listener")
+	flag.StringVar(&tlsKey, clfTLSKey, tlsKey, "PEM file of the private key of -tls-cert")
+	flag.StringVar(&aclStore, clfACLStore, aclStore, "URI of the store that holds bans and whitelists")
+	flag.BoolVar(&aclFailOpen, clfACLFailOpen, aclFailOpen, "should let clients through if the acl store fails to answer")
+	flag.DurationVar(&acceptLogInterval, clfAcceptLogInterval, acceptLogInterval, "logs the number of accepted connections per interval; 0 disables it")
+	flag.StringVar(&unmatchedAction, clfUnmatchedAction, unmatchedAction, "what happens to clients of unknown domains; respond, drop or default_server; defaults to default_server if -default-server is set and to respond otherwise")
+	flag.StringVar(&defaultServer, clfDefaultServer, defaultServer, "domain name or UID (domain@listener) of the proxy that clients of unknown domains are routed to")
+	flag.StringVar(&unmatchedMOTD, clfUnmatchedMOTD, unmatchedMOTD, "MOTD of the status that clients of unknown domains get with the respond action")
+	flag.StringVar(&unmatchedMessage, clfUnmatchedMessage, unmatchedMessage, "message that logins of unknown domains are disconnected with by the respond action")
+	flag.IntVar(&httpProbeStatus, clfHTTPProbeStatus, httpProbeStatus, "status code that HTTP requests are answered with, e.g. 200 or 426; 0 disables it")
+	flag.StringVar(&httpProbeBody, clfHTTPProbeBody, httpProbeBody, "body of the answer to HTTP requests")
+	flag.BoolVar(&raiseFileLimit, clfRaiseFileLimit, raiseFileLimit, "should raise the open file limit to the hard limit if it is too low")
+	flag.IntVar(&processConcurrency, clfProcessConcurrency, processConcurrency, "maximum number of container starts and stops that run at the same time; 0 is unlimited")
+	flag.DurationVar(&clientTimeout, clfClientTimeout, clientTimeout, "time that clients get to finish their handshake and login; 0 disables it")
+	flag.StringVar(&eventSocket, clfEventSocket, eventSocket, "path of a Unix socket that streams the events as JSON lines; empty disables it")
+	flag.IntVar(&overloadGoroutines, clfOverloadGoroutines, overloadGoroutines, "number of goroutines above which new connections are refused; 0 disables it")
+	flag.IntVar(&overloadConnections, clfOverloadConnections, overloadConnections, "number of open connections above which new connections are refused; 0 disables it")
+	flag.BoolVar(&logCompression, clfLogCompression, logCompression, "should log the compression threshold that servers set during login")
+	flag.StringVar(&accessLog, clfAccessLog, accessLog, "path of the access log file or - for stdout; empty disables it")
+	flag.StringVar(&accessLogFormat, clfAccessLogFormat, accessLogFormat, "format of the access log; a template with $variables, json or json:<fields>")
+	flag.Float64Var(&connRateLimit, clfConnRateLimit, connRateLimit, "number of connections per second that an IP may open; 0 disables it")
+	flag.IntVar(&connRateBurst, clfConnRateBurst, connRateBurst, "number of connections that an IP may open at once; 0 is the rate rounded up")
+	flag.StringVar(&logFormat, clfLogFormat, logFormat, "format of the log; text or json")
+	flag.StringVar(&logLevel, clfLogLevel, logLevel, "lowest level of the logged lines; debug, info, warn or error")
+	flag.StringVar(&geoIPDB, clfGeoIPDB, geoIPDB, "path of the MaxMind DB file that the countries of clients are looked up in; empty disables it")
+	flag.DurationVar(&shutdownTimeout, clfShutdownTimeout, shutdownTimeout, "time that open connections get to close on SIGTERM")
+	flag.BoolVar(&logSessionStats, clfLogSessionStats, logSessionStats, "should log the duration and relayed bytes of closed connections")
+	initFlagEnv()
 	flag.Parse()
 }
 
@@ -78,6 +248,13 @@ func init() {
 }
 
 func main() {
+	if err := infrared.SetLogFormat(logFormat, os.Stderr); err != nil {
+		log.Fatal(err)
+	}
+	if err := infrared.SetLogLevel(logLevel); err != nil {
+		log.Fatal(err)
+	}
+
 	log.Println("Loading proxy configs")
 
 	cfgs, err := infrared.LoadProxyConfigsFromPath(configPath, false)
@@ -101,7 +278,70 @@ func main() {
 		}
 	}()
 
-	gateway := infrared.Gateway{}
+	gateway := infrared.Gateway{
+		ReceiveProxyProtocol:        receiveProxyProtocol,
+		RequireProxyProtocolVersion: proxyProtocolVersion,
+		ProxyProtocolTrustedIPs:     splitList(proxyProtocolTrusted),
+		RelayBufferSize:             relayBufferSize,
+		MaxPacketSize:               maxPacketSize,
+		DisableZeroCopy:             !zeroCopy,
+		TCPKeepAlive:                tcpKeepAlive,
+		DisableTCPNoDelay:           !tcpNoDelay,
+		TLSCertFile:                 tlsCert,
+		TLSKeyFile:                  tlsKey,
+		AcceptLogInterval:           acceptLogInterval,
+		UnmatchedAction:             unmatchedAction,
+		DefaultServer:               defaultServer,
+		UnmatchedMOTD:               unmatchedMOTD,
+		UnmatchedMessage:            unmatchedMessage,
+		HTTPProbeStatus:             httpProbeStatus,
+		HTTPProbeBody:               httpProbeBody,
+		HealthPath:                  healthPath,
+		ReadyPath:                   readyPath,
+		LogSessionStats:             logSessionStats,
+		RaiseFileLimit:              raiseFileLimit,
+		ProcessConcurrency:          processConcurrency,
+		ClientTimeout:               clientTimeout,
+		OverloadMaxGoroutines:       overloadGoroutines,
+		OverloadMaxConnections:      overloadConnections,
+		LogCompression:              logCompression,
+		ConnRateLimit:               connRateLimit,
+		ConnRateBurst:               connRateBurst,
+	}
+	switch accessLog {
+	case "":
+	case "-":
+		gateway.AccessLog = os.Stdout
+	default:
+		f, err := os.OpenFile(accessLog, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Printf("Failed opening access log %s; error: %s", accessLog, err)
+			return
+		}
+		defer f.Close()
+		gateway.AccessLog = f
+	}
+	gateway.AccessLogFormat = accessLogFormat
+
+	if aclStore != "" {
+		store, err := acl.NewStore(aclStore)
+		if err != nil {
+			log.Printf("Failed opening acl store %s; error: %s", aclStore, err)
+			return
+		}
+		gateway.ACLStore = store
+		gateway.ACLFailOpen = aclFailOpen
+	}
+
+	if geoIPDB != "" {
+		db, err := geoip.NewFile(geoIPDB)
+		if err != nil {
+			log.Printf("Failed opening geoip database %s; error: %s", geoIPDB, err)
+			return
+		}
+		gateway.GeoIP = db
+	}
+
 	go func() {
 		for {
 			cfg, ok := <-outCfgs
@@ -116,12 +356,67 @@ func main() {
 		}
 	}()
 
+	// SIGHUP reloads all configs without dropping the players
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	go func() {
+		for range hangups {
+			log.Println("Reloading proxy configs")
+			cfgs, err := infrared.LoadProxyConfigsFromPath(configPath, false)
+			if err != nil {
+				log.Printf("Failed reloading proxy configs from %s; error: %s", configPath, err)
+				continue
+			}
+
+			var proxies []*infrared.Proxy
+			for _, cfg := range cfgs {
+				proxies = append(proxies, &infrared.Proxy{Config: cfg})
+			}
+			if err := gateway.Reload(proxies); err != nil {
+				log.Println("Failed reloading proxies; error:", err)
+			}
+		}
+	}()
+
+	// SIGTERM stops accepting connections and waits for the open ones
+	terms := make(chan os.Signal, 1)
+	signal.Notify(terms, syscall.SIGTERM)
+	go func() {
+		<-terms
+		log.Printf("Shutting down; waiting up to %s for open connections", shutdownTimeout)
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		_ = gateway.Shutdown(ctx)
+		os.Exit(0)
+	}()
+
 	if apiEnabled {
-		go api.ListenAndServe(configPath, apiBind)
+		go api.ListenAndServe(&gateway, configPath, apiBind, apiToken)
+	}
+
+	if eventSocket != "" {
+		go func() {
+			if err := gateway.ListenAndServeEventSocket(eventSocket); err != nil {
+				log.Println("Failed serving event socket; error:", err)
+			}
+		}()
+	}
+
+	if startGRPC != nil {
+		startGRPC(&gateway)
 	}
 
 	if prometheusEnabled {
-		gateway.EnablePrometheus(prometheusBind)
+		if err := gateway.EnablePrometheus(prometheusBind); err != nil {
+			log.Printf("Failed enabling Prometheus on %s; error: %s", prometheusBind, err)
+			return
+		}
+	}
+
+	if statsDAddr != "" {
+		if err := gateway.EnableStatsD(statsDAddr, statsDPrefix, dogStatsD); err != nil {
+			log.Println("Failed enabling StatsD; error:", err)
+		}
 	}
 
 	log.Println("Starting Infrared")
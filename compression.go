@@ -0,0 +1,62 @@
+package infrared
+
+import (
+	"log"
+	"net"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+const (
+	// maxCompressionPeekPackets is the number of server packets that are
+	// inspected for the compression threshold, e.g. after login plugin
+	// requests of a forwarding proxy
+	maxCompressionPeekPackets = 8
+)
+
+// logsCompression reports whether the compression thresholds of the
+// servers are logged
+func (proxy *Proxy) logsCompression() bool {
+	return proxy.gateway != nil && proxy.gateway.LogCompression
+}
+
+// relayLoginCompression relays the login packets of the server to the
+// client untouched until the server set the compression, logged the client
+// in or encrypted the connection, and logs the negotiated threshold. It
+// returns the number of relayed bytes.
+func relayLoginCompression(rconn, conn Conn, proxyTo string, connRemoteAddr net.Addr) (int64, error) {
+	var relayed int64
+	for i := 0; i < maxCompressionPeekPackets; i++ {
+		data, err := protocol.ReadPacketBytes(rconn.Reader())
+		if err != nil {
+			return relayed, err
+		}
+
+		n, err := conn.Write(append(protocol.VarInt(len(data)).Encode(), data...))
+		relayed += int64(n)
+		countRelayed(rconn, conn, int64(n))
+		if err != nil {
+			return relayed, err
+		}
+
+		switch data[0] {
+		case login.ClientBoundSetCompressionPacketID:
+			pk, err := login.UnmarshalClientBoundSetCompression(protocol.Packet{ID: data[0], Data: data[1:]})
+			if err != nil {
+				return relayed, nil
+			}
+			log.Printf("[i] %s set the compression threshold of %s to %d bytes", proxyTo, connRemoteAddr, pk.Threshold)
+			return relayed, nil
+		case login.ClientBoundLoginSuccessPacketID:
+			log.Printf("[i] %s logged in %s without compression", proxyTo, connRemoteAddr)
+			return relayed, nil
+		case login.ClientBoundLoginPluginRequestPacketID:
+			continue
+		default:
+			// The connection got encrypted or the login failed
+			return relayed, nil
+		}
+	}
+	return relayed, nil
+}
@@ -0,0 +1,95 @@
+package infrared
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestRelayLoginCompression(t *testing.T) {
+	setCompression := uncompressedFrame(protocol.MarshalPacket(0x03, protocol.VarInt(256)))
+	pluginRequest := uncompressedFrame(protocol.MarshalPacket(0x04, protocol.VarInt(1), protocol.String("velocity:player_info")))
+	loginSuccess := uncompressedFrame(protocol.Packet{ID: 0x02, Data: []byte{0x01, 0x02}})
+	encryptionRequest := uncompressedFrame(protocol.Packet{ID: 0x01, Data: []byte{0x01, 0x02}})
+	// Bytes after the login that must be relayed untouched by the pipe
+	play := []byte{0x05, 0x00, 0x01, 0x02, 0x03, 0x04}
+
+	tt := []struct {
+		name    string
+		peeked  [][]byte
+		wantLog string
+	}{
+		{
+			name:    "Compression",
+			peeked:  [][]byte{pluginRequest, setCompression},
+			wantLog: "set the compression threshold of 127.0.0.1:25565 to 256 bytes",
+		},
+		{
+			name:    "NoCompression",
+			peeked:  [][]byte{loginSuccess},
+			wantLog: "logged in 127.0.0.1:25565 without compression",
+		},
+		{
+			name:   "Encryption",
+			peeked: [][]byte{encryptionRequest},
+		},
+	}
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			logs.Reset()
+
+			server, rc := net.Pipe()
+			c, client := net.Pipe()
+			defer server.Close()
+			defer rc.Close()
+			defer c.Close()
+			defer client.Close()
+
+			var sent []byte
+			for _, frame := range tc.peeked {
+				sent = append(sent, frame...)
+			}
+			sent = append(sent, play...)
+			go func() {
+				_, _ = server.Write(sent)
+				server.Close()
+			}()
+
+			receivedCh := make(chan []byte, 1)
+			go func() {
+				b, _ := ioutil.ReadAll(io.LimitReader(client, int64(len(sent))))
+				receivedCh <- b
+			}()
+
+			rconn, conn := wrapConn(rc), wrapConn(c)
+			addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 25565}
+			n, err := relayLoginCompression(rconn, conn, "backend:25565", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			m, _ := pipe(rconn, conn, defaultBufferPool, false)
+
+			if n+m != int64(len(sent)) {
+				t.Errorf("got: %d; want: %d", n+m, len(sent))
+			}
+			if received := <-receivedCh; !bytes.Equal(received, sent) {
+				t.Errorf("got: %v; want: %v", received, sent)
+			}
+			if !strings.Contains(logs.String(), tc.wantLog) {
+				t.Errorf("got: %q; want: %q", logs.String(), tc.wantLog)
+			}
+		})
+	}
+}
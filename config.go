@@ -1,8 +1,6 @@
 package infrared
 
 import (
-	"bufio"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -29,18 +27,69 @@ type ProxyConfig struct {
 	dialer         *Dialer
 	process        process.Process
 
-	DomainName        string               `json:"domainName"`
-	ListenTo          string               `json:"listenTo"`
-	ProxyTo           string               `json:"proxyTo"`
-	ProxyBind         string               `json:"proxyBind"`
-	ProxyProtocol     bool                 `json:"proxyProtocol"`
-	RealIP            bool                 `json:"realIp"`
-	Timeout           int                  `json:"timeout"`
-	DisconnectMessage string               `json:"disconnectMessage"`
-	Docker            DockerConfig         `json:"docker"`
-	OnlineStatus      StatusConfig         `json:"onlineStatus"`
-	OfflineStatus     StatusConfig         `json:"offlineStatus"`
-	CallbackServer    CallbackServerConfig `json:"callbackServer"`
+	DomainName             string                    `json:"domainName"`
+	ListenTo               string                    `json:"listenTo"`
+	ProxyTo                string                    `json:"proxyTo"`
+	StatusProxyTo          string                    `json:"statusProxyTo"`
+	ProxyBind              string                    `json:"proxyBind"`
+	DialNetwork            string                    `json:"dialNetwork"`
+	ProxyProtocol          bool                      `json:"proxyProtocol"`
+	RealIP                 bool                      `json:"realIp"`
+	BungeeCordForwarding   bool                      `json:"bungeeCordForwarding"`
+	VelocityForwarding     VelocityForwardingConfig  `json:"velocityForwarding"`
+	Timeout                int                       `json:"timeout"`
+	ClientTimeout          int                       `json:"clientTimeout"`
+	IdleTimeout            int                       `json:"idleTimeout"`
+	DisconnectMessage      string                    `json:"disconnectMessage"`
+	Docker                 DockerConfig              `json:"docker"`
+	OnlineStatus           StatusConfig              `json:"onlineStatus"`
+	OfflineStatus          StatusConfig              `json:"offlineStatus"`
+	CallbackServer         CallbackServerConfig      `json:"callbackServer"`
+	Challenge              ChallengeConfig           `json:"challenge"`
+	TransferTo             string                    `json:"transferTo"`
+	Whitelist              bool                      `json:"whitelist"`
+	AllowedIPs             []string                  `json:"allowedIPs"`
+	BlockedIPs             []string                  `json:"blockedIPs"`
+	AllowedCountries       []string                  `json:"allowedCountries"`
+	BlockedCountries       []string                  `json:"blockedCountries"`
+	BanMessage             string                    `json:"banMessage"`
+	WhitelistMessage       string                    `json:"whitelistMessage"`
+	MaxConnections         int                       `json:"maxConnections"`
+	QueueEnabled           bool                      `json:"queueEnabled"`
+	QueueSize              int                       `json:"queueSize"`
+	FullMessage            string                    `json:"fullMessage"`
+	QueueMessage           string                    `json:"queueMessage"`
+	StatusBreaker          BreakerConfig             `json:"statusBreaker"`
+	LoginBreaker           BreakerConfig             `json:"loginBreaker"`
+	HealthCheck            HealthCheckConfig         `json:"healthCheck"`
+	MinProtocol            int                       `json:"minProtocol"`
+	MaxProtocol            int                       `json:"maxProtocol"`
+	OutdatedClientMessage  string                    `json:"outdatedClientMessage"`
+	OutdatedServerMessage  string                    `json:"outdatedServerMessage"`
+	NATKeepAlive           int                       `json:"natKeepAlive"`
+	MaxUsernameLength      int                       `json:"maxUsernameLength"`
+	RelaxedUsernames       bool                      `json:"relaxedUsernames"`
+	StrictProtocol         bool                      `json:"strictProtocol"`
+	CaptureClientInfo      bool                      `json:"captureClientInfo"`
+	InvalidUsernameMessage string                    `json:"invalidUsernameMessage"`
+	SubdomainRoutes        map[string]string         `json:"subdomainRoutes"`
+	VersionRoutes          []VersionRouteConfig      `json:"versionRoutes"`
+	StatusCache            StatusCacheConfig         `json:"statusCache"`
+	Backends               []string                  `json:"backends"`
+	BackendSelection       string                    `json:"backendSelection"`
+	BackendWeights         map[string]int            `json:"backendWeights"`
+	DialRetries            int                       `json:"dialRetries"`
+	DialRetryDelay         int                       `json:"dialRetryDelay"`
+	WaitForBackend         WaitForBackendConfig      `json:"waitForBackend"`
+	WarmPoolSize           int                       `json:"warmPoolSize"`
+	Canary                 CanaryConfig              `json:"canary"`
+	OpenHours              OpenHoursConfig           `json:"openHours"`
+	Maintenance            MaintenanceConfig         `json:"maintenance"`
+	AuthHook               AuthHookConfig            `json:"authHook"`
+	OnlineModeIntercept    OnlineModeInterceptConfig `json:"onlineModeIntercept"`
+	StatusAdmission        StatusAdmissionConfig     `json:"statusAdmission"`
+	StatusRateLimit        StatusRateLimitConfig     `json:"statusRateLimit"`
+	HandshakePort          HandshakePortConfig       `json:"handshakePort"`
 }
 
 func (cfg *ProxyConfig) Dialer() (*Dialer, error) {
@@ -48,6 +97,12 @@ func (cfg *ProxyConfig) Dialer() (*Dialer, error) {
 		return cfg.dialer, nil
 	}
 
+	switch cfg.DialNetwork {
+	case "", DialNetworkTCP, DialNetworkTCP4, DialNetworkTCP6:
+	default:
+		return nil, fmt.Errorf("invalid dial network %q", cfg.DialNetwork)
+	}
+
 	cfg.dialer = &Dialer{
 		Dialer: net.Dialer{
 			Timeout: time.Millisecond * time.Duration(cfg.Timeout),
@@ -55,6 +110,8 @@ func (cfg *ProxyConfig) Dialer() (*Dialer, error) {
 				IP: net.ParseIP(cfg.ProxyBind),
 			},
 		},
+		Network: cfg.DialNetwork,
+		srv:     &srvResolver{},
 	}
 	return cfg.dialer, nil
 }
@@ -88,27 +145,29 @@ type PlayerSample struct {
 
 type StatusConfig struct {
 	cachedPacket *protocol.Packet
-
-	VersionName    string         `json:"versionName"`
-	ProtocolNumber int            `json:"protocolNumber"`
-	MaxPlayers     int            `json:"maxPlayers"`
-	PlayersOnline  int            `json:"playersOnline"`
-	PlayerSamples  []PlayerSample `json:"playerSamples"`
-	IconPath       string         `json:"iconPath"`
-	MOTD           string         `json:"motd"`
+	// iconURLImage is the base64 encoded icon that was downloaded from
+	// the IconPath if it is a URL
+	iconURLImage string
+
+	VersionName         string         `json:"versionName"`
+	ProtocolNumber      int            `json:"protocolNumber"`
+	MaxPlayers          int            `json:"maxPlayers"`
+	PlayersOnline       int            `json:"playersOnline"`
+	PlayerSamples       []PlayerSample `json:"playerSamples"`
+	SampleMode          string         `json:"sampleMode"`
+	IconPath            string         `json:"iconPath"`
+	RejectWrongIconSize bool           `json:"rejectWrongIconSize"`
+	MOTD                string         `json:"motd"`
 }
 
 func (cfg StatusConfig) StatusResponsePacket() (protocol.Packet, error) {
-	if cfg.cachedPacket != nil {
+	if cfg.cachedPacket != nil && !cfg.isRandomSample() {
 		return *cfg.cachedPacket, nil
 	}
 
-	var samples []status.PlayerSampleJSON
-	for _, sample := range cfg.PlayerSamples {
-		samples = append(samples, status.PlayerSampleJSON{
-			Name: sample.Name,
-			ID:   sample.UUID,
-		})
+	samples, err := cfg.playerSamples()
+	if err != nil {
+		return protocol.Packet{}, err
 	}
 
 	responseJSON := status.ResponseJSON{
@@ -126,8 +185,12 @@ func (cfg StatusConfig) StatusResponsePacket() (protocol.Packet, error) {
 		},
 	}
 
-	if cfg.IconPath != "" {
-		img64, err := loadImageAndEncodeToBase64String(cfg.IconPath)
+	if isIconURL(cfg.IconPath) {
+		if cfg.iconURLImage != "" {
+			responseJSON.Favicon = fmt.Sprintf("data:image/png;base64,%s", cfg.iconURLImage)
+		}
+	} else if cfg.IconPath != "" {
+		img64, err := loadImageAndEncodeToBase64String(cfg.IconPath, cfg.RejectWrongIconSize)
 		if err != nil {
 			return protocol.Packet{}, err
 		}
@@ -143,39 +206,250 @@ func (cfg StatusConfig) StatusResponsePacket() (protocol.Packet, error) {
 		JSONResponse: protocol.String(bb),
 	}.Marshal()
 
-	cfg.cachedPacket = &packet
+	if !cfg.isRandomSample() {
+		cfg.cachedPacket = &packet
+	}
 	return packet, nil
 }
 
-func loadImageAndEncodeToBase64String(path string) (string, error) {
-	if path == "" {
-		return "", nil
+type CallbackServerConfig struct {
+	URL           string         `json:"url"`
+	Events        []string       `json:"events"`
+	Timeout       int            `json:"timeout"`
+	EventTimeouts map[string]int `json:"eventTimeouts"`
+	MustDeliver   []string       `json:"mustDeliver"`
+	MaxRetries    int            `json:"maxRetries"`
+	RetryBackoff  []int          `json:"retryBackoff"`
+	Secret        string         `json:"secret"`
+}
+
+// AuthHookConfig configures the HTTP endpoint that decides whether a login
+// is proxied. An empty URL disables it.
+type AuthHookConfig struct {
+	URL     string `json:"url"`
+	Timeout int    `json:"timeout"`
+	Message string `json:"message"`
+	Secret  string `json:"secret"`
+}
+
+func (cfg AuthHookConfig) IsEnabled() bool {
+	return cfg.URL != ""
+}
+
+// OnlineModeInterceptConfig configures the authentication of players with
+// the session server in place of the server, which has to be in offline
+// mode. The session server defaults to the one of Mojang.
+type OnlineModeInterceptConfig struct {
+	Enabled       bool   `json:"enabled"`
+	SessionServer string `json:"sessionServer"`
+	Timeout       int    `json:"timeout"`
+	Message       string `json:"message"`
+}
+
+func (cfg OnlineModeInterceptConfig) IsEnabled() bool {
+	return cfg.Enabled
+}
+
+// ChallengeConfig configures the first connection challenge. A client that
+// was not seen before gets disconnected and has to reconnect within the window.
+type ChallengeConfig struct {
+	Status     bool   `json:"status"`
+	Login      bool   `json:"login"`
+	Window     int    `json:"window"`
+	MaxEntries int    `json:"maxEntries"`
+	Message    string `json:"message"`
+}
+
+func (challenge ChallengeConfig) IsEnabled() bool {
+	return challenge.Status || challenge.Login
+}
+
+// VelocityForwardingConfig configures the modern forwarding of Velocity,
+// which signs the player info with the secret that the server shares. An
+// empty secret disables it.
+type VelocityForwardingConfig struct {
+	Secret string `json:"secret"`
+}
+
+func (cfg VelocityForwardingConfig) IsEnabled() bool {
+	return cfg.Secret != ""
+}
+
+// WaitForBackendConfig holds logins while the server is offline, e.g. while
+// its process starts, until they can be proxied. A max wait of zero
+// disables it.
+type WaitForBackendConfig struct {
+	MaxWait  int    `json:"maxWait"`
+	Interval int    `json:"interval"`
+	MOTD     string `json:"motd"`
+}
+
+func (cfg WaitForBackendConfig) IsEnabled() bool {
+	return cfg.MaxWait > 0
+}
+
+// BreakerConfig configures a circuit breaker. A threshold of zero disables it.
+type BreakerConfig struct {
+	Threshold int `json:"threshold"`
+	Cooldown  int `json:"cooldown"`
+}
+
+func (cfg BreakerConfig) IsEnabled() bool {
+	return cfg.Threshold > 0
+}
+
+func (cfg BreakerConfig) CooldownDuration() time.Duration {
+	if cfg.Cooldown <= 0 {
+		return time.Millisecond * defaultBreakerCooldown
 	}
+	return time.Millisecond * time.Duration(cfg.Cooldown)
+}
 
-	imgFile, err := os.Open(path)
-	if err != nil {
-		return "", err
+// HealthCheckConfig configures the periodic dials to the backends. A
+// backend that failed a number of checks in a row is unhealthy until it
+// passes one again. An interval of zero disables it.
+type HealthCheckConfig struct {
+	Interval int `json:"interval"`
+	Failures int `json:"failures"`
+}
+
+func (cfg HealthCheckConfig) IntervalDuration() time.Duration {
+	return time.Millisecond * time.Duration(cfg.Interval)
+}
+
+func (cfg HealthCheckConfig) FailureThreshold() int {
+	if cfg.Failures <= 0 {
+		return defaultHealthCheckFailures
 	}
-	defer imgFile.Close()
+	return cfg.Failures
+}
 
-	fileInfo, err := imgFile.Stat()
-	if err != nil {
-		return "", err
+// CanaryConfig configures a canary backend that gets a percentage of the
+// logins. A percentage of zero disables it.
+type CanaryConfig struct {
+	Backend    string `json:"backend"`
+	Percentage int    `json:"percentage"`
+	Random     bool   `json:"random"`
+}
+
+func (cfg CanaryConfig) IsEnabled() bool {
+	return cfg.Backend != "" && cfg.Percentage > 0
+}
+
+// VersionRouteConfig routes the clients whose protocol version is within
+// the inclusive ProtocolRange to the server of ProxyTo
+type VersionRouteConfig struct {
+	ProtocolRange []int  `json:"protocolRange"`
+	ProxyTo       string `json:"proxyTo"`
+}
+
+// StatusAdmissionConfig configures how status requests of unknown IPs are
+// shed under a ping flood. A new IP rate of zero disables it.
+type StatusAdmissionConfig struct {
+	NewIPRate  int    `json:"newIpRate"`
+	Window     int    `json:"window"`
+	MaxEntries int    `json:"maxEntries"`
+	Action     string `json:"action"`
+}
+
+func (cfg StatusAdmissionConfig) IsEnabled() bool {
+	return cfg.NewIPRate > 0
+}
+
+func (cfg StatusAdmissionConfig) WindowDuration() time.Duration {
+	if cfg.Window <= 0 {
+		return time.Millisecond * defaultStatusAdmissionWindow
 	}
+	return time.Millisecond * time.Duration(cfg.Window)
+}
 
-	buffer := make([]byte, fileInfo.Size())
-	fileReader := bufio.NewReader(imgFile)
-	_, err = fileReader.Read(buffer)
-	if err != nil {
-		return "", nil
+// StatusRateLimitConfig configures how many status requests an IP that did
+// not log in may send within a window. A rate of zero disables it.
+type StatusRateLimitConfig struct {
+	Rate       int    `json:"rate"`
+	Window     int    `json:"window"`
+	MaxEntries int    `json:"maxEntries"`
+	Action     string `json:"action"`
+}
+
+func (cfg StatusRateLimitConfig) IsEnabled() bool {
+	return cfg.Rate > 0
+}
+
+func (cfg StatusRateLimitConfig) WindowDuration() time.Duration {
+	if cfg.Window <= 0 {
+		return time.Millisecond * defaultStatusRateLimitWindow
 	}
+	return time.Millisecond * time.Duration(cfg.Window)
+}
 
-	return base64.StdEncoding.EncodeToString(buffer), nil
+// HandshakePortConfig configures the check of the port that clients claim
+// to connect to in their handshake. Without allowed ports only the port of
+// listenTo is allowed.
+type HandshakePortConfig struct {
+	Enabled      bool  `json:"enabled"`
+	AllowedPorts []int `json:"allowedPorts"`
 }
 
-type CallbackServerConfig struct {
-	URL    string   `json:"url"`
-	Events []string `json:"events"`
+// OpenHoursConfig configures the times at which the server accepts logins.
+// Without windows the server is always open.
+type OpenHoursConfig struct {
+	Timezone      string             `json:"timezone"`
+	Windows       []OpenWindowConfig `json:"windows"`
+	ClosedMessage string             `json:"closedMessage"`
+	ClosedMOTD    string             `json:"closedMotd"`
+}
+
+func (cfg OpenHoursConfig) IsEnabled() bool {
+	return len(cfg.Windows) > 0
+}
+
+// MaintenanceConfig configures the maintenance of the server, during which
+// new logins are disconnected with the message
+type MaintenanceConfig struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message"`
+	MOTD    string `json:"motd"`
+}
+
+func (cfg MaintenanceConfig) IsEnabled() bool {
+	return cfg.Enabled
+}
+
+// OpenWindowConfig is a time window like "18:00" to "22:00" on the given
+// days. Without days the window is open every day.
+type OpenWindowConfig struct {
+	Days []string `json:"days"`
+	From string   `json:"from"`
+	To   string   `json:"to"`
+}
+
+// StatusCacheConfig configures the cache for the status of the server.
+// A TTL of zero disables it.
+type StatusCacheConfig struct {
+	TTL             int  `json:"ttl"`
+	Prewarm         bool `json:"prewarm"`
+	RefreshInterval int  `json:"refreshInterval"`
+}
+
+func (cfg StatusCacheConfig) IsEnabled() bool {
+	return cfg.TTL > 0
+}
+
+func (cfg StatusCacheConfig) TTLDuration() time.Duration {
+	return time.Millisecond * time.Duration(cfg.TTL)
+}
+
+// RefreshDuration returns the refresh interval of the prewarmed status.
+// It defaults to half of the TTL, so that the cache never runs cold.
+func (cfg StatusCacheConfig) RefreshDuration() time.Duration {
+	if cfg.RefreshInterval <= 0 {
+		if cfg.TTL <= 0 {
+			return time.Millisecond * defaultStatusRefreshInterval
+		}
+		return cfg.TTLDuration() / 2
+	}
+	return time.Millisecond * time.Duration(cfg.RefreshInterval)
 }
 
 func DefaultProxyConfig() ProxyConfig {
@@ -194,6 +468,23 @@ func DefaultProxyConfig() ProxyConfig {
 			MaxPlayers:     20,
 			MOTD:           "Powered by Infrared",
 		},
+		Challenge: ChallengeConfig{
+			Window:     defaultChallengeWindow,
+			MaxEntries: defaultChallengeMaxEntries,
+			Message:    defaultChallengeMessage,
+		},
+		BanMessage:       defaultBanMessage,
+		WhitelistMessage: defaultWhitelistMessage,
+		QueueSize:        defaultQueueSize,
+		FullMessage:      defaultFullMessage,
+		QueueMessage:     defaultQueueMessage,
+		StatusBreaker: BreakerConfig{
+			Cooldown: defaultBreakerCooldown,
+		},
+		OutdatedClientMessage:  defaultOutdatedClientMessage,
+		OutdatedServerMessage:  defaultOutdatedServerMessage,
+		MaxUsernameLength:      defaultMaxUsernameLength,
+		InvalidUsernameMessage: defaultInvalidUsernameMessage,
 	}
 }
 
@@ -338,6 +629,14 @@ func (cfg *ProxyConfig) onConfigWrite(event fsnotify.Event) {
 	cfg.changeCallback()
 }
 
+// stopWatching stops the reloads of the config on changes of its file
+func (cfg *ProxyConfig) stopWatching() {
+	if cfg.watcher == nil {
+		return
+	}
+	_ = cfg.watcher.Close()
+}
+
 // LoadFromPath loads the ProxyConfig from a file
 func (cfg *ProxyConfig) LoadFromPath(path string) error {
 	cfg.Lock()
@@ -373,7 +672,29 @@ func (cfg *ProxyConfig) LoadFromPath(path string) error {
 		return err
 	}
 
-	return json.Unmarshal(bb, cfg)
+	// Unmarshal would add to the maps of the previous load instead of
+	// replacing them, which keeps removed entries and races with readers
+	cfg.SubdomainRoutes = nil
+	cfg.BackendWeights = nil
+	cfg.CallbackServer.EventTimeouts = nil
+	if err := json.Unmarshal(bb, cfg); err != nil {
+		return err
+	}
+
+	if cfg.RealIP && cfg.BungeeCordForwarding {
+		return errRealIPAndBungeeCord
+	}
+
+	if err := cfg.OnlineStatus.validateIcon(); err != nil {
+		return err
+	}
+	if err := cfg.OfflineStatus.validateIcon(); err != nil {
+		return err
+	}
+
+	cfg.OnlineStatus.loadIcon()
+	cfg.OfflineStatus.loadIcon()
+	return nil
 }
 
 func WatchProxyConfigFolder(path string, out chan *ProxyConfig) error {
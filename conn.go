@@ -2,7 +2,10 @@ package infrared
 
 import (
 	"bufio"
+	"bytes"
 	"crypto/cipher"
+	"crypto/tls"
+	"errors"
 	"github.com/haveachin/infrared/protocol"
 	"io"
 	"net"
@@ -20,20 +23,67 @@ type PacketPeeker interface {
 	PeekPacket() (protocol.Packet, error)
 }
 
+// TransportTCP is the transport of plain TCP connections
+const TransportTCP = "tcp"
+
+// The networks that a Dialer can dial servers on
+const (
+	// DialNetworkTCP dials IPv4 and IPv6 addresses
+	DialNetworkTCP  = "tcp"
+	DialNetworkTCP4 = "tcp4"
+	DialNetworkTCP6 = "tcp6"
+)
+
+// maxPeekSize is the size of the biggest packet that can be peeked. It fits
+// handshakes with BungeeCord forwarding data that exceed the default size of
+// the read buffer.
+const maxPeekSize = 1 << 16
+
 type conn struct {
+	// bytesRead and bytesWritten count the relayed bytes. They come first,
+	// so that they are 64-bit aligned for atomic access.
+	bytesRead    int64
+	bytesWritten int64
+
 	net.Conn
 
 	r *bufio.Reader
-	w io.Writer
+	// rd is the reader below r, which decrypts once the connection is
+	// encrypted
+	rd        io.Reader
+	w         io.Writer
+	transport string
+	encrypted bool
+	// access is the access log entry of the connection, if it has one
+	access *accessEntry
+	// proxyTLVs are the TLVs of the PROXY protocol header of the connection
+	proxyTLVs map[string]string
+	// maxPacketSize limits the packets that are read; zero means
+	// protocol.MaxPacketSize
+	maxPacketSize int
 }
 
 type Listener struct {
 	net.Listener
+
+	// Transport is the kind of transport the listener accepts connections with
+	Transport string
+	// TLSConfig terminates the TLS of the accepted connections if it is set
+	TLSConfig *tls.Config
+	// MaxPacketSize limits the packets that are read from the accepted
+	// connections; zero means protocol.MaxPacketSize
+	MaxPacketSize int
 }
 
+// Listen listens on the TCP address or, if it starts with unix://, on the
+// Unix domain socket addr
 func Listen(addr string) (Listener, error) {
+	if path, ok := unixSocketPath(addr); ok {
+		return ListenUnix(path)
+	}
+
 	l, err := net.Listen("tcp", addr)
-	return Listener{Listener: l}, err
+	return Listener{Listener: l, Transport: TransportTCP}, err
 }
 
 func (l Listener) Accept() (Conn, error) {
@@ -41,7 +91,15 @@ func (l Listener) Accept() (Conn, error) {
 	if err != nil {
 		return nil, err
 	}
-	return wrapConn(conn), nil
+	if l.TLSConfig != nil {
+		conn = tlsConn{Conn: tls.Server(conn, l.TLSConfig), raw: conn}
+	}
+	c := wrapConn(conn)
+	if l.Transport != "" {
+		c.transport = l.Transport
+	}
+	c.maxPacketSize = l.MaxPacketSize
+	return c, nil
 }
 
 // Conn is a minecraft Connection
@@ -52,24 +110,42 @@ type Conn interface {
 	PacketPeeker
 
 	Reader() *bufio.Reader
+	// Transport returns the kind of transport of the connection, e.g. "tcp"
+	Transport() string
 }
 
 // wrapConn warp an net.Conn to infared.conn
 func wrapConn(c net.Conn) *conn {
 	return &conn{
-		Conn: c,
-		r:    bufio.NewReader(c),
-		w:    c,
+		Conn:      c,
+		r:         bufio.NewReader(c),
+		rd:        c,
+		w:         c,
+		transport: TransportTCP,
 	}
 }
 
 type Dialer struct {
 	net.Dialer
+	// Network is one of the DialNetwork constants; defaults to DialNetworkTCP
+	Network string
+
+	// srv caches the targets of SRV record addresses between dials
+	srv *srvResolver
 }
 
 // Dial create a Minecraft connection
 func (d Dialer) Dial(addr string) (Conn, error) {
-	conn, err := d.Dialer.Dial("tcp", addr)
+	network := d.Network
+	if network == "" {
+		network = DialNetworkTCP
+	}
+
+	if name, ok := srvName(addr); ok {
+		return d.dialSRV(network, name)
+	}
+
+	conn, err := d.Dialer.Dial(network, addr)
 	if err != nil {
 		return nil, err
 	}
@@ -87,12 +163,34 @@ func (c *conn) Write(b []byte) (int, error) {
 
 // ReadPacket read a Packet from Conn.
 func (c *conn) ReadPacket() (protocol.Packet, error) {
-	return protocol.ReadPacket(c.r)
+	return protocol.ReadPacketLimit(c.r, c.packetLimit())
 }
 
 // PeekPacket peeks a Packet from Conn.
 func (c *conn) PeekPacket() (protocol.Packet, error) {
-	return protocol.PeekPacket(c.r)
+	pk, err := protocol.PeekPacketLimit(c.r, c.packetLimit())
+	if errors.Is(err, bufio.ErrBufferFull) && c.r.Size() < maxPeekSize {
+		// The packet is bigger than the read buffer, e.g. a handshake with
+		// forwarding data, so the buffer has to grow to peek it
+		c.growReader(maxPeekSize)
+		return protocol.PeekPacketLimit(c.r, c.packetLimit())
+	}
+	return pk, err
+}
+
+func (c *conn) packetLimit() int {
+	if c.maxPacketSize <= 0 {
+		return protocol.MaxPacketSize
+	}
+	return c.maxPacketSize
+}
+
+// growReader replaces the reader of c with a bigger one that starts with
+// the data that is buffered in the current one
+func (c *conn) growReader(size int) {
+	buffered, _ := c.r.Peek(c.r.Buffered())
+	buffered = append([]byte(nil), buffered...)
+	c.r = bufio.NewReaderSize(io.MultiReader(bytes.NewReader(buffered), c.rd), size)
 }
 
 //WritePacket write a Packet to Conn.
@@ -107,16 +205,32 @@ func (c *conn) WritePacket(p protocol.Packet) error {
 
 // SetCipher sets the decode/encode stream for this Conn
 func (c *conn) SetCipher(ecoStream, decoStream cipher.Stream) {
-	c.r = bufio.NewReader(cipher.StreamReader{
+	c.rd = cipher.StreamReader{
 		S: decoStream,
 		R: c.Conn,
-	})
+	}
+	c.r = bufio.NewReader(c.rd)
 	c.w = cipher.StreamWriter{
 		S: ecoStream,
 		W: c.Conn,
 	}
+	c.encrypted = true
 }
 
 func (c *conn) Reader() *bufio.Reader {
 	return c.r
 }
+
+func (c *conn) Transport() string {
+	return c.transport
+}
+
+// tcpConn returns the underlying TCP connection if the traffic of c can
+// be relayed without going through its reader and writer
+func (c *conn) tcpConn() (*net.TCPConn, bool) {
+	if c.encrypted {
+		return nil, false
+	}
+	tcpConn, ok := c.Conn.(*net.TCPConn)
+	return tcpConn, ok
+}
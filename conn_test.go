@@ -0,0 +1,216 @@
+package infrared
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+func TestConn_PeekPacket(t *testing.T) {
+	// BungeeCord appends the IP, the UUID and the properties of the player
+	forwarding := handshaking.ForgeSeparator + "127.0.0.1" + handshaking.ForgeSeparator + "069a79f444e94726a5befca90e38aaf5" +
+		handshaking.ForgeSeparator + `[{"name":"textures","value":"` + strings.Repeat("a", 8000) + `"}]`
+
+	tt := []struct {
+		name string
+		addr string
+	}{
+		{
+			name: "Short",
+			addr: "example.com",
+		},
+		{
+			name: "ProtocolMaximum",
+			addr: strings.Repeat("a", 255),
+		},
+		{
+			name: "BiggerThanReadBuffer",
+			addr: "example.com" + forwarding,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hs := handshaking.ServerBoundHandshake{
+				ProtocolVersion: 758,
+				ServerAddress:   protocol.String(tc.addr),
+				ServerPort:      25565,
+				NextState:       handshaking.ServerBoundHandshakeLoginState,
+			}
+			next := protocol.MarshalPacket(0x00, protocol.String("Steve"))
+
+			c1, c2 := net.Pipe()
+			defer c1.Close()
+			go func() {
+				client := wrapConn(c1)
+				_ = client.WritePacket(hs.Marshal())
+				_ = client.WritePacket(next)
+			}()
+
+			c := wrapConn(c2)
+			defer c.Close()
+
+			pk, err := c.PeekPacket()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := handshaking.UnmarshalServerBoundHandshake(pk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual.ServerAddress != hs.ServerAddress {
+				t.Errorf("got: %d bytes; want: %d bytes", len(actual.ServerAddress), len(hs.ServerAddress))
+			}
+
+			// The peeked packet and the one after it still have to be readable
+			for _, want := range []protocol.Packet{hs.Marshal(), next} {
+				pk, err := c.ReadPacket()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if pk.ID != want.ID || !bytes.Equal(pk.Data, want.Data) {
+					t.Errorf("got: %d bytes; want: %d bytes", len(pk.Data), len(want.Data))
+				}
+			}
+		})
+	}
+}
+
+func TestKeepNATAlive(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		c, err := net.Dial("tcp", l.Addr().String())
+		if err == nil {
+			defer c.Close()
+			_, _ = c.Read(make([]byte, 1))
+		}
+	}()
+
+	c, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	if err := keepNATAlive(wrapConn(c), 15*time.Second); err != nil {
+		t.Errorf("got: %v; want: nil", err)
+	}
+
+	// Connections that are not TCP are skipped
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	if err := keepNATAlive(wrapConn(c1), 15*time.Second); err != nil {
+		t.Errorf("got: %v; want: nil", err)
+	}
+}
+
+func TestDialer_Network(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	tt := []struct {
+		network string
+		isValid bool
+	}{
+		{network: "", isValid: true},
+		{network: DialNetworkTCP, isValid: true},
+		{network: DialNetworkTCP4, isValid: true},
+		// An IPv4 address can't be dialed over IPv6
+		{network: DialNetworkTCP6, isValid: false},
+	}
+
+	for _, tc := range tt {
+		cfg := &ProxyConfig{DialNetwork: tc.network, Timeout: 1000}
+		dialer, err := cfg.Dialer()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		c, err := dialer.Dial(l.Addr().String())
+		if (err == nil) != tc.isValid {
+			t.Errorf("%q: got: %v; want valid: %v", tc.network, err, tc.isValid)
+		}
+		if err == nil {
+			c.Close()
+		}
+	}
+
+	cfg := &ProxyConfig{DialNetwork: "udp"}
+	if _, err := cfg.Dialer(); err == nil {
+		t.Error("got: nil; want: error for an invalid network")
+	}
+}
+
+func TestConn_MaxPacketSize(t *testing.T) {
+	hs := serverHandshake("mc.example.com", 25565)
+	hsBytes, err := hs.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		name          string
+		maxPacketSize int
+		data          []byte
+		wantErr       error
+	}{
+		{
+			name: "Handshake",
+			data: hsBytes,
+		},
+		{
+			name:          "AboveLimit",
+			maxPacketSize: 16,
+			data:          hsBytes,
+			wantErr:       protocol.ErrPacketTooLarge,
+		},
+		{
+			// Announces a packet of 2^31 - 1 bytes without sending it
+			name:    "HugeLength",
+			data:    []byte{0xff, 0xff, 0xff, 0xff, 0x07},
+			wantErr: protocol.ErrPacketTooLarge,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c1, c2 := net.Pipe()
+			defer c1.Close()
+			go func() { _, _ = c1.Write(tc.data) }()
+
+			c := wrapConn(c2)
+			defer c.Close()
+			c.maxPacketSize = tc.maxPacketSize
+
+			if _, err := c.PeekPacket(); !errors.Is(err, tc.wantErr) {
+				t.Errorf("got: %v; want: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
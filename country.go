@@ -0,0 +1,74 @@
+package infrared
+
+import (
+	"log"
+	"net"
+	"strings"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+func (proxy *Proxy) AllowedCountries() []string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.AllowedCountries
+}
+
+func (proxy *Proxy) BlockedCountries() []string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.BlockedCountries
+}
+
+// isCountryAllowed reports whether the country of the client may reach the
+// proxy. Clients without a country are only allowed if there are no
+// allowed countries.
+func (proxy *Proxy) isCountryAllowed(connRemoteAddr net.Addr) bool {
+	allowed := proxy.AllowedCountries()
+	blocked := proxy.BlockedCountries()
+	if len(allowed) == 0 && len(blocked) == 0 {
+		return true
+	}
+	if proxy.gateway == nil || proxy.gateway.GeoIP == nil {
+		return true
+	}
+
+	ip := net.ParseIP(remoteIP(connRemoteAddr))
+	if ip == nil {
+		return true
+	}
+
+	country, err := proxy.gateway.GeoIP.Country(ip)
+	if err != nil {
+		log.Printf("[w] Failed to look up the country of %s; error: %s", connRemoteAddr, err)
+	}
+
+	if country != "" && containsCountry(blocked, country) {
+		return false
+	}
+	return len(allowed) == 0 || containsCountry(allowed, country)
+}
+
+// rejectByCountry answers clients whose country is not allowed like
+// rejectByIP. It reports whether the client was rejected.
+func (proxy *Proxy) rejectByCountry(conn Conn, hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr, loginStart login.ServerLoginStart) (bool, error) {
+	if proxy.isCountryAllowed(connRemoteAddr) {
+		return false, nil
+	}
+
+	logWith(connFields("reject", connRemoteAddr, proxy.UID()), "[i] Rejecting %s from a blocked country on %s", connRemoteAddr, proxy.UID())
+	if hs.IsLoginRequest() {
+		return true, proxy.handleLoginRequest(conn, loginStart, connRemoteAddr)
+	}
+	return true, proxy.handleStatusRequest(conn, false)
+}
+
+func containsCountry(countries []string, country string) bool {
+	for _, c := range countries {
+		if strings.EqualFold(c, country) {
+			return true
+		}
+	}
+	return false
+}
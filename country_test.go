@@ -0,0 +1,93 @@
+package infrared
+
+import (
+	"errors"
+	"net"
+	"testing"
+)
+
+type countryLookup map[string]string
+
+func (l countryLookup) Country(ip net.IP) (string, error) {
+	if ip.String() == "192.0.2.1" {
+		return "", errors.New("lookup failed")
+	}
+	return l[ip.String()], nil
+}
+
+func TestProxy_IsCountryAllowed(t *testing.T) {
+	lookup := countryLookup{
+		"203.0.113.7":  "DE",
+		"198.51.100.7": "US",
+	}
+
+	tt := []struct {
+		name    string
+		allowed []string
+		blocked []string
+		ip      string
+		want    bool
+	}{
+		{
+			name: "NoLists",
+			ip:   "203.0.113.7",
+			want: true,
+		},
+		{
+			name:    "Blocked",
+			blocked: []string{"us"},
+			ip:      "198.51.100.7",
+		},
+		{
+			name:    "NotBlocked",
+			blocked: []string{"US"},
+			ip:      "203.0.113.7",
+			want:    true,
+		},
+		{
+			name:    "Allowed",
+			allowed: []string{"DE", "AT"},
+			ip:      "203.0.113.7",
+			want:    true,
+		},
+		{
+			name:    "NotAllowed",
+			allowed: []string{"DE"},
+			ip:      "198.51.100.7",
+		},
+		{
+			name:    "UnknownNotAllowed",
+			allowed: []string{"DE"},
+			ip:      "10.0.0.1",
+		},
+		{
+			name:    "UnknownNotBlocked",
+			blocked: []string{"US"},
+			ip:      "10.0.0.1",
+			want:    true,
+		},
+		{
+			name:    "LookupError",
+			blocked: []string{"US"},
+			ip:      "192.0.2.1",
+			want:    true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{
+				Config: &ProxyConfig{
+					AllowedCountries: tc.allowed,
+					BlockedCountries: tc.blocked,
+				},
+				gateway: &Gateway{GeoIP: lookup},
+			}
+			addr := &net.TCPAddr{IP: net.ParseIP(tc.ip), Port: 50000}
+
+			if got := proxy.isCountryAllowed(addr); got != tc.want {
+				t.Errorf("got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
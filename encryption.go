@@ -0,0 +1,68 @@
+package infrared
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"io"
+)
+
+// cfb8 is the AES/CFB8 stream cipher that Minecraft encrypts connections
+// with. It is not part of crypto/cipher, which only has full block CFB.
+type cfb8 struct {
+	block   cipher.Block
+	iv      []byte
+	tmp     []byte
+	decrypt bool
+}
+
+func newCFB8(block cipher.Block, iv []byte, decrypt bool) cipher.Stream {
+	return &cfb8{
+		block:   block,
+		iv:      append([]byte(nil), iv...),
+		tmp:     make([]byte, block.BlockSize()),
+		decrypt: decrypt,
+	}
+}
+
+func (c *cfb8) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		c.block.Encrypt(c.tmp, c.iv)
+		in := src[i]
+		out := in ^ c.tmp[0]
+		dst[i] = out
+
+		// The ciphertext byte is shifted into the IV
+		if c.decrypt {
+			out = in
+		}
+		copy(c.iv, c.iv[1:])
+		c.iv[len(c.iv)-1] = out
+	}
+}
+
+// enableEncryption encrypts the connection with the shared secret of an
+// encryption response, which Minecraft uses as the key and the IV of
+// AES/CFB8. All data that is read after the response has to be decrypted,
+// including the data that is already buffered.
+func (c *conn) enableEncryption(sharedSecret []byte) error {
+	block, err := aes.NewCipher(sharedSecret)
+	if err != nil {
+		return err
+	}
+
+	buffered, _ := c.r.Peek(c.r.Buffered())
+	buffered = append([]byte(nil), buffered...)
+	c.rd = cipher.StreamReader{
+		S: newCFB8(block, sharedSecret, true),
+		R: io.MultiReader(bytes.NewReader(buffered), c.rd),
+	}
+	c.r = bufio.NewReader(c.rd)
+	c.w = cipher.StreamWriter{
+		S: newCFB8(block, sharedSecret, false),
+		W: c.w,
+	}
+	c.encrypted = true
+	return nil
+}
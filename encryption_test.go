@@ -0,0 +1,79 @@
+package infrared
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"net"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestCFB8(t *testing.T) {
+	// The CFB8-AES128 example of NIST SP 800-38A
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	iv, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	plaintext, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172aae2d")
+	ciphertext, _ := hex.DecodeString("3b79424c9c0dd436bace9e0ed4586a4f32b9")
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make([]byte, len(plaintext))
+	newCFB8(block, iv, false).XORKeyStream(got, plaintext)
+	if !bytes.Equal(got, ciphertext) {
+		t.Errorf("encrypt got: %x; want: %x", got, ciphertext)
+	}
+
+	newCFB8(block, iv, true).XORKeyStream(got, ciphertext)
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypt got: %x; want: %x", got, plaintext)
+	}
+}
+
+func TestConn_EnableEncryption(t *testing.T) {
+	sharedSecret := []byte("0123456789abcdef")
+	plain := protocol.MarshalPacket(0x00, protocol.String("plain"))
+	encrypted := protocol.MarshalPacket(0x01, protocol.String("encrypted"))
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	// The encrypted packet is sent right after the plain one, so that the
+	// reader may have buffered it already
+	go func() {
+		client := wrapConn(c1)
+		plainBytes, _ := plain.Marshal()
+		encryptedBytes, _ := encrypted.Marshal()
+		block, _ := aes.NewCipher(sharedSecret)
+		newCFB8(block, sharedSecret, false).XORKeyStream(encryptedBytes, encryptedBytes)
+		_, _ = client.Write(append(plainBytes, encryptedBytes...))
+	}()
+
+	c := wrapConn(c2)
+	pk, err := c.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pk.ID != plain.ID {
+		t.Errorf("got: %v; want: %v", pk.ID, plain.ID)
+	}
+
+	if err := c.enableEncryption(sharedSecret); err != nil {
+		t.Fatal(err)
+	}
+	pk, err = c.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pk.ID != encrypted.ID || !bytes.Equal(pk.Data, encrypted.Data) {
+		t.Errorf("got: %v; want: %v", pk, encrypted)
+	}
+	if _, ok := c.tcpConn(); ok {
+		t.Error("got: encrypted connection can be spliced; want: relayed in user space")
+	}
+}
@@ -0,0 +1,67 @@
+package infrared
+
+import (
+	"sync"
+
+	"github.com/haveachin/infrared/callback"
+)
+
+// eventSubscriberBuffer is the number of events that a subscriber can fall
+// behind before events are dropped for it
+const eventSubscriberBuffer = 64
+
+// eventBus fans out the events of all proxies of a gateway to its subscribers
+type eventBus struct {
+	mu          sync.Mutex
+	subscribers map[chan callback.Event]struct{}
+}
+
+func (bus *eventBus) subscribe() (<-chan callback.Event, func()) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	if bus.subscribers == nil {
+		bus.subscribers = map[chan callback.Event]struct{}{}
+	}
+
+	ch := make(chan callback.Event, eventSubscriberBuffer)
+	bus.subscribers[ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			bus.mu.Lock()
+			defer bus.mu.Unlock()
+			delete(bus.subscribers, ch)
+			close(ch)
+		})
+	}
+}
+
+// publish sends the event to all subscribers without blocking.
+// Subscribers that are too slow miss the event.
+func (bus *eventBus) publish(event callback.Event) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	for ch := range bus.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// SubscribeEvents returns a channel that receives the events of all proxies
+// of the gateway and a function that ends the subscription
+func (gateway *Gateway) SubscribeEvents() (<-chan callback.Event, func()) {
+	return gateway.events.subscribe()
+}
+
+// Sessions returns all players that are connected through the gateway
+func (gateway *Gateway) Sessions() []Session {
+	var sessions []Session
+	gateway.Proxies.Range(func(_, v interface{}) bool {
+		sessions = append(sessions, v.(*Proxy).Sessions()...)
+		return true
+	})
+	return sessions
+}
@@ -0,0 +1,26 @@
+package infrared
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/haveachin/infrared/callback"
+)
+
+func TestEventBus(t *testing.T) {
+	var bus eventBus
+	events, unsubscribe := bus.subscribe()
+
+	event := callback.PlayerJoinEvent{Username: "Steve"}
+	bus.publish(event)
+
+	if got := <-events; !reflect.DeepEqual(got, event) {
+		t.Errorf("got: %v; want: %v", got, event)
+	}
+
+	unsubscribe()
+	bus.publish(event)
+	if _, ok := <-events; ok {
+		t.Error("got: event after unsubscribing; want: closed channel")
+	}
+}
@@ -0,0 +1,73 @@
+package infrared
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"time"
+
+	"github.com/haveachin/infrared/callback"
+)
+
+// eventSocketWriteTimeout is the time a reader of the event socket gets to
+// read an event before it is disconnected
+const eventSocketWriteTimeout = 5 * time.Second
+
+// ListenAndServeEventSocket streams the events of all proxies as newline
+// delimited JSON to every reader of the Unix socket at path. Readers that
+// are too slow miss events instead of slowing down the proxies.
+func (gateway *Gateway) ListenAndServeEventSocket(path string) error {
+	// Remove the socket of a previous run
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+
+	log.Println("[i] Streaming events to", path)
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go gateway.streamEvents(c)
+	}
+}
+
+func (gateway *Gateway) streamEvents(c net.Conn) {
+	defer c.Close()
+
+	events, unsubscribe := gateway.SubscribeEvents()
+	defer unsubscribe()
+
+	// Readers don't send anything; reading only notices when they leave
+	go func() {
+		_, _ = io.Copy(ioutil.Discard, c)
+		unsubscribe()
+	}()
+
+	enc := json.NewEncoder(c)
+	for event := range events {
+		if err := c.SetWriteDeadline(time.Now().Add(eventSocketWriteTimeout)); err != nil {
+			return
+		}
+
+		if err := enc.Encode(callback.EventLog{
+			Event:     event.EventType(),
+			Timestamp: time.Now(),
+			Payload:   event,
+		}); err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package infrared
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/haveachin/infrared/callback"
+)
+
+func (bus *eventBus) subscriberCount() int {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	return len(bus.subscribers)
+}
+
+func TestGateway_ListenAndServeEventSocket(t *testing.T) {
+	// The path of a Unix socket has to be short
+	dir, err := ioutil.TempDir("", "ev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "events.sock")
+
+	gateway := &Gateway{}
+	go func() {
+		_ = gateway.ListenAndServeEventSocket(path)
+	}()
+
+	var c net.Conn
+	for i := 0; i < 100; i++ {
+		c, err = net.Dial("unix", path)
+		if err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	for i := 0; i < 100 && gateway.events.subscriberCount() == 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	gateway.events.publish(callback.PlayerJoinEvent{
+		Username: "Steve",
+		ProxyUID: "localhost@:25565",
+	})
+
+	if err := c.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	line, err := bufio.NewReader(c).ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var eventLog struct {
+		Event   string                   `json:"event"`
+		Payload callback.PlayerJoinEvent `json:"payload"`
+	}
+	if err := json.Unmarshal(line, &eventLog); err != nil {
+		t.Fatal(err)
+	}
+
+	if eventLog.Event != callback.EventTypePlayerJoin {
+		t.Errorf("got: %v; want: %v", eventLog.Event, callback.EventTypePlayerJoin)
+	}
+	if eventLog.Payload.Username != "Steve" {
+		t.Errorf("got: %v; want: %v", eventLog.Payload.Username, "Steve")
+	}
+
+	// Closing the reader ends the subscription
+	c.Close()
+	for i := 0; i < 100 && gateway.events.subscriberCount() > 0; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := gateway.events.subscriberCount(); n != 0 {
+		t.Errorf("got: %d subscribers; want: 0", n)
+	}
+}
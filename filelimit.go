@@ -0,0 +1,82 @@
+package infrared
+
+import (
+	"errors"
+	"log"
+)
+
+const (
+	// filesPerPlayer are the client and the server connection of a player
+	filesPerPlayer = 2
+	// reservedFiles are the files that Infrared needs besides the
+	// connections, e.g. listeners, configs and the connections to callback
+	// servers
+	reservedFiles = 64
+	// minFileLimit is the limit that is recommended if no proxy limits its
+	// connections
+	minFileLimit = 4096
+)
+
+var errFileLimitUnsupported = errors.New("open file limit is not supported on this platform")
+
+// fileLimitCapacity returns the number of players that fit into a limit of
+// open files
+func fileLimitCapacity(limit uint64) uint64 {
+	if limit <= reservedFiles {
+		return 0
+	}
+	return (limit - reservedFiles) / filesPerPlayer
+}
+
+// requiredFileLimit returns the open file limit that the connection limits
+// of the proxies need. Without limits the minimum file limit is required.
+func requiredFileLimit(proxies []*Proxy) uint64 {
+	var players uint64
+	for _, proxy := range proxies {
+		if proxy.MaxConnections() <= 0 {
+			return uint64(minFileLimit)
+		}
+		players += uint64(proxy.MaxConnections())
+		if proxy.QueueEnabled() {
+			// Queued players only hold a connection while they are told their position
+			players++
+		}
+	}
+
+	required := players*filesPerPlayer + reservedFiles
+	if required < minFileLimit {
+		return minFileLimit
+	}
+	return required
+}
+
+// checkFileLimit logs the open file limit of the process and how many
+// players fit into it. If the limit is too low for the connection limits of
+// the proxies, a warning is logged, since hitting it makes accepting new
+// connections fail with "too many open files". With raise the soft limit is
+// raised to the hard limit first.
+func checkFileLimit(proxies []*Proxy, raise bool) {
+	soft, hard, err := fileLimit()
+	if errors.Is(err, errFileLimitUnsupported) {
+		return
+	}
+	if err != nil {
+		log.Println("[w] Failed to read the open file limit; error:", err)
+		return
+	}
+
+	required := requiredFileLimit(proxies)
+	if raise && soft < required && soft < hard {
+		if err := raiseFileLimit(); err != nil {
+			log.Printf("[w] Failed to raise the open file limit from %d to %d; error: %s", soft, hard, err)
+		} else {
+			log.Printf("[i] Raised the open file limit from %d to %d", soft, hard)
+			soft = hard
+		}
+	}
+
+	log.Printf("[i] Open file limit is %d (hard limit %d); that is enough for about %d players", soft, hard, fileLimitCapacity(soft))
+	if soft < required {
+		log.Printf("[w] Open file limit of %d is lower than the %d that the connection limits of the proxies need; raise it with ulimit -n or LimitNOFILE", soft, required)
+	}
+}
@@ -0,0 +1,12 @@
+//go:build windows || plan9 || js
+// +build windows plan9 js
+
+package infrared
+
+func fileLimit() (uint64, uint64, error) {
+	return 0, 0, errFileLimitUnsupported
+}
+
+func raiseFileLimit() error {
+	return errFileLimitUnsupported
+}
@@ -0,0 +1,58 @@
+package infrared
+
+import "testing"
+
+func TestRequiredFileLimit(t *testing.T) {
+	tt := []struct {
+		name    string
+		configs []*ProxyConfig
+		want    uint64
+	}{
+		{
+			name:    "Unlimited",
+			configs: []*ProxyConfig{{MaxConnections: 100}, {}},
+			want:    minFileLimit,
+		},
+		{
+			name:    "BelowMinimum",
+			configs: []*ProxyConfig{{MaxConnections: 100}},
+			want:    minFileLimit,
+		},
+		{
+			name:    "Limited",
+			configs: []*ProxyConfig{{MaxConnections: 3000}, {MaxConnections: 2000}},
+			want:    5000*filesPerPlayer + reservedFiles,
+		},
+		{
+			name:    "Queue",
+			configs: []*ProxyConfig{{MaxConnections: 5000, QueueEnabled: true}},
+			want:    5001*filesPerPlayer + reservedFiles,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := requiredFileLimit(configsToProxies(tc.configs)); got != tc.want {
+				t.Errorf("got: %d; want: %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFileLimitCapacity(t *testing.T) {
+	tt := []struct {
+		limit uint64
+		want  uint64
+	}{
+		{limit: 0, want: 0},
+		{limit: reservedFiles, want: 0},
+		{limit: 1024, want: (1024 - reservedFiles) / filesPerPlayer},
+		{limit: 1048576, want: (1048576 - reservedFiles) / filesPerPlayer},
+	}
+
+	for _, tc := range tt {
+		if got := fileLimitCapacity(tc.limit); got != tc.want {
+			t.Errorf("got: %d; want: %d", got, tc.want)
+		}
+	}
+}
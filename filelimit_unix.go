@@ -0,0 +1,24 @@
+//go:build !windows && !plan9 && !js
+// +build !windows,!plan9,!js
+
+package infrared
+
+import "syscall"
+
+func fileLimit() (uint64, uint64, error) {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return 0, 0, err
+	}
+	return uint64(rlimit.Cur), uint64(rlimit.Max), nil
+}
+
+// raiseFileLimit raises the soft limit to the hard limit
+func raiseFileLimit() error {
+	var rlimit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rlimit); err != nil {
+		return err
+	}
+	rlimit.Cur = rlimit.Max
+	return syscall.Setrlimit(syscall.RLIMIT_NOFILE, &rlimit)
+}
@@ -0,0 +1,48 @@
+package infrared
+
+// Forwarding is how the address of the client is forwarded to the server.
+// RealIP and BungeeCord are mutually exclusive.
+type Forwarding struct {
+	RealIP        bool `json:"realIp"`
+	ProxyProtocol bool `json:"proxyProtocol"`
+	BungeeCord    bool `json:"bungeeCord"`
+}
+
+// Validate returns an error if the forwarding modes contradict each other
+func (forwarding Forwarding) Validate() error {
+	if forwarding.RealIP && forwarding.BungeeCord {
+		return errRealIPAndBungeeCord
+	}
+	return nil
+}
+
+// Forwarding returns all forwarding flags at once, so that a connection
+// never sees half of a change
+func (proxy *Proxy) Forwarding() Forwarding {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return Forwarding{
+		RealIP:        proxy.Config.RealIP,
+		ProxyProtocol: proxy.Config.ProxyProtocol,
+		BungeeCord:    proxy.Config.BungeeCordForwarding,
+	}
+}
+
+// SetForwarding changes the forwarding of all new connections without a
+// restart. The config file takes over again when it changes.
+func (proxy *Proxy) SetForwarding(forwarding Forwarding) {
+	proxy.Config.Lock()
+	defer proxy.Config.Unlock()
+	proxy.Config.RealIP = forwarding.RealIP
+	proxy.Config.ProxyProtocol = forwarding.ProxyProtocol
+	proxy.Config.BungeeCordForwarding = forwarding.BungeeCord
+}
+
+// Proxy returns the registered proxy with the UID
+func (gateway *Gateway) Proxy(proxyUID string) (*Proxy, bool) {
+	v, ok := gateway.Proxies.Load(proxyUID)
+	if !ok {
+		return nil, false
+	}
+	return v.(*Proxy), true
+}
@@ -0,0 +1,55 @@
+package infrared
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestProxy_SetForwarding(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{RealIP: true}}
+
+	want := Forwarding{RealIP: false, ProxyProtocol: true}
+	proxy.SetForwarding(want)
+	if got := proxy.Forwarding(); got != want {
+		t.Errorf("got: %v; want: %v", got, want)
+	}
+	if proxy.RealIP() || !proxy.ProxyProtocol() {
+		t.Errorf("got: real IP %t and proxy protocol %t; want: %v", proxy.RealIP(), proxy.ProxyProtocol(), want)
+	}
+}
+
+func TestProxy_Forwarding_Consistent(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{}}
+
+	// Both flags are always toggled together, so a reader never sees them differ
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 1000; i++ {
+			on := i%2 == 0
+			proxy.SetForwarding(Forwarding{RealIP: on, ProxyProtocol: on})
+		}
+	}()
+
+	for i := 0; i < 1000; i++ {
+		forwarding := proxy.Forwarding()
+		if forwarding.RealIP != forwarding.ProxyProtocol {
+			t.Fatalf("got: %v; want: equal flags", forwarding)
+		}
+	}
+	wg.Wait()
+}
+
+func TestGateway_Proxy(t *testing.T) {
+	gateway := &Gateway{}
+	proxy := &Proxy{Config: &ProxyConfig{DomainName: "mc.example.com", ListenTo: ":25565"}}
+	gateway.Proxies.Store(proxy.UID(), proxy)
+
+	if got, ok := gateway.Proxy(proxy.UID()); !ok || got != proxy {
+		t.Errorf("got: %v, %v; want: %v, true", got, ok, proxy)
+	}
+	if _, ok := gateway.Proxy("unknown@:25565"); ok {
+		t.Error("got: true; want: false")
+	}
+}
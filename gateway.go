@@ -2,32 +2,177 @@ package infrared
 
 import (
 	"errors"
+	"fmt"
+	"io"
 	"log"
+	"net"
 	"net/http"
+	"strconv"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/haveachin/infrared/acl"
 	"github.com/haveachin/infrared/callback"
+	"github.com/haveachin/infrared/geoip"
 	"github.com/haveachin/infrared/protocol/handshaking"
-	"github.com/pires/go-proxyproto"
-
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
-)
-
-var (
-	proxiesActive = promauto.NewGauge(prometheus.GaugeOpts{
-		Name: "infrared_proxies",
-		Help: "The total number of proxies running",
-	})
 )
 
 type Gateway struct {
-	listeners            sync.Map
-	Proxies              sync.Map
-	closed               chan bool
-	wg                   sync.WaitGroup
-	receiveProxyProtocol bool
+	// activeConns is first to be 64-bit aligned for atomic operations
+	activeConns int64
+
+	listeners sync.Map
+	Proxies   sync.Map
+	closed    chan bool
+	wg        sync.WaitGroup
+
+	// ReceiveProxyProtocol expects a PROXY protocol header at the start of
+	// every connection, e.g. from a load balancer in front of the gateway
+	ReceiveProxyProtocol bool
+	// RequireProxyProtocolVersion rejects the PROXY protocol headers of
+	// other versions if it is 1 or 2. Zero accepts both.
+	RequireProxyProtocolVersion int
+	// ProxyProtocolTrustedIPs are the IPs and CIDR ranges of the load
+	// balancers whose PROXY protocol headers are read. Other clients are
+	// treated as direct clients, so that they cannot spoof their IP. Empty
+	// trusts every client.
+	ProxyProtocolTrustedIPs []string
+
+	// RelayBufferSize is the size in bytes of the buffers that are used to
+	// relay the traffic between clients and servers. Unless DisableZeroCopy
+	// is set, plain TCP connections are spliced without them, even with a
+	// PROXY protocol header, so it only applies to TLS, Unix sockets and
+	// intercepted online mode logins, which are encrypted by Infrared.
+	RelayBufferSize int
+	// DisableZeroCopy relays plain TCP connections through the relay buffers
+	// instead of letting the kernel splice them
+	DisableZeroCopy bool
+	// TCPKeepAlive is the idle time after which TCP keep-alive probes are
+	// sent on the connections of clients and to servers. Zero keeps the
+	// default of Go and a negative value disables them.
+	TCPKeepAlive time.Duration
+	// DisableTCPNoDelay lets the kernel delay small writes to coalesce them
+	// (Nagle's algorithm), which adds latency to Minecraft traffic
+	DisableTCPNoDelay bool
+	// TLSCertFile and TLSKeyFile are the PEM files of the certificate that
+	// terminates the TLS of every listener. The handshake is read from the
+	// decrypted stream. Both empty listen for plain TCP.
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// AcceptLogInterval additionally logs the number of accepted connections
+	// in one info line per listener and interval. Every accepted connection
+	// is still logged at debug level.
+	AcceptLogInterval time.Duration
+
+	// UnmatchedAction is what happens to clients that request a domain that
+	// no proxy has. One of the UnmatchedAction constants; defaults to
+	// UnmatchedActionDefaultServer if DefaultServer is set and to
+	// UnmatchedActionRespond otherwise.
+	UnmatchedAction string
+	// DefaultServer is the domain name or the UID of the proxy that
+	// unmatched clients are routed to with UnmatchedActionDefaultServer
+	DefaultServer string
+	// UnmatchedMOTD is the MOTD of the status that UnmatchedActionRespond
+	// answers with; empty means "Unknown server"
+	UnmatchedMOTD string
+	// UnmatchedMessage is the message that UnmatchedActionRespond
+	// disconnects logins with; empty means "There is no server with this address."
+	UnmatchedMessage string
+
+	// HTTPProbeStatus is the status code that HTTP requests on the Minecraft
+	// ports are answered with, e.g. the ones of uptime monitors.
+	// Zero disables the detection of HTTP requests.
+	HTTPProbeStatus int
+	// HTTPProbeBody is the body of the response to HTTP requests
+	HTTPProbeBody string
+
+	// MaxPacketSize is the length in bytes of the longest packet that is
+	// read from clients, e.g. the handshake. Clients that announce a longer
+	// one are disconnected before it is buffered. Zero means
+	// protocol.MaxPacketSize.
+	MaxPacketSize int
+
+	// HealthPath and ReadyPath are the paths of the liveness and the
+	// readiness probe next to the Prometheus metrics; empty means
+	// DefaultHealthPath and DefaultReadyPath and "-" disables a probe
+	HealthPath string
+	ReadyPath  string
+
+	// RaiseFileLimit raises the soft limit of open files to the hard limit
+	// at the start if it is too low for the connection limits of the proxies
+	RaiseFileLimit bool
+
+	// LogSessionStats adds the duration and the relayed bytes of a
+	// connection to the log line of its close
+	LogSessionStats bool
+
+	// ClientTimeout is the time that clients get to send their handshake
+	// and finish their login before they are proxied. Proxies can override
+	// it. Zero disables it.
+	ClientTimeout time.Duration
+
+	// LogCompression logs the compression threshold that the server of a
+	// login set. It can't be seen for online mode servers.
+	LogCompression bool
+
+	// AccessLog receives an entry per completed connection in the
+	// AccessLogFormat. Nil disables the access log.
+	AccessLog       io.Writer
+	AccessLogFormat string
+
+	// ACLStore holds the bans and whitelists that are checked before a
+	// connection is proxied. Nil disables all checks.
+	ACLStore acl.Store
+	// ACLFailOpen lets clients through if the ACL store fails to answer,
+	// e.g. because its server is down. By default they are disconnected.
+	ACLFailOpen bool
+
+	// GeoIP resolves the countries of clients for the allowed and blocked
+	// countries of the proxies. Nil disables the country checks.
+	GeoIP geoip.Lookup
+
+	// ProcessConcurrency is the maximum number of container starts and
+	// stops that run at the same time. The others are queued. Zero means
+	// unlimited.
+	ProcessConcurrency int
+
+	// OverloadMaxGoroutines and OverloadMaxConnections are the limits of the
+	// overload protection. Above one of them new connections are refused
+	// until the gateway recovers. Zero disables a limit.
+	OverloadMaxGoroutines  int
+	OverloadMaxConnections int
+
+	// ConnRateLimit is the number of connections per second that an IP may
+	// open. The ones above it are closed before they reach a proxy. Zero
+	// disables it.
+	ConnRateLimit float64
+	// ConnRateBurst is the number of connections that an IP may open at once
+	// within ConnRateLimit; defaults to ConnRateLimit rounded up
+	ConnRateBurst int
+
+	overloaded int32
+	// shuttingDown is set once Shutdown was called
+	shuttingDown int32
+	// ready is set once ListenAndServe registered all proxies
+	ready int32
+
+	buffersOnce sync.Once
+	buffers     *bufferPool
+
+	connBucketsOnce sync.Once
+	connBuckets     *ttlCache
+
+	processSlotsOnce sync.Once
+	processSlots     chan struct{}
+
+	events eventBus
+
+	metricsMu     sync.Mutex
+	metricsServer *http.Server
+
+	accessLog *accessLogger
 }
 
 func (gateway *Gateway) ListenAndServe(proxies []*Proxy) error {
@@ -36,6 +181,14 @@ func (gateway *Gateway) ListenAndServe(proxies []*Proxy) error {
 	}
 
 	gateway.closed = make(chan bool, len(proxies))
+	if gateway.AccessLog != nil {
+		logger, err := newAccessLogger(gateway.AccessLog, gateway.AccessLogFormat)
+		if err != nil {
+			return err
+		}
+		gateway.accessLog = logger
+	}
+	checkFileLimit(proxies, gateway.RaiseFileLimit)
 
 	for _, proxy := range proxies {
 		if err := gateway.RegisterProxy(proxy); err != nil {
@@ -44,24 +197,58 @@ func (gateway *Gateway) ListenAndServe(proxies []*Proxy) error {
 		}
 	}
 
+	atomic.StoreInt32(&gateway.ready, 1)
 	log.Println("All proxies are online")
 	return nil
 }
 
+// EnablePrometheus serves the Prometheus metrics and the probes on bind
+// until the gateway shuts down. It fails if bind cannot be listened on.
 func (gateway *Gateway) EnablePrometheus(bind string) error {
-	gateway.wg.Add(1)
+	l, err := net.Listen("tcp", bind)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{Handler: gateway.metricsHandler()}
+	gateway.metricsMu.Lock()
+	gateway.metricsServer = server
+	gateway.metricsMu.Unlock()
 
+	gateway.wg.Add(1)
 	go func() {
 		defer gateway.wg.Done()
 
-		http.Handle("/metrics", promhttp.Handler())
-		http.ListenAndServe(bind, nil)
+		if err := server.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.Println("Failed serving Prometheus metrics; error:", err)
+		}
 	}()
 
 	log.Println("Enabling Prometheus metrics endpoint on", bind)
 	return nil
 }
 
+// EnableStatsD sends all metrics additionally to the StatsD server on addr.
+// Every metric name is prefixed with prefix. If dogStatsD is set, labels are
+// sent as DogStatsD tags instead of being appended to the metric name.
+func (gateway *Gateway) EnableStatsD(addr, prefix string, dogStatsD bool) error {
+	recorder, err := newStatsdRecorder(addr, prefix, dogStatsD)
+	if err != nil {
+		return err
+	}
+	metrics.add(recorder)
+
+	log.Println("Enabling StatsD metrics export to", addr)
+	return nil
+}
+
+func (gateway *Gateway) relayBuffers() *bufferPool {
+	gateway.buffersOnce.Do(func() {
+		gateway.buffers = newBufferPool(gateway.RelayBufferSize)
+	})
+	return gateway.buffers
+}
+
 func (gateway *Gateway) KeepProcessActive() {
 	gateway.wg.Wait()
 }
@@ -76,57 +263,71 @@ func (gateway *Gateway) Close() {
 }
 
 func (gateway *Gateway) CloseProxy(proxyUID string) {
-	log.Println("Closing proxy with UID", proxyUID)
-	v, ok := gateway.Proxies.LoadAndDelete(proxyUID)
-	if !ok {
-		return
-	}
-	proxiesActive.Dec()
-	proxy := v.(*Proxy)
-
-	closeListener := true
-	gateway.Proxies.Range(func(k, v interface{}) bool {
-		otherProxy := v.(*Proxy)
-		if proxy.ListenTo() == otherProxy.ListenTo() {
-			closeListener = false
-			return false
-		}
-		return true
-	})
-
-	if !closeListener {
+	if gateway.unregisterProxy(proxyUID) == nil {
 		return
 	}
+	gateway.closeUnusedListeners()
+}
 
-	v, ok = gateway.listeners.Load(proxy.ListenTo())
+// unregisterProxy removes the proxy and stops its background tasks, but
+// leaves its listener open. It returns nil if no proxy has the UID.
+func (gateway *Gateway) unregisterProxy(proxyUID string) *Proxy {
+	log.Println("Closing proxy with UID", proxyUID)
+	v, ok := gateway.Proxies.LoadAndDelete(proxyUID)
 	if !ok {
-		return
+		return nil
 	}
-	v.(Listener).Close()
+	metrics.AddProxies(-1)
+	proxy := v.(*Proxy)
+	proxy.stopBackgroundTasks()
+	return proxy
 }
 
 func (gateway *Gateway) RegisterProxy(proxy *Proxy) error {
 	// Register new Proxy
 	proxyUID := proxy.UID()
 	log.Println("Registering proxy with UID", proxyUID)
-	gateway.Proxies.Store(proxyUID, proxy)
-	proxiesActive.Inc()
+	if v, ok := gateway.Proxies.Load(proxyUID); ok {
+		// The proxy replaces the one with the same UID; the connections of
+		// the replaced one keep running on it until they close
+		gateway.Proxies.Store(proxyUID, proxy)
+		if replaced := v.(*Proxy); replaced != proxy {
+			replaced.stopBackgroundTasks()
+		}
+	} else {
+		gateway.Proxies.Store(proxyUID, proxy)
+		metrics.AddProxies(1)
+	}
+	proxy.gateway = gateway
+
+	// The callbacks only apply while the proxy is the registered one, so
+	// that a replaced proxy can't remove its successor
+	isRegistered := func() bool {
+		v, ok := gateway.Proxies.Load(proxyUID)
+		return ok && v.(*Proxy) == proxy
+	}
 
 	proxy.Config.removeCallback = func() {
-		gateway.CloseProxy(proxyUID)
+		if isRegistered() {
+			gateway.CloseProxy(proxyUID)
+		}
 	}
 
 	proxy.Config.changeCallback = func() {
-		if proxyUID == proxy.UID() {
+		if proxyUID == proxy.UID() || !isRegistered() {
 			return
 		}
-		gateway.CloseProxy(proxyUID)
+		gateway.unregisterProxy(proxyUID)
 		if err := gateway.RegisterProxy(proxy); err != nil {
 			log.Println(err)
 		}
+		gateway.closeUnusedListeners()
 	}
 
-	playersConnected.WithLabelValues(proxy.DomainName())
+	metrics.AddConnectedPlayers(proxy.DomainName(), TransportTCP, 0)
+	proxy.startStatusPrewarm()
+	proxy.startWarmPool()
+	proxy.startHealthCheck()
 
 	// Check if a gate is already listening to the Proxy address
 	addr := proxy.ListenTo()
@@ -134,12 +335,18 @@ func (gateway *Gateway) RegisterProxy(proxy *Proxy) error {
 		return nil
 	}
 
+	if atomic.LoadInt32(&gateway.shuttingDown) == 1 {
+		return errors.New("gateway is shutting down")
+	}
+
 	log.Println("Creating listener on", addr)
-	listener, err := Listen(addr)
+	listener, err := gateway.listen(addr)
 	if err != nil {
 		return err
 	}
+	listener.MaxPacketSize = gateway.MaxPacketSize
 	gateway.listeners.Store(addr, listener)
+	metrics.AddListeners(1)
 
 	gateway.wg.Add(1)
 	go func() {
@@ -152,40 +359,131 @@ func (gateway *Gateway) RegisterProxy(proxy *Proxy) error {
 
 func (gateway *Gateway) listenAndServe(listener Listener, addr string) error {
 	defer gateway.wg.Done()
+	// The listener only stops serving once it is closed
+	defer metrics.AddListeners(-1)
+
+	var accepted int64
+	if gateway.AcceptLogInterval > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go logAccepted(&accepted, addr, gateway.AcceptLogInterval, done)
+	}
 
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
-			// TODO: Refactor this; it feels hacky
-			if err.Error() == "use of closed network connection" {
+			// A closed listener fails with a *net.OpError that wraps net.ErrClosed
+			if errors.Is(err, net.ErrClosed) {
 				log.Println("Closing listener on", addr)
-				gateway.listeners.Delete(addr)
+				// A reload may have replaced the listener already
+				if v, ok := gateway.listeners.Load(addr); ok && v.(Listener) == listener {
+					gateway.listeners.Delete(addr)
+				}
 				return nil
 			}
 
 			continue
 		}
 
+		if gateway.isOverloaded() {
+			metrics.IncOverloadRefusals(addr)
+			conn.Close()
+			continue
+		}
+
+		if err := gateway.setTCPOptions(conn); err != nil {
+			log.Printf("[w] Failed to set the TCP options of %s; error: %s", conn.RemoteAddr(), err)
+		}
+
+		atomic.AddInt64(&gateway.activeConns, 1)
 		go func() {
-			log.Printf("[>] Incoming %s on listener %s", conn.RemoteAddr(), addr)
+			defer atomic.AddInt64(&gateway.activeConns, -1)
+			if gateway.AcceptLogInterval > 0 {
+				atomic.AddInt64(&accepted, 1)
+			}
+			logWith(logFields{"event": "incoming", "remote_addr": conn.RemoteAddr().String(), "listener": addr, "transport": conn.Transport()},
+				"[>] Incoming %s on listener %s via %s", conn.RemoteAddr(), addr, conn.Transport())
 			defer conn.Close()
-			if err := gateway.serve(conn, addr); err != nil {
-				log.Printf("[x] %s closed connection with %s; error: %s", conn.RemoteAddr(), addr, err)
+			start := time.Now()
+			var entry *accessEntry
+			if gateway.accessLog != nil {
+				entry = trackAccess(conn, start)
+			}
+			err := gateway.serve(conn, addr)
+			gateway.logAccess(conn, entry, err)
+
+			var stats string
+			if gateway.LogSessionStats {
+				received, sent := relayedBytes(conn)
+				stats = fmt.Sprintf(" after %s; received %d bytes, sent %d bytes", time.Since(start).Round(time.Millisecond), received, sent)
+			}
+
+			if err != nil {
+				logWith(logFields{"event": "closed", "remote_addr": conn.RemoteAddr().String(), "listener": addr, "error": err.Error()},
+					"[x] %s closed connection with %s%s; error: %s", conn.RemoteAddr(), addr, stats, err)
 				return
 			}
-			log.Printf("[x] %s closed connection with %s", conn.RemoteAddr(), addr)
+			logWith(logFields{"event": "closed", "remote_addr": conn.RemoteAddr().String(), "listener": addr},
+				"[x] %s closed connection with %s%s", conn.RemoteAddr(), addr, stats)
 		}()
 	}
 }
 
+// matchProxy returns the proxy that the handshake requests on the listener
+// on addr and its UID. Without a match the proxy is nil and the UID is the
+// requested one.
+func (gateway *Gateway) matchProxy(hs handshaking.ServerBoundHandshake, addr string) (*Proxy, string) {
+	proxyUID := proxyUID(hs.ParseServerAddress(), addr)
+	// Proxies with a port in their domain name take precedence over the
+	// ones without, so that the requested port can route to another server
+	proxyUIDWithPort := proxyUIDWithPort(hs, addr)
+
+	v, ok := gateway.Proxies.Load(proxyUIDWithPort)
+	if ok {
+		return v.(*Proxy), proxyUIDWithPort
+	}
+	if v, ok := gateway.Proxies.Load(proxyUID); ok {
+		return v.(*Proxy), proxyUID
+	}
+
+	if subdomainProxy, ok := gateway.loadSubdomainProxy(hs, addr); ok {
+		// Full domain names take precedence over subdomain routes
+		return subdomainProxy, subdomainProxy.UID()
+	}
+	if wildcardProxy, uid, ok := gateway.loadWildcardProxy(hs, addr); ok {
+		// Wildcard domain names only match if nothing more specific does
+		return wildcardProxy, uid
+	}
+	return nil, proxyUID
+}
+
 func (gateway *Gateway) serve(conn Conn, addr string) error {
-	connRemoteAddr := conn.RemoteAddr()
-	if gateway.receiveProxyProtocol {
-		header, err := proxyproto.Read(conn.Reader())
+	if err := setClientDeadline(conn, gateway.ClientTimeout); err != nil {
+		return err
+	}
+
+	connRemoteAddr := peerAddr(conn)
+	if gateway.ReceiveProxyProtocol && gateway.trustsProxyProtocol(connRemoteAddr) {
+		header, err := readProxyProtocolHeader(conn, gateway.RequireProxyProtocolVersion)
 		if err != nil {
+			gateway.handleProxyProtocolError(conn, addr, err)
 			return err
 		}
 		connRemoteAddr = header.SourceAddr
+		setConnProxyTLVs(conn, proxyProtocolTLVs(header))
+	}
+
+	if !gateway.allowConnection(connRemoteAddr) {
+		metrics.IncRateLimitedConnections(addr)
+		return nil
+	}
+
+	if gateway.HTTPProbeStatus != 0 && isHTTPRequest(conn.Reader()) {
+		return gateway.handleHTTPProbe(conn)
+	}
+
+	if isLegacyPing(conn.Reader()) {
+		return gateway.handleLegacyPing(conn, addr)
 	}
 
 	pk, err := conn.PeekPacket()
@@ -198,18 +496,36 @@ func (gateway *Gateway) serve(conn Conn, addr string) error {
 		return err
 	}
 
-	proxyUID := proxyUID(hs.ParseServerAddress(), addr)
+	recordAccess(conn, func(entry *accessEntry) {
+		entry.RemoteIP = remoteIP(connRemoteAddr)
+		entry.Host = hs.ParseServerAddress()
+		entry.Protocol = int(hs.ProtocolVersion)
+	})
 
-	log.Printf("[i] %s requests proxy with UID %s", connRemoteAddr, proxyUID)
-	v, ok := gateway.Proxies.Load(proxyUID)
-	if !ok {
-		// Client send an invalid address/port; we don't have a v for that address
-		return errors.New("no proxy with uid " + proxyUID)
+	proxy, proxyUID := gateway.matchProxy(hs, addr)
+	logWith(connFields("request", connRemoteAddr, proxyUID), "[>] %s requests proxy with UID %s", connRemoteAddr, proxyUID)
+
+	// The requested domain is up to the client, so only the domains of the
+	// proxies are used as labels
+	host := unknownHost
+	if proxy != nil {
+		host = proxy.DomainName()
+	}
+	metrics.IncHandshakes(host, handshakeType(hs), conn.Transport())
+
+	if proxy == nil {
+		// Client send an invalid address/port; we don't have a proxy for that address
+		proxy, err = gateway.handleUnmatched(conn, hs, proxyUID, addr)
+		if proxy == nil {
+			return err
+		}
+		proxyUID = proxy.UID()
 	}
-	proxy := v.(*Proxy)
 
+	recordAccess(conn, func(entry *accessEntry) { entry.ServerID = proxyUID })
 	if err := proxy.handleConn(conn, connRemoteAddr); err != nil {
-		proxy.CallbackLogger().LogEvent(callback.ErrorEvent{
+		proxy.recordViolation(err, connRemoteAddr)
+		proxy.logEvent(callback.ErrorEvent{
 			Error:    err.Error(),
 			ProxyUID: proxyUID,
 		})
@@ -217,3 +533,28 @@ func (gateway *Gateway) serve(conn Conn, addr string) error {
 	}
 	return nil
 }
+
+// logAccepted logs the number of connections that were accepted on the
+// listener within every interval until done is closed
+func logAccepted(accepted *int64, addr string, interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if n := atomic.SwapInt64(accepted, 0); n > 0 {
+				log.Printf("[i] Accepted %d connections on listener %s in the last %s", n, addr, interval)
+			}
+		}
+	}
+}
+
+// proxyUIDWithPort returns the UID of a proxy whose domain name contains
+// the port that the client requested in its handshake
+func proxyUIDWithPort(hs handshaking.ServerBoundHandshake, addr string) string {
+	domain := net.JoinHostPort(hs.ParseServerAddress(), strconv.Itoa(int(hs.ServerPort)))
+	return proxyUID(domain, addr)
+}
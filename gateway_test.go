@@ -1,12 +1,16 @@
 package infrared
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"net"
+	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/haveachin/infrared/protocol"
 	"github.com/haveachin/infrared/protocol/handshaking"
@@ -417,7 +421,7 @@ func TestProxyProtocol(t *testing.T) {
 			go func(wg *sync.WaitGroup) {
 				config := createProxyProtocolConfig(tc.portEnd, tc.proxyproto)
 				gateway := Gateway{
-					receiveProxyProtocol: tc.receiveProxyproto,
+					ReceiveProxyProtocol: tc.receiveProxyproto,
 				}
 				proxies := configToProxies(config)
 				if err := gateway.ListenAndServe(proxies); err != nil {
@@ -478,6 +482,8 @@ func TestRouting(t *testing.T) {
 		id      int
 		domain  string
 		portEnd int
+		// subdomainRoutes maps subdomains to the ids of other servers
+		subdomainRoutes map[string]int
 	}{
 		{
 			id:      0,
@@ -499,12 +505,46 @@ func TestRouting(t *testing.T) {
 			domain:  ".dottedInfrared.",
 			portEnd: 530,
 		},
+		{
+			id:      3,
+			domain:  "infrared:25566",
+			portEnd: 530,
+		},
+		{
+			id:      4,
+			domain:  "hub.infrared",
+			portEnd: 530,
+			subdomainRoutes: map[string]int{
+				"creative": 5,
+			},
+		},
+		{
+			id:      5,
+			domain:  "creative-target",
+			portEnd: 530,
+		},
+		{
+			id:      6,
+			domain:  "*.wild.infrared",
+			portEnd: 532,
+		},
+		{
+			id:      7,
+			domain:  "lobby.wild.infrared",
+			portEnd: 532,
+		},
+		{
+			id:      8,
+			domain:  "*",
+			portEnd: 532,
+		},
 	}
 
 	tt := []struct {
 		name          string
 		expectedId    int
 		requestDomain string
+		requestPort   int
 		portEnd       int
 		expectError   bool
 		shouldMatch   bool
@@ -549,17 +589,101 @@ func TestRouting(t *testing.T) {
 			expectError:   true,
 			shouldMatch:   false,
 		},
+		{
+			name:          "Domain with port",
+			expectedId:    3,
+			requestDomain: "infrared",
+			requestPort:   25566,
+			portEnd:       530,
+			expectError:   false,
+			shouldMatch:   true,
+		},
+		{
+			name:          "Domain with unknown port",
+			expectedId:    0,
+			requestDomain: "infrared",
+			requestPort:   25567,
+			portEnd:       530,
+			expectError:   false,
+			shouldMatch:   true,
+		},
+		{
+			name:          "Subdomain route",
+			expectedId:    5,
+			requestDomain: "creative.hub.infrared",
+			portEnd:       530,
+			expectError:   false,
+			shouldMatch:   true,
+		},
+		{
+			name:          "Subdomain route in upper case",
+			expectedId:    5,
+			requestDomain: "CREATIVE.hub.infrared",
+			portEnd:       530,
+			expectError:   false,
+			shouldMatch:   true,
+		},
+		{
+			name:          "Domain of subdomain routes",
+			expectedId:    4,
+			requestDomain: "hub.infrared",
+			portEnd:       530,
+			expectError:   false,
+			shouldMatch:   true,
+		},
+		{
+			name:          "Unknown subdomain route",
+			expectedId:    4,
+			requestDomain: "survival.hub.infrared",
+			portEnd:       530,
+			expectError:   false,
+			shouldMatch:   false,
+		},
+		{
+			name:          "Wildcard domain",
+			expectedId:    6,
+			requestDomain: "survival.wild.infrared",
+			portEnd:       532,
+			expectError:   false,
+			shouldMatch:   true,
+		},
+		{
+			name:          "Exact domain before wildcard domain",
+			expectedId:    7,
+			requestDomain: "LOBBY.wild.infrared",
+			portEnd:       532,
+			expectError:   false,
+			shouldMatch:   true,
+		},
+		{
+			name:          "Full wildcard domain",
+			expectedId:    8,
+			requestDomain: "infrared",
+			portEnd:       532,
+			expectError:   false,
+			shouldMatch:   true,
+		},
 	}
 
+	serverAddrs := map[int]string{}
 	for i, server := range servers {
-		port := basePort + i
+		serverAddrs[server.id] = serverAddr(basePort + i)
+	}
+
+	for _, server := range servers {
 		proxyC := &ProxyConfig{}
 		serverC := statusListenerConfig{}
 
-		serverAddr := serverAddr(port)
+		serverAddr := serverAddrs[server.id]
 		proxyC.ListenTo = gatewayAddr(server.portEnd)
 		proxyC.ProxyTo = serverAddr
 		proxyC.DomainName = server.domain
+		if server.subdomainRoutes != nil {
+			proxyC.SubdomainRoutes = map[string]string{}
+			for subdomain, id := range server.subdomainRoutes {
+				proxyC.SubdomainRoutes[subdomain] = serverAddrs[id]
+			}
+		}
 		routingConfig = append(routingConfig, proxyC)
 
 		serverC.id = server.id
@@ -601,7 +725,11 @@ func TestRouting(t *testing.T) {
 
 			go func() {
 				expectedName := routeVersionName(tc.expectedId)
-				pk := serverHandshake(tc.requestDomain, tc.portEnd)
+				requestPort := tc.portEnd
+				if tc.requestPort != 0 {
+					requestPort = tc.requestPort
+				}
+				pk := serverHandshake(tc.requestDomain, requestPort)
 				config := statusDialConfig{
 					pk:          pk,
 					gatewayAddr: gatewayAddr(tc.portEnd),
@@ -633,3 +761,166 @@ func TestRouting(t *testing.T) {
 func TestProxyBind(t *testing.T) {
 	// TODO: Figure out a way to test this
 }
+
+func TestUnmatchedAction(t *testing.T) {
+	tt := []struct {
+		name            string
+		portEnd         int
+		action          string
+		defaultServer   string
+		expectError     bool
+		expectedVersion string
+	}{
+		{
+			name:            "Respond",
+			portEnd:         600,
+			action:          UnmatchedActionRespond,
+			expectedVersion: "Infrared",
+		},
+		{
+			name:        "Drop",
+			portEnd:     601,
+			action:      UnmatchedActionDrop,
+			expectError: true,
+		},
+		{
+			name:            "DefaultServer",
+			portEnd:         602,
+			action:          UnmatchedActionDefaultServer,
+			expectedVersion: serverVersionName,
+		},
+		{
+			name:            "DefaultServerWithoutAction",
+			portEnd:         603,
+			expectedVersion: serverVersionName,
+		},
+		{
+			name:            "DefaultServerUID",
+			portEnd:         604,
+			action:          UnmatchedActionDefaultServer,
+			defaultServer:   proxyUID(serverDomain, gatewayAddr(604)),
+			expectedVersion: serverVersionName,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			wg := &sync.WaitGroup{}
+			errorCh := make(chan *testError)
+			resultCh := make(chan bool)
+			wg.Add(2)
+			go func() {
+				defaultServer := serverDomain
+				if tc.defaultServer != "" {
+					defaultServer = tc.defaultServer
+				}
+				gateway := Gateway{
+					UnmatchedAction: tc.action,
+					DefaultServer:   defaultServer,
+				}
+				proxies := configToProxies(proxyConfigWithPortEnd(tc.portEnd))
+				if err := gateway.ListenAndServe(proxies); err != nil {
+					errorCh <- &testError{err, "Can't start gateway"}
+				}
+				wg.Done()
+				gateway.KeepProcessActive()
+			}()
+
+			serverCfg := statusListenerConfig{
+				addr:   serverAddr(tc.portEnd),
+				status: statusPKWithVersion(serverVersionName),
+			}
+			go func() {
+				statusListen(serverCfg, errorCh)
+				wg.Done()
+			}()
+
+			wg.Wait()
+			go func() {
+				config := statusDialConfig{
+					pk:          serverHandshake("unknown.example.com", gatewayPort(tc.portEnd)),
+					gatewayAddr: gatewayAddr(tc.portEnd),
+					dialerPort:  dialerPort(tc.portEnd),
+				}
+				receivedVersion, err := statusDial(config)
+				if err != nil {
+					errorCh <- err
+					return
+				}
+
+				resultCh <- receivedVersion == tc.expectedVersion
+			}()
+
+			select {
+			case err := <-errorCh:
+				if !tc.expectError {
+					t.Fatalf("Unexpected Error in test: %s\n%v", err.Message, err.Error)
+				}
+			case r := <-resultCh:
+				if tc.expectError || !r {
+					t.Fail()
+				}
+			}
+		})
+	}
+}
+
+func TestLogAccepted(t *testing.T) {
+	tt := []struct {
+		name     string
+		accepted int64
+		want     string
+	}{
+		{
+			name:     "Accepted",
+			accepted: 3,
+			want:     "[i] Accepted 3 connections on listener :25565 in the last 10ms",
+		},
+		{
+			name: "NoneAccepted",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := SetLogFormat(LogFormatText, &buf); err != nil {
+				t.Fatal(err)
+			}
+			defer SetLogFormat(LogFormatText, os.Stderr)
+
+			accepted := tc.accepted
+			done := make(chan struct{})
+			stopped := make(chan struct{})
+			go func() {
+				logAccepted(&accepted, ":25565", 10*time.Millisecond, done)
+				close(stopped)
+			}()
+			// Give the ticker a few intervals
+			time.Sleep(50 * time.Millisecond)
+			close(done)
+			<-stopped
+
+			if n := atomic.LoadInt64(&accepted); n != 0 {
+				t.Errorf("got: %d accepted connections left; want: 0", n)
+			}
+
+			// Connections of other tests may still log
+			var lines []string
+			for _, line := range strings.Split(buf.String(), "\n") {
+				if strings.Contains(line, "[i] Accepted") {
+					lines = append(lines, line)
+				}
+			}
+			if tc.want == "" {
+				if len(lines) != 0 {
+					t.Errorf("got: %q; want: no summary", lines)
+				}
+				return
+			}
+			if len(lines) != 1 || !strings.HasSuffix(lines[0], tc.want) {
+				t.Errorf("got: %q; want: one line %q", lines, tc.want)
+			}
+		})
+	}
+}
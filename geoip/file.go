@@ -0,0 +1,108 @@
+package geoip
+
+import (
+	"io/ioutil"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// File is a Lookup that is loaded from a MaxMind DB file and reloaded
+// whenever the file changes
+type File struct {
+	path string
+
+	mu sync.RWMutex
+	db *DB
+}
+
+// NewFile loads the database on path and starts watching it
+func NewFile(path string) (*File, error) {
+	f := &File{path: path}
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := watcher.Add(path); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	go func() {
+		defer watcher.Close()
+		f.watch(watcher, time.Millisecond*50)
+	}()
+
+	return f, nil
+}
+
+func (f *File) Country(ip net.IP) (string, error) {
+	f.mu.RLock()
+	db := f.db
+	f.mu.RUnlock()
+	return db.Country(ip)
+}
+
+func (f *File) load() error {
+	bb, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+
+	db, err := NewDB(bb)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.db = db
+	return nil
+}
+
+func (f *File) watch(watcher *fsnotify.Watcher, interval time.Duration) {
+	// The interval protects the watcher from write event spams
+	tick := time.NewTicker(interval)
+	defer tick.Stop()
+	changed := false
+
+	for {
+		select {
+		case <-tick.C:
+			if !changed {
+				continue
+			}
+			changed = false
+			log.Println("Updating geoip database", f.path)
+			if err := f.load(); err != nil {
+				// The old database stays in use
+				log.Printf("Failed updating geoip database %s; error %s", f.path, err)
+			}
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+				changed = true
+			}
+			if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+				// Updates that replace the file remove the watch of it
+				changed = true
+				_ = watcher.Add(f.path)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("Failed watching geoip database %s; error %s", f.path, err)
+		}
+	}
+}
@@ -0,0 +1,339 @@
+// Package geoip looks up the countries of IPs in MaxMind DB files, e.g. the
+// GeoLite2 Country database.
+package geoip
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+)
+
+// metadataMarker starts the metadata at the end of a MaxMind DB file
+var metadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// dataSectionSeparator is the number of zero bytes between the search tree
+// and the data section
+const dataSectionSeparator = 16
+
+// Lookup resolves IPs to the ISO 3166-1 codes of their countries, e.g. DE.
+// Implementations have to be safe for concurrent use.
+type Lookup interface {
+	// Country returns the country code of the IP or an empty string if
+	// the IP has no country
+	Country(ip net.IP) (string, error)
+}
+
+// DB is a MaxMind DB in memory. It is safe for concurrent use.
+type DB struct {
+	buf        []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	data       []byte
+	// ipv4Start is the node of ::/96 where the IPv4 addresses of an IPv6
+	// tree start
+	ipv4Start uint
+}
+
+// NewDB reads the MaxMind DB in buf
+func NewDB(buf []byte) (*DB, error) {
+	i := bytes.LastIndex(buf, metadataMarker)
+	if i < 0 {
+		return nil, errors.New("no MaxMind DB metadata")
+	}
+
+	d := decoder{buf: buf[i+len(metadataMarker):]}
+	v, _, err := d.decode(0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metadata; %s", err)
+	}
+	metadata, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("invalid metadata")
+	}
+
+	db := &DB{
+		buf:        buf,
+		nodeCount:  uintValue(metadata["node_count"]),
+		recordSize: uintValue(metadata["record_size"]),
+		ipVersion:  uintValue(metadata["ip_version"]),
+	}
+	switch db.recordSize {
+	case 24, 28, 32:
+	default:
+		return nil, fmt.Errorf("unsupported record size %d", db.recordSize)
+	}
+
+	treeSize := db.recordSize * 2 / 8 * db.nodeCount
+	if treeSize+dataSectionSeparator > uint(i) {
+		return nil, errors.New("search tree exceeds the file")
+	}
+	db.data = buf[treeSize+dataSectionSeparator : i]
+
+	if db.ipVersion == 6 {
+		node := uint(0)
+		for j := 0; j < 96 && node < db.nodeCount; j++ {
+			node = db.record(node, 0)
+		}
+		db.ipv4Start = node
+	}
+	return db, nil
+}
+
+// Country returns the country code of the IP or an empty string if the IP
+// has no country. The registered country is used for IPs without one, e.g.
+// the ones of anycast networks.
+func (db *DB) Country(ip net.IP) (string, error) {
+	record, err := db.lookup(ip)
+	if err != nil || record == nil {
+		return "", err
+	}
+
+	for _, key := range []string{"country", "registered_country"} {
+		country, _ := record[key].(map[string]interface{})
+		if code, ok := country["iso_code"].(string); ok {
+			return code, nil
+		}
+	}
+	return "", nil
+}
+
+// lookup returns the record of the network of the IP or nil if there is none
+func (db *DB) lookup(ip net.IP) (map[string]interface{}, error) {
+	node := uint(0)
+	bits := ip.To4()
+	if bits != nil {
+		node = db.ipv4Start
+	} else {
+		bits = ip.To16()
+		if bits == nil {
+			return nil, fmt.Errorf("invalid IP %s", ip)
+		}
+		if db.ipVersion == 4 {
+			return nil, errors.New("IPv6 addresses are not in an IPv4 database")
+		}
+	}
+
+	for i := 0; i < len(bits)*8 && node < db.nodeCount; i++ {
+		bit := uint(bits[i/8]>>(7-uint(i%8))) & 1
+		node = db.record(node, bit)
+	}
+
+	if node <= db.nodeCount {
+		// The IP is in no network of the database
+		return nil, nil
+	}
+
+	offset := node - db.nodeCount - dataSectionSeparator
+	d := decoder{buf: db.data}
+	v, _, err := d.decode(offset)
+	if err != nil {
+		return nil, err
+	}
+	record, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("record is no map")
+	}
+	return record, nil
+}
+
+// record returns the left (0) or right (1) record of the node
+func (db *DB) record(node, bit uint) uint {
+	switch db.recordSize {
+	case 24:
+		b := db.buf[node*6+bit*3:]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		b := db.buf[node*7:]
+		if bit == 0 {
+			return uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		b := db.buf[node*8+bit*4:]
+		return uint(b[0])<<24 | uint(b[1])<<16 | uint(b[2])<<8 | uint(b[3])
+	}
+}
+
+// The types of the data section
+const (
+	typeExtended = iota
+	typePointer
+	typeString
+	typeDouble
+	typeBytes
+	typeUint16
+	typeUint32
+	typeMap
+	typeInt32
+	typeUint64
+	typeUint128
+	typeArray
+	typeContainer
+	typeEndMarker
+	typeBoolean
+	typeFloat
+)
+
+// decoder decodes the values of a data section
+type decoder struct {
+	buf []byte
+}
+
+// decode decodes the value at offset and returns it with the offset after it
+func (d decoder) decode(offset uint) (interface{}, uint, error) {
+	typ, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typ == typePointer {
+		pointer, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		v, _, err := d.decode(pointer)
+		return v, next, err
+	}
+
+	switch typ {
+	case typeMap:
+		m := make(map[string]interface{}, size)
+		for i := uint(0); i < size; i++ {
+			var key, value interface{}
+			key, offset, err = d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			value, offset, err = d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is no string")
+			}
+			m[k] = value
+		}
+		return m, offset, nil
+	case typeArray:
+		a := make([]interface{}, 0, size)
+		for i := uint(0); i < size; i++ {
+			var value interface{}
+			value, offset, err = d.decode(offset)
+			if err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+		}
+		return a, offset, nil
+	case typeBoolean:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d.buf)) {
+		return nil, 0, errors.New("value exceeds the data section")
+	}
+	b := d.buf[offset : offset+size]
+	next := offset + size
+
+	switch typ {
+	case typeString:
+		return string(b), next, nil
+	case typeBytes, typeUint128:
+		return b, next, nil
+	case typeDouble:
+		if size != 8 {
+			return nil, 0, errors.New("invalid double size")
+		}
+		return math.Float64frombits(beUint(b)), next, nil
+	case typeFloat:
+		if size != 4 {
+			return nil, 0, errors.New("invalid float size")
+		}
+		return math.Float32frombits(uint32(beUint(b))), next, nil
+	case typeUint16, typeUint32, typeUint64:
+		return beUint(b), next, nil
+	case typeInt32:
+		return int64(int32(beUint(b))), next, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported data type %d", typ)
+	}
+}
+
+// control decodes the control byte at offset and returns the type and the
+// size of the value as well as the offset of its payload
+func (d decoder) control(offset uint) (uint, uint, uint, error) {
+	if offset >= uint(len(d.buf)) {
+		return 0, 0, 0, errors.New("offset exceeds the data section")
+	}
+	ctrl := d.buf[offset]
+	offset++
+
+	typ := uint(ctrl >> 5)
+	if typ == typePointer {
+		return typ, uint(ctrl & 0x1f), offset, nil
+	}
+	if typ == typeExtended {
+		if offset >= uint(len(d.buf)) {
+			return 0, 0, 0, errors.New("offset exceeds the data section")
+		}
+		typ = 7 + uint(d.buf[offset])
+		offset++
+	}
+
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		n := size - 28
+		if offset+n > uint(len(d.buf)) {
+			return 0, 0, 0, errors.New("size exceeds the data section")
+		}
+		extra := uint(beUint(d.buf[offset : offset+n]))
+		offset += n
+		switch n {
+		case 1:
+			size = 29 + extra
+		case 2:
+			size = 285 + extra
+		default:
+			size = 65821 + extra
+		}
+	}
+	return typ, size, offset, nil
+}
+
+// pointer decodes the pointer with the size bits of its control byte
+func (d decoder) pointer(size, offset uint) (uint, uint, error) {
+	n := (size>>3)&0x3 + 1
+	if offset+n > uint(len(d.buf)) {
+		return 0, 0, errors.New("pointer exceeds the data section")
+	}
+	b := d.buf[offset : offset+n]
+	next := offset + n
+
+	switch n {
+	case 1:
+		return (size&0x7)<<8 | uint(beUint(b)), next, nil
+	case 2:
+		return ((size&0x7)<<16 | uint(beUint(b))) + 2048, next, nil
+	case 3:
+		return ((size&0x7)<<24 | uint(beUint(b))) + 526336, next, nil
+	default:
+		return uint(beUint(b)), next, nil
+	}
+}
+
+func beUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+func uintValue(v interface{}) uint {
+	n, _ := v.(uint64)
+	return uint(n)
+}
@@ -0,0 +1,146 @@
+package geoip
+
+import (
+	"net"
+	"testing"
+)
+
+// encodeString encodes a string of the data section
+func encodeString(s string) []byte {
+	return append([]byte{typeString<<5 | byte(len(s))}, s...)
+}
+
+// encodeUint encodes a uint32 of the data section
+func encodeUint(v uint32) []byte {
+	return []byte{typeUint32<<5 | 4, byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+}
+
+// encodeMap encodes a map of the data section with the encoded pairs of
+// keys and values
+func encodeMap(pairs ...[]byte) []byte {
+	bb := []byte{typeMap<<5 | byte(len(pairs)/2)}
+	for _, pair := range pairs {
+		bb = append(bb, pair...)
+	}
+	return bb
+}
+
+func countryRecord(key, code string) []byte {
+	return encodeMap(encodeString(key), encodeMap(encodeString("iso_code"), encodeString(code)))
+}
+
+// testNetwork is a network of a test database and the offset of its record
+type testNetwork struct {
+	ip     net.IP
+	prefix int
+	offset int
+}
+
+// buildDB builds an IPv4 MaxMind DB with a record size of 24 bits
+func buildDB(networks []testNetwork, data []byte) []byte {
+	const empty = -1
+	nodes := [][2]int{{empty, empty}}
+	var leaves [][3]int // node, bit, offset
+
+	for _, n := range networks {
+		ip := n.ip.To4()
+		node := 0
+		for i := 0; i < n.prefix; i++ {
+			bit := int(ip[i/8]>>(7-uint(i%8))) & 1
+			if i == n.prefix-1 {
+				leaves = append(leaves, [3]int{node, bit, n.offset})
+				break
+			}
+			if nodes[node][bit] == empty {
+				nodes = append(nodes, [2]int{empty, empty})
+				nodes[node][bit] = len(nodes) - 1
+			}
+			node = nodes[node][bit]
+		}
+	}
+
+	nodeCount := len(nodes)
+	for _, leaf := range leaves {
+		nodes[leaf[0]][leaf[1]] = nodeCount + dataSectionSeparator + leaf[2]
+	}
+
+	var bb []byte
+	for _, node := range nodes {
+		for _, record := range node {
+			if record == empty {
+				record = nodeCount
+			}
+			bb = append(bb, byte(record>>16), byte(record>>8), byte(record))
+		}
+	}
+	bb = append(bb, make([]byte, dataSectionSeparator)...)
+	bb = append(bb, data...)
+	bb = append(bb, metadataMarker...)
+	return append(bb, encodeMap(
+		encodeString("node_count"), encodeUint(uint32(nodeCount)),
+		encodeString("record_size"), encodeUint(24),
+		encodeString("ip_version"), encodeUint(4),
+	)...)
+}
+
+func TestDB_Country(t *testing.T) {
+	de := countryRecord("country", "DE")
+	us := countryRecord("registered_country", "US")
+	data := append(append([]byte{}, de...), us...)
+	db, err := NewDB(buildDB([]testNetwork{
+		{ip: net.IPv4(203, 0, 113, 0), prefix: 24, offset: 0},
+		{ip: net.IPv4(198, 51, 100, 0), prefix: 24, offset: len(de)},
+		{ip: net.IPv4(10, 0, 0, 0), prefix: 8, offset: 0},
+	}, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tt := []struct {
+		ip   string
+		want string
+	}{
+		{ip: "203.0.113.7", want: "DE"},
+		{ip: "10.1.2.3", want: "DE"},
+		{ip: "198.51.100.255", want: "US"},
+		{ip: "192.0.2.1", want: ""},
+	}
+
+	for _, tc := range tt {
+		got, err := db.Country(net.ParseIP(tc.ip))
+		if err != nil {
+			t.Errorf("%s: %s", tc.ip, err)
+			continue
+		}
+		if got != tc.want {
+			t.Errorf("%s: got: %q; want: %q", tc.ip, got, tc.want)
+		}
+	}
+
+	if _, err := db.Country(net.ParseIP("2001:db8::1")); err == nil {
+		t.Error("got: nil; want: error for IPv6 in an IPv4 database")
+	}
+}
+
+func TestDecoder_Pointer(t *testing.T) {
+	// A map whose value points to the string at the start
+	data := encodeString("DE")
+	offset := uint(len(data))
+	data = append(data, typeMap<<5|1)
+	data = append(data, encodeString("iso_code")...)
+	data = append(data, typePointer<<5, 0)
+
+	v, _, err := decoder{buf: data}.decode(offset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.(map[string]interface{})["iso_code"]; got != "DE" {
+		t.Errorf("got: %v; want: DE", got)
+	}
+}
+
+func TestNewDB_Invalid(t *testing.T) {
+	if _, err := NewDB([]byte("no database")); err == nil {
+		t.Error("got: nil; want: error")
+	}
+}
@@ -0,0 +1,218 @@
+//go:build grpc
+// +build grpc
+
+// Package grpcapi implements the optional gRPC control API of Infrared.
+// It is only built with the grpc build tag.
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/haveachin/infrared"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements the Infrared service for a gateway
+type Server struct {
+	UnimplementedInfraredServer
+
+	gateway    *infrared.Gateway
+	configPath string
+}
+
+func NewServer(gateway *infrared.Gateway, configPath string) *Server {
+	return &Server{
+		gateway:    gateway,
+		configPath: configPath,
+	}
+}
+
+// ListenAndServe serves the gRPC control API of gateway on bind.
+// If token is not empty, every call needs it as its bearer token.
+func ListenAndServe(gateway *infrared.Gateway, configPath, bind, token string) error {
+	l, err := net.Listen("tcp", bind)
+	if err != nil {
+		return err
+	}
+
+	var opts []grpc.ServerOption
+	if token != "" {
+		opts = append(opts,
+			grpc.UnaryInterceptor(unaryBearerAuth(token)),
+			grpc.StreamInterceptor(streamBearerAuth(token)),
+		)
+	}
+
+	s := grpc.NewServer(opts...)
+	RegisterInfraredServer(s, NewServer(gateway, configPath))
+	log.Println("Starting gRPC API on", bind)
+	return s.Serve(l)
+}
+
+// unaryBearerAuth rejects every unary call that does not have token as its
+// bearer token with Unauthenticated
+func unaryBearerAuth(token string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authorize(ctx, token); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// streamBearerAuth rejects every streaming call that does not have token as
+// its bearer token with Unauthenticated
+func streamBearerAuth(token string) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := authorize(ss.Context(), token); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// authorize checks the authorization metadata of the call in the same way
+// as the bearer token of the Rest API
+func authorize(ctx context.Context, token string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, auth := range md.Get("authorization") {
+		if strings.HasPrefix(auth, "Bearer ") &&
+			subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, "Bearer ")), []byte(token)) == 1 {
+			return nil
+		}
+	}
+	return status.Error(codes.Unauthenticated, "invalid or missing bearer token")
+}
+
+func (s *Server) ListProxies(context.Context, *ListProxiesRequest) (*ListProxiesResponse, error) {
+	var proxies []*Proxy
+	s.gateway.Proxies.Range(func(k, v interface{}) bool {
+		proxy := v.(*infrared.Proxy)
+		proxies = append(proxies, &Proxy{
+			Uid:        k.(string),
+			DomainName: proxy.DomainName(),
+			ListenTo:   proxy.ListenTo(),
+			ProxyTo:    proxy.ProxyTo(),
+			Players:    int32(len(proxy.Sessions())),
+		})
+		return true
+	})
+	sort.Slice(proxies, func(i, j int) bool {
+		return proxies[i].Uid < proxies[j].Uid
+	})
+
+	return &ListProxiesResponse{Proxies: proxies}, nil
+}
+
+func (s *Server) CreateProxy(_ context.Context, req *CreateProxyRequest) (*CreateProxyResponse, error) {
+	var cfg infrared.ProxyConfig
+	if err := json.Unmarshal(req.Config, &cfg); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid config; %s", err)
+	}
+
+	if cfg.DomainName == "" || cfg.ProxyTo == "" {
+		return nil, status.Error(codes.InvalidArgument, "domainName and proxyTo are required")
+	}
+
+	fileName := req.FileName
+	// If fileName is empty use domainName as filename
+	if fileName == "" {
+		fileName = cfg.DomainName
+	}
+
+	path, err := s.configFilePath(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ioutil.WriteFile(path, req.Config, 0644); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &CreateProxyResponse{FileName: filepath.Base(path)}, nil
+}
+
+func (s *Server) RemoveProxy(_ context.Context, req *RemoveProxyRequest) (*RemoveProxyResponse, error) {
+	path, err := s.configFilePath(req.FileName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, status.Errorf(codes.NotFound, "no config %s", req.FileName)
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &RemoveProxyResponse{}, nil
+}
+
+func (s *Server) ListSessions(context.Context, *ListSessionsRequest) (*ListSessionsResponse, error) {
+	var sessions []*Session
+	for _, session := range s.gateway.Sessions() {
+		sessions = append(sessions, &Session{
+			Username:      session.Username,
+			RemoteAddress: session.RemoteAddress,
+			ProxyUid:      session.ProxyUID,
+			ConnectedAt:   session.ConnectedAt.UnixNano() / 1e6,
+		})
+	}
+
+	return &ListSessionsResponse{Sessions: sessions}, nil
+}
+
+func (s *Server) WatchEvents(req *WatchEventsRequest, stream Infrared_WatchEventsServer) error {
+	types := map[string]bool{}
+	for _, t := range req.Types {
+		types[t] = true
+	}
+
+	events, unsubscribe := s.gateway.SubscribeEvents()
+	defer unsubscribe()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			if len(types) > 0 && !types[event.EventType()] {
+				continue
+			}
+
+			payload, err := json.Marshal(event)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+
+			if err := stream.Send(&Event{
+				Type:    event.EventType(),
+				Payload: payload,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// configFilePath returns the path of the config file with the name and
+// makes sure that it stays inside of the config path
+func (s *Server) configFilePath(fileName string) (string, error) {
+	if fileName == "" || fileName != filepath.Base(fileName) || fileName == "." || fileName == ".." {
+		return "", status.Errorf(codes.InvalidArgument, "invalid file name %q", fileName)
+	}
+	return filepath.Join(s.configPath, fileName), nil
+}
@@ -0,0 +1,143 @@
+//go:build grpc
+// +build grpc
+
+package grpcapi
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/haveachin/infrared"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer_CreateProxy(t *testing.T) {
+	tt := []struct {
+		name     string
+		fileName string
+		config   string
+		code     codes.Code
+		wantFile string
+	}{
+		{
+			name:     "DomainAsFileName",
+			config:   `{"domainName":"mc.example.com","proxyTo":":8080"}`,
+			code:     codes.OK,
+			wantFile: "mc.example.com",
+		},
+		{
+			name:     "FileName",
+			fileName: "example",
+			config:   `{"domainName":"mc.example.com","proxyTo":":8080"}`,
+			code:     codes.OK,
+			wantFile: "example",
+		},
+		{
+			name:   "MissingProxyTo",
+			config: `{"domainName":"mc.example.com"}`,
+			code:   codes.InvalidArgument,
+		},
+		{
+			name:     "PathTraversal",
+			fileName: "../example",
+			config:   `{"domainName":"mc.example.com","proxyTo":":8080"}`,
+			code:     codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "infrared")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+
+			s := NewServer(&infrared.Gateway{}, dir)
+			_, err = s.CreateProxy(context.Background(), &CreateProxyRequest{
+				FileName: tc.fileName,
+				Config:   []byte(tc.config),
+			})
+			if code := status.Code(err); code != tc.code {
+				t.Fatalf("got: %v; want: %v", code, tc.code)
+			}
+
+			if tc.wantFile == "" {
+				return
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, tc.wantFile)); err != nil {
+				t.Errorf("got: %v; want: config file %s", err, tc.wantFile)
+			}
+		})
+	}
+}
+
+// contextStream is a grpc.ServerStream that only has a context
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestBearerAuth(t *testing.T) {
+	tt := []struct {
+		name string
+		auth string
+		code codes.Code
+	}{
+		{
+			name: "NoToken",
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "WrongToken",
+			auth: "Bearer wrong",
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "NotBearer",
+			auth: "Basic secret",
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "Token",
+			auth: "Bearer secret",
+			code: codes.OK,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.auth != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tc.auth))
+			}
+
+			_, err := unaryBearerAuth("secret")(ctx, nil, &grpc.UnaryServerInfo{},
+				func(context.Context, interface{}) (interface{}, error) {
+					return nil, nil
+				})
+			if code := status.Code(err); code != tc.code {
+				t.Errorf("unary: got: %v; want: %v", code, tc.code)
+			}
+
+			err = streamBearerAuth("secret")(nil, contextStream{ctx: ctx}, &grpc.StreamServerInfo{},
+				func(interface{}, grpc.ServerStream) error {
+					return nil
+				})
+			if code := status.Code(err); code != tc.code {
+				t.Errorf("stream: got: %v; want: %v", code, tc.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package infrared
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+func (proxy *Proxy) HandshakePort() HandshakePortConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.HandshakePort
+}
+
+// allowedHandshakePorts returns the ports that clients may claim in their
+// handshake
+func (proxy *Proxy) allowedHandshakePorts() []int {
+	if ports := proxy.HandshakePort().AllowedPorts; len(ports) > 0 {
+		return ports
+	}
+
+	_, portString, err := net.SplitHostPort(proxy.ListenTo())
+	if err != nil {
+		return nil
+	}
+
+	port, err := strconv.Atoi(portString)
+	if err != nil {
+		return nil
+	}
+	return []int{port}
+}
+
+// rejectByHandshakePort reports whether the connection has to be dropped,
+// because the port in its handshake is not one that clients connect to.
+// Connections are never dropped if the allowed ports are unknown.
+func (proxy *Proxy) rejectByHandshakePort(hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr) bool {
+	if !proxy.HandshakePort().Enabled {
+		return false
+	}
+
+	allowed := proxy.allowedHandshakePorts()
+	if len(allowed) == 0 {
+		return false
+	}
+
+	for _, port := range allowed {
+		if int(hs.ServerPort) == port {
+			return false
+		}
+	}
+
+	logWith(connFields("reject", connRemoteAddr, proxy.UID()), "[i] Rejecting %s with handshake port %d on %s", connRemoteAddr, hs.ServerPort, proxy.UID())
+	metrics.IncHandshakePortMismatches(proxy.DomainName())
+	return true
+}
@@ -0,0 +1,64 @@
+package infrared
+
+import (
+	"net"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+func TestProxy_RejectByHandshakePort(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 54321}
+
+	tt := []struct {
+		name     string
+		cfg      HandshakePortConfig
+		port     protocol.UnsignedShort
+		rejected bool
+	}{
+		{
+			name: "Disabled",
+			port: 1337,
+		},
+		{
+			name: "ListenPort",
+			cfg:  HandshakePortConfig{Enabled: true},
+			port: 25565,
+		},
+		{
+			name:     "MismatchedListenPort",
+			cfg:      HandshakePortConfig{Enabled: true},
+			port:     1337,
+			rejected: true,
+		},
+		{
+			name: "AllowedPort",
+			cfg:  HandshakePortConfig{Enabled: true, AllowedPorts: []int{25565, 1337}},
+			port: 1337,
+		},
+		{
+			name:     "MismatchedAllowedPort",
+			cfg:      HandshakePortConfig{Enabled: true, AllowedPorts: []int{1337}},
+			port:     25565,
+			rejected: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{Config: &ProxyConfig{
+				ListenTo:      ":25565",
+				HandshakePort: tc.cfg,
+			}}
+			hs := handshaking.ServerBoundHandshake{
+				ServerPort: tc.port,
+				NextState:  handshaking.ServerBoundHandshakeLoginState,
+			}
+
+			if got := proxy.rejectByHandshakePort(hs, addr); got != tc.rejected {
+				t.Errorf("got: %v; want: %v", got, tc.rejected)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package infrared
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"github.com/haveachin/infrared/callback"
+)
+
+const defaultHealthCheckFailures = 3
+
+func (proxy *Proxy) HealthCheck() HealthCheckConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.HealthCheck
+}
+
+// Healthy reports whether at least one backend of the proxy passes its
+// health checks. It is always true without health checks.
+func (proxy *Proxy) Healthy() bool {
+	return proxy.healthy(proxy.Backends())
+}
+
+// healthy reports whether at least one of the backends passes its health
+// checks. Backends that are not checked, like the ones of subdomain routes,
+// are healthy.
+func (proxy *Proxy) healthy(backends []string) bool {
+	for _, addr := range backends {
+		if proxy.backendHealth.IsHealthy(addr) {
+			return true
+		}
+	}
+	return len(backends) == 0
+}
+
+// checkBackends dials every backend once and updates its health. failures
+// counts the failed checks in a row of every backend.
+func (proxy *Proxy) checkBackends(failures map[string]int) {
+	cfg := proxy.HealthCheck()
+	dialer, err := proxy.Dialer()
+	if err != nil {
+		return
+	}
+
+	backends := proxy.Backends()
+	errs := make([]error, len(backends))
+	var wg sync.WaitGroup
+	for i, addr := range backends {
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			rconn, err := dialer.Dial(addr)
+			if err == nil {
+				rconn.Close()
+			}
+			errs[i] = err
+		}(i, addr)
+	}
+	wg.Wait()
+
+	proxyDomain := proxy.DomainName()
+	for i, addr := range backends {
+		wasHealthy := proxy.backendHealth.IsHealthy(addr)
+		if errs[i] == nil {
+			failures[addr] = 0
+			proxy.backendHealth.SetHealthy(addr, true)
+			if !wasHealthy {
+				log.Printf("[i] %s of %s passed its health check again", addr, proxy.UID())
+				proxy.logEvent(callback.BackendOnlineEvent{Backend: addr, ProxyUID: proxy.UID()})
+			}
+			metrics.SetBackendHealthy(proxyDomain, addr, true)
+			continue
+		}
+
+		failures[addr]++
+		if wasHealthy && failures[addr] >= cfg.FailureThreshold() {
+			proxy.backendHealth.SetHealthy(addr, false)
+			log.Printf("[w] %s of %s is unhealthy after %d failed health checks; error: %s", addr, proxy.UID(), failures[addr], errs[i])
+			proxy.logEvent(callback.BackendOfflineEvent{Backend: addr, ProxyUID: proxy.UID()})
+		}
+		metrics.SetBackendHealthy(proxyDomain, addr, proxy.backendHealth.IsHealthy(addr))
+	}
+
+	// Forget backends that were removed from the config
+	for addr := range failures {
+		if !containsString(backends, addr) {
+			delete(failures, addr)
+			proxy.backendHealth.SetHealthy(addr, true)
+		}
+	}
+}
+
+// startHealthCheck checks the backends in every interval until
+// stopHealthCheck is called. The config is read on every check, so that
+// changes apply without restarting the proxy.
+func (proxy *Proxy) startHealthCheck() {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.stopHealthChecks != nil {
+		return
+	}
+
+	stop := make(chan struct{})
+	proxy.stopHealthChecks = stop
+	go func() {
+		failures := map[string]int{}
+		for {
+			interval := proxy.HealthCheck().IntervalDuration()
+			if interval > 0 {
+				proxy.checkBackends(failures)
+			} else {
+				// Health checks were disabled; forget their results
+				for addr := range failures {
+					delete(failures, addr)
+					proxy.backendHealth.SetHealthy(addr, true)
+				}
+				interval = time.Millisecond * defaultStatusRefreshInterval
+			}
+
+			select {
+			case <-stop:
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+}
+
+func (proxy *Proxy) stopHealthCheck() {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.stopHealthChecks == nil {
+		return
+	}
+
+	close(proxy.stopHealthChecks)
+	proxy.stopHealthChecks = nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,128 @@
+package infrared
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/haveachin/infrared/callback"
+)
+
+func TestProxy_CheckBackends(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+
+	dead, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadAddr := dead.Addr().String()
+	dead.Close()
+
+	alive := listener.Addr().String()
+	proxy := &Proxy{
+		Config: &ProxyConfig{
+			Backends:    []string{deadAddr, alive},
+			Timeout:     1000,
+			HealthCheck: HealthCheckConfig{Interval: 1000, Failures: 2},
+		},
+	}
+
+	failures := map[string]int{}
+	proxy.checkBackends(failures)
+	if !proxy.backendHealth.IsHealthy(deadAddr) {
+		t.Error("got: unhealthy after one failure; want: healthy")
+	}
+
+	proxy.checkBackends(failures)
+	if proxy.backendHealth.IsHealthy(deadAddr) {
+		t.Error("got: healthy after two failures; want: unhealthy")
+	}
+	if proxy.backendHealth.IsUp(deadAddr) {
+		t.Error("got: unhealthy backend up; want: down")
+	}
+	if !proxy.backendHealth.IsHealthy(alive) {
+		t.Error("got: unhealthy; want: healthy")
+	}
+	if !proxy.Healthy() {
+		t.Error("got: unhealthy proxy with a healthy backend; want: healthy")
+	}
+
+	proxy.Config.Backends = []string{deadAddr}
+	if proxy.Healthy() {
+		t.Error("got: healthy proxy without a healthy backend; want: unhealthy")
+	}
+}
+
+func TestProxy_CheckBackendsEvents(t *testing.T) {
+	// Reserve an address that is not bound yet, like the one of a stopped server
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	gateway := &Gateway{}
+	events, unsubscribe := gateway.SubscribeEvents()
+	defer unsubscribe()
+
+	proxy := &Proxy{
+		Config: &ProxyConfig{
+			DomainName:  "mc.example.com",
+			ListenTo:    ":25565",
+			Backends:    []string{addr},
+			Timeout:     1000,
+			HealthCheck: HealthCheckConfig{Interval: 1000, Failures: 1},
+		},
+		gateway: gateway,
+	}
+
+	failures := map[string]int{}
+	proxy.checkBackends(failures)
+	proxy.checkBackends(failures)
+
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			c.Close()
+		}
+	}()
+	proxy.checkBackends(failures)
+	proxy.checkBackends(failures)
+
+	want := []callback.Event{
+		callback.BackendOfflineEvent{Backend: addr, ProxyUID: "mc.example.com@:25565"},
+		callback.BackendOnlineEvent{Backend: addr, ProxyUID: "mc.example.com@:25565"},
+	}
+	for _, event := range want {
+		if got := <-events; !reflect.DeepEqual(got, event) {
+			t.Errorf("got: %v; want: %v", got, event)
+		}
+	}
+	select {
+	case event := <-events:
+		t.Errorf("got: %v; want: no event without a transition", event)
+	default:
+	}
+}
@@ -0,0 +1,81 @@
+package infrared
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultHTTPProbeBody = "This is a Minecraft server. Connect to it with a Minecraft client.\n"
+	// httpProbeTimeout is the time a web probe gets to send its request
+	httpProbeTimeout = 5 * time.Second
+)
+
+// httpMethodPrefixes are the first four bytes of the requests of web probes.
+// The second byte of a Minecraft handshake is always its packet ID 0x00, so
+// none of them can be the start of a handshake.
+var httpMethodPrefixes = []string{"GET ", "HEAD", "POST", "PUT ", "OPTI"}
+
+// isHTTPRequest reports whether the connection starts with an HTTP request
+func isHTTPRequest(r *bufio.Reader) bool {
+	first, err := r.Peek(1)
+	if err != nil {
+		return false
+	}
+
+	// Only peek further if the request can be HTTP, so that short legacy
+	// pings don't block
+	switch first[0] {
+	case 'G', 'H', 'P', 'O':
+	default:
+		return false
+	}
+
+	prefix, err := r.Peek(4)
+	if err != nil {
+		return false
+	}
+
+	for _, method := range httpMethodPrefixes {
+		if string(prefix) == method {
+			return true
+		}
+	}
+	return false
+}
+
+// handleHTTPProbe answers the HTTP request on conn with the configured
+// status code and body
+func (gateway *Gateway) handleHTTPProbe(conn Conn) error {
+	if err := conn.SetReadDeadline(time.Now().Add(httpProbeTimeout)); err != nil {
+		return err
+	}
+
+	req, err := http.ReadRequest(conn.Reader())
+	if err != nil {
+		return err
+	}
+	log.Printf("[i] Answering HTTP %s request of %s with %d", req.Method, conn.RemoteAddr(), gateway.HTTPProbeStatus)
+
+	body := gateway.HTTPProbeBody
+	if body == "" {
+		body = defaultHTTPProbeBody
+	}
+
+	header := fmt.Sprintf("HTTP/1.1 %d %s\r\n", gateway.HTTPProbeStatus, http.StatusText(gateway.HTTPProbeStatus))
+	if gateway.HTTPProbeStatus == http.StatusUpgradeRequired {
+		header += "Upgrade: Minecraft\r\n"
+	}
+	header += fmt.Sprintf("Content-Type: text/plain; charset=utf-8\r\nContent-Length: %d\r\nConnection: close\r\n\r\n", len(body))
+
+	response := header
+	if req.Method != http.MethodHead {
+		response += body
+	}
+
+	_, err = conn.Write([]byte(response))
+	return err
+}
@@ -0,0 +1,81 @@
+package infrared
+
+import (
+	"bufio"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestIsHTTPRequest(t *testing.T) {
+	tt := []struct {
+		name   string
+		data   string
+		result bool
+	}{
+		{
+			name:   "Get",
+			data:   "GET / HTTP/1.1\r\n",
+			result: true,
+		},
+		{
+			name:   "Head",
+			data:   "HEAD / HTTP/1.1\r\n",
+			result: true,
+		},
+		{
+			name:   "Handshake",
+			data:   string([]byte{0x10, 0x00, 0xf6, 0x05, 0x09}) + "localhost",
+			result: false,
+		},
+		{
+			name:   "LegacyPing",
+			data:   string([]byte{0xfe}),
+			result: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tc.data))
+			if result := isHTTPRequest(r); result != tc.result {
+				t.Errorf("got: %v; want: %v", result, tc.result)
+			}
+		})
+	}
+}
+
+func TestGateway_HandleHTTPProbe(t *testing.T) {
+	gateway := &Gateway{
+		HTTPProbeStatus: http.StatusUpgradeRequired,
+		HTTPProbeBody:   "Infrared",
+	}
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	go func() {
+		defer c2.Close()
+		_ = gateway.handleHTTPProbe(wrapConn(c2))
+	}()
+
+	if _, err := c1.Write([]byte("GET / HTTP/1.1\r\nHost: mc.example.com\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(c1), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("got: %d; want: %d", resp.StatusCode, http.StatusUpgradeRequired)
+	}
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "Infrared" {
+		t.Errorf("got: %s; want: %s", body, "Infrared")
+	}
+}
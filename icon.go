@@ -0,0 +1,142 @@
+package infrared
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// iconSize is the width and height in pixels that the client expects
+	iconSize = 64
+	// iconFetchTimeout is the time the download of an icon may take
+	iconFetchTimeout = 10 * time.Second
+	// maxIconFetchSize limits the size of a downloaded icon
+	maxIconFetchSize = 1 << 20
+)
+
+// isIconURL reports whether the icon has to be downloaded instead of read from a file
+func isIconURL(iconPath string) bool {
+	return strings.HasPrefix(iconPath, "http://") || strings.HasPrefix(iconPath, "https://")
+}
+
+// loadIcon downloads the icon if IconPath is a URL. A failed download is
+// logged and leaves the status without an icon.
+func (cfg *StatusConfig) loadIcon() {
+	cfg.iconURLImage = ""
+	if !isIconURL(cfg.IconPath) {
+		return
+	}
+
+	img64, err := fetchIconAndEncodeToBase64String(cfg.IconPath, cfg.RejectWrongIconSize)
+	if err != nil {
+		log.Printf("[w] Failed to fetch the icon %s; error: %s", cfg.IconPath, err)
+		return
+	}
+	cfg.iconURLImage = img64
+}
+
+// validateIcon fails if the icon file does not have the size that the
+// client expects and RejectWrongIconSize is set. Otherwise the icon is
+// scaled when the status is built.
+func (cfg StatusConfig) validateIcon() error {
+	if !cfg.RejectWrongIconSize || cfg.IconPath == "" || isIconURL(cfg.IconPath) {
+		return nil
+	}
+
+	if _, err := loadImageAndEncodeToBase64String(cfg.IconPath, true); err != nil {
+		return fmt.Errorf("invalid icon %s; %s", cfg.IconPath, err)
+	}
+	return nil
+}
+
+// fetchIconAndEncodeToBase64String downloads the image of the URL and
+// returns it as a 64x64 PNG that is base64 encoded
+func fetchIconAndEncodeToBase64String(url string, rejectWrongSize bool) (string, error) {
+	client := http.Client{Timeout: iconFetchTimeout}
+	response, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return "", fmt.Errorf("icon server responded with %s", response.Status)
+	}
+
+	bb, err := ioutil.ReadAll(http.MaxBytesReader(nil, response.Body, maxIconFetchSize))
+	if err != nil {
+		return "", err
+	}
+
+	return encodeIcon(bb, rejectWrongSize)
+}
+
+// loadImageAndEncodeToBase64String reads the image of the file and returns
+// it as a 64x64 PNG that is base64 encoded
+func loadImageAndEncodeToBase64String(path string, rejectWrongSize bool) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+
+	bb, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return encodeIcon(bb, rejectWrongSize)
+}
+
+// encodeIcon base64 encodes the image. PNGs with 64x64 pixels are kept as
+// they are and every other image is scaled to that size and encoded as
+// PNG, unless rejectWrongSize is set.
+func encodeIcon(bb []byte, rejectWrongSize bool) (string, error) {
+	img, format, err := image.Decode(bytes.NewReader(bb))
+	if err != nil {
+		return "", err
+	}
+
+	size := img.Bounds().Size()
+	if size == image.Pt(iconSize, iconSize) && format == "png" {
+		return base64.StdEncoding.EncodeToString(bb), nil
+	}
+
+	if rejectWrongSize && size != image.Pt(iconSize, iconSize) {
+		return "", fmt.Errorf("icon is %dx%d but has to be %dx%d", size.X, size.Y, iconSize, iconSize)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, resizeIcon(img)); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// resizeIcon scales the image to 64x64 pixels with the nearest neighbor
+// of each pixel; images that already have the size are returned as is
+func resizeIcon(img image.Image) image.Image {
+	bounds := img.Bounds()
+	if bounds.Dx() == iconSize && bounds.Dy() == iconSize {
+		return img
+	}
+
+	icon := image.NewNRGBA(image.Rect(0, 0, iconSize, iconSize))
+	for y := 0; y < iconSize; y++ {
+		for x := 0; x < iconSize; x++ {
+			srcX := bounds.Min.X + x*bounds.Dx()/iconSize
+			srcY := bounds.Min.Y + y*bounds.Dy()/iconSize
+			icon.Set(x, y, img.At(srcX, srcY))
+		}
+	}
+	return icon
+}
@@ -0,0 +1,229 @@
+package infrared
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func iconServer(t *testing.T, size int) *httptest.Server {
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			img.Set(x, y, color.NRGBA{R: 255, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/icon.png" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(buf.Bytes())
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestIsIconURL(t *testing.T) {
+	tt := []struct {
+		iconPath string
+		isURL    bool
+	}{
+		{iconPath: "icon.png", isURL: false},
+		{iconPath: "/srv/icons/icon.png", isURL: false},
+		{iconPath: "http://example.com/icon.png", isURL: true},
+		{iconPath: "https://example.com/icon.png", isURL: true},
+	}
+
+	for _, tc := range tt {
+		if isURL := isIconURL(tc.iconPath); isURL != tc.isURL {
+			t.Errorf("%s: got: %v; want: %v", tc.iconPath, isURL, tc.isURL)
+		}
+	}
+}
+
+func TestFetchIconAndEncodeToBase64String(t *testing.T) {
+	tt := []struct {
+		name string
+		size int
+	}{
+		{name: "Icon", size: iconSize},
+		{name: "LargeIcon", size: 256},
+		{name: "SmallIcon", size: 16},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			server := iconServer(t, tc.size)
+
+			img64, err := fetchIconAndEncodeToBase64String(server.URL+"/icon.png", false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			bb, err := base64.StdEncoding.DecodeString(img64)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			img, err := png.Decode(bytes.NewReader(bb))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if size := img.Bounds().Size(); size != image.Pt(iconSize, iconSize) {
+				t.Errorf("got: %v; want: %v", size, image.Pt(iconSize, iconSize))
+			}
+			if r, _, _, _ := img.At(iconSize-1, iconSize-1).RGBA(); r != 0xffff {
+				t.Errorf("got: %v; want: %v", r, 0xffff)
+			}
+		})
+	}
+}
+
+func TestStatusConfig_LoadIcon(t *testing.T) {
+	server := iconServer(t, iconSize)
+
+	tt := []struct {
+		name     string
+		iconPath string
+		hasIcon  bool
+	}{
+		{
+			name:     "URL",
+			iconPath: server.URL + "/icon.png",
+			hasIcon:  true,
+		},
+		{
+			name:     "NotFound",
+			iconPath: server.URL + "/missing.png",
+			hasIcon:  false,
+		},
+		{
+			name:     "NoIcon",
+			iconPath: "",
+			hasIcon:  false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := StatusConfig{
+				IconPath:       tc.iconPath,
+				ProtocolNumber: 757,
+			}
+			cfg.loadIcon()
+
+			pk, err := cfg.StatusResponsePacket()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if hasIcon := strings.Contains(string(pk.Data), "data:image/png;base64,"); hasIcon != tc.hasIcon {
+				t.Errorf("got: %v; want: %v", hasIcon, tc.hasIcon)
+			}
+		})
+	}
+}
+
+func TestEncodeIcon(t *testing.T) {
+	tt := []struct {
+		name            string
+		size            int
+		rejectWrongSize bool
+		wantErr         bool
+	}{
+		{name: "Icon", size: iconSize},
+		{name: "ScaledIcon", size: 128},
+		{name: "RejectedIcon", size: 128, rejectWrongSize: true, wantErr: true},
+		{name: "StrictIcon", size: iconSize, rejectWrongSize: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, tc.size, tc.size))); err != nil {
+				t.Fatal(err)
+			}
+
+			img64, err := encodeIcon(buf.Bytes(), tc.rejectWrongSize)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("got: %v; want error: %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+
+			bb, err := base64.StdEncoding.DecodeString(img64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := png.DecodeConfig(bytes.NewReader(bb))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Width != iconSize || cfg.Height != iconSize {
+				t.Errorf("got: %dx%d; want: %dx%d", cfg.Width, cfg.Height, iconSize, iconSize)
+			}
+		})
+	}
+}
+
+func TestStatusConfig_ValidateIcon(t *testing.T) {
+	dir := t.TempDir()
+	writeIcon := func(name string, size int) string {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, size, size))); err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	validIcon := writeIcon("valid.png", iconSize)
+	largeIcon := writeIcon("large.png", 128)
+
+	tt := []struct {
+		name    string
+		cfg     StatusConfig
+		wantErr bool
+	}{
+		{
+			name: "ScaledIcon",
+			cfg:  StatusConfig{IconPath: largeIcon},
+		},
+		{
+			name: "ValidIcon",
+			cfg:  StatusConfig{IconPath: validIcon, RejectWrongIconSize: true},
+		},
+		{
+			name:    "RejectedIcon",
+			cfg:     StatusConfig{IconPath: largeIcon, RejectWrongIconSize: true},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.cfg.validateIcon(); (err != nil) != tc.wantErr {
+				t.Errorf("got: %v; want error: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
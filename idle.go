@@ -0,0 +1,90 @@
+package infrared
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+var errIdleTimeout = errors.New("connection was idle")
+
+// IdleTimeout returns the time that a proxied connection may pass no data
+// in either direction before it is closed. Zero disables it.
+func (proxy *Proxy) IdleTimeout() time.Duration {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return time.Millisecond * time.Duration(proxy.Config.IdleTimeout)
+}
+
+// idleTimer tracks when data last passed through a proxied connection.
+// Both directions share one timer, so that a player that only receives
+// data is not idle.
+type idleTimer struct {
+	timeout    time.Duration
+	lastActive int64
+}
+
+func newIdleTimer(timeout time.Duration) *idleTimer {
+	t := &idleTimer{timeout: timeout}
+	t.touch()
+	return t
+}
+
+func (t *idleTimer) touch() {
+	atomic.StoreInt64(&t.lastActive, time.Now().UnixNano())
+}
+
+// deadline returns the time at which the connection becomes idle
+func (t *idleTimer) deadline() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&t.lastActive)).Add(t.timeout)
+}
+
+func (t *idleTimer) isIdle() bool {
+	return !t.deadline().After(time.Now())
+}
+
+// idleReader touches the timer with every read that returns data
+type idleReader struct {
+	Conn
+	timer *idleTimer
+}
+
+func (r idleReader) Read(b []byte) (int, error) {
+	n, err := r.Conn.Read(b)
+	if n > 0 {
+		r.timer.touch()
+	}
+	return n, err
+}
+
+// pipeIdle is like pipe, but fails with errIdleTimeout once no data passed
+// in either direction for the timeout of the timer. The read deadline of
+// src follows the timer. It always copies in user space, since the kernel
+// does not tell when it relays data.
+func pipeIdle(src, dst Conn, buffers *bufferPool, timer *idleTimer) (int64, error) {
+	var total int64
+	for {
+		deadline := timer.deadline()
+		if !deadline.After(time.Now()) {
+			countRelayed(src, dst, total)
+			return total, errIdleTimeout
+		}
+
+		if err := src.SetReadDeadline(deadline); err != nil {
+			countRelayed(src, dst, total)
+			return total, err
+		}
+
+		n, err := copyConn(idleReader{Conn: src, timer: timer}, dst, buffers, false)
+		total += n
+
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			// The other direction may have passed data in the meantime
+			continue
+		}
+		countRelayed(src, dst, total)
+		return total, err
+	}
+}
@@ -0,0 +1,73 @@
+package infrared
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestPipeIdle(t *testing.T) {
+	const timeout = 50 * time.Millisecond
+
+	tt := []struct {
+		name string
+		// otherDirection is how long the other direction keeps passing data
+		otherDirection time.Duration
+	}{
+		{
+			name: "Idle",
+		},
+		{
+			name:           "OtherDirectionActive",
+			otherDirection: 3 * timeout,
+		},
+	}
+
+	for _, tc := range tt {
+		// The goroutines below may outlive the iteration
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			client, src := net.Pipe()
+			defer client.Close()
+			defer src.Close()
+			dst, server := net.Pipe()
+			defer dst.Close()
+			defer server.Close()
+			go func() { _, _ = ioutil.ReadAll(server) }()
+
+			timer := newIdleTimer(timeout)
+			done := make(chan struct{})
+			defer close(done)
+			go func() {
+				until := time.Now().Add(tc.otherDirection)
+				for time.Now().Before(until) {
+					select {
+					case <-done:
+						return
+					case <-time.After(timeout / 5):
+						timer.touch()
+					}
+				}
+			}()
+
+			// Data keeps the connection active, too
+			go func() { _, _ = client.Write([]byte("hello")) }()
+
+			start := time.Now()
+			n, err := pipeIdle(wrapConn(src), wrapConn(dst), defaultBufferPool, timer)
+			if !errors.Is(err, errIdleTimeout) {
+				t.Fatalf("got: %v; want: %v", err, errIdleTimeout)
+			}
+			if n != 5 {
+				t.Errorf("got: %d bytes; want: %d", n, 5)
+			}
+
+			elapsed := time.Since(start)
+			if min := tc.otherDirection + timeout/2; elapsed < min {
+				t.Errorf("got: %v; want at least: %v", elapsed, min)
+			}
+		})
+	}
+}
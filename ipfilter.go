@@ -0,0 +1,82 @@
+package infrared
+
+import (
+	"log"
+	"net"
+	"strings"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+func (proxy *Proxy) AllowedIPs() []string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.AllowedIPs
+}
+
+func (proxy *Proxy) BlockedIPs() []string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.BlockedIPs
+}
+
+// isIPAllowed reports whether the IP of the client may reach the proxy.
+// Blocked IPs are never allowed and if there are allowed IPs, only those
+// are allowed.
+func (proxy *Proxy) isIPAllowed(connRemoteAddr net.Addr) bool {
+	ip := net.ParseIP(remoteIP(connRemoteAddr))
+	if ip == nil {
+		return true
+	}
+
+	if matchesIP(proxy.BlockedIPs(), ip, proxy.UID()) {
+		return false
+	}
+
+	allowed := proxy.AllowedIPs()
+	return len(allowed) == 0 || matchesIP(allowed, ip, proxy.UID())
+}
+
+// rejectByIP answers clients whose IP is not allowed like an offline server;
+// logins with the disconnect message and status requests with the offline
+// status. It reports whether the client was rejected.
+func (proxy *Proxy) rejectByIP(conn Conn, hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr, loginStart login.ServerLoginStart) (bool, error) {
+	if proxy.isIPAllowed(connRemoteAddr) {
+		return false, nil
+	}
+
+	logWith(connFields("reject", connRemoteAddr, proxy.UID()), "[i] Rejecting blocked %s on %s", connRemoteAddr, proxy.UID())
+	if hs.IsLoginRequest() {
+		return true, proxy.handleLoginRequest(conn, loginStart, connRemoteAddr)
+	}
+	return true, proxy.handleStatusRequest(conn, false)
+}
+
+// matchesIP reports whether ip is one of the IPs or in one of the CIDR
+// ranges of the list. Invalid entries never match.
+func matchesIP(list []string, ip net.IP, proxyUID string) bool {
+	for _, entry := range list {
+		if !strings.Contains(entry, "/") {
+			entryIP := net.ParseIP(entry)
+			if entryIP == nil {
+				log.Printf("[w] Invalid IP %q of %s", entry, proxyUID)
+				continue
+			}
+			if entryIP.Equal(ip) {
+				return true
+			}
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Printf("[w] Invalid IP range %q of %s; error: %s", entry, proxyUID, err)
+			continue
+		}
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
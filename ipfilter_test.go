@@ -0,0 +1,114 @@
+package infrared
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+func TestProxy_IsIPAllowed(t *testing.T) {
+	tt := []struct {
+		name    string
+		allowed []string
+		blocked []string
+		ip      string
+		want    bool
+	}{
+		{
+			name: "NoLists",
+			ip:   "203.0.113.7",
+			want: true,
+		},
+		{
+			name:    "BlockedIP",
+			blocked: []string{"203.0.113.7"},
+			ip:      "203.0.113.7",
+		},
+		{
+			name:    "BlockedRange",
+			blocked: []string{"203.0.113.0/24"},
+			ip:      "203.0.113.7",
+		},
+		{
+			name:    "NotBlocked",
+			blocked: []string{"203.0.113.0/24"},
+			ip:      "198.51.100.7",
+			want:    true,
+		},
+		{
+			name:    "AllowedRange",
+			allowed: []string{"10.0.0.0/8", "2001:db8::/32"},
+			ip:      "2001:db8::1",
+			want:    true,
+		},
+		{
+			name:    "NotAllowed",
+			allowed: []string{"10.0.0.0/8"},
+			ip:      "203.0.113.7",
+		},
+		{
+			name:    "BlockedWithinAllowed",
+			allowed: []string{"10.0.0.0/8"},
+			blocked: []string{"10.0.0.7"},
+			ip:      "10.0.0.7",
+		},
+		{
+			name:    "InvalidEntries",
+			allowed: []string{"10.0.0.0/33", "not an ip", "203.0.113.7"},
+			ip:      "203.0.113.7",
+			want:    true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{Config: &ProxyConfig{
+				AllowedIPs: tc.allowed,
+				BlockedIPs: tc.blocked,
+			}}
+			addr := &net.TCPAddr{IP: net.ParseIP(tc.ip), Port: 50000}
+
+			if got := proxy.isIPAllowed(addr); got != tc.want {
+				t.Errorf("got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProxy_RejectByIP(t *testing.T) {
+	cfg := DefaultProxyConfig()
+	cfg.BlockedIPs = []string{"203.0.113.0/24"}
+	proxy := &Proxy{Config: &cfg}
+	addr := &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 50000}
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	received := make(chan protocol.Packet, 1)
+	go func() {
+		pk, err := wrapConn(c1).ReadPacket()
+		if err == nil {
+			received <- pk
+		}
+	}()
+
+	hs := handshaking.ServerBoundHandshake{NextState: handshaking.ServerBoundHandshakeLoginState}
+	loginStart := login.ServerLoginStart{Name: "Steve"}
+	rejected, err := proxy.rejectByIP(wrapConn(c2), hs, addr, loginStart)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rejected {
+		t.Fatal("got: allowed; want: rejected")
+	}
+
+	want := disconnectPacket("Sorry Steve, but the server is offline.")
+	if pk := <-received; !bytes.Equal(pk.Data, want.Data) {
+		t.Errorf("got: %s; want: %s", pk.Data, want.Data)
+	}
+}
@@ -0,0 +1,29 @@
+package infrared
+
+import (
+	"time"
+)
+
+func (proxy *Proxy) NATKeepAlive() time.Duration {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return time.Millisecond * time.Duration(proxy.Config.NATKeepAlive)
+}
+
+// keepNATAlive makes the kernel send TCP keep-alive probes to the client
+// whenever the connection was idle for the period. The probes keep the
+// mappings of NATs on the way alive without touching the Minecraft stream,
+// which is encrypted and compressed in the play phase and therefore can't
+// carry injected keep-alive packets. Clients answer the probes in their
+// TCP stack, so the keep-alives of the server stay untouched.
+func keepNATAlive(c Conn, period time.Duration) error {
+	tcpConn, ok := asTCPConn(c)
+	if !ok {
+		return nil
+	}
+
+	if err := tcpConn.SetKeepAlive(true); err != nil {
+		return err
+	}
+	return tcpConn.SetKeepAlivePeriod(period)
+}
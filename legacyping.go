@@ -0,0 +1,196 @@
+package infrared
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+const (
+	// legacyPingID starts the server list pings of clients before 1.7
+	legacyPingID = 0xFE
+	// legacyPingPayload follows legacyPingID since 1.4
+	legacyPingPayload = 0x01
+	// legacyPluginMessageID starts the MC|PingHost message of 1.6 clients
+	// that holds the address that they connect to
+	legacyPluginMessageID = 0xFA
+	// legacyKickID is the packet that the status is answered with
+	legacyKickID = 0xFF
+	// legacyMaxStringLength is the maximum length of the strings in a
+	// legacy ping; longer ones are invalid
+	legacyMaxStringLength = 0xff
+)
+
+// legacyPing is the server list ping of a client before 1.7
+type legacyPing struct {
+	// beta is set for the pings of clients before 1.4, which can only
+	// show the MOTD and the players
+	beta bool
+	// host and port are only sent by 1.6 clients
+	host string
+	port int
+}
+
+// isLegacyPing reports whether the connection starts with a legacy ping.
+// The first byte of a handshake is its length, so 0xFE can also start a
+// handshake of 254 bytes, whose third byte is the packet ID 0x00 then.
+// Legacy clients send their ping in one segment, so only the buffered
+// bytes are looked at and the peek does not block.
+func isLegacyPing(r *bufio.Reader) bool {
+	first, err := r.Peek(1)
+	if err != nil || first[0] != legacyPingID {
+		return false
+	}
+
+	bb, _ := r.Peek(r.Buffered())
+	switch {
+	case len(bb) == 1:
+		return true
+	case bb[1] != legacyPingPayload:
+		return false
+	case len(bb) == 2:
+		return true
+	default:
+		return bb[2] == legacyPluginMessageID
+	}
+}
+
+// readLegacyPing reads the legacy ping that isLegacyPing detected
+func readLegacyPing(r *bufio.Reader) (legacyPing, error) {
+	buffered := r.Buffered()
+	if _, err := r.Discard(1); err != nil {
+		return legacyPing{}, err
+	}
+	if buffered == 1 {
+		return legacyPing{beta: true}, nil
+	}
+	if _, err := r.Discard(1); err != nil {
+		return legacyPing{}, err
+	}
+	if buffered == 2 {
+		return legacyPing{}, nil
+	}
+
+	// The MC|PingHost plugin message of 1.6
+	if _, err := r.Discard(1); err != nil {
+		return legacyPing{}, err
+	}
+	channel, err := readLegacyString(r)
+	if err != nil {
+		return legacyPing{}, err
+	}
+	if channel != "MC|PingHost" {
+		return legacyPing{}, fmt.Errorf("unexpected legacy plugin channel %q", channel)
+	}
+
+	// The length of the data and the protocol version
+	if _, err := r.Discard(3); err != nil {
+		return legacyPing{}, err
+	}
+	host, err := readLegacyString(r)
+	if err != nil {
+		return legacyPing{}, err
+	}
+	var port int32
+	if err := binary.Read(r, binary.BigEndian, &port); err != nil {
+		return legacyPing{}, err
+	}
+
+	return legacyPing{
+		host: host,
+		port: int(port),
+	}, nil
+}
+
+// readLegacyString reads a string that is prefixed with its length in
+// UTF-16 code units
+func readLegacyString(r io.Reader) (string, error) {
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return "", err
+	}
+	if length > legacyMaxStringLength {
+		return "", errors.New("legacy string is too long")
+	}
+
+	units := make([]uint16, length)
+	if err := binary.Read(r, binary.BigEndian, units); err != nil {
+		return "", err
+	}
+	return string(utf16.Decode(units)), nil
+}
+
+// legacyKick encodes the kick packet that answers a legacy ping with the
+// status
+func legacyKick(ping legacyPing, status StatusConfig) []byte {
+	// Legacy clients can't show more than one line
+	motd := strings.ReplaceAll(status.MOTD, "\n", " ")
+
+	var reason string
+	if ping.beta {
+		reason = fmt.Sprintf("%s§%d§%d", strings.ReplaceAll(motd, "§", ""), status.PlayersOnline, status.MaxPlayers)
+	} else {
+		reason = strings.Join([]string{
+			"§1",
+			fmt.Sprint(status.ProtocolNumber),
+			status.VersionName,
+			motd,
+			fmt.Sprint(status.PlayersOnline),
+			fmt.Sprint(status.MaxPlayers),
+		}, "\x00")
+	}
+
+	units := utf16.Encode([]rune(reason))
+	bb := make([]byte, 3, 3+len(units)*2)
+	bb[0] = legacyKickID
+	binary.BigEndian.PutUint16(bb[1:], uint16(len(units)))
+	for _, unit := range units {
+		bb = append(bb, byte(unit>>8), byte(unit))
+	}
+	return bb
+}
+
+// handleLegacyPing answers the legacy ping with the online status of the
+// proxy that it requests. Pings without an address are answered by the
+// default server. Legacy clients can't join, so they are never proxied.
+func (gateway *Gateway) handleLegacyPing(conn Conn, addr string) error {
+	ping, err := readLegacyPing(conn.Reader())
+	if err != nil {
+		return err
+	}
+
+	var proxy *Proxy
+	if ping.host != "" {
+		proxy, _ = gateway.matchProxy(handshaking.ServerBoundHandshake{
+			ServerAddress: protocol.String(ping.host),
+			ServerPort:    protocol.UnsignedShort(ping.port),
+			NextState:     handshaking.ServerBoundHandshakeStatusState,
+		}, addr)
+	}
+	if proxy == nil && gateway.UnmatchedAction == UnmatchedActionDrop {
+		return nil
+	}
+	if proxy == nil && gateway.DefaultServer != "" {
+		proxy, _ = gateway.defaultProxy(addr)
+	}
+
+	status := StatusConfig{MOTD: unmatchedMOTD}
+	if proxy != nil {
+		proxy.Config.RLock()
+		status = proxy.Config.OnlineStatus
+		proxy.Config.RUnlock()
+		status = status.withPlaceholders(proxy.UID(), nil)
+	}
+
+	logWith(logFields{"event": "legacy_ping", "remote_addr": conn.RemoteAddr().String(), "listener": addr},
+		"[i] Answering legacy ping of %s on listener %s", conn.RemoteAddr(), addr)
+	_, err = conn.Write(legacyKick(ping, status))
+	return err
+}
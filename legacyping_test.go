@@ -0,0 +1,160 @@
+package infrared
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func legacyString(s string) []byte {
+	units := utf16.Encode([]rune(s))
+	bb := make([]byte, 2, 2+len(units)*2)
+	binary.BigEndian.PutUint16(bb, uint16(len(units)))
+	for _, unit := range units {
+		bb = append(bb, byte(unit>>8), byte(unit))
+	}
+	return bb
+}
+
+// legacyPingHost encodes the ping of a 1.6 client
+func legacyPingHost(host string, port int32) []byte {
+	bb := []byte{legacyPingID, legacyPingPayload, legacyPluginMessageID}
+	bb = append(bb, legacyString("MC|PingHost")...)
+	data := append([]byte{78}, legacyString(host)...)
+	data = append(data, byte(port>>24), byte(port>>16), byte(port>>8), byte(port))
+	bb = append(bb, byte(len(data)>>8), byte(len(data)))
+	return append(bb, data...)
+}
+
+func handshakeBytes(pk protocol.Packet) []byte {
+	bb, _ := pk.Marshal()
+	return bb
+}
+
+func TestIsLegacyPing(t *testing.T) {
+	tt := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{name: "Beta", data: []byte{0xFE}, want: true},
+		{name: "1.4", data: []byte{0xFE, 0x01}, want: true},
+		{name: "1.6", data: legacyPingHost("mc.example.com", 25565), want: true},
+		{name: "Handshake", data: handshakeBytes(serverHandshake("mc.example.com", 25565))},
+		{name: "LongHandshake", data: []byte{0xFE, 0x01, 0x00, 0xFF}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewReader(tc.data))
+			if got := isLegacyPing(r); got != tc.want {
+				t.Errorf("got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestReadLegacyPing(t *testing.T) {
+	r := bufio.NewReader(bytes.NewReader(legacyPingHost("mc.example.com", 25565)))
+	ping, err := readLegacyPing(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := legacyPing{host: "mc.example.com", port: 25565}
+	if ping != want {
+		t.Errorf("got: %+v; want: %+v", ping, want)
+	}
+	if r.Buffered() != 0 {
+		t.Errorf("got: %d unread bytes; want: 0", r.Buffered())
+	}
+}
+
+func TestLegacyKick(t *testing.T) {
+	status := StatusConfig{
+		VersionName:    "Infrared 1.18",
+		ProtocolNumber: 757,
+		MOTD:           "§aHello\nWorld",
+		PlayersOnline:  3,
+		MaxPlayers:     20,
+	}
+
+	tt := []struct {
+		name string
+		ping legacyPing
+		want string
+	}{
+		{
+			name: "1.4",
+			want: "§1\x00757\x00Infrared 1.18\x00§aHello World\x003\x0020",
+		},
+		{
+			name: "Beta",
+			ping: legacyPing{beta: true},
+			want: "aHello World§3§20",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			bb := legacyKick(tc.ping, status)
+			if bb[0] != legacyKickID {
+				t.Fatalf("got: packet ID %#x; want: %#x", bb[0], legacyKickID)
+			}
+
+			got, err := readLegacyString(bytes.NewReader(bb[1:]))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got: %q; want: %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestGateway_HandleLegacyPing(t *testing.T) {
+	cfg := DefaultProxyConfig()
+	cfg.DomainName = "mc.example.com"
+	cfg.OnlineStatus = StatusConfig{VersionName: "1.18", ProtocolNumber: 757, MOTD: "Hello", MaxPlayers: 20}
+	proxy := &Proxy{Config: &cfg}
+	gateway := &Gateway{}
+	gateway.Proxies.Store(proxy.UID(), proxy)
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+
+	go func() {
+		defer c2.Close()
+		conn := wrapConn(c2)
+		if !isLegacyPing(conn.Reader()) {
+			t.Error("got: no legacy ping; want: legacy ping")
+			return
+		}
+		if err := gateway.handleLegacyPing(conn, cfg.ListenTo); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if _, err := c1.Write(legacyPingHost("mc.example.com", 25565)); err != nil {
+		t.Fatal(err)
+	}
+
+	bb, err := io.ReadAll(c1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := readLegacyString(bytes.NewReader(bb[1:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "§1\x00757\x001.18\x00Hello\x000\x0020"; got != want {
+		t.Errorf("got: %q; want: %q", got, want)
+	}
+}
@@ -0,0 +1,185 @@
+package infrared
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The formats of the log
+const (
+	// LogFormatText writes every log line as it is, e.g.
+	// 2024/01/02 15:04:05 [i] Challenging 1.2.3.4:50000 on mc.example.com@:25565
+	LogFormatText = "text"
+	// LogFormatJSON writes every log line as a JSON object with its time,
+	// level and message as well as the fields of the call site, e.g.
+	// {"level":"info","message":"...","proxy_uid":"...","remote_addr":"...","time":"..."}
+	LogFormatJSON = "json"
+)
+
+// The levels of the log lines. The level of a line is given by its prefix:
+// [>] and [x] are debug, [i] is info and [w] is warn. Lines without a prefix
+// are errors if they report a failure and info otherwise.
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+var logLevels = map[string]int{
+	LogLevelDebug: 0,
+	LogLevelInfo:  1,
+	LogLevelWarn:  2,
+	LogLevelError: 3,
+}
+
+// logFields are the structured fields of a log line. Only the JSON log
+// format writes them; the text format has them in its message.
+type logFields map[string]interface{}
+
+// logOutput is the writer of the standard logger once SetLogFormat or
+// SetLogLevel was called; nil until then
+var logOutput struct {
+	sync.RWMutex
+	w *logWriter
+}
+
+// SetLogFormat writes the standard logger to out in one of the LogFormat
+// constants. Every log of the gateway and its proxies goes through it.
+func SetLogFormat(format string, out io.Writer) error {
+	switch format {
+	case "", LogFormatText, LogFormatJSON:
+	default:
+		return fmt.Errorf("invalid log format %q", format)
+	}
+
+	logOutput.Lock()
+	defer logOutput.Unlock()
+	level := LogLevelDebug
+	if logOutput.w != nil {
+		level = logOutput.w.level
+	}
+	logOutput.w = &logWriter{
+		out:   out,
+		json:  format == LogFormatJSON,
+		level: level,
+	}
+	log.SetOutput(logOutput.w)
+	log.SetFlags(0)
+	return nil
+}
+
+// SetLogLevel drops the log lines below one of the LogLevel constants
+func SetLogLevel(level string) error {
+	if _, ok := logLevels[level]; !ok {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+
+	logOutput.RLock()
+	w := logOutput.w
+	logOutput.RUnlock()
+	if w == nil {
+		if err := SetLogFormat(LogFormatText, log.Writer()); err != nil {
+			return err
+		}
+		return SetLogLevel(level)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.level = level
+	return nil
+}
+
+// logWith logs the line like log.Printf and adds the fields to it in the
+// JSON log format
+func logWith(fields logFields, format string, v ...interface{}) {
+	logOutput.RLock()
+	w := logOutput.w
+	logOutput.RUnlock()
+	if w == nil {
+		log.Printf(format, v...)
+		return
+	}
+
+	_ = w.writeLine(fmt.Sprintf(format, v...), fields)
+}
+
+// connFields returns the fields of a log line about the connection of a
+// client to a proxy
+func connFields(event string, connRemoteAddr fmt.Stringer, proxyUID string) logFields {
+	return logFields{
+		"event":       event,
+		"remote_addr": connRemoteAddr.String(),
+		"proxy_uid":   proxyUID,
+	}
+}
+
+// logWriter filters the lines of the standard logger by their level and
+// writes them in the text or JSON log format
+type logWriter struct {
+	mu    sync.Mutex
+	out   io.Writer
+	json  bool
+	level string
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	if err := w.writeLine(string(p), nil); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *logWriter) writeLine(line string, fields logFields) error {
+	line = strings.TrimSuffix(line, "\n")
+	level, message := logLevel(line)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if logLevels[level] < logLevels[w.level] {
+		return nil
+	}
+
+	now := time.Now()
+	if !w.json {
+		_, err := fmt.Fprintf(w.out, "%s %s\n", now.Format("2006/01/02 15:04:05"), line)
+		return err
+	}
+
+	entry := logFields{}
+	for k, v := range fields {
+		entry[k] = v
+	}
+	entry["time"] = now.Format(time.RFC3339Nano)
+	entry["level"] = level
+	entry["message"] = message
+
+	bb, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = w.out.Write(append(bb, '\n'))
+	return err
+}
+
+// logLevel splits the level prefix of a log line from its message
+func logLevel(line string) (string, string) {
+	switch {
+	case strings.HasPrefix(line, "[>] "), strings.HasPrefix(line, "[x] "):
+		return LogLevelDebug, line[4:]
+	case strings.HasPrefix(line, "[i] "):
+		return LogLevelInfo, line[4:]
+	case strings.HasPrefix(line, "[w] "):
+		return LogLevelWarn, line[4:]
+	case strings.HasPrefix(line, "Failed"):
+		return LogLevelError, line
+	default:
+		return LogLevelInfo, line
+	}
+}
@@ -0,0 +1,112 @@
+package infrared
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestSetLogFormat_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SetLogFormat(LogFormatJSON, &buf); err != nil {
+		t.Fatal(err)
+	}
+	defer SetLogFormat(LogFormatText, os.Stderr)
+
+	addr := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000}
+	log.Println("[w] Something is odd")
+	log.Println("Failed loading config.json")
+	logWith(connFields("reject", addr, "mc.example.com@:25565"), "[i] Rejecting %s", addr)
+
+	tt := []logFields{
+		{"level": "warn", "message": "Something is odd"},
+		{"level": "error", "message": "Failed loading config.json"},
+		{
+			"level":       "info",
+			"message":     "Rejecting 10.0.0.1:50000",
+			"event":       "reject",
+			"remote_addr": "10.0.0.1:50000",
+			"proxy_uid":   "mc.example.com@:25565",
+		},
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(tt) {
+		t.Fatalf("got: %d lines; want: %d", len(lines), len(tt))
+	}
+
+	for i, want := range tt {
+		var got map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[i]), &got); err != nil {
+			t.Fatalf("line %d: %s", i, err)
+		}
+		if got["time"] == nil {
+			t.Errorf("line %d: got: no time; want: time", i)
+		}
+		for k, v := range want {
+			if got[k] != v {
+				t.Errorf("line %d: %s: got: %v; want: %v", i, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestSetLogFormat_Text(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SetLogFormat(LogFormatText, &buf); err != nil {
+		t.Fatal(err)
+	}
+	defer SetLogFormat(LogFormatText, os.Stderr)
+
+	addr := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000}
+	logWith(connFields("reject", addr, "mc.example.com@:25565"), "[i] Rejecting %s", addr)
+	if got := buf.String(); !strings.HasSuffix(got, "[i] Rejecting 10.0.0.1:50000\n") {
+		t.Errorf("got: %q; want: text line", got)
+	}
+}
+
+func TestSetLogFormat_Invalid(t *testing.T) {
+	if err := SetLogFormat("yaml", os.Stderr); err == nil {
+		t.Error("got: nil; want: error")
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SetLogFormat(LogFormatText, &buf); err != nil {
+		t.Fatal(err)
+	}
+	defer SetLogFormat(LogFormatText, os.Stderr)
+	if err := SetLogLevel(LogLevelWarn); err != nil {
+		t.Fatal(err)
+	}
+	defer SetLogLevel(LogLevelDebug)
+
+	tt := []struct {
+		line   string
+		logged bool
+	}{
+		{line: "[>] Incoming 10.0.0.1:50000 on listener :25565 via tcp"},
+		{line: "[x] 10.0.0.1:50000 closed connection with :25565"},
+		{line: "[i] Challenging 10.0.0.1:50000 on mc.example.com@:25565"},
+		{line: "Loading config.json"},
+		{line: "[w] Something is odd", logged: true},
+		{line: "Failed loading config.json", logged: true},
+	}
+
+	for _, tc := range tt {
+		buf.Reset()
+		log.Println(tc.line)
+		if logged := buf.Len() > 0; logged != tc.logged {
+			t.Errorf("%s: got: %v; want: %v", tc.line, logged, tc.logged)
+		}
+	}
+
+	if err := SetLogLevel("verbose"); err == nil {
+		t.Error("got: nil; want: error")
+	}
+}
@@ -0,0 +1,73 @@
+package infrared
+
+import (
+	"log"
+	"net"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+const (
+	defaultMaintenanceMessage = "The server is under maintenance. Please come back later."
+	defaultMaintenanceMOTD    = "Under maintenance"
+)
+
+func (proxy *Proxy) Maintenance() MaintenanceConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.Maintenance
+}
+
+// SetMaintenance starts or ends the maintenance of the server without a
+// restart. Players that are already connected stay connected. The config
+// file takes over again when it changes.
+func (proxy *Proxy) SetMaintenance(enabled bool) {
+	proxy.Config.Lock()
+	proxy.Config.Maintenance.Enabled = enabled
+	proxy.Config.Unlock()
+
+	if enabled {
+		log.Printf("[i] Starting the maintenance of %s", proxy.UID())
+	} else {
+		log.Printf("[i] Ending the maintenance of %s", proxy.UID())
+	}
+}
+
+func (cfg MaintenanceConfig) message() string {
+	if cfg.Message == "" {
+		return defaultMaintenanceMessage
+	}
+	return cfg.Message
+}
+
+func (cfg MaintenanceConfig) motd() string {
+	if cfg.MOTD == "" {
+		return defaultMaintenanceMOTD
+	}
+	return cfg.MOTD
+}
+
+// rejectByMaintenance disconnects new logins while the server is under
+// maintenance and answers status requests with the maintenance MOTD. It
+// reports whether the client was handled.
+func (proxy *Proxy) rejectByMaintenance(conn Conn, hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr) (bool, error) {
+	cfg := proxy.Maintenance()
+	if !cfg.IsEnabled() {
+		return false, nil
+	}
+
+	if hs.IsStatusRequest() {
+		pk, err := proxy.closedStatusPacket(cfg.motd())
+		if err != nil {
+			return true, err
+		}
+		return true, writeStatus(conn, pk, proxy.StrictProtocol())
+	}
+
+	if !hs.IsLoginRequest() {
+		return false, nil
+	}
+
+	logWith(connFields("reject", connRemoteAddr, proxy.UID()), "[i] Rejecting %s on %s during its maintenance", connRemoteAddr, proxy.UID())
+	return true, conn.WritePacket(disconnectPacket(cfg.message()))
+}
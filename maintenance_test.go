@@ -0,0 +1,102 @@
+package infrared
+
+import (
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/status"
+)
+
+func TestProxy_RejectByMaintenance(t *testing.T) {
+	tt := []struct {
+		name        string
+		maintenance bool
+		nextState   protocol.Byte
+		rejected    bool
+	}{
+		{
+			name:      "Login",
+			nextState: handshaking.ServerBoundHandshakeLoginState,
+		},
+		{
+			name:        "MaintenanceLogin",
+			maintenance: true,
+			nextState:   handshaking.ServerBoundHandshakeLoginState,
+			rejected:    true,
+		},
+		{
+			name:        "MaintenanceStatus",
+			maintenance: true,
+			nextState:   handshaking.ServerBoundHandshakeStatusState,
+			rejected:    true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{
+				Config: &ProxyConfig{
+					DomainName:    "mc.example.com",
+					ListenTo:      ":25565",
+					OfflineStatus: statusPKWithVersion("Infrared-test-offline"),
+					Maintenance: MaintenanceConfig{
+						Message: "Back soon",
+						MOTD:    "Updating",
+					},
+				},
+			}
+			proxy.SetMaintenance(tc.maintenance)
+
+			c1, c2 := net.Pipe()
+			defer c1.Close()
+			defer c2.Close()
+			received := make(chan string, 1)
+			go func() {
+				client := wrapConn(c1)
+				if tc.nextState == handshaking.ServerBoundHandshakeStatusState {
+					_ = client.WritePacket(status.ServerBoundRequest{}.Marshal())
+				}
+				pk, err := client.ReadPacket()
+				if err != nil {
+					received <- ""
+					return
+				}
+				if tc.nextState == handshaking.ServerBoundHandshakeLoginState {
+					received <- string(pk.Data)
+					return
+				}
+				response, _ := status.UnmarshalClientBoundResponse(pk)
+				var responseJSON status.ResponseJSON
+				_ = json.Unmarshal([]byte(response.JSONResponse), &responseJSON)
+				received <- responseJSON.Description.Text
+				_ = client.WritePacket(status.ServerBoundPing{Payload: 1}.Marshal())
+				_, _ = client.ReadPacket()
+			}()
+
+			hs := handshaking.ServerBoundHandshake{NextState: tc.nextState}
+			remoteAddr := &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 50000}
+			rejected, err := proxy.rejectByMaintenance(wrapConn(c2), hs, remoteAddr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rejected != tc.rejected {
+				t.Errorf("got: %v; want: %v", rejected, tc.rejected)
+			}
+
+			c2.Close()
+			got := <-received
+			switch {
+			case !tc.rejected && got != "":
+				t.Errorf("got: %v; want: no answer", got)
+			case tc.nextState == handshaking.ServerBoundHandshakeLoginState && tc.rejected && !strings.Contains(got, "Back soon"):
+				t.Errorf("got: %q; want the maintenance message", got)
+			case tc.nextState == handshaking.ServerBoundHandshakeStatusState && got != "Updating":
+				t.Errorf("got: %v; want: %v", got, "Updating")
+			}
+		})
+	}
+}
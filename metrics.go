@@ -0,0 +1,331 @@
+package infrared
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// unknownHost is the host label of handshakes that match no proxy
+const unknownHost = "unknown"
+
+var (
+	playersConnected = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "infrared_connected",
+		Help: "The total number of connected players",
+	}, []string{"host", "transport"})
+	proxiesActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "infrared_proxies",
+		Help: "The total number of proxies running",
+	})
+	listenersActive = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "infrared_listeners_active",
+		Help: "The total number of addresses that the gateway listens on",
+	})
+	handshakeCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "infrared_handshakes",
+		Help: "The total number of received handshakes",
+	}, []string{"host", "type", "transport"})
+	dialErrorCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "infrared_dial_errors",
+		Help: "The total number of failed dials to a proxy's server",
+	}, []string{"host"})
+	backendLoginCloseCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "infrared_backend_login_closes",
+		Help: "The total number of connections that a proxy's server closed during login",
+	}, []string{"host"})
+	proxyProtocolErrorCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "infrared_proxy_protocol_errors",
+		Help: "The total number of connections without a valid PROXY protocol header",
+	}, []string{"listener", "reason"})
+	invalidUsernameCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "infrared_invalid_usernames",
+		Help: "The total number of logins that were rejected because of an invalid username",
+	}, []string{"host"})
+	relayedBytesCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "infrared_relayed_bytes",
+		Help: "The total number of bytes that were relayed between clients and servers",
+	}, []string{"host", "direction"})
+	bytesTransmittedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "infrared_bytes_transmitted",
+		Help: "The total number of bytes that were sent to the clients of a proxy",
+	}, []string{"host"})
+	bytesReceivedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "infrared_bytes_received",
+		Help: "The total number of bytes that were received from the clients of a proxy",
+	}, []string{"host"})
+	shedStatusCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "infrared_shed_status_requests",
+		Help: "The total number of status requests of unknown IPs that were shed under a ping flood",
+	}, []string{"host"})
+	rateLimitedStatusCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "infrared_rate_limited_status_requests",
+		Help: "The total number of status requests that exceeded the status rate limit of their IP",
+	}, []string{"host"})
+	overloadRefusalCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "infrared_overload_refusals",
+		Help: "The total number of connections that were refused, because the gateway was overloaded",
+	}, []string{"listener"})
+	rateLimitedConnCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "infrared_rate_limited_connections",
+		Help: "The total number of connections that were closed, because their IP exceeded the connection rate limit",
+	}, []string{"listener"})
+	strictViolationCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "infrared_strict_protocol_violations",
+		Help: "The total number of connections that were dropped, because they deviated from the expected packet sequence",
+	}, []string{"host", "reason"})
+	handshakePortMismatchCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "infrared_handshake_port_mismatches",
+		Help: "The total number of connections that were dropped, because their handshake claimed another port",
+	}, []string{"host"})
+	clientLocaleCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "infrared_client_locales",
+		Help: "The total number of logins by the language of the client",
+	}, []string{"host", "locale"})
+	connectionDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "infrared_connection_duration_seconds",
+		Help: "The time that players were connected to a proxy",
+		// From short visits to long sessions of several hours
+		Buckets: []float64{5, 15, 30, 60, 300, 600, 1800, 3600, 7200, 14400, 28800},
+	}, []string{"host"})
+	statusBreakerOpen = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "infrared_status_breaker_open",
+		Help: "If the status circuit breaker of a proxy is open",
+	}, []string{"host"})
+	loginBreakerOpen = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "infrared_login_breaker_open",
+		Help: "If the login circuit breaker of a proxy is open",
+	}, []string{"host"})
+	backendHealthy = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "infrared_backend_healthy",
+		Help: "If a server of a proxy passes its health checks",
+	}, []string{"host", "backend"})
+)
+
+// MetricsRecorder receives the operational metrics of the gateway and its proxies
+type MetricsRecorder interface {
+	AddProxies(delta int)
+	AddListeners(delta int)
+	AddConnectedPlayers(host, transport string, delta int)
+	IncHandshakes(host, handshakeType, transport string)
+	IncDialErrors(host string)
+	IncBackendLoginCloses(host string)
+	SetStatusBreakerOpen(host string, open bool)
+	SetLoginBreakerOpen(host string, open bool)
+	SetBackendHealthy(host, backend string, healthy bool)
+	IncProxyProtocolErrors(listener, reason string)
+	IncInvalidUsernames(host string)
+	AddRelayedBytes(host string, sent, received int64)
+	IncClientLocales(host, locale string)
+	IncShedStatusRequests(host string)
+	IncRateLimitedStatusRequests(host string)
+	IncOverloadRefusals(listener string)
+	IncRateLimitedConnections(listener string)
+	IncStrictProtocolViolations(host, reason string)
+	IncHandshakePortMismatches(host string)
+	ObserveConnectionDuration(host string, duration time.Duration)
+}
+
+// metrics is the recorder that all metrics are reported to.
+// It forwards them to every enabled metrics backend.
+var metrics = &multiRecorder{
+	recorders: []MetricsRecorder{prometheusRecorder{}},
+}
+
+type multiRecorder struct {
+	mu        sync.RWMutex
+	recorders []MetricsRecorder
+}
+
+func (m *multiRecorder) add(recorder MetricsRecorder) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.recorders = append(m.recorders, recorder)
+}
+
+func (m *multiRecorder) each(fn func(recorder MetricsRecorder)) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, recorder := range m.recorders {
+		fn(recorder)
+	}
+}
+
+func (m *multiRecorder) AddProxies(delta int) {
+	m.each(func(r MetricsRecorder) { r.AddProxies(delta) })
+}
+
+func (m *multiRecorder) AddListeners(delta int) {
+	m.each(func(r MetricsRecorder) { r.AddListeners(delta) })
+}
+
+func (m *multiRecorder) AddConnectedPlayers(host, transport string, delta int) {
+	m.each(func(r MetricsRecorder) { r.AddConnectedPlayers(host, transport, delta) })
+}
+
+func (m *multiRecorder) IncHandshakes(host, handshakeType, transport string) {
+	m.each(func(r MetricsRecorder) { r.IncHandshakes(host, handshakeType, transport) })
+}
+
+func (m *multiRecorder) IncDialErrors(host string) {
+	m.each(func(r MetricsRecorder) { r.IncDialErrors(host) })
+}
+
+func (m *multiRecorder) IncBackendLoginCloses(host string) {
+	m.each(func(r MetricsRecorder) { r.IncBackendLoginCloses(host) })
+}
+
+func (m *multiRecorder) SetStatusBreakerOpen(host string, open bool) {
+	m.each(func(r MetricsRecorder) { r.SetStatusBreakerOpen(host, open) })
+}
+
+func (m *multiRecorder) SetLoginBreakerOpen(host string, open bool) {
+	m.each(func(r MetricsRecorder) { r.SetLoginBreakerOpen(host, open) })
+}
+
+func (m *multiRecorder) SetBackendHealthy(host, backend string, healthy bool) {
+	m.each(func(r MetricsRecorder) { r.SetBackendHealthy(host, backend, healthy) })
+}
+
+func (m *multiRecorder) IncProxyProtocolErrors(listener, reason string) {
+	m.each(func(r MetricsRecorder) { r.IncProxyProtocolErrors(listener, reason) })
+}
+
+func (m *multiRecorder) IncInvalidUsernames(host string) {
+	m.each(func(r MetricsRecorder) { r.IncInvalidUsernames(host) })
+}
+
+func (m *multiRecorder) AddRelayedBytes(host string, sent, received int64) {
+	m.each(func(r MetricsRecorder) { r.AddRelayedBytes(host, sent, received) })
+}
+
+func (m *multiRecorder) IncClientLocales(host, locale string) {
+	m.each(func(r MetricsRecorder) { r.IncClientLocales(host, locale) })
+}
+
+func (m *multiRecorder) IncShedStatusRequests(host string) {
+	m.each(func(r MetricsRecorder) { r.IncShedStatusRequests(host) })
+}
+
+func (m *multiRecorder) IncRateLimitedStatusRequests(host string) {
+	m.each(func(r MetricsRecorder) { r.IncRateLimitedStatusRequests(host) })
+}
+
+func (m *multiRecorder) IncOverloadRefusals(listener string) {
+	m.each(func(r MetricsRecorder) { r.IncOverloadRefusals(listener) })
+}
+
+func (m *multiRecorder) IncRateLimitedConnections(listener string) {
+	m.each(func(r MetricsRecorder) { r.IncRateLimitedConnections(listener) })
+}
+
+func (m *multiRecorder) IncStrictProtocolViolations(host, reason string) {
+	m.each(func(r MetricsRecorder) { r.IncStrictProtocolViolations(host, reason) })
+}
+
+func (m *multiRecorder) IncHandshakePortMismatches(host string) {
+	m.each(func(r MetricsRecorder) { r.IncHandshakePortMismatches(host) })
+}
+
+func (m *multiRecorder) ObserveConnectionDuration(host string, duration time.Duration) {
+	m.each(func(r MetricsRecorder) { r.ObserveConnectionDuration(host, duration) })
+}
+
+type prometheusRecorder struct{}
+
+func (prometheusRecorder) AddProxies(delta int) {
+	proxiesActive.Add(float64(delta))
+}
+
+func (prometheusRecorder) AddListeners(delta int) {
+	listenersActive.Add(float64(delta))
+}
+
+func (prometheusRecorder) AddConnectedPlayers(host, transport string, delta int) {
+	playersConnected.With(prometheus.Labels{"host": host, "transport": transport}).Add(float64(delta))
+}
+
+func (prometheusRecorder) IncHandshakes(host, handshakeType, transport string) {
+	handshakeCount.With(prometheus.Labels{"host": host, "type": handshakeType, "transport": transport}).Inc()
+}
+
+func (prometheusRecorder) IncDialErrors(host string) {
+	dialErrorCount.With(prometheus.Labels{"host": host}).Inc()
+}
+
+func (prometheusRecorder) IncBackendLoginCloses(host string) {
+	backendLoginCloseCount.With(prometheus.Labels{"host": host}).Inc()
+}
+
+func (prometheusRecorder) SetStatusBreakerOpen(host string, open bool) {
+	value := 0.0
+	if open {
+		value = 1
+	}
+	statusBreakerOpen.With(prometheus.Labels{"host": host}).Set(value)
+}
+
+func (prometheusRecorder) SetLoginBreakerOpen(host string, open bool) {
+	value := 0.0
+	if open {
+		value = 1
+	}
+	loginBreakerOpen.With(prometheus.Labels{"host": host}).Set(value)
+}
+
+func (prometheusRecorder) SetBackendHealthy(host, backend string, healthy bool) {
+	value := 0.0
+	if healthy {
+		value = 1
+	}
+	backendHealthy.With(prometheus.Labels{"host": host, "backend": backend}).Set(value)
+}
+
+func (prometheusRecorder) IncProxyProtocolErrors(listener, reason string) {
+	proxyProtocolErrorCount.With(prometheus.Labels{"listener": listener, "reason": reason}).Inc()
+}
+
+func (prometheusRecorder) IncInvalidUsernames(host string) {
+	invalidUsernameCount.With(prometheus.Labels{"host": host}).Inc()
+}
+
+func (prometheusRecorder) AddRelayedBytes(host string, sent, received int64) {
+	relayedBytesCount.With(prometheus.Labels{"host": host, "direction": "sent"}).Add(float64(sent))
+	relayedBytesCount.With(prometheus.Labels{"host": host, "direction": "received"}).Add(float64(received))
+	bytesTransmittedCount.With(prometheus.Labels{"host": host}).Add(float64(sent))
+	bytesReceivedCount.With(prometheus.Labels{"host": host}).Add(float64(received))
+}
+
+func (prometheusRecorder) IncClientLocales(host, locale string) {
+	clientLocaleCount.With(prometheus.Labels{"host": host, "locale": locale}).Inc()
+}
+
+func (prometheusRecorder) IncShedStatusRequests(host string) {
+	shedStatusCount.With(prometheus.Labels{"host": host}).Inc()
+}
+
+func (prometheusRecorder) IncRateLimitedStatusRequests(host string) {
+	rateLimitedStatusCount.With(prometheus.Labels{"host": host}).Inc()
+}
+
+func (prometheusRecorder) IncOverloadRefusals(listener string) {
+	overloadRefusalCount.With(prometheus.Labels{"listener": listener}).Inc()
+}
+
+func (prometheusRecorder) IncRateLimitedConnections(listener string) {
+	rateLimitedConnCount.With(prometheus.Labels{"listener": listener}).Inc()
+}
+
+func (prometheusRecorder) IncStrictProtocolViolations(host, reason string) {
+	strictViolationCount.With(prometheus.Labels{"host": host, "reason": reason}).Inc()
+}
+
+func (prometheusRecorder) IncHandshakePortMismatches(host string) {
+	handshakePortMismatchCount.With(prometheus.Labels{"host": host}).Inc()
+}
+
+func (prometheusRecorder) ObserveConnectionDuration(host string, duration time.Duration) {
+	connectionDuration.With(prometheus.Labels{"host": host}).Observe(duration.Seconds())
+}
@@ -0,0 +1,219 @@
+package infrared
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// waitForGauge waits until the gauge has the wanted value, since listeners
+// are only counted down once they stopped serving
+func waitForGauge(t *testing.T, name string, gauge prometheus.Gauge, want float64) {
+	t.Helper()
+	for i := 0; testutil.ToFloat64(gauge) != want; i++ {
+		if i == 100 {
+			t.Fatalf("%s got: %v; want: %v", name, testutil.ToFloat64(gauge), want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestGateway_ProxyAndListenerMetrics(t *testing.T) {
+	proxies := testutil.ToFloat64(proxiesActive)
+	listeners := testutil.ToFloat64(listenersActive)
+
+	cfgs := []*ProxyConfig{
+		createBasicProxyConfig("a.example.com", gatewayAddr(820), serverAddr(820)),
+		createBasicProxyConfig("b.example.com", gatewayAddr(820), serverAddr(820)),
+		createBasicProxyConfig("c.example.com", gatewayAddr(821), serverAddr(821)),
+	}
+	gateway := Gateway{}
+	if err := gateway.ListenAndServe(configsToProxies(cfgs)); err != nil {
+		t.Fatal(err)
+	}
+	waitForGauge(t, "proxies", proxiesActive, proxies+3)
+	waitForGauge(t, "listeners", listenersActive, listeners+2)
+
+	// A proxy that replaces one with the same UID is not counted twice
+	replacement := createBasicProxyConfig("a.example.com", gatewayAddr(820), serverAddr(821))
+	if err := gateway.RegisterProxy(&Proxy{Config: replacement}); err != nil {
+		t.Fatal(err)
+	}
+	waitForGauge(t, "proxies", proxiesActive, proxies+3)
+
+	gateway.CloseProxy(proxyUID("c.example.com", gatewayAddr(821)))
+	waitForGauge(t, "proxies", proxiesActive, proxies+2)
+	waitForGauge(t, "listeners", listenersActive, listeners+1)
+
+	// The listener stays open while another proxy uses it
+	gateway.CloseProxy(proxyUID("a.example.com", gatewayAddr(820)))
+	waitForGauge(t, "proxies", proxiesActive, proxies+1)
+	waitForGauge(t, "listeners", listenersActive, listeners+1)
+
+	gateway.CloseProxy(proxyUID("b.example.com", gatewayAddr(820)))
+	gateway.CloseProxy("unknown.example.com@" + gatewayAddr(820))
+	waitForGauge(t, "proxies", proxiesActive, proxies)
+	waitForGauge(t, "listeners", listenersActive, listeners)
+}
+
+func TestGateway_HandshakeMetrics(t *testing.T) {
+	labels := prometheus.Labels{"host": unknownHost, "type": "status", "transport": TransportTCP}
+	unknown := testutil.ToFloat64(handshakeCount.With(labels))
+	series := testutil.CollectAndCount(handshakeCount)
+
+	gateway := Gateway{UnmatchedAction: UnmatchedActionDrop}
+	for _, domain := range []string{"attacker-1.example.com", "attacker-2.example.com"} {
+		c1, c2 := net.Pipe()
+		go func(domain string) { _ = wrapConn(c1).WritePacket(serverHandshake(domain, 25565)) }(domain)
+		if err := gateway.serve(wrapConn(c2), gatewayAddr(822)); err != nil {
+			t.Fatal(err)
+		}
+		c1.Close()
+		c2.Close()
+	}
+
+	if got := testutil.ToFloat64(handshakeCount.With(labels)); got != unknown+2 {
+		t.Errorf("got: %v; want: %v", got, unknown+2)
+	}
+	if got := testutil.CollectAndCount(handshakeCount); got != series {
+		t.Errorf("got: %d series; want: %d", got, series)
+	}
+}
+
+// relayLogin logs a player in to the proxy and sends payload after the login
+// start. The server of the proxy reads the payload, answers with reply and
+// closes the connection. inRelay is called while the player is connected.
+func relayLogin(t *testing.T, domain string, payload, reply []byte, inRelay func()) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		rconn := wrapConn(c)
+		defer rconn.Close()
+		// Handshake and login start
+		for i := 0; i < 2; i++ {
+			if _, err := rconn.ReadPacket(); err != nil {
+				return
+			}
+		}
+		if _, err := io.ReadFull(rconn, make([]byte, len(payload))); err != nil {
+			return
+		}
+		_, _ = rconn.Write(reply)
+	}()
+
+	proxy := &Proxy{
+		Config: &ProxyConfig{
+			DomainName: domain,
+			ProxyTo:    l.Addr().String(),
+			Timeout:    1000,
+		},
+	}
+
+	c1, c2 := net.Pipe()
+	client := wrapConn(c1)
+	done := make(chan struct{})
+	go func() {
+		_ = proxy.handleConn(wrapConn(c2), c2.RemoteAddr())
+		close(done)
+	}()
+
+	hs := handshaking.ServerBoundHandshake{
+		ProtocolVersion: 758,
+		ServerAddress:   protocol.String(domain),
+		ServerPort:      25565,
+		NextState:       handshaking.ServerBoundHandshakeLoginState,
+	}
+	if err := client.WritePacket(hs.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	loginStart := protocol.MarshalPacket(login.ServerBoundLoginStartPacketID, protocol.String("Steve"))
+	if err := client.WritePacket(loginStart); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Write(payload); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, len(reply))
+	if _, err := io.ReadFull(client, got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, reply) {
+		t.Fatalf("got: %q; want: %q", got, reply)
+	}
+	if inRelay != nil {
+		inRelay()
+	}
+
+	client.Close()
+	<-done
+}
+
+func TestProxy_RelayedBytesMetrics(t *testing.T) {
+	const domain = "relayed-bytes.example.com"
+	sentCounter := relayedBytesCount.With(prometheus.Labels{"host": domain, "direction": "sent"})
+	receivedCounter := relayedBytesCount.With(prometheus.Labels{"host": domain, "direction": "received"})
+	transmittedCounter := bytesTransmittedCount.With(prometheus.Labels{"host": domain})
+	bytesReceivedCounter := bytesReceivedCount.With(prometheus.Labels{"host": domain})
+	sent := testutil.ToFloat64(sentCounter)
+	received := testutil.ToFloat64(receivedCounter)
+	transmitted := testutil.ToFloat64(transmittedCounter)
+	bytesReceived := testutil.ToFloat64(bytesReceivedCounter)
+
+	relayLogin(t, domain, []byte("from the player"), []byte("to the player!!!!"), nil)
+
+	if got := testutil.ToFloat64(sentCounter); got != sent+17 {
+		t.Errorf("sent got: %v; want: %v", got, sent+17)
+	}
+	if got := testutil.ToFloat64(receivedCounter); got != received+15 {
+		t.Errorf("received got: %v; want: %v", got, received+15)
+	}
+	if got := testutil.ToFloat64(transmittedCounter); got != transmitted+17 {
+		t.Errorf("transmitted got: %v; want: %v", got, transmitted+17)
+	}
+	if got := testutil.ToFloat64(bytesReceivedCounter); got != bytesReceived+15 {
+		t.Errorf("bytes received got: %v; want: %v", got, bytesReceived+15)
+	}
+}
+
+func TestProxy_ConnectedMetrics(t *testing.T) {
+	const domain = "connected.example.com"
+	gauge := playersConnected.With(prometheus.Labels{"host": domain, "transport": TransportTCP})
+	connected := testutil.ToFloat64(gauge)
+
+	relayLogin(t, domain, []byte("ping"), []byte("pong"), func() {
+		if got := testutil.ToFloat64(gauge); got != connected+1 {
+			t.Errorf("while connected got: %v; want: %v", got, connected+1)
+		}
+	})
+	if got := testutil.ToFloat64(gauge); got != connected {
+		t.Errorf("after leaving got: %v; want: %v", got, connected)
+	}
+
+	// A login that the server closes before answering leaves as well
+	relayLogin(t, domain, nil, nil, nil)
+	if got := testutil.ToFloat64(gauge); got != connected {
+		t.Errorf("after failed login got: %v; want: %v", got, connected)
+	}
+}
@@ -0,0 +1,110 @@
+package infrared
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/status"
+)
+
+const (
+	motdOnlinePlaceholder   = "{{online}}"
+	motdMaxPlaceholder      = "{{max}}"
+	motdProxyUIDPlaceholder = "{{proxy_uid}}"
+)
+
+// withPlaceholders returns the status with the placeholders of its MOTD
+// replaced. The player counts are taken from players or, if it is nil, from
+// the status itself.
+func (cfg StatusConfig) withPlaceholders(proxyUID string, players *status.PlayersJSON) StatusConfig {
+	online, max := cfg.PlayersOnline, cfg.MaxPlayers
+	if players != nil {
+		online, max = players.Online, players.Max
+	}
+
+	motd := strings.NewReplacer(
+		motdOnlinePlaceholder, strconv.Itoa(online),
+		motdMaxPlaceholder, strconv.Itoa(max),
+		motdProxyUIDPlaceholder, proxyUID,
+	).Replace(cfg.MOTD)
+	if motd != cfg.MOTD {
+		cfg.cachedPacket = nil
+		cfg.MOTD = motd
+	}
+	return cfg
+}
+
+// hasPlayerPlaceholders reports whether the MOTD shows the player counts
+func hasPlayerPlaceholders(motd string) bool {
+	return strings.Contains(motd, motdOnlinePlaceholder) || strings.Contains(motd, motdMaxPlaceholder)
+}
+
+// handleOnlineStatusRequest answers the status request with the online
+// status. If its MOTD shows the player counts, they are taken from the
+// status of the server on rconn.
+func (proxy *Proxy) handleOnlineStatusRequest(conn, rconn Conn, hs handshaking.ServerBoundHandshake, route string, connRemoteAddr net.Addr) error {
+	proxy.Config.RLock()
+	cfg := proxy.Config.OnlineStatus
+	proxy.Config.RUnlock()
+
+	if !hasPlayerPlaceholders(cfg.MOTD) {
+		return proxy.handleStatusRequest(conn, true)
+	}
+
+	players, err := proxy.serverPlayers(rconn, hs, route, connRemoteAddr)
+	if err != nil {
+		// Show the configured counts instead of failing the status
+		log.Printf("[w] Failed to get the player counts of %s; error: %s", proxy.UID(), err)
+	}
+
+	responsePk, err := cfg.withPlaceholders(proxy.UID(), players).StatusResponsePacket()
+	if err != nil {
+		return err
+	}
+	return writeStatus(conn, responsePk, proxy.StrictProtocol())
+}
+
+// serverPlayers returns the player counts from the status of the server.
+// If the status cache is enabled, the status of the route is cached for its
+// TTL, so that not every ping queries the server.
+func (proxy *Proxy) serverPlayers(rconn Conn, hs handshaking.ServerBoundHandshake, route string, connRemoteAddr net.Addr) (*status.PlayersJSON, error) {
+	cacheCfg := proxy.StatusCache()
+	if cacheCfg.IsEnabled() {
+		if v, ok := proxy.statusCache().Get(route); ok {
+			return statusPlayers(v.(protocol.Packet))
+		}
+	}
+
+	responsePk, err := proxy.requestStatus(rconn, hs, connRemoteAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	if cacheCfg.IsEnabled() {
+		proxy.statusCache().Set(route, responsePk, cacheCfg.TTLDuration())
+	}
+	return statusPlayers(responsePk)
+}
+
+// statusPlayers returns the player counts of a status response
+func statusPlayers(responsePk protocol.Packet) (*status.PlayersJSON, error) {
+	response, err := status.UnmarshalClientBoundResponse(responsePk)
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the players are decoded, since servers send the description
+	// in all kinds of formats
+	var responseJSON struct {
+		Players status.PlayersJSON `json:"players"`
+	}
+	if err := json.Unmarshal([]byte(response.JSONResponse), &responseJSON); err != nil {
+		return nil, err
+	}
+	return &responseJSON.Players, nil
+}
@@ -0,0 +1,192 @@
+package infrared
+
+import (
+	"encoding/json"
+	"net"
+	"sync/atomic"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/status"
+)
+
+func TestStatusConfig_WithPlaceholders(t *testing.T) {
+	tt := []struct {
+		name    string
+		motd    string
+		players *status.PlayersJSON
+		want    string
+	}{
+		{
+			name: "NoPlaceholders",
+			motd: "Powered by Infrared",
+			want: "Powered by Infrared",
+		},
+		{
+			name: "ConfiguredCounts",
+			motd: "{{online}}/{{max}} players",
+			want: "3/20 players",
+		},
+		{
+			name:    "ServerCounts",
+			motd:    "{{online}}/{{max}} players",
+			players: &status.PlayersJSON{Online: 42, Max: 100},
+			want:    "42/100 players",
+		},
+		{
+			name: "ProxyUID",
+			motd: "Welcome to {{proxy_uid}}",
+			want: "Welcome to mc.example.com@:25565",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := StatusConfig{MOTD: tc.motd, PlayersOnline: 3, MaxPlayers: 20}
+			if got := cfg.withPlaceholders("mc.example.com@:25565", tc.players).MOTD; got != tc.want {
+				t.Errorf("got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStatusPlayers(t *testing.T) {
+	tt := []struct {
+		name     string
+		response string
+	}{
+		{
+			name:     "TextDescription",
+			response: `{"version":{"name":"1.20.4","protocol":765},"players":{"max":100,"online":42},"description":"A Minecraft Server"}`,
+		},
+		{
+			name:     "ChatDescription",
+			response: `{"version":{"name":"1.20.4","protocol":765},"players":{"max":100,"online":42,"sample":[]},"description":{"text":"","extra":[{"text":"A Minecraft Server","color":"gold"}]}}`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			pk := status.ClientBoundResponse{JSONResponse: protocol.String(tc.response)}.Marshal()
+			players, err := statusPlayers(pk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if players.Online != 42 || players.Max != 100 {
+				t.Errorf("got: %d/%d; want: %d/%d", players.Online, players.Max, 42, 100)
+			}
+		})
+	}
+}
+
+func requestOnlineStatus(t *testing.T, proxy *Proxy, serverAddr string) string {
+	rc, err := net.Dial("tcp", serverAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rconn := wrapConn(rc)
+	defer rconn.Close()
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	hs := handshaking.ServerBoundHandshake{
+		ProtocolVersion: 754,
+		ServerAddress:   "localhost",
+		NextState:       handshaking.ServerBoundHandshakeStatusState,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- proxy.handleOnlineStatusRequest(wrapConn(c2), rconn, hs, "", c2.RemoteAddr())
+	}()
+
+	client := wrapConn(c1)
+	if err := client.WritePacket(status.ServerBoundRequest{}.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	pk, err := client.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.WritePacket(protocol.Packet{ID: 0x01, Data: []byte{0}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ReadPacket(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := status.UnmarshalClientBoundResponse(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resJSON := &status.ResponseJSON{}
+	if err := json.Unmarshal([]byte(res.JSONResponse), resJSON); err != nil {
+		t.Fatal(err)
+	}
+	return resJSON.Description.Text
+}
+
+func TestProxy_HandleOnlineStatusRequest(t *testing.T) {
+	// The counting server reports 0 of 20 players
+	addr, requests := countingStatusServer(t, "online")
+
+	tt := []struct {
+		name  string
+		motd  string
+		cache StatusCacheConfig
+		want  string
+		// requests is the number of status requests to the server
+		requests int32
+	}{
+		{
+			name:     "StaticMOTD",
+			motd:     "Powered by Infrared",
+			want:     "Powered by Infrared",
+			requests: 0,
+		},
+		{
+			name:     "LiveCounts",
+			motd:     "{{online}}/{{max}} players",
+			want:     "0/20 players",
+			requests: 2,
+		},
+		{
+			name:     "CachedCounts",
+			motd:     "{{online}}/{{max}} players",
+			cache:    StatusCacheConfig{TTL: 60000},
+			want:     "0/20 players",
+			requests: 1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			atomic.StoreInt32(requests, 0)
+			proxy := &Proxy{Config: &ProxyConfig{
+				StatusCache: tc.cache,
+				OnlineStatus: StatusConfig{
+					VersionName:    "Infrared",
+					ProtocolNumber: 754,
+					MaxPlayers:     500,
+					MOTD:           tc.motd,
+				},
+			}}
+
+			for i := 0; i < 2; i++ {
+				if got := requestOnlineStatus(t, proxy, addr); got != tc.want {
+					t.Errorf("got: %v; want: %v", got, tc.want)
+				}
+			}
+
+			if got := atomic.LoadInt32(requests); got != tc.requests {
+				t.Errorf("got: %d requests; want: %d", got, tc.requests)
+			}
+		})
+	}
+}
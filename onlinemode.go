@@ -0,0 +1,255 @@
+package infrared
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+const (
+	defaultSessionServer      = "https://sessionserver.mojang.com/session/minecraft/hasJoined"
+	defaultOnlineModeTimeout  = 5 * time.Second
+	defaultOnlineModeMessage  = "Failed to verify username!"
+	onlineModeKeyBits         = 1024
+	onlineModeVerifyTokenSize = 4
+)
+
+var errNotEncryptable = errors.New("connection can't be encrypted")
+
+// gameProfile is the profile that a player joins the server with. Only
+// profiles that were verified by the session server have properties.
+type gameProfile struct {
+	ID         protocol.UUID
+	Name       string
+	Properties []gameProfileProperty
+}
+
+type gameProfileProperty struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// offlineProfile returns the profile that an offline mode server would give
+// the player
+func offlineProfile(username string) gameProfile {
+	return gameProfile{
+		ID:   offlinePlayerUUID(username),
+		Name: username,
+	}
+}
+
+// encrypter is a connection that can switch to the encryption of Minecraft
+type encrypter interface {
+	enableEncryption(sharedSecret []byte) error
+}
+
+func (proxy *Proxy) OnlineModeIntercept() OnlineModeInterceptConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.OnlineModeIntercept
+}
+
+func (cfg OnlineModeInterceptConfig) sessionServer() string {
+	if cfg.SessionServer == "" {
+		return defaultSessionServer
+	}
+	return cfg.SessionServer
+}
+
+func (cfg OnlineModeInterceptConfig) timeout() time.Duration {
+	if cfg.Timeout <= 0 {
+		return defaultOnlineModeTimeout
+	}
+	return time.Millisecond * time.Duration(cfg.Timeout)
+}
+
+func (cfg OnlineModeInterceptConfig) message() string {
+	if cfg.Message == "" {
+		return defaultOnlineModeMessage
+	}
+	return cfg.Message
+}
+
+// hasJoined asks the session server whether the player authenticated for
+// the server hash and returns its profile. It reports false if the player
+// did not.
+func (cfg OnlineModeInterceptConfig) hasJoined(username, serverHash string) (gameProfile, bool, error) {
+	query := url.Values{}
+	query.Set("username", username)
+	query.Set("serverId", serverHash)
+
+	client := http.Client{Timeout: cfg.timeout()}
+	response, err := client.Get(cfg.sessionServer() + "?" + query.Encode())
+	if err != nil {
+		return gameProfile{}, false, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNoContent:
+		return gameProfile{}, false, nil
+	default:
+		return gameProfile{}, false, fmt.Errorf("session server answered with %s", response.Status)
+	}
+
+	var body struct {
+		ID         string                `json:"id"`
+		Name       string                `json:"name"`
+		Properties []gameProfileProperty `json:"properties"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return gameProfile{}, false, err
+	}
+
+	id, err := uuid.FromString(body.ID)
+	if err != nil {
+		return gameProfile{}, false, err
+	}
+
+	return gameProfile{
+		ID:         protocol.UUID(id),
+		Name:       body.Name,
+		Properties: body.Properties,
+	}, true, nil
+}
+
+// onlineModeKey returns the key pair of the proxy that clients encrypt the
+// shared secret with and the public key in its DER encoding
+func (proxy *Proxy) onlineModeKey() (*rsa.PrivateKey, []byte, error) {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.onlineModePrivateKey != nil {
+		return proxy.onlineModePrivateKey, proxy.onlineModePublicKey, nil
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, onlineModeKeyBits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	proxy.onlineModePrivateKey = key
+	proxy.onlineModePublicKey = publicKey
+	return key, publicKey, nil
+}
+
+// minecraftHash returns the server hash that clients and the session server
+// agree on. It is the SHA-1 of its parts as a signed hexadecimal number.
+func minecraftHash(serverID string, sharedSecret, publicKey []byte) string {
+	h := sha1.New()
+	h.Write([]byte(serverID))
+	h.Write(sharedSecret)
+	h.Write(publicKey)
+	sum := h.Sum(nil)
+
+	n := new(big.Int).SetBytes(sum)
+	if sum[0]&0x80 != 0 {
+		// Two's complement
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(sum)*8)))
+	}
+	return n.Text(16)
+}
+
+// authenticate completes the encryption with the client and verifies the
+// player with the session server like an online mode server does, if the
+// proxy intercepts the online mode. The connection stays encrypted, so the
+// server has to be in offline mode. The profile of players that were not
+// authenticated is their offline profile. It reports whether the client
+// was rejected.
+func (proxy *Proxy) authenticate(conn Conn, hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr, loginStart login.ServerLoginStart) (gameProfile, bool, error) {
+	username := string(loginStart.Name)
+	cfg := proxy.OnlineModeIntercept()
+	if !cfg.IsEnabled() || !hs.IsLoginRequest() {
+		return offlineProfile(username), false, nil
+	}
+
+	enc, ok := conn.(encrypter)
+	if !ok {
+		return gameProfile{}, true, errNotEncryptable
+	}
+
+	key, publicKey, err := proxy.onlineModeKey()
+	if err != nil {
+		return gameProfile{}, true, err
+	}
+
+	verifyToken := make([]byte, onlineModeVerifyTokenSize)
+	if _, err := rand.Read(verifyToken); err != nil {
+		return gameProfile{}, true, err
+	}
+
+	request := login.ClientBoundEncryptionRequest{
+		PublicKey:   publicKey,
+		VerifyToken: verifyToken,
+	}
+	if err := conn.WritePacket(request.Marshal(hs.ProtocolVersion)); err != nil {
+		return gameProfile{}, true, err
+	}
+
+	pk, err := conn.ReadPacket()
+	if err != nil {
+		return gameProfile{}, true, err
+	}
+
+	response, err := login.UnmarshalServerBoundEncryptionResponse(pk, hs.ProtocolVersion)
+	if err != nil {
+		return gameProfile{}, true, err
+	}
+
+	sharedSecret, err := rsa.DecryptPKCS1v15(rand.Reader, key, response.SharedSecret)
+	if err != nil {
+		return gameProfile{}, true, err
+	}
+
+	// The client encrypts everything after its response, so that the
+	// disconnect messages have to be encrypted as well
+	if err := enc.enableEncryption(sharedSecret); err != nil {
+		return gameProfile{}, true, err
+	}
+
+	// Clients from 1.19 up to 1.19.2 may sign a salt instead of sending
+	// the verify token. The session server still authenticates them.
+	if response.HasVerifyToken {
+		token, err := rsa.DecryptPKCS1v15(rand.Reader, key, response.VerifyToken)
+		if err != nil || subtle.ConstantTimeCompare(token, verifyToken) != 1 {
+			logWith(connFields("reject", connRemoteAddr, proxy.UID()), "[i] Rejecting %s with an invalid verify token on %s", connRemoteAddr, proxy.UID())
+			return gameProfile{}, true, conn.WritePacket(disconnectPacket(cfg.message()))
+		}
+	}
+
+	profile, joined, err := cfg.hasJoined(username, minecraftHash("", sharedSecret, publicKey))
+	if err != nil {
+		if err := conn.WritePacket(disconnectPacket(cfg.message())); err != nil {
+			return gameProfile{}, true, err
+		}
+		return gameProfile{}, true, fmt.Errorf("session server failed: %w", err)
+	}
+
+	if !joined {
+		logWith(connFields("reject", connRemoteAddr, proxy.UID()), "[i] Rejecting %s that did not authenticate as %s on %s", connRemoteAddr, username, proxy.UID())
+		return gameProfile{}, true, conn.WritePacket(disconnectPacket(cfg.message()))
+	}
+
+	return profile, false, nil
+}
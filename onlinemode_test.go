@@ -0,0 +1,229 @@
+package infrared
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+func TestMinecraftHash(t *testing.T) {
+	tt := []struct {
+		name string
+		want string
+	}{
+		{
+			name: "Notch",
+			want: "4ed1f46bbe04bc756bcb17c0c7ce3e4632f06a48",
+		},
+		{
+			name: "jeb_",
+			want: "-7c9d5b0044c130109a5d7b5fb5c317c02b4e28c1",
+		},
+		{
+			name: "simon",
+			want: "88e16a1019277b15d58faf0541e11910eb756f6",
+		},
+	}
+
+	for _, tc := range tt {
+		if got := minecraftHash(tc.name, nil, nil); got != tc.want {
+			t.Errorf("%s: got: %v; want: %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+// onlineModeClient logs in like a client of an online mode server. It
+// answers the encryption request with the verify token, encrypts the
+// connection and returns the server hash it authenticated with.
+func onlineModeClient(c net.Conn, sharedSecret []byte, forgeToken bool) (*conn, string, error) {
+	client := wrapConn(c)
+	pk, err := client.ReadPacket()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var serverID protocol.String
+	var publicKey, verifyToken protocol.ByteArray
+	if err := pk.Scan(&serverID, &publicKey, &verifyToken); err != nil {
+		return nil, "", err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if forgeToken {
+		verifyToken = []byte{0, 0, 0, 0}
+	}
+
+	encryptedSecret, err := rsa.EncryptPKCS1v15(rand.Reader, key.(*rsa.PublicKey), sharedSecret)
+	if err != nil {
+		return nil, "", err
+	}
+	encryptedToken, err := rsa.EncryptPKCS1v15(rand.Reader, key.(*rsa.PublicKey), verifyToken)
+	if err != nil {
+		return nil, "", err
+	}
+
+	response := protocol.MarshalPacket(login.ServerBoundEncryptionResponsePacketID, protocol.ByteArray(encryptedSecret), protocol.ByteArray(encryptedToken))
+	if err := client.WritePacket(response); err != nil {
+		return nil, "", err
+	}
+
+	if err := client.enableEncryption(sharedSecret); err != nil {
+		return nil, "", err
+	}
+	return client, minecraftHash(string(serverID), sharedSecret, publicKey), nil
+}
+
+func TestProxy_Authenticate(t *testing.T) {
+	steveID := uuid.Must(uuid.FromString("069a79f444e94726a5befca90e38aaf5"))
+	serverHashes := make(chan string, 1)
+	sessionServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		serverHashes <- r.URL.Query().Get("serverId")
+		switch r.URL.Query().Get("username") {
+		case "Steve":
+			_, _ = w.Write([]byte(`{"id":"069a79f444e94726a5befca90e38aaf5","name":"Steve","properties":[{"name":"textures","value":"e30=","signature":"c2ln"}]}`))
+		case "Herobrine":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer sessionServer.Close()
+
+	tt := []struct {
+		name       string
+		username   string
+		forgeToken bool
+		rejected   bool
+		err        bool
+		profile    gameProfile
+	}{
+		{
+			name:     "Authenticated",
+			username: "Steve",
+			profile: gameProfile{
+				ID:         protocol.UUID(steveID),
+				Name:       "Steve",
+				Properties: []gameProfileProperty{{Name: "textures", Value: "e30=", Signature: "c2ln"}},
+			},
+		},
+		{
+			name:     "NotJoined",
+			username: "Alex",
+			rejected: true,
+		},
+		{
+			name:       "InvalidVerifyToken",
+			username:   "Steve",
+			forgeToken: true,
+			rejected:   true,
+		},
+		{
+			name:     "SessionServerFailed",
+			username: "Herobrine",
+			rejected: true,
+			err:      true,
+		},
+	}
+
+	proxy := &Proxy{
+		Config: &ProxyConfig{
+			DomainName: "mc.example.com",
+			OnlineModeIntercept: OnlineModeInterceptConfig{
+				Enabled:       true,
+				SessionServer: sessionServer.URL,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c1, c2 := net.Pipe()
+			defer c1.Close()
+			defer c2.Close()
+
+			sharedSecret := []byte("0123456789abcdef")
+			hashes := make(chan string, 1)
+			disconnects := make(chan protocol.Packet, 1)
+			go func() {
+				client, hash, err := onlineModeClient(c1, sharedSecret, tc.forgeToken)
+				if err != nil {
+					return
+				}
+				hashes <- hash
+
+				pk, err := client.ReadPacket()
+				if err == nil {
+					disconnects <- pk
+				}
+			}()
+
+			hs := handshaking.ServerBoundHandshake{
+				ProtocolVersion: 763,
+				NextState:       handshaking.ServerBoundHandshakeLoginState,
+			}
+			addr := &net.TCPAddr{IP: net.IPv4(203, 0, 113, 1), Port: 50000}
+			loginStart := login.ServerLoginStart{Name: protocol.String(tc.username)}
+			profile, rejected, err := proxy.authenticate(wrapConn(c2), hs, addr, loginStart)
+			if rejected != tc.rejected {
+				t.Errorf("got: %v; want: %v", rejected, tc.rejected)
+			}
+			if (err != nil) != tc.err {
+				t.Errorf("got: %v; want error: %v", err, tc.err)
+			}
+
+			hash := <-hashes
+			if !tc.forgeToken {
+				if serverHash := <-serverHashes; serverHash != hash {
+					t.Errorf("got: %v; want: %v", serverHash, hash)
+				}
+			}
+
+			if tc.rejected {
+				pk := <-disconnects
+				want := disconnectPacket(defaultOnlineModeMessage)
+				if pk.ID != want.ID || !bytes.Equal(pk.Data, want.Data) {
+					t.Errorf("got: %v; want: %v", pk, want)
+				}
+				return
+			}
+
+			if profile.ID != tc.profile.ID || profile.Name != tc.profile.Name || len(profile.Properties) != 1 || profile.Properties[0] != tc.profile.Properties[0] {
+				t.Errorf("got: %v; want: %v", profile, tc.profile)
+			}
+		})
+	}
+}
+
+func TestProxy_Authenticate_Disabled(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{DomainName: "mc.example.com"}}
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	hs := handshaking.ServerBoundHandshake{NextState: handshaking.ServerBoundHandshakeLoginState}
+	addr := &net.TCPAddr{IP: net.IPv4(203, 0, 113, 1), Port: 50000}
+	profile, rejected, err := proxy.authenticate(wrapConn(c2), hs, addr, login.ServerLoginStart{Name: "Steve"})
+	if rejected || err != nil {
+		t.Fatalf("got: %v, %v; want: false, <nil>", rejected, err)
+	}
+
+	if want := offlineProfile("Steve"); profile.ID != want.ID || profile.Name != want.Name {
+		t.Errorf("got: %v; want: %v", profile, want)
+	}
+}
@@ -0,0 +1,235 @@
+package infrared
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+const (
+	defaultClosedMessage = "The server is closed. Come back {{opens}}."
+	defaultClosedMOTD    = "Closed until {{opens}}"
+	openHoursClockLayout = "15:04"
+	openHoursOpensLayout = "Mon 15:04 MST"
+	minutesPerDay        = 24 * 60
+)
+
+// locations caches the loaded time zones by their name
+var locations sync.Map
+
+func (proxy *Proxy) OpenHours() OpenHoursConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.OpenHours
+}
+
+// rejectByOpenHours disconnects logins outside of the open hours of the
+// proxy and answers status requests with the closed MOTD. It reports
+// whether the client was handled. An invalid schedule lets everyone in.
+func (proxy *Proxy) rejectByOpenHours(conn Conn, hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr) (bool, error) {
+	cfg := proxy.OpenHours()
+	if !cfg.IsEnabled() {
+		return false, nil
+	}
+
+	open, opens, err := cfg.openAt(time.Now())
+	if err != nil {
+		log.Printf("[w] Invalid open hours of %s; error: %s", proxy.UID(), err)
+		return false, nil
+	}
+	if open {
+		return false, nil
+	}
+
+	if hs.IsStatusRequest() {
+		pk, err := proxy.closedStatusPacket(withOpens(cfg.closedMOTD(), opens))
+		if err != nil {
+			return true, err
+		}
+		return true, writeStatus(conn, pk, proxy.StrictProtocol())
+	}
+
+	if !hs.IsLoginRequest() {
+		return false, nil
+	}
+
+	logWith(connFields("reject", connRemoteAddr, proxy.UID()), "[i] Rejecting %s on %s outside of its open hours", connRemoteAddr, proxy.UID())
+	return true, conn.WritePacket(disconnectPacket(withOpens(cfg.closedMessage(), opens)))
+}
+
+// closedStatusPacket returns the offline status with motd
+func (proxy *Proxy) closedStatusPacket(motd string) (protocol.Packet, error) {
+	proxy.Config.RLock()
+	cfg := proxy.Config.OfflineStatus
+	proxy.Config.RUnlock()
+
+	cfg.cachedPacket = nil
+	cfg.MOTD = motd
+	return cfg.StatusResponsePacket()
+}
+
+func withOpens(message string, opens time.Time) string {
+	value := "later"
+	if !opens.IsZero() {
+		value = opens.Format(openHoursOpensLayout)
+	}
+	return strings.Replace(message, "{{opens}}", value, -1)
+}
+
+func (cfg OpenHoursConfig) closedMessage() string {
+	if cfg.ClosedMessage == "" {
+		return defaultClosedMessage
+	}
+	return cfg.ClosedMessage
+}
+
+func (cfg OpenHoursConfig) closedMOTD() string {
+	if cfg.ClosedMOTD == "" {
+		return defaultClosedMOTD
+	}
+	return cfg.ClosedMOTD
+}
+
+func (cfg OpenHoursConfig) location() (*time.Location, error) {
+	if cfg.Timezone == "" {
+		return time.Local, nil
+	}
+
+	if loc, ok := locations.Load(cfg.Timezone); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(cfg.Timezone, loc)
+	return loc, nil
+}
+
+// openAt reports whether now is inside one of the windows. If it is not,
+// the start of the next window is returned as well.
+func (cfg OpenHoursConfig) openAt(now time.Time) (bool, time.Time, error) {
+	loc, err := cfg.location()
+	if err != nil {
+		return false, time.Time{}, err
+	}
+	now = now.In(loc)
+
+	windows := make([]openWindow, 0, len(cfg.Windows))
+	for _, windowCfg := range cfg.Windows {
+		window, err := parseOpenWindow(windowCfg)
+		if err != nil {
+			return false, time.Time{}, err
+		}
+		windows = append(windows, window)
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	today := now.Weekday()
+	yesterday := (today + 6) % 7
+	for _, window := range windows {
+		if window.contains(today, yesterday, minute) {
+			return true, time.Time{}, nil
+		}
+	}
+
+	var opens time.Time
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	for day := 0; day <= 7; day++ {
+		date := midnight.AddDate(0, 0, day)
+		for _, window := range windows {
+			if !window.days[date.Weekday()] {
+				continue
+			}
+
+			start := date.Add(time.Duration(window.from) * time.Minute)
+			if start.After(now) && (opens.IsZero() || start.Before(opens)) {
+				opens = start
+			}
+		}
+	}
+	return false, opens, nil
+}
+
+// openWindow is a parsed OpenWindowConfig. The times are minutes since
+// midnight. A window whose end is before its start ends on the next day.
+type openWindow struct {
+	days [7]bool
+	from int
+	to   int
+}
+
+func (window openWindow) contains(today, yesterday time.Weekday, minute int) bool {
+	switch {
+	case window.from == window.to:
+		return window.days[today]
+	case window.from < window.to:
+		return window.days[today] && minute >= window.from && minute < window.to
+	default:
+		return window.days[today] && minute >= window.from ||
+			window.days[yesterday] && minute < window.to
+	}
+}
+
+func parseOpenWindow(cfg OpenWindowConfig) (openWindow, error) {
+	var window openWindow
+
+	from, err := parseClock(cfg.From)
+	if err != nil {
+		return window, err
+	}
+	to, err := parseClock(cfg.To)
+	if err != nil {
+		return window, err
+	}
+	window.from = from
+	window.to = to
+
+	if len(cfg.Days) == 0 {
+		for i := range window.days {
+			window.days[i] = true
+		}
+		return window, nil
+	}
+
+	for _, name := range cfg.Days {
+		day, err := parseWeekday(name)
+		if err != nil {
+			return window, err
+		}
+		window.days[day] = true
+	}
+	return window, nil
+}
+
+// parseClock returns the minutes since midnight of a time like "18:30".
+// "24:00" is the end of the day.
+func parseClock(clock string) (int, error) {
+	if clock == "24:00" {
+		return minutesPerDay, nil
+	}
+
+	t, err := time.Parse(openHoursClockLayout, clock)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	name = strings.ToLower(name)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		full := strings.ToLower(day.String())
+		if name == full || name == full[:3] {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid day %q", name)
+}
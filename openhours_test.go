@@ -0,0 +1,84 @@
+package infrared
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOpenHoursConfig_OpenAt(t *testing.T) {
+	cfg := OpenHoursConfig{
+		Timezone: "UTC",
+		Windows: []OpenWindowConfig{
+			{Days: []string{"mon", "Wednesday"}, From: "18:00", To: "20:00"},
+			{Days: []string{"fri"}, From: "22:00", To: "02:00"},
+			{Days: []string{"sun"}, From: "10:00", To: "12:00"},
+			{Days: []string{"sun"}, From: "14:00", To: "24:00"},
+		},
+	}
+
+	// 2024-01-01 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tt := []struct {
+		now   time.Time
+		open  bool
+		opens time.Time
+	}{
+		{now: at(1, 18, 0), open: true},
+		{now: at(1, 19, 59), open: true},
+		{now: at(1, 20, 0), open: false, opens: at(3, 18, 0)},
+		{now: at(1, 9, 0), open: false, opens: at(1, 18, 0)},
+		{now: at(5, 23, 0), open: true},
+		{now: at(6, 1, 30), open: true},
+		{now: at(6, 2, 0), open: false, opens: at(7, 10, 0)},
+		{now: at(7, 12, 30), open: false, opens: at(7, 14, 0)},
+		{now: at(7, 23, 59), open: true},
+		{now: at(8, 0, 0), open: false, opens: at(8, 18, 0)},
+	}
+
+	for _, tc := range tt {
+		open, opens, err := cfg.openAt(tc.now)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if open != tc.open {
+			t.Errorf("%s: got: %v; want: %v", tc.now, open, tc.open)
+		}
+		if !opens.Equal(tc.opens) {
+			t.Errorf("%s: got: %v; want: %v", tc.now, opens, tc.opens)
+		}
+	}
+}
+
+func TestOpenHoursConfig_OpenAt_Timezone(t *testing.T) {
+	cfg := OpenHoursConfig{
+		Timezone: "Europe/Berlin",
+		Windows:  []OpenWindowConfig{{From: "18:00", To: "20:00"}},
+	}
+
+	// 17:30 UTC is 18:30 in Berlin in the winter
+	open, _, err := cfg.openAt(time.Date(2024, 1, 1, 17, 30, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !open {
+		t.Error("got: false; want: true")
+	}
+}
+
+func TestOpenHoursConfig_OpenAt_Invalid(t *testing.T) {
+	tt := []OpenHoursConfig{
+		{Timezone: "Mars/Olympus_Mons", Windows: []OpenWindowConfig{{From: "18:00", To: "20:00"}}},
+		{Windows: []OpenWindowConfig{{From: "6pm", To: "20:00"}}},
+		{Windows: []OpenWindowConfig{{Days: []string{"someday"}, From: "18:00", To: "20:00"}}},
+	}
+
+	for _, cfg := range tt {
+		if _, _, err := cfg.openAt(time.Now()); err == nil {
+			t.Errorf("got: nil; want: error for %v", cfg)
+		}
+	}
+}
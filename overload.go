@@ -0,0 +1,46 @@
+package infrared
+
+import (
+	"log"
+	"runtime"
+	"sync/atomic"
+)
+
+// overloadRecovery is the share of the limits that the gateway has to get
+// below to recover from an overload, so that it does not flap at a limit
+const overloadRecovery = 0.9
+
+// isOverloaded reports whether new connections should be refused, because
+// the gateway is above one of its overload limits. Once overloaded, it stays
+// overloaded until it got below the recovery share of all limits.
+func (gateway *Gateway) isOverloaded() bool {
+	if gateway.OverloadMaxGoroutines <= 0 && gateway.OverloadMaxConnections <= 0 {
+		return false
+	}
+
+	goroutines := runtime.NumGoroutine()
+	conns := int(atomic.LoadInt64(&gateway.activeConns))
+	if atomic.LoadInt32(&gateway.overloaded) == 0 {
+		if !exceeds(goroutines, gateway.OverloadMaxGoroutines, 1) && !exceeds(conns, gateway.OverloadMaxConnections, 1) {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&gateway.overloaded, 0, 1) {
+			log.Printf("[w] Overloaded with %d goroutines and %d connections; refusing new connections", goroutines, conns)
+		}
+		return true
+	}
+
+	if exceeds(goroutines, gateway.OverloadMaxGoroutines, overloadRecovery) || exceeds(conns, gateway.OverloadMaxConnections, overloadRecovery) {
+		return true
+	}
+	if atomic.CompareAndSwapInt32(&gateway.overloaded, 1, 0) {
+		log.Printf("[i] Recovered from overload with %d goroutines and %d connections", goroutines, conns)
+	}
+	return false
+}
+
+// exceeds reports whether value reached the share of limit.
+// A limit of zero is never reached.
+func exceeds(value, limit int, share float64) bool {
+	return limit > 0 && float64(value) >= float64(limit)*share
+}
@@ -0,0 +1,42 @@
+package infrared
+
+import (
+	"sync/atomic"
+	"testing"
+)
+
+func TestGateway_IsOverloaded(t *testing.T) {
+	gateway := &Gateway{OverloadMaxConnections: 10}
+
+	tt := []struct {
+		conns int64
+		want  bool
+	}{
+		{conns: 5, want: false},
+		{conns: 10, want: true},
+		// Still overloaded until it gets below 90% of the limit
+		{conns: 9, want: true},
+		{conns: 8, want: false},
+		{conns: 9, want: false},
+	}
+
+	for _, tc := range tt {
+		atomic.StoreInt64(&gateway.activeConns, tc.conns)
+		if got := gateway.isOverloaded(); got != tc.want {
+			t.Errorf("%d connections: got: %v; want: %v", tc.conns, got, tc.want)
+		}
+	}
+}
+
+func TestGateway_IsOverloaded_Goroutines(t *testing.T) {
+	gateway := &Gateway{OverloadMaxGoroutines: 1}
+	if !gateway.isOverloaded() {
+		t.Error("got: false; want: true")
+	}
+
+	gateway = &Gateway{}
+	atomic.StoreInt64(&gateway.activeConns, 1000000)
+	if gateway.isOverloaded() {
+		t.Error("got: true; want: false")
+	}
+}
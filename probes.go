@@ -0,0 +1,58 @@
+package infrared
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// DefaultHealthPath is the path of the liveness probe if none is set
+	DefaultHealthPath = "/healthz"
+	// DefaultReadyPath is the path of the readiness probe if none is set
+	DefaultReadyPath = "/readyz"
+)
+
+// isReady reports whether all proxies of ListenAndServe are registered and
+// the gateway does not shut down
+func (gateway *Gateway) isReady() bool {
+	return atomic.LoadInt32(&gateway.ready) == 1 && atomic.LoadInt32(&gateway.shuttingDown) == 0
+}
+
+// metricsHandler returns the handler of the Prometheus endpoint. Besides
+// /metrics it serves the liveness probe on HealthPath, which always
+// answers with 200, and the readiness probe on ReadyPath, which answers
+// with 503 until all proxies are registered and again once the gateway
+// shuts down. Every call returns a new mux, so that nothing is registered
+// twice on the default mux.
+func (gateway *Gateway) metricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	if gateway.HealthPath != "-" {
+		path := gateway.HealthPath
+		if path == "" {
+			path = DefaultHealthPath
+		}
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("ok"))
+		})
+	}
+
+	if gateway.ReadyPath != "-" {
+		path := gateway.ReadyPath
+		if path == "" {
+			path = DefaultReadyPath
+		}
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if !gateway.isReady() {
+				http.Error(w, "not ready", http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte("ok"))
+		})
+	}
+
+	return mux
+}
@@ -0,0 +1,82 @@
+package infrared
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGateway_MetricsHandler(t *testing.T) {
+	tt := []struct {
+		name         string
+		gateway      *Gateway
+		ready        bool
+		shuttingDown bool
+		path         string
+		statusCode   int
+	}{
+		{
+			name:       "Metrics",
+			gateway:    &Gateway{},
+			path:       "/metrics",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "Healthy",
+			gateway:    &Gateway{},
+			path:       DefaultHealthPath,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "NotReady",
+			gateway:    &Gateway{},
+			path:       DefaultReadyPath,
+			statusCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "Ready",
+			gateway:    &Gateway{},
+			ready:      true,
+			path:       DefaultReadyPath,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:         "ShuttingDown",
+			gateway:      &Gateway{},
+			ready:        true,
+			shuttingDown: true,
+			path:         DefaultReadyPath,
+			statusCode:   http.StatusServiceUnavailable,
+		},
+		{
+			name:       "HealthPath",
+			gateway:    &Gateway{HealthPath: "/live"},
+			path:       "/live",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "DisabledReadyPath",
+			gateway:    &Gateway{ReadyPath: "-"},
+			ready:      true,
+			path:       DefaultReadyPath,
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.ready {
+				tc.gateway.ready = 1
+			}
+			if tc.shuttingDown {
+				tc.gateway.shuttingDown = 1
+			}
+
+			w := httptest.NewRecorder()
+			tc.gateway.metricsHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, tc.path, nil))
+			if w.Code != tc.statusCode {
+				t.Errorf("got: %d; want: %d", w.Code, tc.statusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package infrared
+
+import "log"
+
+func (gateway *Gateway) processCommandSlots() chan struct{} {
+	gateway.processSlotsOnce.Do(func() {
+		if gateway.ProcessConcurrency > 0 {
+			gateway.processSlots = make(chan struct{}, gateway.ProcessConcurrency)
+		}
+	})
+	return gateway.processSlots
+}
+
+// runProcessCommand runs command, the start or stop of the process of a
+// proxy. At most ProcessConcurrency commands run at the same time; the
+// others are queued until a running one is done.
+func (proxy *Proxy) runProcessCommand(command string, fn func() error) error {
+	if proxy.gateway == nil {
+		return fn()
+	}
+
+	slots := proxy.gateway.processCommandSlots()
+	if slots == nil {
+		return fn()
+	}
+
+	select {
+	case slots <- struct{}{}:
+	default:
+		log.Printf("[i] Queued %s of container for %s; %d commands are running", command, proxy.UID(), cap(slots))
+		slots <- struct{}{}
+	}
+	defer func() { <-slots }()
+
+	return fn()
+}
+
+// beginProcessStart reports whether the process of the proxy should be
+// started, so that a mass reconnect starts it only once. endProcessStart
+// has to be called after the start.
+func (proxy *Proxy) beginProcessStart() bool {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.processStarting {
+		return false
+	}
+	proxy.processStarting = true
+	return true
+}
+
+func (proxy *Proxy) endProcessStart() {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	proxy.processStarting = false
+}
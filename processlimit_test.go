@@ -0,0 +1,62 @@
+package infrared
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProxy_RunProcessCommand_LimitsConcurrency(t *testing.T) {
+	gateway := &Gateway{ProcessConcurrency: 2}
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	command := func() error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		proxy := &Proxy{Config: &ProxyConfig{}, gateway: gateway}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := proxy.runProcessCommand("start", command); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if maxRunning != 2 {
+		t.Errorf("got: %d; want: %d", maxRunning, 2)
+	}
+}
+
+func TestProxy_BeginProcessStart(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{}}
+
+	if !proxy.beginProcessStart() {
+		t.Error("got: false; want: true")
+	}
+	if proxy.beginProcessStart() {
+		t.Error("got: true; want: false")
+	}
+
+	proxy.endProcessStart()
+	if !proxy.beginProcessStart() {
+		t.Error("got: false; want: true")
+	}
+}
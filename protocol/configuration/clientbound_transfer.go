@@ -0,0 +1,27 @@
+package configuration
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+const (
+	ClientBoundTransferPacketID byte = 0x0B
+
+	// TransferProtocolVersion is the first protocol version (1.20.5)
+	// whose clients can be transferred to another server
+	TransferProtocolVersion protocol.VarInt = 766
+)
+
+// ClientBoundTransfer tells the client to connect to another server
+type ClientBoundTransfer struct {
+	Host protocol.String
+	Port protocol.VarInt
+}
+
+func (pk ClientBoundTransfer) Marshal() protocol.Packet {
+	return protocol.MarshalPacket(
+		ClientBoundTransferPacketID,
+		pk.Host,
+		pk.Port,
+	)
+}
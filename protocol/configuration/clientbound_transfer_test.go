@@ -0,0 +1,37 @@
+package configuration
+
+import (
+	"bytes"
+	"github.com/haveachin/infrared/protocol"
+	"testing"
+)
+
+func TestClientBoundTransfer_Marshal(t *testing.T) {
+	tt := []struct {
+		packet          ClientBoundTransfer
+		marshaledPacket protocol.Packet
+	}{
+		{
+			packet: ClientBoundTransfer{
+				Host: protocol.String("example.com"),
+				Port: 25565,
+			},
+			marshaledPacket: protocol.Packet{
+				ID:   0x0B,
+				Data: []byte{0x0B, 0x65, 0x78, 0x61, 0x6D, 0x70, 0x6C, 0x65, 0x2E, 0x63, 0x6F, 0x6D, 0xDD, 0xC7, 0x01},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		pk := tc.packet.Marshal()
+
+		if pk.ID != ClientBoundTransferPacketID {
+			t.Error("invalid packet id")
+		}
+
+		if !bytes.Equal(pk.Data, tc.marshaledPacket.Data) {
+			t.Errorf("got: %v, want: %v", pk.Data, tc.marshaledPacket.Data)
+		}
+	}
+}
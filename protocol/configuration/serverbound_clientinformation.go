@@ -0,0 +1,35 @@
+package configuration
+
+import (
+	"bytes"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+const (
+	ServerBoundClientInformationPacketID byte = 0x00
+
+	// ProtocolVersion is the first protocol version (1.20.2) that has
+	// a configuration state between login and play
+	ProtocolVersion protocol.VarInt = 764
+)
+
+// ServerBoundClientInformation holds the settings of the client.
+// Only the locale is decoded, the other settings are ignored.
+type ServerBoundClientInformation struct {
+	Locale protocol.String
+}
+
+func UnmarshalServerBoundClientInformation(packet protocol.Packet) (ServerBoundClientInformation, error) {
+	var pk ServerBoundClientInformation
+
+	if packet.ID != ServerBoundClientInformationPacketID {
+		return pk, protocol.ErrInvalidPacketID
+	}
+
+	if err := protocol.ScanFields(bytes.NewReader(packet.Data), &pk.Locale); err != nil {
+		return pk, err
+	}
+
+	return pk, nil
+}
@@ -0,0 +1,49 @@
+package configuration
+
+import (
+	"github.com/haveachin/infrared/protocol"
+	"testing"
+)
+
+func TestUnmarshalServerBoundClientInformation(t *testing.T) {
+	tt := []struct {
+		packet  protocol.Packet
+		locale  string
+		isValid bool
+	}{
+		{
+			packet: protocol.Packet{
+				ID:   0x00,
+				Data: []byte{0x05, 0x65, 0x6E, 0x5F, 0x75, 0x73, 0x0C, 0x00, 0x01, 0x7F, 0x01},
+			},
+			locale:  "en_us",
+			isValid: true,
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x01,
+				Data: []byte{0x05, 0x65, 0x6E, 0x5F, 0x75, 0x73},
+			},
+			isValid: false,
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x00,
+				Data: []byte{0x05, 0x65},
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range tt {
+		pk, err := UnmarshalServerBoundClientInformation(tc.packet)
+		if (err == nil) != tc.isValid {
+			t.Errorf("got: %v, want valid: %v", err, tc.isValid)
+			continue
+		}
+
+		if string(pk.Locale) != tc.locale {
+			t.Errorf("got: %v, want: %v", pk.Locale, tc.locale)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package configuration
+
+import (
+	"bytes"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+// BrandChannel is the plugin channel that clients announce their brand on
+const BrandChannel = "minecraft:brand"
+
+// ServerBoundPluginMessagePacketID returns the ID of the plugin message,
+// which moved with the cookie response in 1.20.5
+func ServerBoundPluginMessagePacketID(protocolVersion protocol.VarInt) byte {
+	if protocolVersion >= TransferProtocolVersion {
+		return 0x02
+	}
+	return 0x01
+}
+
+// ServerBoundAcknowledgeFinishConfigurationPacketID returns the ID of the
+// packet that switches the client into the play state
+func ServerBoundAcknowledgeFinishConfigurationPacketID(protocolVersion protocol.VarInt) byte {
+	if protocolVersion >= TransferProtocolVersion {
+		return 0x03
+	}
+	return 0x02
+}
+
+type ServerBoundPluginMessage struct {
+	Channel protocol.String
+	Data    []byte
+}
+
+func UnmarshalServerBoundPluginMessage(packet protocol.Packet, protocolVersion protocol.VarInt) (ServerBoundPluginMessage, error) {
+	var pk ServerBoundPluginMessage
+
+	if packet.ID != ServerBoundPluginMessagePacketID(protocolVersion) {
+		return pk, protocol.ErrInvalidPacketID
+	}
+
+	r := bytes.NewReader(packet.Data)
+	if err := protocol.ScanFields(r, &pk.Channel); err != nil {
+		return pk, err
+	}
+
+	pk.Data = packet.Data[len(packet.Data)-r.Len():]
+	return pk, nil
+}
+
+// Brand decodes the data of a message on the BrandChannel
+func (pk ServerBoundPluginMessage) Brand() (string, error) {
+	var brand protocol.String
+	if err := brand.Decode(bytes.NewReader(pk.Data)); err != nil {
+		return "", err
+	}
+	return string(brand), nil
+}
@@ -0,0 +1,67 @@
+package configuration
+
+import (
+	"github.com/haveachin/infrared/protocol"
+	"testing"
+)
+
+func TestUnmarshalServerBoundPluginMessage(t *testing.T) {
+	brandMessage := append(protocol.String(BrandChannel).Encode(), protocol.String("vanilla").Encode()...)
+
+	tt := []struct {
+		packet          protocol.Packet
+		protocolVersion protocol.VarInt
+		brand           string
+		isValid         bool
+	}{
+		{
+			packet: protocol.Packet{
+				ID:   0x02,
+				Data: brandMessage,
+			},
+			protocolVersion: 767,
+			brand:           "vanilla",
+			isValid:         true,
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x01,
+				Data: brandMessage,
+			},
+			protocolVersion: 764,
+			brand:           "vanilla",
+			isValid:         true,
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x01,
+				Data: brandMessage,
+			},
+			protocolVersion: 767,
+			isValid:         false,
+		},
+	}
+
+	for _, tc := range tt {
+		pk, err := UnmarshalServerBoundPluginMessage(tc.packet, tc.protocolVersion)
+		if (err == nil) != tc.isValid {
+			t.Errorf("got: %v, want valid: %v", err, tc.isValid)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if pk.Channel != BrandChannel {
+			t.Errorf("got: %v, want: %v", pk.Channel, BrandChannel)
+		}
+
+		brand, err := pk.Brand()
+		if err != nil {
+			t.Error(err)
+		}
+		if brand != tc.brand {
+			t.Errorf("got: %v, want: %v", brand, tc.brand)
+		}
+	}
+}
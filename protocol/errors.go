@@ -5,5 +5,7 @@ import (
 )
 
 var (
-	ErrInvalidPacketID = errors.New("invalid packet id")
+	ErrInvalidPacketID     = errors.New("invalid packet id")
+	ErrInvalidStringLength = errors.New("invalid string length")
+	ErrPacketTooLarge      = errors.New("packet too large")
 )
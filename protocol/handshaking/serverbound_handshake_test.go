@@ -87,6 +87,22 @@ func TestUnmarshalServerBoundHandshake(t *testing.T) {
 		},
 	}
 
+	for _, addr := range []string{strings.Repeat("a", 255), strings.Repeat("a", 32767)} {
+		hs := ServerBoundHandshake{
+			ProtocolVersion: 758,
+			ServerAddress:   protocol.String(addr),
+			ServerPort:      25565,
+			NextState:       ServerBoundHandshakeLoginState,
+		}
+		tt = append(tt, struct {
+			packet             protocol.Packet
+			unmarshalledPacket ServerBoundHandshake
+		}{
+			packet:             hs.Marshal(),
+			unmarshalledPacket: hs,
+		})
+	}
+
 	for _, tc := range tt {
 		actual, err := UnmarshalServerBoundHandshake(tc.packet)
 		if err != nil {
@@ -147,6 +163,12 @@ func TestServerBoundHandshake_IsLoginRequest(t *testing.T) {
 			},
 			result: true,
 		},
+		{
+			handshake: ServerBoundHandshake{
+				NextState: ServerBoundHandshakeTransferState,
+			},
+			result: true,
+		},
 	}
 
 	for _, tc := range tt {
@@ -271,6 +293,15 @@ func TestServerBoundHandshake_ParseServerAddress(t *testing.T) {
 			addr:         "example.com:1234" + ForgeSeparator + "some data" + RealIPSeparator + "more",
 			expectedAddr: "example.com:1234",
 		},
+		{
+			addr:         strings.Repeat("a", 255),
+			expectedAddr: strings.Repeat("a", 255),
+		},
+		{
+			// Forge marker and BungeeCord forwarding data
+			addr:         "example.com" + ForgeSeparator + "FML2" + ForgeSeparator + "127.0.0.1" + ForgeSeparator + strings.Repeat("a", 32) + ForgeSeparator + "[" + strings.Repeat("{}", 4000) + "]",
+			expectedAddr: "example.com",
+		},
 	}
 
 	for _, tc := range tt {
@@ -281,6 +312,69 @@ func TestServerBoundHandshake_ParseServerAddress(t *testing.T) {
 	}
 }
 
+// forgeHandshakes are handshakes of Forge clients for mc.example.com:25565
+// as they are sent on the wire, including their length
+var forgeHandshakes = []struct {
+	name   string
+	raw    []byte
+	marker string
+}{
+	{
+		name: "Forge1.12.2",
+		raw: append(append([]byte{0x1A, 0x00, 0xD4, 0x02, 0x13}, "mc.example.com"...),
+			0x00, 'F', 'M', 'L', 0x00, 0x63, 0xDD, 0x02),
+		marker: "FML",
+	},
+	{
+		name: "Forge1.16.5",
+		raw: append(append([]byte{0x1B, 0x00, 0xF2, 0x05, 0x14}, "mc.example.com"...),
+			0x00, 'F', 'M', 'L', '2', 0x00, 0x63, 0xDD, 0x02),
+		marker: "FML2",
+	},
+	{
+		name: "Forge1.18.2",
+		raw: append(append([]byte{0x1B, 0x00, 0xF6, 0x05, 0x14}, "mc.example.com"...),
+			0x00, 'F', 'M', 'L', '3', 0x00, 0x63, 0xDD, 0x01),
+		marker: "FML3",
+	},
+}
+
+func TestUnmarshalServerBoundHandshake_Forge(t *testing.T) {
+	for _, tc := range forgeHandshakes {
+		t.Run(tc.name, func(t *testing.T) {
+			pk, err := protocol.ReadPacket(bytes.NewReader(tc.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			hs, err := UnmarshalServerBoundHandshake(pk)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := hs.ParseServerAddress(); got != "mc.example.com" {
+				t.Errorf("got: %q; want: %q", got, "mc.example.com")
+			}
+			if !hs.IsForgeAddress() {
+				t.Error("got: no Forge address; want: Forge address")
+			}
+			if want := "mc.example.com\x00" + tc.marker + "\x00"; string(hs.ServerAddress) != want {
+				t.Errorf("got: %q; want: %q", hs.ServerAddress, want)
+			}
+
+			// The server gets the handshake as the client sent it
+			marshaled := hs.Marshal()
+			got, err := marshaled.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tc.raw) {
+				t.Errorf("got: %v; want: %v", got, tc.raw)
+			}
+		})
+	}
+}
+
 func TestServerBoundHandshake_UpgradeToRealIP(t *testing.T) {
 	tt := []struct {
 		addr       string
@@ -0,0 +1,31 @@
+package login
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+const ClientBoundEncryptionRequestPacketID byte = 0x01
+
+// ClientBoundEncryptionRequest asks the client to authenticate with the
+// session server and to encrypt the connection with a shared secret
+type ClientBoundEncryptionRequest struct {
+	ServerID    protocol.String
+	PublicKey   protocol.ByteArray
+	VerifyToken protocol.ByteArray
+}
+
+// Marshal encodes the packet for clients with the given protocol version.
+// Clients since 1.20.5 are additionally told to authenticate.
+func (pk ClientBoundEncryptionRequest) Marshal(protocolVersion protocol.VarInt) protocol.Packet {
+	fields := []protocol.FieldEncoder{
+		pk.ServerID,
+		pk.PublicKey,
+		pk.VerifyToken,
+	}
+
+	if protocolVersion >= 766 {
+		fields = append(fields, protocol.Boolean(true)) // Should authenticate
+	}
+
+	return protocol.MarshalPacket(ClientBoundEncryptionRequestPacketID, fields...)
+}
@@ -0,0 +1,48 @@
+package login
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestClientBoundEncryptionRequest_Marshal(t *testing.T) {
+	packet := ClientBoundEncryptionRequest{
+		ServerID:    protocol.String(""),
+		PublicKey:   protocol.ByteArray{0x30, 0x81},
+		VerifyToken: protocol.ByteArray{0x01, 0x02, 0x03, 0x04},
+	}
+
+	tt := []struct {
+		protocolVersion protocol.VarInt
+		marshaledPacket protocol.Packet
+	}{
+		{
+			protocolVersion: 765,
+			marshaledPacket: protocol.Packet{
+				ID:   0x01,
+				Data: []byte{0x00, 0x02, 0x30, 0x81, 0x04, 0x01, 0x02, 0x03, 0x04},
+			},
+		},
+		{
+			protocolVersion: 766,
+			marshaledPacket: protocol.Packet{
+				ID:   0x01,
+				Data: []byte{0x00, 0x02, 0x30, 0x81, 0x04, 0x01, 0x02, 0x03, 0x04, 0x01},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		pk := packet.Marshal(tc.protocolVersion)
+
+		if pk.ID != ClientBoundEncryptionRequestPacketID {
+			t.Error("invalid packet id")
+		}
+
+		if !bytes.Equal(pk.Data, tc.marshaledPacket.Data) {
+			t.Errorf("got: %v, want: %v", pk.Data, tc.marshaledPacket.Data)
+		}
+	}
+}
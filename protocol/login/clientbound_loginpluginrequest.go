@@ -0,0 +1,29 @@
+package login
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+const ClientBoundLoginPluginRequestPacketID byte = 0x04
+
+// ClientBoundLoginPluginRequest is a custom query of the server during the
+// login, e.g. for the player info of a forwarding proxy
+type ClientBoundLoginPluginRequest struct {
+	MessageID protocol.VarInt
+	Channel   protocol.String
+	Data      protocol.OptionalByteArray
+}
+
+func UnmarshalClientBoundLoginPluginRequest(packet protocol.Packet) (ClientBoundLoginPluginRequest, error) {
+	var pk ClientBoundLoginPluginRequest
+
+	if packet.ID != ClientBoundLoginPluginRequestPacketID {
+		return pk, protocol.ErrInvalidPacketID
+	}
+
+	if err := packet.Scan(&pk.MessageID, &pk.Channel, &pk.Data); err != nil {
+		return pk, err
+	}
+
+	return pk, nil
+}
@@ -0,0 +1,57 @@
+package login
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestUnmarshalClientBoundLoginPluginRequest(t *testing.T) {
+	tt := []struct {
+		packet    protocol.Packet
+		messageID protocol.VarInt
+		channel   protocol.String
+		data      []byte
+		isValid   bool
+	}{
+		{
+			packet: protocol.Packet{
+				ID:   0x04,
+				Data: append([]byte{0x2a, 0x03}, []byte("a:b\x04")...),
+			},
+			messageID: 42,
+			channel:   "a:b",
+			data:      []byte{0x04},
+			isValid:   true,
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x04,
+				Data: append([]byte{0x01, 0x03}, []byte("a:b")...),
+			},
+			messageID: 1,
+			channel:   "a:b",
+			isValid:   true,
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x03,
+				Data: append([]byte{0x01, 0x03}, []byte("a:b")...),
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range tt {
+		pk, err := UnmarshalClientBoundLoginPluginRequest(tc.packet)
+		if (err == nil) != tc.isValid {
+			t.Errorf("got: %v, want valid: %v", err, tc.isValid)
+			continue
+		}
+
+		if pk.MessageID != tc.messageID || pk.Channel != tc.channel || !bytes.Equal(pk.Data, tc.data) {
+			t.Errorf("got: %v, want: %v %v %v", pk, tc.messageID, tc.channel, tc.data)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package login
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+const ClientBoundLoginSuccessPacketID byte = 0x02
+
+type ClientBoundLoginSuccess struct {
+	UUID     protocol.UUID
+	Username protocol.String
+}
+
+// Marshal encodes the packet for clients with the given protocol version.
+// It only supports clients since 1.20.5 and never sends any properties.
+func (pk ClientBoundLoginSuccess) Marshal(protocolVersion protocol.VarInt) protocol.Packet {
+	fields := []protocol.FieldEncoder{
+		pk.UUID,
+		pk.Username,
+		protocol.VarInt(0), // Number of properties
+	}
+
+	// Clients from 1.20.5 up to 1.21.1 expect the strict error handling flag
+	if protocolVersion <= 767 {
+		fields = append(fields, protocol.Boolean(false))
+	}
+
+	return protocol.MarshalPacket(ClientBoundLoginSuccessPacketID, fields...)
+}
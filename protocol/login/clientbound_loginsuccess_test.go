@@ -0,0 +1,54 @@
+package login
+
+import (
+	"bytes"
+	"github.com/haveachin/infrared/protocol"
+	"testing"
+)
+
+func TestClientBoundLoginSuccess_Marshal(t *testing.T) {
+	uuid := protocol.UUID{0x86, 0x67, 0xba, 0x71, 0xb8, 0x5a, 0x40, 0x04, 0xaf, 0x54, 0x45, 0x7a, 0x97, 0x34, 0xee, 0xd7}
+
+	tt := []struct {
+		packet          ClientBoundLoginSuccess
+		protocolVersion protocol.VarInt
+		marshaledPacket protocol.Packet
+	}{
+		{
+			packet: ClientBoundLoginSuccess{
+				UUID:     uuid,
+				Username: protocol.String("Steve"),
+			},
+			protocolVersion: 766,
+			marshaledPacket: protocol.Packet{
+				ID: 0x02,
+				Data: []byte{0x86, 0x67, 0xba, 0x71, 0xb8, 0x5a, 0x40, 0x04, 0xaf, 0x54, 0x45, 0x7a, 0x97, 0x34, 0xee, 0xd7,
+					0x05, 0x53, 0x74, 0x65, 0x76, 0x65, 0x00, 0x00},
+			},
+		},
+		{
+			packet: ClientBoundLoginSuccess{
+				UUID:     uuid,
+				Username: protocol.String("Steve"),
+			},
+			protocolVersion: 768,
+			marshaledPacket: protocol.Packet{
+				ID: 0x02,
+				Data: []byte{0x86, 0x67, 0xba, 0x71, 0xb8, 0x5a, 0x40, 0x04, 0xaf, 0x54, 0x45, 0x7a, 0x97, 0x34, 0xee, 0xd7,
+					0x05, 0x53, 0x74, 0x65, 0x76, 0x65, 0x00},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		pk := tc.packet.Marshal(tc.protocolVersion)
+
+		if pk.ID != ClientBoundLoginSuccessPacketID {
+			t.Error("invalid packet id")
+		}
+
+		if !bytes.Equal(pk.Data, tc.marshaledPacket.Data) {
+			t.Errorf("got: %v, want: %v", pk.Data, tc.marshaledPacket.Data)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package login
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+const ClientBoundSetCompressionPacketID byte = 0x03
+
+// ClientBoundSetCompression enables the compression of all packets that are
+// at least Threshold bytes big. A negative threshold disables it.
+type ClientBoundSetCompression struct {
+	Threshold protocol.VarInt
+}
+
+func UnmarshalClientBoundSetCompression(packet protocol.Packet) (ClientBoundSetCompression, error) {
+	var pk ClientBoundSetCompression
+
+	if packet.ID != ClientBoundSetCompressionPacketID {
+		return pk, protocol.ErrInvalidPacketID
+	}
+
+	if err := packet.Scan(&pk.Threshold); err != nil {
+		return pk, err
+	}
+
+	return pk, nil
+}
@@ -0,0 +1,42 @@
+package login
+
+import (
+	"github.com/haveachin/infrared/protocol"
+	"testing"
+)
+
+func TestUnmarshalClientBoundSetCompression(t *testing.T) {
+	tt := []struct {
+		packet    protocol.Packet
+		threshold protocol.VarInt
+		isValid   bool
+	}{
+		{
+			packet: protocol.Packet{
+				ID:   0x03,
+				Data: []byte{0x80, 0x02},
+			},
+			threshold: 256,
+			isValid:   true,
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x02,
+				Data: []byte{0x80, 0x02},
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range tt {
+		pk, err := UnmarshalClientBoundSetCompression(tc.packet)
+		if (err == nil) != tc.isValid {
+			t.Errorf("got: %v, want valid: %v", err, tc.isValid)
+			continue
+		}
+
+		if pk.Threshold != tc.threshold {
+			t.Errorf("got: %v, want: %v", pk.Threshold, tc.threshold)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package login
+
+import (
+	"bytes"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+const ServerBoundEncryptionResponsePacketID byte = 0x01
+
+// ServerBoundEncryptionResponse has the shared secret and the verify token
+// of the client, both encrypted with the public key of the server
+type ServerBoundEncryptionResponse struct {
+	SharedSecret protocol.ByteArray
+	// VerifyToken is only missing if a client from 1.19 up to 1.19.2 signed
+	// a salt with its profile key instead
+	VerifyToken    protocol.ByteArray
+	HasVerifyToken bool
+	Salt           protocol.Long
+	Signature      protocol.ByteArray
+}
+
+func UnmarshalServerBoundEncryptionResponse(packet protocol.Packet, protocolVersion protocol.VarInt) (ServerBoundEncryptionResponse, error) {
+	var pk ServerBoundEncryptionResponse
+
+	if packet.ID != ServerBoundEncryptionResponsePacketID {
+		return pk, protocol.ErrInvalidPacketID
+	}
+
+	r := bytes.NewReader(packet.Data)
+	if err := protocol.ScanFields(r, &pk.SharedSecret); err != nil {
+		return pk, err
+	}
+
+	// 1.19 up to 1.19.2 tell whether the verify token or a signature follows
+	if protocolVersion == 759 || protocolVersion == 760 {
+		var hasVerifyToken protocol.Boolean
+		if err := protocol.ScanFields(r, &hasVerifyToken); err != nil {
+			return pk, err
+		}
+
+		if !hasVerifyToken {
+			err := protocol.ScanFields(r, &pk.Salt, &pk.Signature)
+			return pk, err
+		}
+	}
+
+	if err := protocol.ScanFields(r, &pk.VerifyToken); err != nil {
+		return pk, err
+	}
+	pk.HasVerifyToken = true
+
+	return pk, nil
+}
@@ -0,0 +1,93 @@
+package login
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestUnmarshalServerBoundEncryptionResponse(t *testing.T) {
+	tt := []struct {
+		packet          protocol.Packet
+		protocolVersion protocol.VarInt
+		sharedSecret    []byte
+		verifyToken     []byte
+		hasVerifyToken  bool
+		salt            protocol.Long
+		signature       []byte
+		isValid         bool
+	}{
+		{
+			packet: protocol.Packet{
+				ID:   0x01,
+				Data: []byte{0x02, 0xaa, 0xbb, 0x02, 0xcc, 0xdd},
+			},
+			protocolVersion: 758,
+			sharedSecret:    []byte{0xaa, 0xbb},
+			verifyToken:     []byte{0xcc, 0xdd},
+			hasVerifyToken:  true,
+			isValid:         true,
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x01,
+				Data: []byte{0x02, 0xaa, 0xbb, 0x01, 0x02, 0xcc, 0xdd},
+			},
+			protocolVersion: 759,
+			sharedSecret:    []byte{0xaa, 0xbb},
+			verifyToken:     []byte{0xcc, 0xdd},
+			hasVerifyToken:  true,
+			isValid:         true,
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x01,
+				Data: []byte{0x02, 0xaa, 0xbb, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x2a, 0x01, 0xee},
+			},
+			protocolVersion: 760,
+			sharedSecret:    []byte{0xaa, 0xbb},
+			salt:            42,
+			signature:       []byte{0xee},
+			isValid:         true,
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x01,
+				Data: []byte{0x02, 0xaa, 0xbb, 0x02, 0xcc, 0xdd},
+			},
+			protocolVersion: 761,
+			sharedSecret:    []byte{0xaa, 0xbb},
+			verifyToken:     []byte{0xcc, 0xdd},
+			hasVerifyToken:  true,
+			isValid:         true,
+		},
+		{
+			packet: protocol.Packet{
+				ID:   0x00,
+				Data: []byte{0x02, 0xaa, 0xbb, 0x02, 0xcc, 0xdd},
+			},
+			protocolVersion: 761,
+			isValid:         false,
+		},
+	}
+
+	for _, tc := range tt {
+		pk, err := UnmarshalServerBoundEncryptionResponse(tc.packet, tc.protocolVersion)
+		if (err == nil) != tc.isValid {
+			t.Errorf("got: %v, want valid: %v", err, tc.isValid)
+			continue
+		}
+		if !tc.isValid {
+			continue
+		}
+
+		if !bytes.Equal(pk.SharedSecret, tc.sharedSecret) ||
+			!bytes.Equal(pk.VerifyToken, tc.verifyToken) ||
+			pk.HasVerifyToken != tc.hasVerifyToken ||
+			pk.Salt != tc.salt ||
+			!bytes.Equal(pk.Signature, tc.signature) {
+			t.Errorf("got: %v", pk)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package login
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+const ServerBoundLoginAcknowledgedPacketID byte = 0x03
+
+// ServerBoundLoginAcknowledged is sent by clients since 1.20.2 to
+// switch into the configuration state
+type ServerBoundLoginAcknowledged struct{}
+
+func UnmarshalServerBoundLoginAcknowledged(packet protocol.Packet) (ServerBoundLoginAcknowledged, error) {
+	var pk ServerBoundLoginAcknowledged
+
+	if packet.ID != ServerBoundLoginAcknowledgedPacketID {
+		return pk, protocol.ErrInvalidPacketID
+	}
+
+	return pk, nil
+}
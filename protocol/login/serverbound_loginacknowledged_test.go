@@ -0,0 +1,33 @@
+package login
+
+import (
+	"github.com/haveachin/infrared/protocol"
+	"testing"
+)
+
+func TestUnmarshalServerBoundLoginAcknowledged(t *testing.T) {
+	tt := []struct {
+		packet  protocol.Packet
+		isValid bool
+	}{
+		{
+			packet: protocol.Packet{
+				ID: 0x03,
+			},
+			isValid: true,
+		},
+		{
+			packet: protocol.Packet{
+				ID: 0x00,
+			},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range tt {
+		_, err := UnmarshalServerBoundLoginAcknowledged(tc.packet)
+		if (err == nil) != tc.isValid {
+			t.Errorf("got: %v, want valid: %v", err, tc.isValid)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package login
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+const ServerBoundLoginPluginResponsePacketID byte = 0x02
+
+// ServerBoundLoginPluginResponse answers the login plugin request with the
+// same message ID. Data is only sent if Successful is set.
+type ServerBoundLoginPluginResponse struct {
+	MessageID  protocol.VarInt
+	Successful protocol.Boolean
+	Data       protocol.OptionalByteArray
+}
+
+func (pk ServerBoundLoginPluginResponse) Marshal() protocol.Packet {
+	return protocol.MarshalPacket(
+		ServerBoundLoginPluginResponsePacketID,
+		pk.MessageID,
+		pk.Successful,
+		pk.Data,
+	)
+}
@@ -0,0 +1,43 @@
+package login
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestServerBoundLoginPluginResponse_Marshal(t *testing.T) {
+	tt := []struct {
+		pk       ServerBoundLoginPluginResponse
+		expected protocol.Packet
+	}{
+		{
+			pk: ServerBoundLoginPluginResponse{
+				MessageID:  42,
+				Successful: true,
+				Data:       []byte{0x01, 0x02},
+			},
+			expected: protocol.Packet{
+				ID:   0x02,
+				Data: []byte{0x2a, 0x01, 0x01, 0x02},
+			},
+		},
+		{
+			pk: ServerBoundLoginPluginResponse{
+				MessageID: 1,
+			},
+			expected: protocol.Packet{
+				ID:   0x02,
+				Data: []byte{0x01, 0x00},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		pk := tc.pk.Marshal()
+		if pk.ID != tc.expected.ID || !bytes.Equal(pk.Data, tc.expected.Data) {
+			t.Errorf("got: %v, want: %v", pk, tc.expected)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package login
 
 import (
+	"bytes"
+
 	"github.com/haveachin/infrared/protocol"
 )
 
@@ -8,6 +10,9 @@ const ServerBoundLoginStartPacketID byte = 0x00
 
 type ServerLoginStart struct {
 	Name protocol.String
+	// PlayerUUID is only sent by clients since 1.20.2
+	PlayerUUID    protocol.UUID
+	HasPlayerUUID bool
 }
 
 func UnmarshalServerBoundLoginStart(packet protocol.Packet) (ServerLoginStart, error) {
@@ -17,9 +22,18 @@ func UnmarshalServerBoundLoginStart(packet protocol.Packet) (ServerLoginStart, e
 		return pk, protocol.ErrInvalidPacketID
 	}
 
-	if err := packet.Scan(&pk.Name); err != nil {
+	r := bytes.NewReader(packet.Data)
+	if err := protocol.ScanFields(r, &pk.Name); err != nil {
 		return pk, err
 	}
 
+	// Since 1.20.2 the name is directly followed by the UUID
+	if r.Len() == len(pk.PlayerUUID) {
+		if err := pk.PlayerUUID.Decode(r); err != nil {
+			return pk, err
+		}
+		pk.HasPlayerUUID = true
+	}
+
 	return pk, nil
 }
@@ -28,6 +28,18 @@ func TestUnmarshalServerBoundLoginStart(t *testing.T) {
 				Name: protocol.String("Hello, World!"),
 			},
 		},
+		{
+			packet: protocol.Packet{
+				ID: 0x00,
+				Data: []byte{0x05, 0x53, 0x74, 0x65, 0x76, 0x65,
+					0x86, 0x67, 0xba, 0x71, 0xb8, 0x5a, 0x40, 0x04, 0xaf, 0x54, 0x45, 0x7a, 0x97, 0x34, 0xee, 0xd7},
+			},
+			unmarshalledPacket: ServerLoginStart{
+				Name:          protocol.String("Steve"),
+				PlayerUUID:    protocol.UUID{0x86, 0x67, 0xba, 0x71, 0xb8, 0x5a, 0x40, 0x04, 0xaf, 0x54, 0x45, 0x7a, 0x97, 0x34, 0xee, 0xd7},
+				HasPlayerUUID: true,
+			},
+		},
 	}
 
 	for _, tc := range tt {
@@ -39,5 +51,10 @@ func TestUnmarshalServerBoundLoginStart(t *testing.T) {
 		if loginStart.Name != tc.unmarshalledPacket.Name {
 			t.Errorf("got: %v, want: %v", loginStart.Name, tc.unmarshalledPacket.Name)
 		}
+
+		if loginStart.HasPlayerUUID != tc.unmarshalledPacket.HasPlayerUUID ||
+			loginStart.PlayerUUID != tc.unmarshalledPacket.PlayerUUID {
+			t.Errorf("got: %v, want: %v", loginStart.PlayerUUID, tc.unmarshalledPacket.PlayerUUID)
+		}
 	}
 }
@@ -6,6 +6,10 @@ import (
 	"io"
 )
 
+// MaxPacketSize is the largest length of a packet, the largest number that
+// fits into a VarInt of three bytes
+const MaxPacketSize = 2097151
+
 // Packet is the raw representation of message that is send between the client and the server
 type Packet struct {
 	ID   byte
@@ -52,6 +56,12 @@ func MarshalPacket(ID byte, fields ...FieldEncoder) Packet {
 
 // ReadPacketBytes decodes a byte stream and cuts the first Packet as a byte array out
 func ReadPacketBytes(r DecodeReader) ([]byte, error) {
+	return ReadPacketBytesLimit(r, MaxPacketSize)
+}
+
+// ReadPacketBytesLimit is like ReadPacketBytes, but fails with
+// ErrPacketTooLarge before reading a packet that is longer than maxSize
+func ReadPacketBytesLimit(r DecodeReader, maxSize int) ([]byte, error) {
 	var packetLength VarInt
 	if err := packetLength.Decode(r); err != nil {
 		return nil, err
@@ -61,9 +71,13 @@ func ReadPacketBytes(r DecodeReader) ([]byte, error) {
 		return nil, fmt.Errorf("packet length too short")
 	}
 
+	if int(packetLength) > maxSize {
+		return nil, fmt.Errorf("%w: %d bytes exceed the limit of %d", ErrPacketTooLarge, packetLength, maxSize)
+	}
+
 	data := make([]byte, packetLength)
 	if _, err := io.ReadFull(r, data); err != nil {
-		return nil, fmt.Errorf("reading the content of the packet failed: %v", err)
+		return nil, fmt.Errorf("reading the content of the packet failed: %w", err)
 	}
 
 	return data, nil
@@ -71,7 +85,13 @@ func ReadPacketBytes(r DecodeReader) ([]byte, error) {
 
 // ReadPacket decodes and decompresses a byte stream and cuts the first Packet out
 func ReadPacket(r DecodeReader) (Packet, error) {
-	data, err := ReadPacketBytes(r)
+	return ReadPacketLimit(r, MaxPacketSize)
+}
+
+// ReadPacketLimit is like ReadPacket, but fails with ErrPacketTooLarge for
+// packets that are longer than maxSize
+func ReadPacketLimit(r DecodeReader, maxSize int) (Packet, error) {
+	data, err := ReadPacketBytesLimit(r, maxSize)
 	if err != nil {
 		return Packet{}, err
 	}
@@ -84,10 +104,16 @@ func ReadPacket(r DecodeReader) (Packet, error) {
 
 // PeekPacket decodes and decompresses a byte stream and peeks the first Packet
 func PeekPacket(p PeekReader) (Packet, error) {
+	return PeekPacketLimit(p, MaxPacketSize)
+}
+
+// PeekPacketLimit is like PeekPacket, but fails with ErrPacketTooLarge for
+// packets that are longer than maxSize
+func PeekPacketLimit(p PeekReader, maxSize int) (Packet, error) {
 	r := bytePeeker{
 		PeekReader: p,
 		cursor:     0,
 	}
 
-	return ReadPacket(&r)
+	return ReadPacketLimit(&r, maxSize)
 }
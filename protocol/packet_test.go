@@ -3,6 +3,7 @@ package protocol
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"testing"
 )
 
@@ -235,3 +236,45 @@ func TestPeekPacket(t *testing.T) {
 		}
 	}
 }
+
+func TestReadPacketLimit(t *testing.T) {
+	tt := []struct {
+		name    string
+		data    []byte
+		maxSize int
+		wantErr error
+	}{
+		{
+			name:    "WithinLimit",
+			data:    []byte{0x03, 0x00, 0x00, 0xf2},
+			maxSize: 3,
+		},
+		{
+			name:    "AboveLimit",
+			data:    []byte{0x03, 0x00, 0x00, 0xf2},
+			maxSize: 2,
+			wantErr: ErrPacketTooLarge,
+		},
+		{
+			// A length of 2^31 - 1 without the data that it claims
+			name:    "AboveMaxPacketSize",
+			data:    []byte{0xff, 0xff, 0xff, 0xff, 0x07},
+			maxSize: MaxPacketSize,
+			wantErr: ErrPacketTooLarge,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ReadPacketLimit(bytes.NewReader(tc.data), tc.maxSize)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("got: %v; want: %v", err, tc.wantErr)
+			}
+
+			_, err = PeekPacketLimit(bufio.NewReader(bytes.NewReader(tc.data)), tc.maxSize)
+			if !errors.Is(err, tc.wantErr) {
+				t.Errorf("peek: got: %v; want: %v", err, tc.wantErr)
+			}
+		})
+	}
+}
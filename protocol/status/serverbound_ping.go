@@ -0,0 +1,20 @@
+package status
+
+import (
+	"github.com/haveachin/infrared/protocol"
+)
+
+const ServerBoundPingPacketID byte = 0x01
+
+// ServerBoundPing is sent after the status response; the server answers
+// it with the same payload
+type ServerBoundPing struct {
+	Payload protocol.Long
+}
+
+func (pk ServerBoundPing) Marshal() protocol.Packet {
+	return protocol.MarshalPacket(
+		ServerBoundPingPacketID,
+		pk.Payload,
+	)
+}
@@ -107,6 +107,10 @@ func (s *String) Decode(r DecodeReader) error {
 		return err
 	}
 
+	if l < 0 {
+		return ErrInvalidStringLength
+	}
+
 	bb, err := ReadNBytes(r, int(l))
 	if err != nil {
 		return err
@@ -239,6 +243,11 @@ func (u *UUID) Decode(r DecodeReader) error {
 	return err
 }
 
+// String returns the UUID in its dashed form like 8667ba71-b85a-4004-af54-457a9734eed7
+func (u UUID) String() string {
+	return uuid.UUID(u).String()
+}
+
 // Encode a OptionalByteArray
 func (b OptionalByteArray) Encode() []byte {
 	return b
@@ -170,6 +170,14 @@ func TestString_Decode(t *testing.T) {
 	}
 }
 
+func TestString_Decode_NegativeLength(t *testing.T) {
+	var s String
+	encoded := VarInt(-1).Encode()
+	if err := s.Decode(bytes.NewReader(encoded)); err != ErrInvalidStringLength {
+		t.Errorf("got: %v; want: %v", err, ErrInvalidStringLength)
+	}
+}
+
 var byteTestTable = []struct {
 	decoded Byte
 	encoded []byte
@@ -413,3 +421,11 @@ func TestUUID_Decode(t *testing.T) {
 		}
 	}
 }
+
+func TestUUID_String(t *testing.T) {
+	u := UUID{0x86, 0x67, 0xba, 0x71, 0xb8, 0x5a, 0x40, 0x04, 0xaf, 0x54, 0x45, 0x7a, 0x97, 0x34, 0xee, 0xd7}
+	want := "8667ba71-b85a-4004-af54-457a9734eed7"
+	if got := u.String(); got != want {
+		t.Errorf("got: %v; want: %v", got, want)
+	}
+}
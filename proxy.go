@@ -1,6 +1,10 @@
 package infrared
 
 import (
+	"crypto/md5"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -14,16 +18,11 @@ import (
 	"github.com/haveachin/infrared/protocol/handshaking"
 	"github.com/haveachin/infrared/protocol/login"
 	"github.com/pires/go-proxyproto"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
-var (
-	playersConnected = promauto.NewGaugeVec(prometheus.GaugeOpts{
-		Name: "infrared_connected",
-		Help: "The total number of connected players",
-	}, []string{"host"})
-)
+// lostConnectionMessage is the disconnect message for players whose server
+// closed the connection during login
+const lostConnectionMessage = "Lost connection to server"
 
 func proxyUID(domain, addr string) string {
 	return fmt.Sprintf("%s@%s", strings.ToLower(domain), addr)
@@ -32,9 +31,35 @@ func proxyUID(domain, addr string) string {
 type Proxy struct {
 	Config *ProxyConfig
 
+	gateway           *Gateway
 	cancelTimeoutFunc func()
-	players           map[Conn]string
-	mu                sync.Mutex
+	players           map[Conn]Session
+	challengedStatus  *ttlCache
+	challengedLogin   *ttlCache
+	known             *ttlCache
+	newIPs            rateWindow
+	statusClients     *ttlCache
+	queue             *connQueue
+	statusBreaker     circuitBreaker
+	loginBreaker      circuitBreaker
+	backendHealth     backendHealth
+	nextBackend       uint32
+	weightedBackends  weightedBalancer
+	cachedStatus      *ttlCache
+	statusFetches     map[string]*statusFetch
+	stopPrewarm       chan struct{}
+	stopHealthChecks  chan struct{}
+	warmPool          warmPool
+	processStarting   bool
+	// onlineModePrivateKey is the key pair that clients encrypt their
+	// shared secret with when the online mode is intercepted
+	onlineModePrivateKey *rsa.PrivateKey
+	onlineModePublicKey  []byte
+	// waitingSince is when the first of the logins that wait for the
+	// server started waiting; zero if none waits
+	waitingSince time.Time
+	waiting      int
+	mu           sync.Mutex
 }
 
 func (proxy *Proxy) Process() process.Process {
@@ -91,6 +116,14 @@ func (proxy *Proxy) ProxyTo() string {
 	return proxy.Config.ProxyTo
 }
 
+// StatusProxyTo returns the address that status requests are sent to
+// instead of the backends; empty if they use the backends like logins
+func (proxy *Proxy) StatusProxyTo() string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.StatusProxyTo
+}
+
 func (proxy *Proxy) Dialer() (*Dialer, error) {
 	proxy.Config.RLock()
 	defer proxy.Config.RUnlock()
@@ -110,15 +143,17 @@ func (proxy *Proxy) IsOnlineStatusConfigured() bool {
 }
 
 func (proxy *Proxy) OnlineStatusPacket() (protocol.Packet, error) {
+	proxyUID := proxy.UID()
 	proxy.Config.Lock()
 	defer proxy.Config.Unlock()
-	return proxy.Config.OnlineStatus.StatusResponsePacket()
+	return proxy.Config.OnlineStatus.withPlaceholders(proxyUID, nil).StatusResponsePacket()
 }
 
 func (proxy *Proxy) OfflineStatusPacket() (protocol.Packet, error) {
+	proxyUID := proxy.UID()
 	proxy.Config.Lock()
 	defer proxy.Config.Unlock()
-	return proxy.Config.OfflineStatus.StatusResponsePacket()
+	return proxy.Config.OfflineStatus.withPlaceholders(proxyUID, nil).StatusResponsePacket()
 }
 
 func (proxy *Proxy) Timeout() time.Duration {
@@ -148,9 +183,25 @@ func (proxy *Proxy) RealIP() bool {
 func (proxy *Proxy) CallbackLogger() callback.Logger {
 	proxy.Config.RLock()
 	defer proxy.Config.RUnlock()
+	cfg := proxy.Config.CallbackServer
+	eventTimeouts := map[string]time.Duration{}
+	for event, timeout := range cfg.EventTimeouts {
+		eventTimeouts[event] = time.Millisecond * time.Duration(timeout)
+	}
+	retryBackoff := make([]time.Duration, 0, len(cfg.RetryBackoff))
+	for _, delay := range cfg.RetryBackoff {
+		retryBackoff = append(retryBackoff, time.Millisecond*time.Duration(delay))
+	}
+
 	return callback.Logger{
-		URL:    proxy.Config.CallbackServer.URL,
-		Events: proxy.Config.CallbackServer.Events,
+		URL:           cfg.URL,
+		Events:        cfg.Events,
+		Timeout:       time.Millisecond * time.Duration(cfg.Timeout),
+		EventTimeouts: eventTimeouts,
+		MustDeliver:   cfg.MustDeliver,
+		MaxRetries:    cfg.MaxRetries,
+		RetryBackoff:  retryBackoff,
+		Secret:        cfg.Secret,
 	}
 }
 
@@ -158,20 +209,31 @@ func (proxy *Proxy) UID() string {
 	return proxyUID(proxy.DomainName(), proxy.ListenTo())
 }
 
-func (proxy *Proxy) addPlayer(conn Conn, username string) {
+// Session is a player that is connected through a proxy
+type Session struct {
+	Username      string
+	RemoteAddress string
+	ProxyUID      string
+	Route         string
+	ConnectedAt   time.Time
+}
+
+// Sessions returns all players that are connected through the proxy
+func (proxy *Proxy) Sessions() []Session {
 	proxy.mu.Lock()
 	defer proxy.mu.Unlock()
-	if proxy.players == nil {
-		proxy.players = map[Conn]string{}
+	sessions := make([]Session, 0, len(proxy.players))
+	for _, session := range proxy.players {
+		sessions = append(sessions, session)
 	}
-	proxy.players[conn] = username
+	return sessions
 }
 
 func (proxy *Proxy) removePlayer(conn Conn) int {
 	proxy.mu.Lock()
 	defer proxy.mu.Unlock()
 	if proxy.players == nil {
-		proxy.players = map[Conn]string{}
+		proxy.players = map[Conn]Session{}
 		return 0
 	}
 	delete(proxy.players, conn)
@@ -179,12 +241,22 @@ func (proxy *Proxy) removePlayer(conn Conn) int {
 }
 
 func (proxy *Proxy) logEvent(event callback.Event) {
+	if proxy.gateway != nil {
+		proxy.gateway.events.publish(event)
+	}
+
 	if _, err := proxy.CallbackLogger().LogEvent(event); err != nil {
 		log.Println("[w] Failed callback logging; error:", err)
 	}
 }
 
 func (proxy *Proxy) handleConn(conn Conn, connRemoteAddr net.Addr) error {
+	// The timeout of the proxy replaces the one of the gateway
+	clientTimeout := proxy.ClientTimeout()
+	if err := setClientDeadline(conn, clientTimeout); err != nil {
+		return err
+	}
+
 	pk, err := conn.ReadPacket()
 	if err != nil {
 		return err
@@ -195,34 +267,171 @@ func (proxy *Proxy) handleConn(conn Conn, connRemoteAddr net.Addr) error {
 		return err
 	}
 
+	strict := proxy.StrictProtocol()
+	if strict {
+		if err := validateHandshake(pk, hs); err != nil {
+			return err
+		}
+	}
+
+	if proxy.rejectByHandshakePort(hs, connRemoteAddr) {
+		return nil
+	}
+
+	// The login start is read before dialing the server, so that the player
+	// can be rejected without ever reaching it
+	var loginPk protocol.Packet
+	var loginStart login.ServerLoginStart
+	if hs.IsLoginRequest() {
+		loginPk, err = conn.ReadPacket()
+		if err != nil {
+			return err
+		}
+
+		loginStart, err = login.UnmarshalServerBoundLoginStart(loginPk)
+		if err != nil {
+			return err
+		}
+
+		if strict {
+			if err := validateLoginStart(conn, loginPk, hs.ProtocolVersion, loginStart); err != nil {
+				return err
+			}
+		}
+	}
+	username := string(loginStart.Name)
+	recordAccess(conn, func(entry *accessEntry) { entry.Player = username })
+
+	if rejected, err := proxy.rejectByIP(conn, hs, connRemoteAddr, loginStart); rejected || err != nil {
+		return err
+	}
+
+	if rejected, err := proxy.rejectByCountry(conn, hs, connRemoteAddr, loginStart); rejected || err != nil {
+		return err
+	}
+
+	if proxy.shedStatus(hs, connRemoteAddr) {
+		return proxy.handleShedStatus(conn)
+	}
+
+	if proxy.limitStatus(hs, connRemoteAddr) {
+		return proxy.handleLimitedStatus(conn)
+	}
+
+	if rejected, err := proxy.rejectByVersion(conn, hs, connRemoteAddr); rejected || err != nil {
+		return err
+	}
+
+	if rejected, err := proxy.rejectByUsername(conn, hs, connRemoteAddr, username); rejected || err != nil {
+		return err
+	}
+
+	if rejected, err := proxy.rejectByACL(conn, hs, connRemoteAddr, username); rejected || err != nil {
+		return err
+	}
+
+	if rejected, err := proxy.rejectByMaintenance(conn, hs, connRemoteAddr); rejected || err != nil {
+		return err
+	}
+
+	if rejected, err := proxy.rejectByOpenHours(conn, hs, connRemoteAddr); rejected || err != nil {
+		return err
+	}
+
+	if rejected, err := proxy.rejectByAuthHook(conn, hs, connRemoteAddr, loginStart); rejected || err != nil {
+		return err
+	}
+
+	if proxy.isChallenged(hs, connRemoteAddr) {
+		logWith(connFields("challenge", connRemoteAddr, proxy.UID()), "[i] Challenging %s on %s", connRemoteAddr, proxy.UID())
+		return proxy.handleChallenge(conn, hs)
+	}
+
+	profile, rejected, err := proxy.authenticate(conn, hs, connRemoteAddr, loginStart)
+	if rejected || err != nil {
+		return err
+	}
+
+	if proxy.canTransfer(hs) {
+		return proxy.handleTransfer(conn, hs, loginStart, connRemoteAddr)
+	}
+
+	backends, route := proxy.backendsFor(hs, connRemoteAddr, loginStart)
+
+	if hs.IsLoginRequest() {
+		// Admitting the player takes its slot until the connection ends
+		admitted, position := proxy.admit(conn, Session{
+			Username:      username,
+			RemoteAddress: connRemoteAddr.String(),
+			ProxyUID:      proxy.UID(),
+			Route:         route,
+			ConnectedAt:   time.Now(),
+		})
+		if !admitted {
+			logWith(connFields("queue", connRemoteAddr, proxy.UID()), "[i] %s is full; %s with username %s is at position %d in the queue", proxy.UID(), connRemoteAddr, username, position)
+			return proxy.handleFullServer(conn, position)
+		}
+		defer proxy.removePlayer(conn)
+	}
+
 	proxyDomain := proxy.DomainName()
-	proxyTo := proxy.ProxyTo()
 	proxyUID := proxy.UID()
 
-	dialer, err := proxy.Dialer()
-	if err != nil {
-		return err
+	if hs.IsStatusRequest() && proxy.usesStatusCache() {
+		return proxy.handleCachedStatusRequest(conn, hs, route, backends, connRemoteAddr)
 	}
 
-	rconn, err := dialer.Dial(proxyTo)
+	if !proxy.healthy(backends) {
+		// Every server failed its health checks; answer right away instead
+		// of letting the client wait for the dial timeout
+		if hs.IsStatusRequest() {
+			return proxy.handleStatusRequest(conn, false)
+		}
+		return proxy.handleOfflineLogin(conn, loginStart, connRemoteAddr)
+	}
+
+	if hs.IsStatusRequest() && !proxy.allowStatusDial() {
+		// The server failed too often; spare it the dial
+		return proxy.handleStatusRequest(conn, false)
+	}
+
+	if hs.IsLoginRequest() && !proxy.allowLoginDial() {
+		// The server failed too often; tell the player right away instead
+		// of letting it wait for the dial timeout
+		return proxy.handleOfflineLogin(conn, loginStart, connRemoteAddr)
+	}
+
+	rconn, proxyTo, err := proxy.connectBackend(hs, backends)
+	proxy.recordDial(err)
+	if hs.IsLoginRequest() {
+		proxy.recordLoginDial(err)
+	}
 	if err != nil {
+		metrics.IncDialErrors(proxyDomain)
 		log.Printf("[i] %s did not respond to ping; is the target offline?", proxyTo)
 		if hs.IsStatusRequest() {
+			if since, ok := proxy.startingSince(); ok {
+				return proxy.handleStartingStatus(conn, since)
+			}
 			return proxy.handleStatusRequest(conn, false)
 		}
-		if err := proxy.startProcessIfNotRunning(); err != nil {
-			return err
+		if !proxy.WaitForBackend().IsEnabled() {
+			return proxy.handleOfflineLogin(conn, loginStart, connRemoteAddr)
+		}
+
+		rconn, proxyTo, err = proxy.waitForBackend(backends)
+		if err != nil {
+			return proxy.handleOfflineLogin(conn, loginStart, connRemoteAddr)
 		}
-		proxy.timeoutProcess()
-		return proxy.handleLoginRequest(conn)
 	}
 	defer rconn.Close()
 
 	if hs.IsStatusRequest() && proxy.IsOnlineStatusConfigured() {
-		return proxy.handleStatusRequest(conn, true)
+		return proxy.handleOnlineStatusRequest(conn, rconn, hs, route, connRemoteAddr)
 	}
 
-	if proxy.ProxyProtocol() {
+	forwarding := proxy.Forwarding()
+	if forwarding.ProxyProtocol {
 		header := &proxyproto.Header{
 			Version:           2,
 			Command:           proxyproto.PROXY,
@@ -236,70 +445,196 @@ func (proxy *Proxy) handleConn(conn Conn, connRemoteAddr net.Addr) error {
 		}
 	}
 
-	if proxy.RealIP() {
+	if forwarding.RealIP {
 		hs.UpgradeToRealIP(connRemoteAddr, time.Now())
 		pk = hs.Marshal()
 	}
 
+	if forwarding.BungeeCord && hs.IsLoginRequest() {
+		hs.ServerAddress = bungeeCordAddress(hs, connRemoteAddr, profile)
+		pk = hs.Marshal()
+	}
+
 	if err := rconn.WritePacket(pk); err != nil {
 		return err
 	}
+	recordAccess(conn, func(entry *accessEntry) { entry.proxied = true })
 
-	var username string
 	connected := false
 	if hs.IsLoginRequest() {
 		proxy.cancelProcessTimeout()
-		username, err = proxy.sniffUsername(conn, rconn, connRemoteAddr)
-		if err != nil {
+		if err := rconn.WritePacket(loginPk); err != nil {
 			return err
 		}
-		proxy.addPlayer(conn, username)
-		proxy.logEvent(callback.PlayerJoinEvent{
-			Username:      username,
-			RemoteAddress: connRemoteAddr.String(),
-			TargetAddress: proxyTo,
-			ProxyUID:      proxyUID,
-		})
-		playersConnected.With(prometheus.Labels{"host": proxyDomain}).Inc()
+
+		if velocity := proxy.VelocityForwarding(); velocity.IsEnabled() {
+			if err := answerVelocityForwarding(rconn, conn, velocity.Secret, connRemoteAddr, profile); err != nil {
+				return err
+			}
+		}
+		logWith(connFields("join", connRemoteAddr, proxyUID), "[i] %s with username %s connects through %s via %s", connRemoteAddr, username, proxyUID, conn.Transport())
+		metrics.AddConnectedPlayers(proxyDomain, conn.Transport(), 1)
+		// The player leaves with every return, even if its login never completes
+		defer metrics.AddConnectedPlayers(proxyDomain, conn.Transport(), -1)
 		connected = true
+
+		if period := proxy.NATKeepAlive(); period > 0 {
+			if err := keepNATAlive(conn, period); err != nil {
+				log.Printf("[w] Failed to enable NAT keep-alive for %s; error: %s", connRemoteAddr, err)
+			}
+		}
+	}
+
+	if clientTimeout > 0 {
+		// Players may idle as long as they want once they are proxied
+		if err := conn.SetDeadline(time.Time{}); err != nil {
+			return err
+		}
+	}
+
+	connectedAt := time.Now()
+	buffers := proxy.relayBuffers()
+	zeroCopy := proxy.zeroCopy()
+	var idle *idleTimer
+	if timeout := proxy.IdleTimeout(); timeout > 0 {
+		idle = newIdleTimer(timeout)
+	}
+	sentCh := make(chan int64, 1)
+	go func() {
+		var n int64
+		var err error
+		if connected && proxy.logsCompression() {
+			n, err = relayLoginCompression(rconn, conn, proxyTo, connRemoteAddr)
+		}
+		if err == nil {
+			var m int64
+			if idle != nil {
+				m, err = pipeIdle(rconn, conn, buffers, idle)
+			} else {
+				m, err = pipe(rconn, conn, buffers, zeroCopy)
+			}
+			n += m
+		}
+		if errors.Is(err, errIdleTimeout) {
+			// Stop the relay from the client as well
+			conn.Close()
+		}
+		sentCh <- n
+		if connected && n == 0 && !errors.Is(err, net.ErrClosed) {
+			// The server closed the connection before it answered the login.
+			// The client is still in the unencrypted login state, so it can
+			// be told why instead of waiting for its timeout.
+			log.Printf("[i] %s closed the connection of %s during login", proxyTo, connRemoteAddr)
+			metrics.IncBackendLoginCloses(proxyDomain)
+			_ = conn.WritePacket(disconnectPacket(lostConnectionMessage))
+			conn.Close()
+		}
+	}()
+
+	var received int64
+	var captureErr error
+	if connected {
+		join := callback.PlayerJoinEvent{
+			Username:          username,
+			RemoteAddress:     connRemoteAddr.String(),
+			TargetAddress:     proxyTo,
+			ProxyUID:          proxyUID,
+			PlayerUUID:        playerUUID(loginStart),
+			ProxyProtocolTLVs: connProxyTLVs(conn),
+		}
+		if proxy.capturesClientInfo(hs) {
+			// The join is published once the client told its locale and brand
+			var info clientInfo
+			info, received, captureErr = captureClientInfo(conn, rconn, hs.ProtocolVersion)
+			join.Locale = info.Locale
+			join.Brand = info.Brand
+			if info.Locale != "" {
+				metrics.IncClientLocales(proxyDomain, localeLabel(info.Locale))
+			}
+		}
+		proxy.logEvent(join)
+	}
+
+	var relayErr error
+	if captureErr == nil {
+		var n int64
+		if strict && hs.IsStatusRequest() {
+			n, relayErr = relayStrictStatus(conn, rconn)
+		} else if idle != nil {
+			n, _ = pipeIdle(conn, rconn, buffers, idle)
+		} else {
+			n, _ = pipe(conn, rconn, buffers, zeroCopy)
+		}
+		received += n
 	}
 
-	go pipe(rconn, conn)
-	pipe(conn, rconn)
+	// Stop the relay to the client, so that its byte count is final
+	rconn.Close()
+	sent := <-sentCh
+	metrics.AddRelayedBytes(proxyDomain, sent, received)
+
+	if idle != nil && idle.isIdle() && relayErr == nil {
+		// Returned to be logged and sent as an error event
+		relayErr = fmt.Errorf("%w for %s", errIdleTimeout, idle.timeout)
+	}
 
 	if connected {
+		// Only players are observed; status pings never get connected
+		duration := time.Since(connectedAt)
+		metrics.ObserveConnectionDuration(proxyDomain, duration)
 		proxy.logEvent(callback.PlayerLeaveEvent{
 			Username:      username,
 			RemoteAddress: connRemoteAddr.String(),
 			TargetAddress: proxyTo,
 			ProxyUID:      proxyUID,
+			PlayerUUID:    playerUUID(loginStart),
+			BytesSent:     sent,
+			BytesReceived: received,
+			Duration:      duration.Milliseconds(),
+
+			ProxyProtocolTLVs: connProxyTLVs(conn),
 		})
-		playersConnected.With(prometheus.Labels{"host": proxyDomain}).Dec()
 	}
 
 	remainingPlayers := proxy.removePlayer(conn)
 	if remainingPlayers <= 0 {
 		proxy.timeoutProcess()
 	}
-	return nil
+	return relayErr
 }
 
-func pipe(src, dst Conn) {
-	buffer := make([]byte, 0xffff)
-
-	for {
-		n, err := src.Read(buffer)
-		if err != nil {
-			return
-		}
+// playerUUID returns the UUID that the player sent in its login start or
+// an empty string if its client is too old to send one
+func playerUUID(loginStart login.ServerLoginStart) string {
+	if !loginStart.HasPlayerUUID {
+		return ""
+	}
+	return loginStart.PlayerUUID.String()
+}
 
-		data := buffer[:n]
+// handshakeType returns the metrics label for the requested state of hs
+func handshakeType(hs handshaking.ServerBoundHandshake) string {
+	switch {
+	case hs.IsStatusRequest():
+		return "status"
+	case hs.IsLoginRequest():
+		return "login"
+	default:
+		return "unknown"
+	}
+}
 
-		_, err = dst.Write(data)
-		if err != nil {
-			return
-		}
+// relayBuffers returns the buffer pool of the gateway that the proxy is registered at
+func (proxy *Proxy) relayBuffers() *bufferPool {
+	if proxy.gateway == nil {
+		return defaultBufferPool
 	}
+	return proxy.gateway.relayBuffers()
+}
+
+// zeroCopy reports whether plain TCP connections are relayed by the kernel
+func (proxy *Proxy) zeroCopy() bool {
+	return proxy.gateway == nil || !proxy.gateway.DisableZeroCopy
 }
 
 func (proxy *Proxy) startProcessIfNotRunning() error {
@@ -316,9 +651,17 @@ func (proxy *Proxy) startProcessIfNotRunning() error {
 		return nil
 	}
 
-	log.Println("[i] Starting container for", proxy.UID())
-	proxy.logEvent(callback.ContainerStartEvent{ProxyUID: proxy.UID()})
-	return proxy.Process().Start()
+	if !proxy.beginProcessStart() {
+		// Another connection is already starting it
+		return nil
+	}
+	defer proxy.endProcessStart()
+
+	return proxy.runProcessCommand("start", func() error {
+		log.Println("[i] Starting container for", proxy.UID())
+		proxy.logEvent(callback.ContainerStartEvent{ProxyUID: proxy.UID()})
+		return proxy.Process().Start()
+	})
 }
 
 func (proxy *Proxy) timeoutProcess() {
@@ -334,9 +677,12 @@ func (proxy *Proxy) timeoutProcess() {
 
 	log.Printf("[i] Starting container timeout %s on %s", proxy.DockerTimeout(), proxy.UID())
 	timer := time.AfterFunc(proxy.DockerTimeout(), func() {
-		log.Println("[i] Stopping container on", proxy.UID())
-		proxy.logEvent(callback.ContainerStopEvent{ProxyUID: proxy.UID()})
-		if err := proxy.Process().Stop(); err != nil {
+		err := proxy.runProcessCommand("stop", func() error {
+			log.Println("[i] Stopping container on", proxy.UID())
+			proxy.logEvent(callback.ContainerStopEvent{ProxyUID: proxy.UID()})
+			return proxy.Process().Stop()
+		})
+		if err != nil {
 			log.Printf("[w] Failed to stop the container for %s; error: %s", proxy.UID(), err)
 		}
 	})
@@ -357,37 +703,33 @@ func (proxy *Proxy) cancelProcessTimeout() {
 	proxy.cancelTimeoutFunc = nil
 }
 
-func (proxy *Proxy) sniffUsername(conn, rconn Conn, connRemoteAddr net.Addr) (string, error) {
-	pk, err := conn.ReadPacket()
-	if err != nil {
-		return "", err
-	}
-	rconn.WritePacket(pk)
-
-	ls, err := login.UnmarshalServerBoundLoginStart(pk)
-	if err != nil {
-		return "", err
-	}
-	log.Printf("[i] %s with username %s connects through %s", connRemoteAddr, ls.Name, proxy.UID())
-	return string(ls.Name), nil
+// offlinePlayerUUID returns the UUID that an offline mode server assigns to username
+func offlinePlayerUUID(username string) protocol.UUID {
+	sum := md5.Sum([]byte("OfflinePlayer:" + username))
+	sum[6] = sum[6]&0x0f | 0x30 // Version 3
+	sum[8] = sum[8]&0x3f | 0x80 // IETF variant
+	return protocol.UUID(sum)
 }
 
-func (proxy *Proxy) handleLoginRequest(conn Conn) error {
-	packet, err := conn.ReadPacket()
-	if err != nil {
-		return err
-	}
-
-	loginStart, err := login.UnmarshalServerBoundLoginStart(packet)
-	if err != nil {
+// handleOfflineLogin starts the process of the server and disconnects the
+// player with the disconnect message
+func (proxy *Proxy) handleOfflineLogin(conn Conn, loginStart login.ServerLoginStart, connRemoteAddr net.Addr) error {
+	if err := proxy.startProcessIfNotRunning(); err != nil {
 		return err
 	}
+	proxy.timeoutProcess()
+	return proxy.handleLoginRequest(conn, loginStart, connRemoteAddr)
+}
 
-	message := proxy.DisconnectMessage()
+// handleLoginRequest disconnects the player with the disconnect message.
+// Its legacy color codes like &c are translated before the placeholders
+// are filled, so that the values are sent as they are.
+func (proxy *Proxy) handleLoginRequest(conn Conn, loginStart login.ServerLoginStart, connRemoteAddr net.Addr) error {
+	message := translateColorCodes(proxy.DisconnectMessage())
 	templates := map[string]string{
 		"username":      string(loginStart.Name),
 		"now":           time.Now().Format(time.RFC822),
-		"remoteAddress": conn.LocalAddr().String(),
+		"remoteAddress": connRemoteAddr.String(),
 		"localAddress":  conn.LocalAddr().String(),
 		"domain":        proxy.DomainName(),
 		"proxyTo":       proxy.ProxyTo(),
@@ -398,19 +740,39 @@ func (proxy *Proxy) handleLoginRequest(conn Conn) error {
 		message = strings.Replace(message, fmt.Sprintf("{{%s}}", key), value, -1)
 	}
 
-	return conn.WritePacket(login.ClientBoundDisconnect{
-		Reason: protocol.Chat(fmt.Sprintf("{\"text\":\"%s\"}", message)),
-	}.Marshal())
+	return conn.WritePacket(disconnectPacket(message))
 }
 
-func (proxy *Proxy) handleStatusRequest(conn Conn, online bool) error {
-	// Read the request packet and send status response back
-	_, err := conn.ReadPacket()
-	if err != nil {
-		return err
+// colorCodes are the characters that follow the section sign in legacy
+// formatting codes; colors, obfuscated, bold, strikethrough, underline,
+// italic and reset
+const colorCodes = "0123456789abcdefklmnorABCDEFKLMNOR"
+
+// translateColorCodes replaces the & of legacy formatting codes like &c
+// with the section sign that the client expects. Every other & is kept.
+func translateColorCodes(message string) string {
+	var b strings.Builder
+	for i := 0; i < len(message); i++ {
+		if message[i] == '&' && i+1 < len(message) && strings.IndexByte(colorCodes, message[i+1]) >= 0 {
+			b.WriteString("§")
+			continue
+		}
+		b.WriteByte(message[i])
 	}
+	return b.String()
+}
+
+// disconnectPacket creates a login disconnect packet with message as its reason
+func disconnectPacket(message string) protocol.Packet {
+	reason, _ := json.Marshal(map[string]string{"text": message})
+	return login.ClientBoundDisconnect{
+		Reason: protocol.Chat(reason),
+	}.Marshal()
+}
 
+func (proxy *Proxy) handleStatusRequest(conn Conn, online bool) error {
 	var responsePk protocol.Packet
+	var err error
 	if online {
 		responsePk, err = proxy.OnlineStatusPacket()
 		if err != nil {
@@ -423,6 +785,24 @@ func (proxy *Proxy) handleStatusRequest(conn Conn, online bool) error {
 		}
 	}
 
+	return writeStatus(conn, responsePk, proxy.StrictProtocol())
+}
+
+// writeStatus answers the status request of the client with responsePk and
+// its ping with a pong. In strict mode both packets are validated.
+func writeStatus(conn Conn, responsePk protocol.Packet, strict bool) error {
+	// Read the request packet and send status response back
+	requestPk, err := conn.ReadPacket()
+	if err != nil {
+		return err
+	}
+
+	if strict {
+		if err := validateStatusRequest(requestPk); err != nil {
+			return err
+		}
+	}
+
 	if err := conn.WritePacket(responsePk); err != nil {
 		return err
 	}
@@ -432,5 +812,14 @@ func (proxy *Proxy) handleStatusRequest(conn Conn, online bool) error {
 		return err
 	}
 
+	if strict {
+		if err := validatePing(pingPk); err != nil {
+			return err
+		}
+		if conn.Reader().Buffered() > 0 {
+			return violation(strictReasonExtra, "packet after the ping")
+		}
+	}
+
 	return conn.WritePacket(pingPk)
 }
@@ -0,0 +1,358 @@
+package infrared
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+func TestOfflinePlayerUUID(t *testing.T) {
+	tt := []struct {
+		username string
+		uuid     string
+	}{
+		{
+			username: "Notch",
+			uuid:     "b50ad385-829d-3141-a216-7e7d7539ba7f",
+		},
+	}
+
+	for _, tc := range tt {
+		actual := uuid.UUID(offlinePlayerUUID(tc.username)).String()
+		if actual != tc.uuid {
+			t.Errorf("got: %s; want: %s", actual, tc.uuid)
+		}
+	}
+}
+
+func TestProxy_HandleConn_BackendClosesDuringLogin(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		rconn := wrapConn(c)
+		// Read the handshake and the login start, then die
+		_, _ = rconn.ReadPacket()
+		_, _ = rconn.ReadPacket()
+		rconn.Close()
+	}()
+
+	proxy := &Proxy{
+		Config: &ProxyConfig{
+			DomainName: "infrared",
+			ProxyTo:    l.Addr().String(),
+			Timeout:    1000,
+		},
+	}
+
+	c1, c2 := net.Pipe()
+	client := wrapConn(c1)
+	defer client.Close()
+	go func() {
+		_ = proxy.handleConn(wrapConn(c2), c2.RemoteAddr())
+	}()
+
+	hs := handshaking.ServerBoundHandshake{
+		ProtocolVersion: 758,
+		ServerAddress:   "infrared",
+		ServerPort:      25565,
+		NextState:       handshaking.ServerBoundHandshakeLoginState,
+	}
+	if err := client.WritePacket(hs.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	loginStart := protocol.MarshalPacket(login.ServerBoundLoginStartPacketID, protocol.String("Steve"))
+	if err := client.WritePacket(loginStart); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	pk, err := client.ReadPacket()
+	if err != nil {
+		t.Fatalf("got: %v; want: disconnect packet", err)
+	}
+
+	want := disconnectPacket(lostConnectionMessage)
+	if pk.ID != want.ID || !bytes.Equal(pk.Data, want.Data) {
+		t.Errorf("got: %v; want: %v", pk, want)
+	}
+}
+
+// TestProxy_HandleConn_ConfigurationPhase checks that the configuration
+// phase of 1.20.2+ is relayed untouched in both directions
+func TestProxy_HandleConn_ConfigurationPhase(t *testing.T) {
+	const protocolVersion = 764 // 1.20.2
+
+	// Packets of the configuration phase of 1.20.2
+	clientBound := []protocol.Packet{
+		// Plugin message with the brand of the server
+		protocol.MarshalPacket(0x00, protocol.String("minecraft:brand"), protocol.String("vanilla")),
+		// Registry data
+		{ID: 0x05, Data: []byte{0x0a, 0x00, 0x00, 0x00}},
+		// Finish configuration
+		{ID: 0x02},
+	}
+	serverBound := []protocol.Packet{
+		// Client information
+		protocol.MarshalPacket(0x00, protocol.String("en_us"), protocol.Byte(12)),
+		// Plugin message with the brand of the client
+		protocol.MarshalPacket(0x01, protocol.String("minecraft:brand"), protocol.String("vanilla")),
+		// Finish configuration acknowledgement
+		{ID: 0x02},
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		rconn := wrapConn(c)
+		defer rconn.Close()
+
+		// Handshake and login start
+		for i := 0; i < 2; i++ {
+			if _, err := rconn.ReadPacket(); err != nil {
+				serverErr <- err
+				return
+			}
+		}
+
+		loginSuccess := login.ClientBoundLoginSuccess{
+			UUID:     offlinePlayerUUID("Steve"),
+			Username: "Steve",
+		}
+		if err := rconn.WritePacket(loginSuccess.Marshal(protocolVersion)); err != nil {
+			serverErr <- err
+			return
+		}
+
+		pk, err := rconn.ReadPacket()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		if _, err := login.UnmarshalServerBoundLoginAcknowledged(pk); err != nil {
+			serverErr <- err
+			return
+		}
+
+		for _, pk := range clientBound {
+			if err := rconn.WritePacket(pk); err != nil {
+				serverErr <- err
+				return
+			}
+		}
+
+		for _, want := range serverBound {
+			pk, err := rconn.ReadPacket()
+			if err != nil {
+				serverErr <- err
+				return
+			}
+			if pk.ID != want.ID || !bytes.Equal(pk.Data, want.Data) {
+				t.Errorf("got: %v; want: %v", pk, want)
+			}
+		}
+		serverErr <- nil
+	}()
+
+	proxy := &Proxy{
+		Config: &ProxyConfig{
+			DomainName: "infrared",
+			ProxyTo:    l.Addr().String(),
+			Timeout:    1000,
+		},
+	}
+
+	c1, c2 := net.Pipe()
+	client := wrapConn(c1)
+	defer client.Close()
+	go func() {
+		_ = proxy.handleConn(wrapConn(c2), c2.RemoteAddr())
+	}()
+
+	if err := client.SetDeadline(time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	hs := handshaking.ServerBoundHandshake{
+		ProtocolVersion: protocolVersion,
+		ServerAddress:   "infrared",
+		ServerPort:      25565,
+		NextState:       handshaking.ServerBoundHandshakeLoginState,
+	}
+	if err := client.WritePacket(hs.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	loginStart := protocol.MarshalPacket(login.ServerBoundLoginStartPacketID, protocol.String("Steve"), offlinePlayerUUID("Steve"))
+	if err := client.WritePacket(loginStart); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.ReadPacket(); err != nil {
+		t.Fatalf("got: %v; want: login success", err)
+	}
+	if err := client.WritePacket(protocol.Packet{ID: login.ServerBoundLoginAcknowledgedPacketID}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range clientBound {
+		pk, err := client.ReadPacket()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if pk.ID != want.ID || !bytes.Equal(pk.Data, want.Data) {
+			t.Errorf("got: %v; want: %v", pk, want)
+		}
+	}
+
+	for _, pk := range serverBound {
+		if err := client.WritePacket(pk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := <-serverErr; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPlayerUUID(t *testing.T) {
+	tt := []struct {
+		name       string
+		loginStart login.ServerLoginStart
+		want       string
+	}{
+		{
+			name:       "OldClient",
+			loginStart: login.ServerLoginStart{Name: "Steve"},
+			want:       "",
+		},
+		{
+			name: "ClientWithUUID",
+			loginStart: login.ServerLoginStart{
+				Name:          "Steve",
+				PlayerUUID:    protocol.UUID{0x86, 0x67, 0xba, 0x71, 0xb8, 0x5a, 0x40, 0x04, 0xaf, 0x54, 0x45, 0x7a, 0x97, 0x34, 0xee, 0xd7},
+				HasPlayerUUID: true,
+			},
+			want: "8667ba71-b85a-4004-af54-457a9734eed7",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := playerUUID(tc.loginStart); got != tc.want {
+				t.Errorf("got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTranslateColorCodes(t *testing.T) {
+	tt := []struct {
+		message string
+		want    string
+	}{
+		{message: "&cServer is offline", want: "§cServer is offline"},
+		{message: "&C&lOffline&r!", want: "§C§lOffline§r!"},
+		{message: "Tom & Jerry", want: "Tom & Jerry"},
+		{message: "&z is no code", want: "&z is no code"},
+		{message: "trailing &", want: "trailing &"},
+	}
+
+	for _, tc := range tt {
+		if got := translateColorCodes(tc.message); got != tc.want {
+			t.Errorf("got: %v; want: %v", got, tc.want)
+		}
+	}
+}
+
+func TestProxy_HandleLoginRequest(t *testing.T) {
+	proxy := &Proxy{
+		Config: &ProxyConfig{
+			DomainName:        "mc.example.com",
+			DisconnectMessage: "&cSorry {{username}} from {{remoteAddress}}, {{domain}} is offline.",
+		},
+	}
+	remoteAddr := &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 50000}
+
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- proxy.handleLoginRequest(wrapConn(c2), login.ServerLoginStart{Name: "Steve"}, remoteAddr)
+	}()
+
+	pk, err := wrapConn(c1).ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+
+	want := disconnectPacket("§cSorry Steve from 203.0.113.7:50000, mc.example.com is offline.")
+	if !bytes.Equal(pk.Data, want.Data) {
+		t.Errorf("got: %s; want: %s", pk.Data, want.Data)
+	}
+}
+
+func TestProxyConfig_LoadFromPath_EventTimeouts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "infrared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "proxy.json")
+	if err := ioutil.WriteFile(path, []byte(`{"callbackServer": {"eventTimeouts": {"PlayerJoin": 100, "PlayerLeave": 200}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg ProxyConfig
+	if err := cfg.LoadFromPath(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"callbackServer": {"eventTimeouts": {"PlayerJoin": 300}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.LoadFromPath(path); err != nil {
+		t.Fatal(err)
+	}
+
+	proxy := &Proxy{Config: &cfg}
+	want := map[string]time.Duration{"PlayerJoin": 300 * time.Millisecond}
+	if got := proxy.CallbackLogger().EventTimeouts; !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v; want: %v", got, want)
+	}
+}
@@ -0,0 +1,149 @@
+package infrared
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/haveachin/infrared/callback"
+	"github.com/pires/go-proxyproto"
+	"github.com/pires/go-proxyproto/tlvparse"
+)
+
+// The reasons why a PROXY protocol header could not be read
+const (
+	proxyProtocolErrorMissing   = "missing"
+	proxyProtocolErrorMalformed = "malformed"
+	proxyProtocolErrorRead      = "read"
+	proxyProtocolErrorVersion   = "version"
+)
+
+// errProxyProtocolVersion is returned for headers of another version than
+// the required one
+var errProxyProtocolVersion = errors.New("proxyproto: unexpected version")
+
+// readProxyProtocolHeader reads the PROXY protocol header of conn. If
+// version is not zero, headers of other versions are rejected.
+func readProxyProtocolHeader(conn Conn, version int) (*proxyproto.Header, error) {
+	header, err := proxyproto.Read(conn.Reader())
+	if err != nil {
+		return nil, err
+	}
+
+	if version != 0 && int(header.Version) != version {
+		return nil, fmt.Errorf("%w %d; want %d", errProxyProtocolVersion, header.Version, version)
+	}
+	return header, nil
+}
+
+// trustsProxyProtocol reports whether the PROXY protocol header of the
+// client on addr is read
+func (gateway *Gateway) trustsProxyProtocol(addr net.Addr) bool {
+	if len(gateway.ProxyProtocolTrustedIPs) == 0 {
+		return true
+	}
+	ip := net.ParseIP(remoteIP(addr))
+	return matchesIP(gateway.ProxyProtocolTrustedIPs, ip, "the trusted PROXY protocol sources")
+}
+
+// proxyProtocolTLVs returns the TLVs of a version 2 header by their name.
+// Known types get a readable name and value, all others are named by their
+// type like 0xea and have their value hex encoded.
+func proxyProtocolTLVs(header *proxyproto.Header) map[string]string {
+	tlvs, err := header.TLVs()
+	if err != nil || len(tlvs) == 0 {
+		return nil
+	}
+
+	values := map[string]string{}
+	if id := tlvparse.FindAWSVPCEndpointID(tlvs); id != "" {
+		values["awsVpcEndpointId"] = id
+	}
+	if id, ok := tlvparse.FindAzurePrivateEndpointLinkID(tlvs); ok {
+		values["azurePrivateEndpointLinkId"] = strconv.FormatUint(uint64(id), 10)
+	}
+
+	for _, tlv := range tlvs {
+		switch tlv.Type {
+		case proxyproto.PP2_TYPE_ALPN:
+			values["alpn"] = string(tlv.Value)
+		case proxyproto.PP2_TYPE_AUTHORITY:
+			values["authority"] = string(tlv.Value)
+		case proxyproto.PP2_TYPE_UNIQUE_ID:
+			values["uniqueId"] = hex.EncodeToString(tlv.Value)
+		case proxyproto.PP2_TYPE_CRC32C:
+			// The checksum of the header is no metadata
+		default:
+			if _, ok := tlvparse.FindAzurePrivateEndpointLinkID([]proxyproto.TLV{tlv}); ok || tlvparse.IsAWSVPCEndpointID(tlv) {
+				// Already added by its name
+				continue
+			}
+			values[fmt.Sprintf("0x%02x", byte(tlv.Type))] = hex.EncodeToString(tlv.Value)
+		}
+	}
+	return values
+}
+
+// classifyProxyProtocolError returns the reason why proxyproto.Read failed
+func classifyProxyProtocolError(err error) string {
+	switch {
+	case errors.Is(err, proxyproto.ErrNoProxyProtocol):
+		return proxyProtocolErrorMissing
+	case errors.Is(err, errProxyProtocolVersion):
+		return proxyProtocolErrorVersion
+	case strings.HasPrefix(err.Error(), "proxyproto:"):
+		return proxyProtocolErrorMalformed
+	default:
+		return proxyProtocolErrorRead
+	}
+}
+
+// handleProxyProtocolError records a connection whose PROXY protocol header
+// could not be read. A lot of these usually mean that the load balancer in
+// front of the listener does not send the header.
+func (gateway *Gateway) handleProxyProtocolError(conn Conn, addr string, err error) {
+	reason := classifyProxyProtocolError(err)
+	log.Printf("[w] %s sent no valid PROXY protocol header (%s) on listener %s; is the load balancer configured to send it? error: %s", conn.RemoteAddr(), reason, addr, err)
+	metrics.IncProxyProtocolErrors(addr, reason)
+
+	event := callback.ProxyProtocolErrorEvent{
+		Error:         err.Error(),
+		Reason:        reason,
+		RemoteAddress: conn.RemoteAddr().String(),
+		ListenTo:      addr,
+	}
+	gateway.events.publish(event)
+
+	// The connection belongs to no proxy yet, so every proxy on the
+	// listener gets the event
+	gateway.Proxies.Range(func(_, v interface{}) bool {
+		proxy := v.(*Proxy)
+		if proxy.ListenTo() != addr {
+			return true
+		}
+		if _, err := proxy.CallbackLogger().LogEvent(event); err != nil {
+			log.Println("[w] Failed callback logging; error:", err)
+		}
+		return true
+	})
+}
+
+func setConnProxyTLVs(c Conn, tlvs map[string]string) {
+	if cc, ok := c.(*conn); ok {
+		cc.proxyTLVs = tlvs
+	}
+}
+
+// connProxyTLVs returns the TLVs of the PROXY protocol header that c was
+// received with
+func connProxyTLVs(c Conn) map[string]string {
+	cc, ok := c.(*conn)
+	if !ok {
+		return nil
+	}
+	return cc.proxyTLVs
+}
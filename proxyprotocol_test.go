@@ -0,0 +1,193 @@
+package infrared
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/pires/go-proxyproto/tlvparse"
+)
+
+func TestClassifyProxyProtocolError(t *testing.T) {
+	tt := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "NoHeader",
+			err:  proxyproto.ErrNoProxyProtocol,
+			want: proxyProtocolErrorMissing,
+		},
+		{
+			name: "InvalidHeader",
+			err:  proxyproto.ErrLineMustEndWithCrlf,
+			want: proxyProtocolErrorMalformed,
+		},
+		{
+			name: "WrongVersion",
+			err:  fmt.Errorf("%w 1; want 2", errProxyProtocolVersion),
+			want: proxyProtocolErrorVersion,
+		},
+		{
+			name: "ConnectionClosed",
+			err:  io.EOF,
+			want: proxyProtocolErrorRead,
+		},
+		{
+			name: "Other",
+			err:  errors.New("connection reset by peer"),
+			want: proxyProtocolErrorRead,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := classifyProxyProtocolError(tc.err); got != tc.want {
+				t.Errorf("got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func proxyProtocolHeader(version byte, tlvs []proxyproto.TLV) *proxyproto.Header {
+	header := proxyproto.HeaderProxyFromAddrs(version,
+		&net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 50000},
+		&net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 25565},
+	)
+	if tlvs != nil {
+		_ = header.SetTLVs(tlvs)
+	}
+	return header
+}
+
+func TestReadProxyProtocolHeader(t *testing.T) {
+	tt := []struct {
+		name     string
+		version  byte
+		required int
+		wantErr  bool
+	}{
+		{
+			name:    "AnyVersion1",
+			version: 1,
+		},
+		{
+			name:    "AnyVersion2",
+			version: 2,
+		},
+		{
+			name:     "RequiredVersion2",
+			version:  2,
+			required: 2,
+		},
+		{
+			name:     "Version1NotRequired",
+			version:  1,
+			required: 2,
+			wantErr:  true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if _, err := proxyProtocolHeader(tc.version, nil).WriteTo(&buf); err != nil {
+				t.Fatal(err)
+			}
+			c1, c2 := net.Pipe()
+			defer c1.Close()
+			defer c2.Close()
+			go c1.Write(buf.Bytes())
+
+			header, err := readProxyProtocolHeader(wrapConn(c2), tc.required)
+			if tc.wantErr {
+				if !errors.Is(err, errProxyProtocolVersion) {
+					t.Errorf("got: %v; want: %v", err, errProxyProtocolVersion)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := header.SourceAddr.String(); got != "203.0.113.7:50000" {
+				t.Errorf("got: %v; want: %v", got, "203.0.113.7:50000")
+			}
+		})
+	}
+}
+
+func TestProxyProtocolTLVs(t *testing.T) {
+	vpce := append([]byte{tlvparse.PP2_SUBTYPE_AWS_VPCE_ID}, "vpce-08d2bf15fac5001c9"...)
+	header := proxyProtocolHeader(2, []proxyproto.TLV{
+		{Type: tlvparse.PP2_TYPE_AWS, Value: vpce},
+		{Type: proxyproto.PP2_TYPE_AUTHORITY, Value: []byte("mc.example.com")},
+		{Type: proxyproto.PP2_TYPE_UNIQUE_ID, Value: []byte{0xca, 0xfe}},
+		{Type: proxyproto.PP2_TYPE_MIN_CUSTOM, Value: []byte("mc")},
+	})
+
+	want := map[string]string{
+		"awsVpcEndpointId": "vpce-08d2bf15fac5001c9",
+		"authority":        "mc.example.com",
+		"uniqueId":         "cafe",
+		"0xe0":             "6d63",
+	}
+	if got := proxyProtocolTLVs(header); !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v; want: %v", got, want)
+	}
+
+	if got := proxyProtocolTLVs(proxyProtocolHeader(2, nil)); got != nil {
+		t.Errorf("got: %v; want: nil", got)
+	}
+}
+
+func TestGateway_TrustsProxyProtocol(t *testing.T) {
+	tt := []struct {
+		name       string
+		trustedIPs []string
+		addr       string
+		trusted    bool
+	}{
+		{
+			name:    "NoTrustedIPs",
+			addr:    "203.0.113.7:50000",
+			trusted: true,
+		},
+		{
+			name:       "TrustedIP",
+			trustedIPs: []string{"192.0.2.10"},
+			addr:       "192.0.2.10:50000",
+			trusted:    true,
+		},
+		{
+			name:       "TrustedRange",
+			trustedIPs: []string{"192.0.2.10", "10.0.0.0/8"},
+			addr:       "10.1.2.3:50000",
+			trusted:    true,
+		},
+		{
+			name:       "Untrusted",
+			trustedIPs: []string{"192.0.2.10", "10.0.0.0/8"},
+			addr:       "203.0.113.7:50000",
+			trusted:    false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			addr, err := net.ResolveTCPAddr("tcp", tc.addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			gateway := Gateway{ProxyProtocolTrustedIPs: tc.trustedIPs}
+			if trusted := gateway.trustsProxyProtocol(addr); trusted != tc.trusted {
+				t.Errorf("got: %v; want: %v", trusted, tc.trusted)
+			}
+		})
+	}
+}
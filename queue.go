@@ -0,0 +1,178 @@
+package infrared
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultQueueSize    = 100
+	defaultFullMessage  = "The server is full."
+	defaultQueueMessage = "The server is full. You are #{{position}} in the queue, please reconnect."
+	// queueTimeout is the time a queued player has to reconnect before
+	// it loses its position in the queue
+	queueTimeout = time.Minute
+)
+
+func (proxy *Proxy) MaxConnections() int {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.MaxConnections
+}
+
+func (proxy *Proxy) QueueEnabled() bool {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.QueueEnabled
+}
+
+func (proxy *Proxy) QueueSize() int {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	if proxy.Config.QueueSize <= 0 {
+		return defaultQueueSize
+	}
+	return proxy.Config.QueueSize
+}
+
+func (proxy *Proxy) FullMessage() string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	if proxy.Config.FullMessage == "" {
+		return defaultFullMessage
+	}
+	return proxy.Config.FullMessage
+}
+
+func (proxy *Proxy) QueueMessage() string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	if proxy.Config.QueueMessage == "" {
+		return defaultQueueMessage
+	}
+	return proxy.Config.QueueMessage
+}
+
+// admit takes a slot on the server for the player if the server is not full
+// and the player is not behind others in the queue. Otherwise the player is
+// queued and its position is returned. A position of 0 means that the player
+// could not be queued.
+func (proxy *Proxy) admit(conn Conn, session Session) (bool, int) {
+	maxConnections := proxy.MaxConnections()
+	queueEnabled := proxy.QueueEnabled()
+	queueSize := proxy.QueueSize()
+
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.players == nil {
+		proxy.players = map[Conn]Session{}
+	}
+
+	if maxConnections <= 0 {
+		proxy.players[conn] = session
+		return true, 0
+	}
+
+	freeSlots := maxConnections - len(proxy.players)
+	if !queueEnabled {
+		if freeSlots <= 0 {
+			return false, 0
+		}
+		proxy.players[conn] = session
+		return true, 0
+	}
+
+	if proxy.queue == nil {
+		proxy.queue = &connQueue{}
+	}
+
+	key := strings.ToLower(session.Username)
+	now := time.Now()
+	position := proxy.queue.position(key, now)
+	if freeSlots > 0 && (position > 0 && position <= freeSlots || position == 0 && proxy.queue.len() < freeSlots) {
+		proxy.queue.remove(key)
+		proxy.players[conn] = session
+		return true, 0
+	}
+
+	if position == 0 {
+		position = proxy.queue.push(key, now.Add(queueTimeout), queueSize)
+	} else {
+		proxy.queue.refresh(key, now.Add(queueTimeout))
+	}
+	return false, position
+}
+
+// handleFullServer disconnects the player with its position in the queue
+func (proxy *Proxy) handleFullServer(conn Conn, position int) error {
+	if position == 0 {
+		return conn.WritePacket(disconnectPacket(proxy.FullMessage()))
+	}
+
+	message := strings.Replace(proxy.QueueMessage(), "{{position}}", strconv.Itoa(position), -1)
+	return conn.WritePacket(disconnectPacket(message))
+}
+
+// connQueue is a FIFO queue of players that wait for a free slot on a
+// full server. Players are told their position and have to reconnect before
+// their entry expires to keep it. It is not safe for concurrent use.
+type connQueue struct {
+	entries []connQueueEntry
+}
+
+type connQueueEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+func (q *connQueue) len() int {
+	return len(q.entries)
+}
+
+// position removes all expired entries and returns the 1-based position of
+// key or 0 if it is not queued
+func (q *connQueue) position(key string, now time.Time) int {
+	entries := q.entries[:0]
+	for _, entry := range q.entries {
+		if now.Before(entry.expiresAt) {
+			entries = append(entries, entry)
+		}
+	}
+	q.entries = entries
+
+	for i, entry := range q.entries {
+		if entry.key == key {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// push appends key to the queue and returns its position or 0 if the
+// queue already holds maxSize entries
+func (q *connQueue) push(key string, expiresAt time.Time, maxSize int) int {
+	if len(q.entries) >= maxSize {
+		return 0
+	}
+	q.entries = append(q.entries, connQueueEntry{key: key, expiresAt: expiresAt})
+	return len(q.entries)
+}
+
+func (q *connQueue) refresh(key string, expiresAt time.Time) {
+	for i := range q.entries {
+		if q.entries[i].key == key {
+			q.entries[i].expiresAt = expiresAt
+			return
+		}
+	}
+}
+
+func (q *connQueue) remove(key string) {
+	for i, entry := range q.entries {
+		if entry.key == key {
+			q.entries = append(q.entries[:i], q.entries[i+1:]...)
+			return
+		}
+	}
+}
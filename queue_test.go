@@ -0,0 +1,155 @@
+package infrared
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+)
+
+func TestProxy_Admit(t *testing.T) {
+	proxy := &Proxy{
+		Config: &ProxyConfig{
+			MaxConnections: 1,
+			QueueEnabled:   true,
+			QueueSize:      2,
+		},
+	}
+
+	steve, alex, notch, jeb := wrapConn(nil), wrapConn(nil), wrapConn(nil), wrapConn(nil)
+
+	tt := []struct {
+		name     string
+		action   func() (bool, int)
+		admitted bool
+		position int
+	}{
+		{
+			name:     "FreeSlot",
+			action:   func() (bool, int) { return proxy.admit(steve, Session{Username: "Steve"}) },
+			admitted: true,
+		},
+		{
+			name:     "FirstInQueue",
+			action:   func() (bool, int) { return proxy.admit(alex, Session{Username: "Alex"}) },
+			position: 1,
+		},
+		{
+			name:     "SecondInQueue",
+			action:   func() (bool, int) { return proxy.admit(notch, Session{Username: "Notch"}) },
+			position: 2,
+		},
+		{
+			name:   "QueueFull",
+			action: func() (bool, int) { return proxy.admit(jeb, Session{Username: "jeb_"}) },
+		},
+		{
+			name:     "Reconnect",
+			action:   func() (bool, int) { return proxy.admit(alex, Session{Username: "alex"}) },
+			position: 1,
+		},
+		{
+			name: "QueuedBehind",
+			action: func() (bool, int) {
+				proxy.removePlayer(steve)
+				return proxy.admit(notch, Session{Username: "Notch"})
+			},
+			position: 2,
+		},
+		{
+			name:     "FrontOfQueue",
+			action:   func() (bool, int) { return proxy.admit(alex, Session{Username: "Alex"}) },
+			admitted: true,
+		},
+		{
+			name:     "MovedUp",
+			action:   func() (bool, int) { return proxy.admit(notch, Session{Username: "Notch"}) },
+			position: 1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			admitted, position := tc.action()
+			if admitted != tc.admitted {
+				t.Errorf("admitted got: %v; want: %v", admitted, tc.admitted)
+			}
+			if position != tc.position {
+				t.Errorf("position got: %v; want: %v", position, tc.position)
+			}
+		})
+	}
+}
+
+func TestProxy_Admit_WithoutQueue(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{MaxConnections: 2}}
+	steve, alex, notch := wrapConn(nil), wrapConn(nil), wrapConn(nil)
+
+	for _, conn := range []Conn{steve, alex} {
+		if admitted, _ := proxy.admit(conn, Session{}); !admitted {
+			t.Fatal("got: rejected; want: admitted")
+		}
+	}
+
+	if admitted, position := proxy.admit(notch, Session{}); admitted || position != 0 {
+		t.Errorf("got: %v, %v; want: false, 0", admitted, position)
+	}
+
+	// A closed connection frees its slot
+	if got := proxy.removePlayer(steve); got != 1 {
+		t.Errorf("got: %v; want: %v", got, 1)
+	}
+	if admitted, _ := proxy.admit(notch, Session{}); !admitted {
+		t.Error("got: rejected; want: admitted")
+	}
+}
+
+func TestProxy_HandleFullServer(t *testing.T) {
+	tt := []struct {
+		name     string
+		position int
+		want     string
+	}{
+		{
+			name:     "Full",
+			position: 0,
+			want:     "Sorry, the server is full.",
+		},
+		{
+			name:     "Queued",
+			position: 3,
+			want:     "You are #3 in the queue.",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{Config: &ProxyConfig{
+				FullMessage:  "Sorry, the server is full.",
+				QueueMessage: "You are #{{position}} in the queue.",
+			}}
+
+			c1, c2 := net.Pipe()
+			defer c1.Close()
+			defer c2.Close()
+
+			received := make(chan protocol.Packet, 1)
+			go func() {
+				pk, err := wrapConn(c1).ReadPacket()
+				if err == nil {
+					received <- pk
+				}
+			}()
+
+			if err := proxy.handleFullServer(wrapConn(c2), tc.position); err != nil {
+				t.Fatal(err)
+			}
+
+			want := disconnectPacket(tc.want)
+			if pk := <-received; !bytes.Equal(pk.Data, want.Data) {
+				t.Errorf("got: %s; want: %s", pk.Data, want.Data)
+			}
+		})
+	}
+}
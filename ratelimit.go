@@ -0,0 +1,74 @@
+package infrared
+
+import (
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// connRateLimitMaxEntries bounds the number of IPs whose buckets are
+// tracked. The least recently seen ones are forgotten first.
+const connRateLimitMaxEntries = 100000
+
+// tokenBucket allows bursts of events up to its size and refills at its
+// rate per second. It is safe for concurrent use.
+type tokenBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// Allow takes a token and reports whether there was one
+func (b *tokenBucket) Allow(rate float64, burst int, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.last.IsZero() {
+		b.tokens = float64(burst)
+	} else {
+		b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// connRateBurst returns the burst of the connection rate limit. It defaults
+// to the rate rounded up.
+func (gateway *Gateway) connRateBurst() int {
+	if gateway.ConnRateBurst > 0 {
+		return gateway.ConnRateBurst
+	}
+	return int(math.Ceil(gateway.ConnRateLimit))
+}
+
+// allowConnection reports whether the IP is within the connection rate
+// limit of the gateway
+func (gateway *Gateway) allowConnection(connRemoteAddr net.Addr) bool {
+	if gateway.ConnRateLimit <= 0 {
+		return true
+	}
+
+	gateway.connBucketsOnce.Do(func() {
+		gateway.connBuckets = newTTLCache(connRateLimitMaxEntries)
+	})
+
+	rate, burst := gateway.ConnRateLimit, gateway.connRateBurst()
+	// A bucket that was refilled completely is the same as a new one
+	ttl := time.Duration(float64(burst)/rate*float64(time.Second)) + time.Second
+
+	ip := remoteIP(connRemoteAddr)
+	var bucket *tokenBucket
+	if v, ok := gateway.connBuckets.Get(ip); ok {
+		bucket = v.(*tokenBucket)
+	} else {
+		bucket = &tokenBucket{}
+	}
+	gateway.connBuckets.Set(ip, bucket, ttl)
+	return bucket.Allow(rate, burst, time.Now())
+}
@@ -0,0 +1,62 @@
+package infrared
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket_Allow(t *testing.T) {
+	var bucket tokenBucket
+	now := time.Now()
+
+	tt := []struct {
+		name  string
+		after time.Duration
+		want  bool
+	}{
+		{name: "Burst1", want: true},
+		{name: "Burst2", want: true},
+		{name: "Burst3", want: true},
+		{name: "BurstUsedUp", want: false},
+		{name: "PartlyRefilled", after: 100 * time.Millisecond, want: false},
+		{name: "Refilled", after: 500 * time.Millisecond, want: true},
+		{name: "UsedUpAgain", want: false},
+	}
+
+	for _, tc := range tt {
+		now = now.Add(tc.after)
+		if got := bucket.Allow(2, 3, now); got != tc.want {
+			t.Errorf("%s: got: %v; want: %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestGateway_AllowConnection(t *testing.T) {
+	gateway := &Gateway{ConnRateLimit: 1, ConnRateBurst: 2}
+	bot := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000}
+	player := &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 50000}
+
+	for i, want := range []bool{true, true, false, false} {
+		// Every connection of the bot comes from another port
+		bot.Port++
+		if got := gateway.allowConnection(bot); got != want {
+			t.Errorf("connection %d: got: %v; want: %v", i, got, want)
+		}
+	}
+
+	if !gateway.allowConnection(player) {
+		t.Error("got: other IP limited; want: allowed")
+	}
+}
+
+func TestGateway_AllowConnection_Disabled(t *testing.T) {
+	gateway := &Gateway{}
+	addr := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000}
+
+	for i := 0; i < 100; i++ {
+		if !gateway.allowConnection(addr) {
+			t.Fatal("got: limited; want: allowed")
+		}
+	}
+}
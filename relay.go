@@ -0,0 +1,108 @@
+package infrared
+
+import (
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+const defaultRelayBufferSize = 0xffff
+
+// defaultBufferPool is used by proxies that are not registered at a gateway
+var defaultBufferPool = newBufferPool(defaultRelayBufferSize)
+
+// bufferPool hands out reusable relay buffers of a fixed size to
+// reduce the allocations per connection
+type bufferPool struct {
+	size int
+	pool sync.Pool
+}
+
+func newBufferPool(size int) *bufferPool {
+	if size <= 0 {
+		size = defaultRelayBufferSize
+	}
+
+	p := &bufferPool{size: size}
+	p.pool.New = func() interface{} {
+		buf := make([]byte, p.size)
+		return &buf
+	}
+	return p
+}
+
+func (p *bufferPool) Get() *[]byte {
+	return p.pool.Get().(*[]byte)
+}
+
+func (p *bufferPool) Put(buf *[]byte) {
+	p.pool.Put(buf)
+}
+
+// pipe copies everything from src to dst until one of them fails and
+// returns the number of copied bytes. The error is nil if src was closed
+// by its peer. With zeroCopy, plain TCP connections are relayed by the
+// kernel.
+func pipe(src, dst Conn, buffers *bufferPool, zeroCopy bool) (int64, error) {
+	n, err := copyConn(src, dst, buffers, zeroCopy)
+	countRelayed(src, dst, n)
+	return n, err
+}
+
+func copyConn(src, dst Conn, buffers *bufferPool, zeroCopy bool) (int64, error) {
+	if zeroCopy {
+		srcTCP, srcOK := rawTCPConn(src)
+		dstTCP, dstOK := rawTCPConn(dst)
+		if srcOK && dstOK {
+			return spliceTCP(src, srcTCP, dstTCP)
+		}
+	}
+
+	buf := buffers.Get()
+	defer buffers.Put(buf)
+
+	return io.CopyBuffer(dst, src, *buf)
+}
+
+// countRelayed adds n to the relayed bytes of src and dst
+func countRelayed(src, dst Conn, n int64) {
+	if c, ok := src.(*conn); ok {
+		atomic.AddInt64(&c.bytesRead, n)
+	}
+	if c, ok := dst.(*conn); ok {
+		atomic.AddInt64(&c.bytesWritten, n)
+	}
+}
+
+// relayedBytes returns the number of bytes that were relayed from and to c
+func relayedBytes(c Conn) (int64, int64) {
+	cc, ok := c.(*conn)
+	if !ok {
+		return 0, 0
+	}
+	return atomic.LoadInt64(&cc.bytesRead), atomic.LoadInt64(&cc.bytesWritten)
+}
+
+func rawTCPConn(c Conn) (*net.TCPConn, bool) {
+	tc, ok := c.(interface {
+		tcpConn() (*net.TCPConn, bool)
+	})
+	if !ok {
+		return nil, false
+	}
+	return tc.tcpConn()
+}
+
+// spliceTCP copies from srcTCP to dstTCP with TCPConn.ReadFrom, which
+// splices the data on Linux without copying it into user space. The data
+// that src already buffered is copied first.
+func spliceTCP(src Conn, srcTCP, dstTCP *net.TCPConn) (int64, error) {
+	n, err := io.CopyN(dstTCP, src.Reader(), int64(src.Reader().Buffered()))
+	if err != nil {
+		return n, err
+	}
+
+	m, err := dstTCP.ReadFrom(srcTCP)
+	return n + m, err
+}
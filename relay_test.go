@@ -0,0 +1,253 @@
+package infrared
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+)
+
+// discardListener accepts connections and discards everything they send
+func discardListener(b *testing.B) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	go func() {
+		for {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				_, _ = io.Copy(ioutil.Discard, c)
+			}()
+		}
+	}()
+	return listener
+}
+
+// benchmarkPipe relays a payload per iteration. Without buffers, every
+// iteration allocates its own buffer like an unpooled copy does.
+func benchmarkPipe(b *testing.B, buffers *bufferPool, zeroCopy bool) {
+	const payloadSize = 1 << 20
+
+	server := discardListener(b)
+	defer server.Close()
+
+	gateway, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer gateway.Close()
+
+	payload := make([]byte, payloadSize)
+	b.SetBytes(payloadSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		go func() {
+			client, err := net.Dial("tcp", gateway.Addr().String())
+			if err != nil {
+				return
+			}
+			_, _ = client.Write(payload)
+			client.Close()
+		}()
+
+		src, err := gateway.Accept()
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		rconn, err := Dialer{}.Dial(server.Addr().String())
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		pool := buffers
+		if pool == nil {
+			pool = newBufferPool(defaultRelayBufferSize)
+		}
+		n, _ := pipe(wrapConn(src), rconn, pool, zeroCopy)
+		if n != payloadSize {
+			b.Fatalf("got: %d; want: %d", n, payloadSize)
+		}
+		src.Close()
+		rconn.Close()
+	}
+}
+
+func BenchmarkPipe(b *testing.B) {
+	for _, size := range []int{4 << 10, 32 << 10, defaultRelayBufferSize, 256 << 10} {
+		b.Run(fmt.Sprintf("BufferSize%d", size), func(b *testing.B) {
+			benchmarkPipe(b, newBufferPool(size), false)
+		})
+	}
+
+	b.Run("Unpooled", func(b *testing.B) {
+		benchmarkPipe(b, nil, false)
+	})
+
+	b.Run("ZeroCopy", func(b *testing.B) {
+		benchmarkPipe(b, defaultBufferPool, true)
+	})
+}
+
+func TestPipe_ZeroCopyBufferedData(t *testing.T) {
+	gateway, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gateway.Close()
+
+	received := make(chan string)
+	server, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	go func() {
+		c, err := server.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		data, _ := ioutil.ReadAll(c)
+		received <- string(data)
+	}()
+
+	go func() {
+		client, err := net.Dial("tcp", gateway.Addr().String())
+		if err != nil {
+			return
+		}
+		_, _ = client.Write([]byte("infrared"))
+		client.Close()
+	}()
+
+	c, err := gateway.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := wrapConn(c)
+	defer src.Close()
+
+	// Fill the read buffer like peeking the handshake does
+	if _, err := src.Reader().Peek(4); err != nil {
+		t.Fatal(err)
+	}
+
+	rconn, err := Dialer{}.Dial(server.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = pipe(src, rconn, defaultBufferPool, true)
+	rconn.Close()
+
+	if data := <-received; data != "infrared" {
+		t.Errorf("got: %s; want: %s", data, "infrared")
+	}
+}
+
+func TestPipe_ZeroCopyAfterProxyProtocol(t *testing.T) {
+	gateway, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gateway.Close()
+
+	received := make(chan string)
+	server, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	go func() {
+		c, err := server.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		data, _ := ioutil.ReadAll(c)
+		received <- string(data)
+	}()
+
+	go func() {
+		client, err := net.Dial("tcp", gateway.Addr().String())
+		if err != nil {
+			return
+		}
+		header, _ := proxyProtocolHeader(2, nil).Format()
+		_, _ = client.Write(append(header, "infrared"...))
+		client.Close()
+	}()
+
+	c, err := gateway.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := wrapConn(c)
+	defer src.Close()
+
+	if _, err := readProxyProtocolHeader(src, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reading the header must not wrap the connection, or it is not spliced
+	if _, ok := rawTCPConn(src); !ok {
+		t.Fatal("got: wrapped connection; want: raw TCP connection")
+	}
+
+	rconn, err := Dialer{}.Dial(server.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, _ = pipe(src, rconn, defaultBufferPool, true)
+	rconn.Close()
+
+	if data := <-received; data != "infrared" {
+		t.Errorf("got: %s; want: %s", data, "infrared")
+	}
+}
+
+func TestPipe_CountsRelayedBytes(t *testing.T) {
+	for _, zeroCopy := range []bool{false, true} {
+		t.Run(fmt.Sprintf("ZeroCopy%v", zeroCopy), func(t *testing.T) {
+			c1, c2 := net.Pipe()
+			src := wrapConn(c2)
+			dst := wrapConn(&discardConn{})
+
+			go func() {
+				_, _ = c1.Write([]byte("infrared"))
+				c1.Close()
+			}()
+
+			if _, err := pipe(src, dst, defaultBufferPool, zeroCopy); err != nil {
+				t.Fatal(err)
+			}
+
+			if read, _ := relayedBytes(src); read != 8 {
+				t.Errorf("got: %d read; want: %d", read, 8)
+			}
+			if _, written := relayedBytes(dst); written != 8 {
+				t.Errorf("got: %d written; want: %d", written, 8)
+			}
+		})
+	}
+}
+
+// discardConn is a net.Conn that discards everything written to it
+type discardConn struct {
+	net.Conn
+}
+
+func (discardConn) Write(b []byte) (int, error) {
+	return len(b), nil
+}
@@ -0,0 +1,85 @@
+package infrared
+
+import "log"
+
+// Reload replaces the registered proxies with the given ones without
+// dropping the connections of players. A proxy that keeps its UID is
+// swapped, so that its open connections stay on the old config and server
+// while new connections get the new ones. Only the listeners that no proxy
+// uses anymore are closed.
+func (gateway *Gateway) Reload(proxies []*Proxy) error {
+	reloaded := map[string]*Proxy{}
+	for _, proxy := range proxies {
+		reloaded[proxy.UID()] = proxy
+	}
+
+	var retired []*Proxy
+	gateway.Proxies.Range(func(k, v interface{}) bool {
+		proxy := v.(*Proxy)
+		successor, ok := reloaded[k.(string)]
+		if !ok {
+			gateway.unregisterProxy(k.(string))
+		}
+		if successor != proxy {
+			retired = append(retired, proxy)
+		}
+		return true
+	})
+
+	var firstErr error
+	for _, proxy := range proxies {
+		if err := gateway.RegisterProxy(proxy); err != nil {
+			log.Printf("Failed registering proxy with UID %s; error: %s", proxy.UID(), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+
+	gateway.closeUnusedListeners()
+
+	// The configs of the retired proxies would otherwise keep reloading
+	// themselves from their files
+	for _, proxy := range retired {
+		if !usesConfig(proxies, proxy.Config) {
+			proxy.Config.stopWatching()
+		}
+	}
+	return firstErr
+}
+
+// closeUnusedListeners closes the listeners on the addresses that no
+// registered proxy listens to
+func (gateway *Gateway) closeUnusedListeners() {
+	used := map[string]bool{}
+	gateway.Proxies.Range(func(k, v interface{}) bool {
+		used[v.(*Proxy).ListenTo()] = true
+		return true
+	})
+
+	gateway.listeners.Range(func(k, v interface{}) bool {
+		if used[k.(string)] {
+			return true
+		}
+		gateway.listeners.Delete(k)
+		_ = v.(Listener).Close()
+		return true
+	})
+}
+
+// stopBackgroundTasks stops everything that the proxy runs next to its
+// connections. The open connections are not affected.
+func (proxy *Proxy) stopBackgroundTasks() {
+	proxy.stopStatusPrewarm()
+	proxy.stopWarmPool()
+	proxy.stopHealthCheck()
+}
+
+func usesConfig(proxies []*Proxy, cfg *ProxyConfig) bool {
+	for _, proxy := range proxies {
+		if proxy.Config == cfg {
+			return true
+		}
+	}
+	return false
+}
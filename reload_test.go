@@ -0,0 +1,64 @@
+package infrared
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestGateway_Reload(t *testing.T) {
+	kept := createBasicProxyConfig("kept.example.com", gatewayAddr(800), serverAddr(800))
+	removed := createBasicProxyConfig("removed.example.com", gatewayAddr(801), serverAddr(801))
+	gateway := Gateway{}
+	if err := gateway.ListenAndServe(configsToProxies([]*ProxyConfig{kept, removed})); err != nil {
+		t.Fatal(err)
+	}
+	defer gateway.Close()
+
+	// An open connection must survive the reload
+	conn, err := net.Dial("tcp", gatewayAddr(800))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	changed := createBasicProxyConfig("kept.example.com", gatewayAddr(800), serverAddr(802))
+	added := createBasicProxyConfig("added.example.com", gatewayAddr(800), serverAddr(803))
+	proxies := configsToProxies([]*ProxyConfig{changed, added})
+	if err := gateway.Reload(proxies); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, proxy := range proxies {
+		v, ok := gateway.Proxies.Load(proxy.UID())
+		if !ok || v.(*Proxy) != proxy {
+			t.Errorf("got: %s not registered; want: registered", proxy.UID())
+		}
+	}
+	if _, ok := gateway.Proxies.Load(proxyUID("removed.example.com", gatewayAddr(801))); ok {
+		t.Error("got: removed proxy registered; want: unregistered")
+	}
+
+	if _, ok := gateway.listeners.Load(gatewayAddr(800)); !ok {
+		t.Errorf("got: listener on %s closed; want: open", gatewayAddr(800))
+	}
+	if _, ok := gateway.listeners.Load(gatewayAddr(801)); ok {
+		t.Errorf("got: listener on %s open; want: closed", gatewayAddr(801))
+	}
+	if c, err := net.Dial("tcp", gatewayAddr(801)); err == nil {
+		c.Close()
+		t.Errorf("got: %s accepts connections; want: refused", gatewayAddr(801))
+	}
+
+	if err := conn.SetReadDeadline(time.Now().Add(50 * time.Millisecond)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Read(make([]byte, 1)); !isTimeout(err) {
+		t.Errorf("got: %v; want: open connection", err)
+	}
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
@@ -0,0 +1,94 @@
+package infrared
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"github.com/gofrs/uuid"
+	"github.com/haveachin/infrared/protocol/status"
+)
+
+// The modes of the player samples of a status
+const (
+	// SampleModeStatic sends the configured player samples as they are
+	SampleModeStatic = "static"
+	// SampleModeRandomUUIDs replaces the UUIDs of the samples in every ping
+	SampleModeRandomUUIDs = "random_uuids"
+	// SampleModeRandom replaces the UUIDs and names of the samples in every ping
+	SampleModeRandom = "random"
+	// SampleModeHidden sends no samples but keeps the online count
+	SampleModeHidden = "hidden"
+)
+
+const (
+	sampleNameChars     = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_"
+	sampleNameMinLength = 3
+	sampleNameMaxLength = 16
+)
+
+// playerSamples returns the player samples of the status after its sample
+// mode. Random samples are generated on every call, so that they are not a
+// stable signature of the server.
+func (cfg StatusConfig) playerSamples() ([]status.PlayerSampleJSON, error) {
+	if cfg.SampleMode == SampleModeHidden {
+		return nil, nil
+	}
+
+	var samples []status.PlayerSampleJSON
+	for _, sample := range cfg.PlayerSamples {
+		sampleJSON := status.PlayerSampleJSON{
+			Name: sample.Name,
+			ID:   sample.UUID,
+		}
+
+		switch cfg.SampleMode {
+		case SampleModeRandom:
+			name, err := randomSampleName()
+			if err != nil {
+				return nil, err
+			}
+			sampleJSON.Name = name
+			fallthrough
+		case SampleModeRandomUUIDs:
+			id, err := uuid.NewV4()
+			if err != nil {
+				return nil, err
+			}
+			sampleJSON.ID = id.String()
+		}
+
+		samples = append(samples, sampleJSON)
+	}
+	return samples, nil
+}
+
+// isRandomSample reports whether the samples change in every ping
+func (cfg StatusConfig) isRandomSample() bool {
+	return cfg.SampleMode == SampleModeRandom || cfg.SampleMode == SampleModeRandomUUIDs
+}
+
+// randomSampleName returns a random name that looks like a username
+func randomSampleName() (string, error) {
+	length, err := randomInt(sampleNameMaxLength - sampleNameMinLength + 1)
+	if err != nil {
+		return "", err
+	}
+
+	name := make([]byte, sampleNameMinLength+length)
+	for i := range name {
+		n, err := randomInt(len(sampleNameChars))
+		if err != nil {
+			return "", err
+		}
+		name[i] = sampleNameChars[n]
+	}
+	return string(name), nil
+}
+
+func randomInt(max int) (int, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, err
+	}
+	return int(n.Int64()), nil
+}
@@ -0,0 +1,110 @@
+package infrared
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/haveachin/infrared/protocol/status"
+)
+
+func statusResponseJSON(t *testing.T, cfg StatusConfig) status.ResponseJSON {
+	pk, err := cfg.StatusResponsePacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := status.UnmarshalClientBoundResponse(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var resJSON status.ResponseJSON
+	if err := json.Unmarshal([]byte(res.JSONResponse), &resJSON); err != nil {
+		t.Fatal(err)
+	}
+	return resJSON
+}
+
+func TestStatusConfig_SampleMode(t *testing.T) {
+	samples := []PlayerSample{
+		{Name: "Steve", UUID: "8667ba71-b85a-4004-af54-457a9734eed7"},
+		{Name: "Alex", UUID: "ec561538-f3fd-461d-aff5-086b22154bce"},
+	}
+
+	tt := []struct {
+		mode         string
+		samples      int
+		staticNames  bool
+		staticUUIDs  bool
+		changeInPing bool
+	}{
+		{
+			mode:        "",
+			samples:     2,
+			staticNames: true,
+			staticUUIDs: true,
+		},
+		{
+			mode:        SampleModeStatic,
+			samples:     2,
+			staticNames: true,
+			staticUUIDs: true,
+		},
+		{
+			mode:         SampleModeRandomUUIDs,
+			samples:      2,
+			staticNames:  true,
+			changeInPing: true,
+		},
+		{
+			mode:         SampleModeRandom,
+			samples:      2,
+			changeInPing: true,
+		},
+		{
+			mode:    SampleModeHidden,
+			samples: 0,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.mode, func(t *testing.T) {
+			cfg := StatusConfig{
+				PlayersOnline: 7,
+				PlayerSamples: samples,
+				SampleMode:    tc.mode,
+			}
+
+			first := statusResponseJSON(t, cfg)
+			second := statusResponseJSON(t, cfg)
+
+			if first.Players.Online != 7 {
+				t.Errorf("got: %d online; want: %d", first.Players.Online, 7)
+			}
+			if len(first.Players.Sample) != tc.samples {
+				t.Fatalf("got: %d samples; want: %d", len(first.Players.Sample), tc.samples)
+			}
+
+			for i, sample := range first.Players.Sample {
+				if tc.staticNames != (sample.Name == samples[i].Name) {
+					t.Errorf("got name: %s; want static: %v", sample.Name, tc.staticNames)
+				}
+				if tc.staticUUIDs != (sample.ID == samples[i].UUID) {
+					t.Errorf("got UUID: %s; want static: %v", sample.ID, tc.staticUUIDs)
+				}
+				if _, err := uuid.FromString(sample.ID); err != nil {
+					t.Errorf("got invalid UUID: %s", sample.ID)
+				}
+				if !isValidUsername(sample.Name, defaultMaxUsernameLength, false) {
+					t.Errorf("got invalid name: %s", sample.Name)
+				}
+
+				changed := sample.ID != second.Players.Sample[i].ID
+				if changed != tc.changeInPing {
+					t.Errorf("got changed: %v; want: %v", changed, tc.changeInPing)
+				}
+			}
+		})
+	}
+}
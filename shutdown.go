@@ -0,0 +1,64 @@
+package infrared
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// shutdownPollInterval is how often Shutdown checks if all connections
+// are closed
+const shutdownPollInterval = 100 * time.Millisecond
+
+// Shutdown closes all listeners, so that no new connections are accepted,
+// and then waits until all active connections are closed or ctx is done.
+// Every proxy that is registered after the start of the shutdown fails to
+// open a new listener. If connections are still open when ctx is done, the
+// error of ctx is returned. The Prometheus endpoint is closed last, so
+// that its readiness probe reports the shutdown while the connections drain.
+func (gateway *Gateway) Shutdown(ctx context.Context) error {
+	// Keep KeepProcessActive from returning before the connections drained
+	gateway.wg.Add(1)
+	defer gateway.wg.Done()
+	defer gateway.shutdownMetrics(ctx)
+
+	atomic.StoreInt32(&gateway.shuttingDown, 1)
+	gateway.listeners.Range(func(k, v interface{}) bool {
+		_ = v.(Listener).Close()
+		return true
+	})
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for {
+		active := atomic.LoadInt64(&gateway.activeConns)
+		if active <= 0 {
+			log.Println("[i] All connections are closed")
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Printf("[w] Shutting down with %d open connections; error: %s", active, ctx.Err())
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// shutdownMetrics stops the Prometheus endpoint. Requests that are still
+// open when ctx is done are closed.
+func (gateway *Gateway) shutdownMetrics(ctx context.Context) {
+	gateway.metricsMu.Lock()
+	server := gateway.metricsServer
+	gateway.metricsServer = nil
+	gateway.metricsMu.Unlock()
+	if server == nil {
+		return
+	}
+
+	if err := server.Shutdown(ctx); err != nil {
+		_ = server.Close()
+	}
+}
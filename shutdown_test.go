@@ -0,0 +1,123 @@
+package infrared
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// dialActive opens a connection to addr and waits until the gateway
+// accepted it
+func dialActive(t *testing.T, gateway *Gateway, addr string) net.Conn {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; atomic.LoadInt64(&gateway.activeConns) == 0; i++ {
+		if i == 100 {
+			t.Fatal("got: no active connection; want: active connection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return conn
+}
+
+func TestGateway_Shutdown(t *testing.T) {
+	cfg := createBasicProxyConfig("mc.example.com", gatewayAddr(810), serverAddr(810))
+	gateway := Gateway{}
+	if err := gateway.ListenAndServe(configsToProxies([]*ProxyConfig{cfg})); err != nil {
+		t.Fatal(err)
+	}
+
+	conn := dialActive(t, &gateway, gatewayAddr(810))
+	defer conn.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- gateway.Shutdown(context.Background())
+	}()
+
+	// New connections are refused while the open one drains
+	time.Sleep(50 * time.Millisecond)
+	if c, err := net.Dial("tcp", gatewayAddr(810)); err == nil {
+		c.Close()
+		t.Errorf("got: %s accepts connections; want: refused", gatewayAddr(810))
+	}
+
+	select {
+	case err := <-errCh:
+		t.Fatalf("got: shut down with %v; want: waiting for the open connection", err)
+	default:
+	}
+
+	conn.Close()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Errorf("got: %v; want: nil", err)
+		}
+	case <-time.After(time.Second):
+		t.Error("got: still waiting; want: shut down")
+	}
+}
+
+func TestGateway_Shutdown_Timeout(t *testing.T) {
+	cfg := createBasicProxyConfig("mc.example.com", gatewayAddr(811), serverAddr(811))
+	gateway := Gateway{}
+	if err := gateway.ListenAndServe(configsToProxies([]*ProxyConfig{cfg})); err != nil {
+		t.Fatal(err)
+	}
+
+	conn := dialActive(t, &gateway, gatewayAddr(811))
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := gateway.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("got: %v; want: %v", err, context.DeadlineExceeded)
+	}
+
+	if err := gateway.RegisterProxy(&Proxy{Config: createBasicProxyConfig("mc.example.com", gatewayAddr(812), serverAddr(812))}); err == nil {
+		t.Error("got: registered listener; want: error")
+	}
+}
+
+func TestGateway_Shutdown_Prometheus(t *testing.T) {
+	// Reserve an address for the metrics endpoint
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+
+	gateway := Gateway{}
+	if err := gateway.EnablePrometheus(addr); err == nil {
+		t.Error("got: enabled on a bound address; want: error")
+	}
+	l.Close()
+
+	if err := gateway.EnablePrometheus(addr); err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := http.Get("http://" + addr + DefaultHealthPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("got: %d; want: %d", response.StatusCode, http.StatusOK)
+	}
+
+	if err := gateway.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := net.Dial("tcp", addr); err == nil {
+		t.Error("got: metrics endpoint open after the shutdown; want: closed")
+	}
+}
@@ -0,0 +1,116 @@
+package infrared
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// srvScheme marks a server address as the name of an SRV record,
+	// e.g. srv://_minecraft._tcp.example.com
+	srvScheme = "srv://"
+	// srvResolveInterval is the time that the targets of an SRV record are
+	// used before it is resolved again. The resolver of Go doesn't expose
+	// the TTLs of records, so a short interval keeps up with their changes.
+	srvResolveInterval = 30 * time.Second
+)
+
+// srvName returns the name of the SRV record of addr, if it is one
+func srvName(addr string) (string, bool) {
+	if !strings.HasPrefix(addr, srvScheme) {
+		return "", false
+	}
+	return strings.TrimPrefix(addr, srvScheme), true
+}
+
+// srvResolver resolves SRV records to the addresses of their targets and
+// caches them for srvResolveInterval. It is safe for concurrent use.
+type srvResolver struct {
+	mu      sync.Mutex
+	entries map[string]srvEntry
+	// lookup defaults to the resolver of the system
+	lookup func(name string) ([]*net.SRV, error)
+}
+
+type srvEntry struct {
+	targets    []string
+	resolvedAt time.Time
+}
+
+// Resolve returns the addresses of the targets of the SRV record in the
+// order of their priority and weight
+func (r *srvResolver) Resolve(name string) ([]string, error) {
+	r.mu.Lock()
+	entry, ok := r.entries[name]
+	r.mu.Unlock()
+	if ok && time.Since(entry.resolvedAt) < srvResolveInterval {
+		return entry.targets, nil
+	}
+
+	lookup := r.lookup
+	if lookup == nil {
+		lookup = lookupSRV
+	}
+
+	records, err := lookup(name)
+	if err == nil && len(records) == 0 {
+		err = fmt.Errorf("no targets in SRV record %s", name)
+	}
+	if err != nil {
+		if ok {
+			// The last targets are better than none while DNS fails
+			return entry.targets, nil
+		}
+		return nil, err
+	}
+
+	targets := make([]string, 0, len(records))
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+		targets = append(targets, net.JoinHostPort(host, strconv.Itoa(int(record.Port))))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.entries == nil {
+		r.entries = map[string]srvEntry{}
+	}
+	r.entries[name] = srvEntry{
+		targets:    targets,
+		resolvedAt: time.Now(),
+	}
+	return targets, nil
+}
+
+func lookupSRV(name string) ([]*net.SRV, error) {
+	// An empty service and proto look up the name as it is
+	_, records, err := net.LookupSRV("", "", name)
+	return records, err
+}
+
+// dialSRV dials the targets of the SRV record in order until one of them
+// answers
+func (d Dialer) dialSRV(network, name string) (Conn, error) {
+	resolver := d.srv
+	if resolver == nil {
+		resolver = &srvResolver{}
+	}
+
+	targets, err := resolver.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, target := range targets {
+		var conn net.Conn
+		conn, err = d.Dialer.Dial(network, target)
+		if err == nil {
+			return wrapConn(conn), nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,114 @@
+package infrared
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSrvName(t *testing.T) {
+	tt := []struct {
+		addr  string
+		name  string
+		isSRV bool
+	}{
+		{addr: "srv://_minecraft._tcp.example.com", name: "_minecraft._tcp.example.com", isSRV: true},
+		{addr: "mc.example.com:25565"},
+		{addr: ":25565"},
+	}
+
+	for _, tc := range tt {
+		name, isSRV := srvName(tc.addr)
+		if name != tc.name || isSRV != tc.isSRV {
+			t.Errorf("%s: got: %q, %v; want: %q, %v", tc.addr, name, isSRV, tc.name, tc.isSRV)
+		}
+	}
+}
+
+func TestSrvResolver_Resolve(t *testing.T) {
+	lookups := 0
+	var lookupErr error
+	resolver := srvResolver{
+		lookup: func(name string) ([]*net.SRV, error) {
+			lookups++
+			if lookupErr != nil {
+				return nil, lookupErr
+			}
+			return []*net.SRV{
+				{Target: "mc1.example.com.", Port: 25565},
+				{Target: "mc2.example.com.", Port: 25566},
+			}, nil
+		},
+	}
+
+	want := []string{"mc1.example.com:25565", "mc2.example.com:25566"}
+	for i := 0; i < 2; i++ {
+		targets, err := resolver.Resolve("_minecraft._tcp.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(targets) != len(want) || targets[0] != want[0] || targets[1] != want[1] {
+			t.Errorf("got: %v; want: %v", targets, want)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("got: %d lookups; want: 1", lookups)
+	}
+
+	// Expire the targets, so that the record is resolved again
+	entry := resolver.entries["_minecraft._tcp.example.com"]
+	entry.resolvedAt = time.Now().Add(-srvResolveInterval)
+	resolver.entries["_minecraft._tcp.example.com"] = entry
+	lookupErr = errors.New("dns failed")
+
+	targets, err := resolver.Resolve("_minecraft._tcp.example.com")
+	if err != nil {
+		t.Errorf("got: %v; want: the last targets", err)
+	}
+	if lookups != 2 || len(targets) != len(want) {
+		t.Errorf("got: %d lookups and %v; want: 2 lookups and %v", lookups, targets, want)
+	}
+
+	if _, err := resolver.Resolve("_minecraft._tcp.unknown.example.com"); err == nil {
+		t.Error("got: nil; want: error")
+	}
+}
+
+func TestDialer_DialSRV(t *testing.T) {
+	server, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	// The first target refuses connections, so the second one is dialed
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedAddr := closed.Addr().(*net.TCPAddr)
+	closed.Close()
+	serverAddr := server.Addr().(*net.TCPAddr)
+
+	dialer := Dialer{
+		srv: &srvResolver{
+			lookup: func(name string) ([]*net.SRV, error) {
+				return []*net.SRV{
+					{Target: "127.0.0.1.", Port: uint16(closedAddr.Port)},
+					{Target: "127.0.0.1.", Port: uint16(serverAddr.Port)},
+				}, nil
+			},
+		},
+	}
+
+	rconn, err := dialer.Dial("srv://_minecraft._tcp.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rconn.Close()
+
+	if got := rconn.RemoteAddr().String(); got != serverAddr.String() {
+		t.Errorf("got: %s; want: %s", got, serverAddr)
+	}
+}
@@ -0,0 +1,170 @@
+package infrared
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// statsdRecorder sends metrics in the StatsD line format over UDP.
+// If dogStatsD is set, labels are sent as DogStatsD tags, otherwise
+// they are appended to the metric name.
+type statsdRecorder struct {
+	conn      net.Conn
+	prefix    string
+	dogStatsD bool
+}
+
+func newStatsdRecorder(addr, prefix string, dogStatsD bool) (*statsdRecorder, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &statsdRecorder{
+		conn:      conn,
+		prefix:    strings.TrimSuffix(prefix, "."),
+		dogStatsD: dogStatsD,
+	}, nil
+}
+
+// label is a key value pair that is attached to a metric
+type label struct {
+	key   string
+	value string
+}
+
+func (r *statsdRecorder) send(name, value, metricType string, labels ...label) {
+	if r.prefix != "" {
+		name = r.prefix + "." + name
+	}
+
+	var tags []string
+	for _, l := range labels {
+		if r.dogStatsD {
+			tags = append(tags, l.key+":"+sanitizeStatsdValue(l.value))
+			continue
+		}
+		name += "." + sanitizeStatsdValue(l.value)
+	}
+
+	line := fmt.Sprintf("%s:%s|%s", name, value, metricType)
+	if len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+
+	// StatsD is fire and forget; a lost metric is not worth an error
+	_, _ = r.conn.Write([]byte(line))
+}
+
+// sanitizeStatsdValue replaces the characters that have a meaning in the
+// StatsD line format
+func sanitizeStatsdValue(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return strings.NewReplacer(".", "_", ":", "_", "|", "_", "#", "_", ",", "_", "@", "_").Replace(value)
+}
+
+func gaugeDelta(delta int) string {
+	if delta < 0 {
+		return fmt.Sprintf("%d", delta)
+	}
+	return fmt.Sprintf("+%d", delta)
+}
+
+func (r *statsdRecorder) AddProxies(delta int) {
+	r.send("proxies", gaugeDelta(delta), "g")
+}
+
+func (r *statsdRecorder) AddListeners(delta int) {
+	r.send("listeners_active", gaugeDelta(delta), "g")
+}
+
+func (r *statsdRecorder) AddConnectedPlayers(host, transport string, delta int) {
+	r.send("connected", gaugeDelta(delta), "g", label{"host", host}, label{"transport", transport})
+}
+
+func (r *statsdRecorder) IncHandshakes(host, handshakeType, transport string) {
+	r.send("handshakes", "1", "c", label{"host", host}, label{"type", handshakeType}, label{"transport", transport})
+}
+
+func (r *statsdRecorder) IncDialErrors(host string) {
+	r.send("dial_errors", "1", "c", label{"host", host})
+}
+
+func (r *statsdRecorder) IncBackendLoginCloses(host string) {
+	r.send("backend_login_closes", "1", "c", label{"host", host})
+}
+
+func (r *statsdRecorder) SetStatusBreakerOpen(host string, open bool) {
+	value := "0"
+	if open {
+		value = "1"
+	}
+	r.send("status_breaker_open", value, "g", label{"host", host})
+}
+
+func (r *statsdRecorder) SetLoginBreakerOpen(host string, open bool) {
+	value := "0"
+	if open {
+		value = "1"
+	}
+	r.send("login_breaker_open", value, "g", label{"host", host})
+}
+
+func (r *statsdRecorder) SetBackendHealthy(host, backend string, healthy bool) {
+	value := "0"
+	if healthy {
+		value = "1"
+	}
+	r.send("backend_healthy", value, "g", label{"host", host}, label{"backend", backend})
+}
+
+func (r *statsdRecorder) IncProxyProtocolErrors(listener, reason string) {
+	r.send("proxy_protocol_errors", "1", "c", label{"listener", listener}, label{"reason", reason})
+}
+
+func (r *statsdRecorder) IncInvalidUsernames(host string) {
+	r.send("invalid_usernames", "1", "c", label{"host", host})
+}
+
+func (r *statsdRecorder) AddRelayedBytes(host string, sent, received int64) {
+	r.send("relayed_bytes", fmt.Sprintf("%d", sent), "c", label{"host", host}, label{"direction", "sent"})
+	r.send("relayed_bytes", fmt.Sprintf("%d", received), "c", label{"host", host}, label{"direction", "received"})
+	r.send("bytes_transmitted", fmt.Sprintf("%d", sent), "c", label{"host", host})
+	r.send("bytes_received", fmt.Sprintf("%d", received), "c", label{"host", host})
+}
+
+func (r *statsdRecorder) IncClientLocales(host, locale string) {
+	r.send("client_locales", "1", "c", label{"host", host}, label{"locale", locale})
+}
+
+func (r *statsdRecorder) IncShedStatusRequests(host string) {
+	r.send("shed_status_requests", "1", "c", label{"host", host})
+}
+
+func (r *statsdRecorder) IncRateLimitedStatusRequests(host string) {
+	r.send("rate_limited_status_requests", "1", "c", label{"host", host})
+}
+
+func (r *statsdRecorder) IncOverloadRefusals(listener string) {
+	r.send("overload_refusals", "1", "c", label{"listener", listener})
+}
+
+func (r *statsdRecorder) IncRateLimitedConnections(listener string) {
+	r.send("rate_limited_connections", "1", "c", label{"listener", listener})
+}
+
+func (r *statsdRecorder) IncStrictProtocolViolations(host, reason string) {
+	r.send("strict_protocol_violations", "1", "c", label{"host", host}, label{"reason", reason})
+}
+
+func (r *statsdRecorder) IncHandshakePortMismatches(host string) {
+	r.send("handshake_port_mismatches", "1", "c", label{"host", host})
+}
+
+func (r *statsdRecorder) ObserveConnectionDuration(host string, duration time.Duration) {
+	r.send("connection_duration", fmt.Sprintf("%d", duration.Milliseconds()), "ms", label{"host", host})
+}
@@ -0,0 +1,98 @@
+package infrared
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStatsdRecorder_Send(t *testing.T) {
+	tt := []struct {
+		name      string
+		dogStatsD bool
+		send      func(r *statsdRecorder)
+		line      string
+	}{
+		{
+			name: "Gauge",
+			send: func(r *statsdRecorder) { r.AddProxies(-1) },
+			line: "infrared.proxies:-1|g",
+		},
+		{
+			name: "LabelInName",
+			send: func(r *statsdRecorder) { r.AddConnectedPlayers("mc.example.com", TransportTCP, 1) },
+			line: "infrared.connected.mc_example_com.tcp:+1|g",
+		},
+		{
+			name:      "DogStatsDTags",
+			dogStatsD: true,
+			send:      func(r *statsdRecorder) { r.IncHandshakes("mc.example.com", "login", TransportTCP) },
+			line:      "infrared.handshakes:1|c|#host:mc_example_com,type:login,transport:tcp",
+		},
+		{
+			name: "Timer",
+			send: func(r *statsdRecorder) { r.ObserveConnectionDuration("mc.example.com", 90*time.Second) },
+			line: "infrared.connection_duration.mc_example_com:90000|ms",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			server, err := net.ListenPacket("udp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer server.Close()
+
+			recorder, err := newStatsdRecorder(server.LocalAddr().String(), "infrared.", tc.dogStatsD)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tc.send(recorder)
+
+			buf := make([]byte, 512)
+			n, _, err := server.ReadFrom(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(buf[:n]) != tc.line {
+				t.Errorf("got: %s; want: %s", buf[:n], tc.line)
+			}
+		})
+	}
+}
+
+func TestStatsdRecorder_AddRelayedBytes(t *testing.T) {
+	server, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	recorder, err := newStatsdRecorder(server.LocalAddr().String(), "infrared.", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder.AddRelayedBytes("mc.example.com", 17, 15)
+
+	want := []string{
+		"infrared.relayed_bytes.mc_example_com.sent:17|c",
+		"infrared.relayed_bytes.mc_example_com.received:15|c",
+		"infrared.bytes_transmitted.mc_example_com:17|c",
+		"infrared.bytes_received.mc_example_com:15|c",
+	}
+	buf := make([]byte, 512)
+	for _, line := range want {
+		if err := server.SetReadDeadline(time.Now().Add(time.Second)); err != nil {
+			t.Fatal(err)
+		}
+		n, _, err := server.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf[:n]) != line {
+			t.Errorf("got: %s; want: %s", buf[:n], line)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package infrared
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+const (
+	StatusAdmissionActionMinimal = "minimal"
+	StatusAdmissionActionDrop    = "drop"
+
+	defaultStatusAdmissionWindow     = 600000
+	defaultStatusAdmissionMaxEntries = 10000
+)
+
+func (proxy *Proxy) StatusAdmission() StatusAdmissionConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.StatusAdmission
+}
+
+// shedStatus reports whether the status request should be shed instead of
+// being answered fully. Clients that logged in or got a full status within
+// the window are known and always answered. Unknown clients are answered
+// up to the new IP rate per second, the rest is shed.
+func (proxy *Proxy) shedStatus(hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr) bool {
+	cfg := proxy.StatusAdmission()
+	if !cfg.IsEnabled() {
+		return false
+	}
+
+	known := proxy.knownClients(cfg.MaxEntries)
+	ip := remoteIP(connRemoteAddr)
+	if hs.IsLoginRequest() {
+		known.Set(ip, true, cfg.WindowDuration())
+		return false
+	}
+
+	if !hs.IsStatusRequest() {
+		return false
+	}
+
+	if _, ok := known.Get(ip); ok {
+		return false
+	}
+
+	if !proxy.newIPs.Allow(cfg.NewIPRate) {
+		metrics.IncShedStatusRequests(proxy.DomainName())
+		return true
+	}
+
+	known.Set(ip, true, cfg.WindowDuration())
+	return false
+}
+
+func (proxy *Proxy) knownClients(maxEntries int) *ttlCache {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.known == nil {
+		if maxEntries <= 0 {
+			maxEntries = defaultStatusAdmissionMaxEntries
+		}
+		proxy.known = newTTLCache(maxEntries)
+	}
+	return proxy.known
+}
+
+// handleShedStatus answers a shed status request with the offline status,
+// which needs no dial, or closes the connection right away
+func (proxy *Proxy) handleShedStatus(conn Conn) error {
+	if proxy.StatusAdmission().Action == StatusAdmissionActionDrop {
+		return nil
+	}
+	return proxy.handleStatusRequest(conn, false)
+}
+
+// rateWindow counts events in windows of one second.
+// It is safe for concurrent use.
+type rateWindow struct {
+	mu    sync.Mutex
+	start time.Time
+	count int
+}
+
+// Allow counts an event and reports whether it is within the rate of the
+// current window
+func (w *rateWindow) Allow(rate int) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(w.start) >= time.Second {
+		w.start = now
+		w.count = 0
+	}
+
+	if w.count >= rate {
+		return false
+	}
+	w.count++
+	return true
+}
@@ -0,0 +1,56 @@
+package infrared
+
+import (
+	"net"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+func TestProxy_ShedStatus(t *testing.T) {
+	status := handshaking.ServerBoundHandshake{NextState: handshaking.ServerBoundHandshakeStatusState}
+	login := handshaking.ServerBoundHandshake{NextState: handshaking.ServerBoundHandshakeLoginState}
+	addr := func(ip string) net.Addr {
+		return &net.TCPAddr{IP: net.ParseIP(ip), Port: 50000}
+	}
+
+	proxy := &Proxy{Config: &ProxyConfig{
+		StatusAdmission: StatusAdmissionConfig{NewIPRate: 2},
+	}}
+
+	// A player logged in before the flood
+	if proxy.shedStatus(login, addr("10.0.0.1")) {
+		t.Error("got: shed login; want: not shed")
+	}
+
+	tt := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "10.0.0.2", want: false},
+		{ip: "10.0.0.3", want: false},
+		// The new IP rate is used up
+		{ip: "10.0.0.4", want: true},
+		{ip: "10.0.0.5", want: true},
+		// Known IPs are always answered
+		{ip: "10.0.0.1", want: false},
+		{ip: "10.0.0.2", want: false},
+	}
+
+	for _, tc := range tt {
+		if got := proxy.shedStatus(status, addr(tc.ip)); got != tc.want {
+			t.Errorf("%s: got: %v; want: %v", tc.ip, got, tc.want)
+		}
+	}
+}
+
+func TestProxy_ShedStatus_Disabled(t *testing.T) {
+	status := handshaking.ServerBoundHandshake{NextState: handshaking.ServerBoundHandshakeStatusState}
+	proxy := &Proxy{Config: &ProxyConfig{}}
+
+	for i := 0; i < 100; i++ {
+		if proxy.shedStatus(status, &net.TCPAddr{IP: net.IPv4(10, 0, 0, byte(i))}) {
+			t.Fatal("got: shed; want: not shed")
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package infrared
+
+import (
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+	"github.com/haveachin/infrared/protocol/status"
+	"github.com/pires/go-proxyproto"
+)
+
+const (
+	// statusCacheMaxEntries bounds the cache to the servers of the proxy
+	// and its subdomain routes
+	statusCacheMaxEntries = 64
+	statusFetchTimeout    = 5 * time.Second
+	// prewarmProtocolVersion is the version that clients send if they ping
+	// to determine which version to use
+	prewarmProtocolVersion = -1
+	// defaultStatusRefreshInterval is how often a proxy checks if it should
+	// prewarm its status while its status cache is disabled
+	defaultStatusRefreshInterval = 30000
+)
+
+func (proxy *Proxy) StatusCache() StatusCacheConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.StatusCache
+}
+
+// usesStatusCache reports whether status requests are answered from the
+// status cache instead of being passed through to the server
+func (proxy *Proxy) usesStatusCache() bool {
+	return proxy.StatusCache().IsEnabled() && !proxy.IsOnlineStatusConfigured()
+}
+
+func (proxy *Proxy) statusCache() *ttlCache {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.cachedStatus == nil {
+		proxy.cachedStatus = newTTLCache(statusCacheMaxEntries)
+	}
+	return proxy.cachedStatus
+}
+
+// handleCachedStatusRequest answers a status request with the cached status
+// of the server. If there is none, the status is fetched from the server
+// and cached. The status of every subdomain route is cached separately.
+func (proxy *Proxy) handleCachedStatusRequest(conn Conn, hs handshaking.ServerBoundHandshake, route string, backends []string, connRemoteAddr net.Addr) error {
+	cache := proxy.statusCache()
+	if v, ok := cache.Get(route); ok {
+		return writeStatus(conn, v.(protocol.Packet), proxy.StrictProtocol())
+	}
+
+	if !proxy.allowStatusDial() {
+		return proxy.handleStatusRequest(conn, false)
+	}
+
+	responsePk, err := proxy.fetchStatusOnce(route, func() (protocol.Packet, error) {
+		responsePk, err := proxy.fetchStatus(backends, hs, connRemoteAddr)
+		proxy.recordDial(err)
+		if err != nil {
+			metrics.IncDialErrors(proxy.DomainName())
+			log.Printf("[i] %s did not respond to ping; is the target offline?", backends[len(backends)-1])
+			return responsePk, err
+		}
+
+		cache.Set(route, responsePk, proxy.StatusCache().TTLDuration())
+		return responsePk, nil
+	})
+	if err != nil {
+		return proxy.handleStatusRequest(conn, false)
+	}
+	return writeStatus(conn, responsePk, proxy.StrictProtocol())
+}
+
+// statusFetch is a running fetch of the status of a route
+type statusFetch struct {
+	done chan struct{}
+	pk   protocol.Packet
+	err  error
+}
+
+// fetchStatusOnce calls fetch for the route unless a fetch of the route is
+// already running. Then it waits for that one and returns its result, so
+// that a burst of pings on a cold cache dials the server only once.
+func (proxy *Proxy) fetchStatusOnce(route string, fetch func() (protocol.Packet, error)) (protocol.Packet, error) {
+	proxy.mu.Lock()
+	if f, ok := proxy.statusFetches[route]; ok {
+		proxy.mu.Unlock()
+		<-f.done
+		return f.pk, f.err
+	}
+
+	f := &statusFetch{done: make(chan struct{})}
+	if proxy.statusFetches == nil {
+		proxy.statusFetches = map[string]*statusFetch{}
+	}
+	proxy.statusFetches[route] = f
+	proxy.mu.Unlock()
+
+	f.pk, f.err = fetch()
+
+	proxy.mu.Lock()
+	delete(proxy.statusFetches, route)
+	proxy.mu.Unlock()
+	close(f.done)
+	return f.pk, f.err
+}
+
+// fetchStatus does a status request to the first of the backends that
+// answers and returns its status response. connRemoteAddr is forwarded to
+// the server if the proxy uses the PROXY or RealIP protocol; nil forwards
+// the address of Infrared.
+func (proxy *Proxy) fetchStatus(backends []string, hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr) (protocol.Packet, error) {
+	rconn, _, err := proxy.dialBackend(backends)
+	if err != nil {
+		return protocol.Packet{}, err
+	}
+	defer rconn.Close()
+
+	return proxy.requestStatus(rconn, hs, connRemoteAddr)
+}
+
+// requestStatus does a status request on the connection to the server and
+// returns its status response
+func (proxy *Proxy) requestStatus(rconn Conn, hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr) (protocol.Packet, error) {
+	if err := rconn.SetDeadline(time.Now().Add(statusFetchTimeout)); err != nil {
+		return protocol.Packet{}, err
+	}
+
+	if connRemoteAddr == nil {
+		connRemoteAddr = rconn.LocalAddr()
+	}
+
+	forwarding := proxy.Forwarding()
+	if forwarding.ProxyProtocol {
+		header := &proxyproto.Header{
+			Version:           2,
+			Command:           proxyproto.PROXY,
+			TransportProtocol: proxyproto.TCPv4,
+			SourceAddr:        connRemoteAddr,
+			DestinationAddr:   rconn.RemoteAddr(),
+		}
+
+		if _, err := header.WriteTo(rconn); err != nil {
+			return protocol.Packet{}, err
+		}
+	}
+
+	if forwarding.RealIP {
+		hs.UpgradeToRealIP(connRemoteAddr, time.Now())
+	}
+
+	if err := rconn.WritePacket(hs.Marshal()); err != nil {
+		return protocol.Packet{}, err
+	}
+
+	if err := rconn.WritePacket(status.ServerBoundRequest{}.Marshal()); err != nil {
+		return protocol.Packet{}, err
+	}
+
+	responsePk, err := rconn.ReadPacket()
+	if err != nil {
+		return protocol.Packet{}, err
+	}
+
+	if _, err := status.UnmarshalClientBoundResponse(responsePk); err != nil {
+		return protocol.Packet{}, err
+	}
+	return responsePk, nil
+}
+
+// prewarmHandshake returns the handshake that is used to fetch the status
+// of the server without a client
+func (proxy *Proxy) prewarmHandshake() handshaking.ServerBoundHandshake {
+	host := proxy.DomainName()
+	if domain, _, err := net.SplitHostPort(host); err == nil {
+		host = domain
+	}
+
+	var port int
+	if _, portString, err := net.SplitHostPort(proxy.Backends()[0]); err == nil {
+		port, _ = strconv.Atoi(portString)
+	}
+
+	return handshaking.ServerBoundHandshake{
+		ProtocolVersion: prewarmProtocolVersion,
+		ServerAddress:   protocol.String(host),
+		ServerPort:      protocol.UnsignedShort(port),
+		NextState:       handshaking.ServerBoundHandshakeStatusState,
+	}
+}
+
+// prewarmStatus fetches the status of the server and caches it, if the
+// proxy is configured to prewarm its status cache
+func (proxy *Proxy) prewarmStatus() {
+	cfg := proxy.StatusCache()
+	if !cfg.Prewarm || !proxy.usesStatusCache() {
+		return
+	}
+
+	// Spare servers that are known to be down
+	if !proxy.allowStatusDial() {
+		return
+	}
+
+	hs := proxy.prewarmHandshake()
+	backends, _ := proxy.backendsFor(hs, nil, login.ServerLoginStart{})
+	responsePk, err := proxy.fetchStatus(backends, hs, nil)
+	proxy.recordDial(err)
+	if err != nil {
+		log.Printf("[w] Failed to prewarm the status of %s; error: %s", proxy.UID(), err)
+		return
+	}
+
+	proxy.statusCache().Set("", responsePk, cfg.TTLDuration())
+}
+
+// startStatusPrewarm prewarms the status cache right away and then
+// refreshes it in every refresh interval until stopStatusPrewarm is called.
+// The config is read on every refresh, so that changes apply without
+// restarting the proxy.
+func (proxy *Proxy) startStatusPrewarm() {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.stopPrewarm != nil {
+		return
+	}
+
+	stop := make(chan struct{})
+	proxy.stopPrewarm = stop
+	go func() {
+		for {
+			proxy.prewarmStatus()
+
+			select {
+			case <-stop:
+				return
+			case <-time.After(proxy.StatusCache().RefreshDuration()):
+			}
+		}
+	}()
+}
+
+func (proxy *Proxy) stopStatusPrewarm() {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.stopPrewarm == nil {
+		return
+	}
+
+	close(proxy.stopPrewarm)
+	proxy.stopPrewarm = nil
+}
@@ -0,0 +1,229 @@
+package infrared
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/status"
+)
+
+// countingStatusServer answers every status request with the status of
+// name and counts the requests
+func countingStatusServer(t *testing.T, name string) (string, *int32) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	pk, err := statusPKWithVersion(name).StatusResponsePacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var requests int32
+	go func() {
+		for {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				conn := wrapConn(c)
+				defer conn.Close()
+				// Handshake and status request
+				for i := 0; i < 2; i++ {
+					if _, err := conn.ReadPacket(); err != nil {
+						return
+					}
+				}
+				atomic.AddInt32(&requests, 1)
+				_ = conn.WritePacket(pk)
+			}()
+		}
+	}()
+
+	return listener.Addr().String(), &requests
+}
+
+func requestCachedStatus(t *testing.T, proxy *Proxy) string {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	hs := handshaking.ServerBoundHandshake{
+		ProtocolVersion: 754,
+		ServerAddress:   "localhost",
+		NextState:       handshaking.ServerBoundHandshakeStatusState,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- proxy.handleCachedStatusRequest(wrapConn(c2), hs, "", proxy.Backends(), c2.RemoteAddr())
+	}()
+
+	client := wrapConn(c1)
+	if err := client.WritePacket(status.ServerBoundRequest{}.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+	pk, err := client.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.WritePacket(protocol.Packet{ID: 0x01, Data: []byte{0}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ReadPacket(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := status.UnmarshalClientBoundResponse(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resJSON := &status.ResponseJSON{}
+	if err := json.Unmarshal([]byte(res.JSONResponse), resJSON); err != nil {
+		t.Fatal(err)
+	}
+	return resJSON.Version.Name
+}
+
+func TestProxy_HandleCachedStatusRequest(t *testing.T) {
+	addr, requests := countingStatusServer(t, "cached")
+	proxy := &Proxy{
+		Config: &ProxyConfig{
+			ProxyTo:     addr,
+			StatusCache: StatusCacheConfig{TTL: 60000},
+			OfflineStatus: StatusConfig{
+				VersionName: "offline",
+			},
+		},
+	}
+
+	for i := 0; i < 3; i++ {
+		if got := requestCachedStatus(t, proxy); got != "cached" {
+			t.Errorf("got: %v; want: %v", got, "cached")
+		}
+	}
+
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Errorf("got: %d requests; want: %d", got, 1)
+	}
+}
+
+func TestProxy_FetchStatusOnce(t *testing.T) {
+	proxy := &Proxy{Config: &ProxyConfig{}}
+	want := protocol.Packet{ID: 0x00, Data: []byte{0x01}}
+
+	var fetches int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	fetch := func() (protocol.Packet, error) {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			close(started)
+		}
+		<-release
+		return want, nil
+	}
+
+	const pings = 10
+	results := make(chan protocol.Packet, pings)
+	go func() {
+		pk, _ := proxy.fetchStatusOnce("", fetch)
+		results <- pk
+	}()
+	<-started
+
+	for i := 1; i < pings; i++ {
+		go func() {
+			pk, _ := proxy.fetchStatusOnce("", fetch)
+			results <- pk
+		}()
+	}
+	// Give the other pings the time to wait for the running fetch, even
+	// when the test runs next to the others under load
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+
+	for i := 0; i < pings; i++ {
+		if pk := <-results; pk.ID != want.ID || !bytes.Equal(pk.Data, want.Data) {
+			t.Errorf("got: %v; want: %v", pk, want)
+		}
+	}
+	if got := atomic.LoadInt32(&fetches); got != 1 {
+		t.Errorf("got: %d fetches; want: %d", got, 1)
+	}
+}
+
+func TestProxy_PrewarmStatus(t *testing.T) {
+	addr, requests := countingStatusServer(t, "prewarmed")
+	proxy := &Proxy{
+		Config: &ProxyConfig{
+			DomainName: "localhost",
+			ProxyTo:    addr,
+			StatusCache: StatusCacheConfig{
+				TTL:     60000,
+				Prewarm: true,
+			},
+		},
+	}
+
+	proxy.prewarmStatus()
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Fatalf("got: %d requests; want: %d", got, 1)
+	}
+
+	if got := requestCachedStatus(t, proxy); got != "prewarmed" {
+		t.Errorf("got: %v; want: %v", got, "prewarmed")
+	}
+	if got := atomic.LoadInt32(requests); got != 1 {
+		t.Errorf("got: %d requests; want: %d", got, 1)
+	}
+}
+
+func TestProxy_PrewarmStatus_BreakerOpen(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nothing listens on the address anymore
+	addr := listener.Addr().String()
+	listener.Close()
+
+	proxy := &Proxy{
+		Config: &ProxyConfig{
+			ProxyTo: addr,
+			StatusCache: StatusCacheConfig{
+				TTL:     60000,
+				Prewarm: true,
+			},
+			StatusBreaker: BreakerConfig{
+				Threshold: 1,
+				Cooldown:  60000,
+			},
+		},
+	}
+
+	proxy.prewarmStatus()
+	if proxy.allowStatusDial() {
+		t.Fatal("got: breaker closed after failed prewarm; want: open")
+	}
+
+	// A server that is up again must not be dialed while the breaker is open
+	upAddr, requests := countingStatusServer(t, "up")
+	proxy.Config.ProxyTo = upAddr
+	proxy.prewarmStatus()
+	if got := atomic.LoadInt32(requests); got != 0 {
+		t.Errorf("got: %d requests; want: %d", got, 0)
+	}
+}
@@ -0,0 +1,102 @@
+package infrared
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+const (
+	defaultStatusRateLimitWindow     = 60000
+	defaultStatusRateLimitMaxEntries = 10000
+)
+
+func (proxy *Proxy) StatusRateLimit() StatusRateLimitConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.StatusRateLimit
+}
+
+// statusClient is the behavior of one IP within the current window
+type statusClient struct {
+	mu          sync.Mutex
+	windowStart time.Time
+	statuses    int
+	loggedIn    bool
+}
+
+// limitStatus reports whether the status request exceeds the rate of its
+// IP. IPs that logged in are players and never limited, so that only
+// status only clients like monitors and scrapers are throttled.
+func (proxy *Proxy) limitStatus(hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr) bool {
+	cfg := proxy.StatusRateLimit()
+	if !cfg.IsEnabled() || (!hs.IsStatusRequest() && !hs.IsLoginRequest()) {
+		return false
+	}
+
+	window := cfg.WindowDuration()
+	ip := remoteIP(connRemoteAddr)
+	client := proxy.statusClient(cfg.MaxEntries, ip, window)
+
+	client.mu.Lock()
+	defer client.mu.Unlock()
+	if hs.IsLoginRequest() {
+		client.loggedIn = true
+		return false
+	}
+	if client.loggedIn {
+		return false
+	}
+
+	now := time.Now()
+	if now.Sub(client.windowStart) >= window {
+		client.windowStart = now
+		client.statuses = 0
+	}
+
+	client.statuses++
+	if client.statuses <= cfg.Rate {
+		return false
+	}
+
+	if client.statuses == cfg.Rate+1 {
+		// Log once per window, since a scraper would flood the log otherwise
+		logWith(connFields("rate_limit", connRemoteAddr, proxy.UID()), "[i] Rate limiting status requests of %s on %s", connRemoteAddr, proxy.UID())
+	}
+	metrics.IncRateLimitedStatusRequests(proxy.DomainName())
+	return true
+}
+
+// statusClient returns the behavior of the IP. It is forgotten once the IP
+// was not seen for a window.
+func (proxy *Proxy) statusClient(maxEntries int, ip string, window time.Duration) *statusClient {
+	proxy.mu.Lock()
+	if proxy.statusClients == nil {
+		if maxEntries <= 0 {
+			maxEntries = defaultStatusRateLimitMaxEntries
+		}
+		proxy.statusClients = newTTLCache(maxEntries)
+	}
+	clients := proxy.statusClients
+	proxy.mu.Unlock()
+
+	var client *statusClient
+	if v, ok := clients.Get(ip); ok {
+		client = v.(*statusClient)
+	} else {
+		client = &statusClient{}
+	}
+	clients.Set(ip, client, window)
+	return client
+}
+
+// handleLimitedStatus answers a rate limited status request with the
+// offline status, which needs no dial, or closes the connection right away
+func (proxy *Proxy) handleLimitedStatus(conn Conn) error {
+	if proxy.StatusRateLimit().Action == StatusAdmissionActionDrop {
+		return nil
+	}
+	return proxy.handleStatusRequest(conn, false)
+}
@@ -0,0 +1,62 @@
+package infrared
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+func TestProxy_LimitStatus(t *testing.T) {
+	status := handshaking.ServerBoundHandshake{NextState: handshaking.ServerBoundHandshakeStatusState}
+	login := handshaking.ServerBoundHandshake{NextState: handshaking.ServerBoundHandshakeLoginState}
+	monitor := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000}
+	player := &net.TCPAddr{IP: net.ParseIP("10.0.0.2"), Port: 50000}
+
+	proxy := &Proxy{Config: &ProxyConfig{
+		StatusRateLimit: StatusRateLimitConfig{Rate: 2, Window: 50},
+	}}
+
+	if proxy.limitStatus(login, player) {
+		t.Error("got: limited login; want: not limited")
+	}
+
+	tt := []struct {
+		name string
+		addr net.Addr
+		want bool
+	}{
+		{name: "FirstPing", addr: monitor, want: false},
+		{name: "SecondPing", addr: monitor, want: false},
+		{name: "ThirdPing", addr: monitor, want: true},
+		{name: "FourthPing", addr: monitor, want: true},
+		// Players are never limited
+		{name: "PlayerPing", addr: player, want: false},
+		{name: "PlayerPing", addr: player, want: false},
+		{name: "PlayerPing", addr: player, want: false},
+	}
+
+	for _, tc := range tt {
+		if got := proxy.limitStatus(status, tc.addr); got != tc.want {
+			t.Errorf("%s: got: %v; want: %v", tc.name, got, tc.want)
+		}
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if proxy.limitStatus(status, monitor) {
+		t.Error("got: limited in a new window; want: not limited")
+	}
+}
+
+func TestProxy_LimitStatus_Disabled(t *testing.T) {
+	status := handshaking.ServerBoundHandshake{NextState: handshaking.ServerBoundHandshakeStatusState}
+	proxy := &Proxy{Config: &ProxyConfig{}}
+	addr := &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1)}
+
+	for i := 0; i < 100; i++ {
+		if proxy.limitStatus(status, addr) {
+			t.Fatal("got: limited; want: not limited")
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package infrared
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+	"github.com/haveachin/infrared/protocol/status"
+)
+
+// The reasons of strict protocol violations; they are the metrics labels
+const (
+	strictReasonHandshake     = "handshake"
+	strictReasonLoginStart    = "login_start"
+	strictReasonStatusRequest = "status_request"
+	strictReasonPing          = "ping"
+	strictReasonExtra         = "extra"
+)
+
+const (
+	maxServerAddressLength = 255
+	// The login start has signature data from 1.19 until 1.20.2, which
+	// is not checked
+	loginStartSignatureVersion = 759
+	loginStartUUIDVersion      = 764
+)
+
+// protocolViolation is an error for a client that deviated from the
+// expected packet sequence in strict mode
+type protocolViolation struct {
+	reason string
+	detail string
+}
+
+func (v protocolViolation) Error() string {
+	return fmt.Sprintf("strict protocol violation in %s: %s", v.reason, v.detail)
+}
+
+func violation(reason, format string, args ...interface{}) error {
+	return protocolViolation{reason: reason, detail: fmt.Sprintf(format, args...)}
+}
+
+func (proxy *Proxy) StrictProtocol() bool {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.StrictProtocol
+}
+
+// recordViolation logs and counts err if it is a protocol violation
+func (proxy *Proxy) recordViolation(err error, connRemoteAddr net.Addr) {
+	v, ok := err.(protocolViolation)
+	if !ok {
+		return
+	}
+
+	logWith(connFields("reject", connRemoteAddr, proxy.UID()), "[i] Dropped %s on %s; %s", connRemoteAddr, proxy.UID(), v)
+	metrics.IncStrictProtocolViolations(proxy.DomainName(), v.reason)
+}
+
+// validateHandshake checks that the handshake has no extra data, a known
+// next state and a vanilla length of the server address
+func validateHandshake(pk protocol.Packet, hs handshaking.ServerBoundHandshake) error {
+	if !bytes.Equal(hs.Marshal().Data, pk.Data) {
+		return violation(strictReasonHandshake, "extra data")
+	}
+
+	if !hs.IsStatusRequest() && !hs.IsLoginRequest() {
+		return violation(strictReasonHandshake, "unknown next state %d", hs.NextState)
+	}
+
+	if len(hs.ServerAddress) > maxServerAddressLength {
+		return violation(strictReasonHandshake, "server address is %d bytes long", len(hs.ServerAddress))
+	}
+
+	if hs.IsLoginRequest() && hs.ProtocolVersion <= 0 {
+		return violation(strictReasonHandshake, "login with protocol version %d", hs.ProtocolVersion)
+	}
+	return nil
+}
+
+// validateLoginStart checks that the login start has no extra data and that
+// the client did not send anything before the server answered it
+func validateLoginStart(conn Conn, pk protocol.Packet, protocolVersion protocol.VarInt, loginStart login.ServerLoginStart) error {
+	nameLength := len(protocol.String(loginStart.Name).Encode())
+	switch {
+	case protocolVersion < loginStartSignatureVersion:
+		if len(pk.Data) != nameLength {
+			return violation(strictReasonLoginStart, "extra data")
+		}
+	case protocolVersion >= loginStartUUIDVersion:
+		if len(pk.Data) != nameLength+len(loginStart.PlayerUUID) {
+			return violation(strictReasonLoginStart, "extra data")
+		}
+	}
+
+	if conn.Reader().Buffered() > 0 {
+		return violation(strictReasonExtra, "packet after the login start")
+	}
+	return nil
+}
+
+func validateStatusRequest(pk protocol.Packet) error {
+	if pk.ID != status.ServerBoundRequestPacketID || len(pk.Data) != 0 {
+		return violation(strictReasonStatusRequest, "packet 0x%02x with %d bytes", pk.ID, len(pk.Data))
+	}
+	return nil
+}
+
+func validatePing(pk protocol.Packet) error {
+	if pk.ID != status.ServerBoundPingPacketID || len(pk.Data) != len(status.ServerBoundPing{}.Marshal().Data) {
+		return violation(strictReasonPing, "packet 0x%02x with %d bytes", pk.ID, len(pk.Data))
+	}
+	return nil
+}
+
+// relayStrictStatus relays the status request and the ping of the client
+// to the server after validating them. Anything after the ping is a
+// violation. It returns the number of relayed bytes.
+func relayStrictStatus(conn, rconn Conn) (int64, error) {
+	var relayed int64
+	for _, validate := range []func(protocol.Packet) error{validateStatusRequest, validatePing} {
+		pk, err := conn.ReadPacket()
+		if err != nil {
+			return relayed, err
+		}
+
+		if err := validate(pk); err != nil {
+			return relayed, err
+		}
+
+		b, _ := pk.Marshal()
+		n, err := rconn.Write(b)
+		relayed += int64(n)
+		countRelayed(conn, rconn, int64(n))
+		if err != nil {
+			return relayed, err
+		}
+	}
+
+	if _, err := conn.ReadPacket(); err == nil {
+		return relayed, violation(strictReasonExtra, "packet after the ping")
+	}
+	return relayed, nil
+}
@@ -0,0 +1,224 @@
+package infrared
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+func violationReason(err error) string {
+	v, ok := err.(protocolViolation)
+	if !ok {
+		return ""
+	}
+	return v.reason
+}
+
+func TestValidateHandshake(t *testing.T) {
+	valid := handshaking.ServerBoundHandshake{
+		ProtocolVersion: 767,
+		ServerAddress:   "mc.example.com",
+		ServerPort:      25565,
+		NextState:       handshaking.ServerBoundHandshakeLoginState,
+	}
+	withExtraData := valid.Marshal()
+	withExtraData.Data = append(withExtraData.Data, 0x00)
+
+	tt := []struct {
+		name string
+		pk   protocol.Packet
+		want string
+	}{
+		{
+			name: "Valid",
+			pk:   valid.Marshal(),
+		},
+		{
+			name: "StatusWithUnknownVersion",
+			pk: handshaking.ServerBoundHandshake{
+				ProtocolVersion: -1,
+				ServerAddress:   "mc.example.com",
+				ServerPort:      25565,
+				NextState:       handshaking.ServerBoundHandshakeStatusState,
+			}.Marshal(),
+		},
+		{
+			name: "ExtraData",
+			pk:   withExtraData,
+			want: strictReasonHandshake,
+		},
+		{
+			name: "UnknownNextState",
+			pk: handshaking.ServerBoundHandshake{
+				ProtocolVersion: 767,
+				ServerAddress:   "mc.example.com",
+				ServerPort:      25565,
+				NextState:       9,
+			}.Marshal(),
+			want: strictReasonHandshake,
+		},
+		{
+			name: "LoginWithoutVersion",
+			pk: handshaking.ServerBoundHandshake{
+				ProtocolVersion: 0,
+				ServerAddress:   "mc.example.com",
+				ServerPort:      25565,
+				NextState:       handshaking.ServerBoundHandshakeLoginState,
+			}.Marshal(),
+			want: strictReasonHandshake,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hs, err := handshaking.UnmarshalServerBoundHandshake(tc.pk)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := violationReason(validateHandshake(tc.pk, hs)); got != tc.want {
+				t.Errorf("got: %q; want: %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestValidateLoginStart(t *testing.T) {
+	tt := []struct {
+		name            string
+		pk              protocol.Packet
+		protocolVersion protocol.VarInt
+		buffered        []byte
+		want            string
+	}{
+		{
+			name:            "Legacy",
+			pk:              protocol.MarshalPacket(0x00, protocol.String("Steve")),
+			protocolVersion: 758,
+		},
+		{
+			name:            "LegacyWithExtraData",
+			pk:              protocol.MarshalPacket(0x00, protocol.String("Steve"), protocol.Byte(1)),
+			protocolVersion: 758,
+			want:            strictReasonLoginStart,
+		},
+		{
+			name:            "WithSignatureData",
+			pk:              protocol.MarshalPacket(0x00, protocol.String("Steve"), protocol.Boolean(false), protocol.Boolean(false)),
+			protocolVersion: 760,
+		},
+		{
+			name:            "WithUUID",
+			pk:              protocol.MarshalPacket(0x00, protocol.String("Steve"), protocol.UUID{}),
+			protocolVersion: 767,
+		},
+		{
+			name:            "WithoutUUID",
+			pk:              protocol.MarshalPacket(0x00, protocol.String("Steve")),
+			protocolVersion: 767,
+			want:            strictReasonLoginStart,
+		},
+		{
+			name:            "PacketAfterLoginStart",
+			pk:              protocol.MarshalPacket(0x00, protocol.String("Steve"), protocol.UUID{}),
+			protocolVersion: 767,
+			buffered:        []byte{0x01, 0x03},
+			want:            strictReasonExtra,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			loginStart, err := login.UnmarshalServerBoundLoginStart(tc.pk)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c, client := net.Pipe()
+			defer c.Close()
+			defer client.Close()
+			conn := wrapConn(c)
+			if len(tc.buffered) > 0 {
+				go func() {
+					_, _ = client.Write(tc.buffered)
+				}()
+				if _, err := conn.Reader().Peek(len(tc.buffered)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			err = validateLoginStart(conn, tc.pk, tc.protocolVersion, loginStart)
+			if got := violationReason(err); got != tc.want {
+				t.Errorf("got: %q; want: %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRelayStrictStatus(t *testing.T) {
+	request := uncompressedFrame(protocol.Packet{ID: 0x00})
+	ping := uncompressedFrame(protocol.MarshalPacket(0x01, protocol.Long(42)))
+
+	tt := []struct {
+		name   string
+		frames [][]byte
+		want   string
+	}{
+		{
+			name:   "Valid",
+			frames: [][]byte{request, ping},
+		},
+		{
+			name:   "RequestWithData",
+			frames: [][]byte{uncompressedFrame(protocol.Packet{ID: 0x00, Data: []byte{0x01}})},
+			want:   strictReasonStatusRequest,
+		},
+		{
+			name:   "PingFirst",
+			frames: [][]byte{ping},
+			want:   strictReasonStatusRequest,
+		},
+		{
+			name:   "ShortPing",
+			frames: [][]byte{request, uncompressedFrame(protocol.Packet{ID: 0x01, Data: []byte{0x01}})},
+			want:   strictReasonPing,
+		},
+		{
+			name:   "PacketAfterPing",
+			frames: [][]byte{request, ping, request},
+			want:   strictReasonExtra,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c, client := net.Pipe()
+			rc, server := net.Pipe()
+			defer c.Close()
+			defer rc.Close()
+			defer server.Close()
+
+			go func() {
+				for _, frame := range tc.frames {
+					if _, err := client.Write(frame); err != nil {
+						return
+					}
+				}
+				client.Close()
+			}()
+			go func() {
+				_, _ = io.Copy(ioutil.Discard, server)
+			}()
+
+			_, err := relayStrictStatus(wrapConn(c), wrapConn(rc))
+			if got := violationReason(err); got != tc.want {
+				t.Errorf("got: %q (%v); want: %q", got, err, tc.want)
+			}
+		})
+	}
+}
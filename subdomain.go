@@ -0,0 +1,92 @@
+package infrared
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+func (proxy *Proxy) SubdomainRoutes() map[string]string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	routes := make(map[string]string, len(proxy.Config.SubdomainRoutes))
+	for route, addr := range proxy.Config.SubdomainRoutes {
+		routes[route] = addr
+	}
+	return routes
+}
+
+// subdomainRoute returns the server address of the route for prefix
+func (proxy *Proxy) subdomainRoute(prefix string) (string, bool) {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	for route, addr := range proxy.Config.SubdomainRoutes {
+		if strings.EqualFold(route, prefix) {
+			return addr, true
+		}
+	}
+	return "", false
+}
+
+// splitSubdomain splits the first label off of a server address, e.g.
+// creative.hub.example.com into creative and hub.example.com
+func splitSubdomain(addr string) (string, string, bool) {
+	i := strings.IndexByte(addr, '.')
+	if i <= 0 || i == len(addr)-1 {
+		return "", "", false
+	}
+	return addr[:i], addr[i+1:], true
+}
+
+// routeTo returns the subdomain route of the client and the address of its
+// server. Clients that request a subdomain of the domain name of the proxy
+// are routed after its first label if there is a route for it. Both are
+// empty if there is none.
+func (proxy *Proxy) routeTo(hs handshaking.ServerBoundHandshake) (string, string) {
+	prefix, rest, ok := splitSubdomain(hs.ParseServerAddress())
+	if !ok {
+		return "", ""
+	}
+
+	domain := proxy.DomainName()
+	if host, _, err := net.SplitHostPort(domain); err == nil {
+		domain = host
+	}
+	if !strings.EqualFold(rest, domain) {
+		return "", ""
+	}
+
+	addr, ok := proxy.subdomainRoute(prefix)
+	if !ok {
+		return "", ""
+	}
+	return addr, strings.ToLower(prefix)
+}
+
+// loadSubdomainProxy returns the proxy whose domain name is the requested
+// address without its first label, if the proxy has a route for that label
+func (gateway *Gateway) loadSubdomainProxy(hs handshaking.ServerBoundHandshake, addr string) (*Proxy, bool) {
+	prefix, rest, ok := splitSubdomain(hs.ParseServerAddress())
+	if !ok {
+		return nil, false
+	}
+
+	uids := []string{
+		proxyUID(net.JoinHostPort(rest, strconv.Itoa(int(hs.ServerPort))), addr),
+		proxyUID(rest, addr),
+	}
+	for _, uid := range uids {
+		v, ok := gateway.Proxies.Load(uid)
+		if !ok {
+			continue
+		}
+
+		proxy := v.(*Proxy)
+		if _, ok := proxy.subdomainRoute(prefix); ok {
+			return proxy, true
+		}
+	}
+	return nil, false
+}
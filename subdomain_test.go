@@ -0,0 +1,132 @@
+package infrared
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+func TestProxy_RouteTo(t *testing.T) {
+	tt := []struct {
+		name       string
+		domainName string
+		address    string
+		wantAddr   string
+		wantRoute  string
+	}{
+		{
+			name:       "DomainName",
+			domainName: "hub.example.com",
+			address:    "hub.example.com",
+		},
+		{
+			name:       "Route",
+			domainName: "hub.example.com",
+			address:    "creative.hub.example.com",
+			wantAddr:   "creative:25565",
+			wantRoute:  "creative",
+		},
+		{
+			name:       "RouteInUpperCase",
+			domainName: "hub.example.com",
+			address:    "Creative.HUB.example.com",
+			wantAddr:   "creative:25565",
+			wantRoute:  "creative",
+		},
+		{
+			name:       "RouteWithForge",
+			domainName: "hub.example.com",
+			address:    "creative.hub.example.com\x00FML\x00",
+			wantAddr:   "creative:25565",
+			wantRoute:  "creative",
+		},
+		{
+			name:       "RouteOfDomainNameWithPort",
+			domainName: "hub.example.com:25566",
+			address:    "creative.hub.example.com",
+			wantAddr:   "creative:25565",
+			wantRoute:  "creative",
+		},
+		{
+			name:       "UnknownRoute",
+			domainName: "hub.example.com",
+			address:    "survival.hub.example.com",
+		},
+		{
+			name:       "OnlyFirstLabel",
+			domainName: "hub.example.com",
+			address:    "a.creative.hub.example.com",
+		},
+		{
+			name:       "OtherDomain",
+			domainName: "hub.example.com",
+			address:    "creative.example.org",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{
+				Config: &ProxyConfig{
+					DomainName: tc.domainName,
+					ProxyTo:    "hub:25565",
+					SubdomainRoutes: map[string]string{
+						"creative": "creative:25565",
+					},
+				},
+			}
+			hs := handshaking.ServerBoundHandshake{
+				ServerAddress: protocol.String(tc.address),
+			}
+
+			addr, route := proxy.routeTo(hs)
+			if addr != tc.wantAddr {
+				t.Errorf("got: %v; want: %v", addr, tc.wantAddr)
+			}
+			if route != tc.wantRoute {
+				t.Errorf("got: %v; want: %v", route, tc.wantRoute)
+			}
+		})
+	}
+}
+
+func TestProxyConfig_LoadFromPath_SubdomainRoutes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "infrared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "proxy.json")
+	if err := ioutil.WriteFile(path, []byte(`{"subdomainRoutes": {"creative": "creative:25565", "survival": "survival:25565"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg ProxyConfig
+	if err := cfg.LoadFromPath(path); err != nil {
+		t.Fatal(err)
+	}
+	proxy := &Proxy{Config: &cfg}
+	routes := proxy.SubdomainRoutes()
+
+	if err := ioutil.WriteFile(path, []byte(`{"subdomainRoutes": {"creative": "creative:25566"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.LoadFromPath(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := proxy.subdomainRoute("survival"); ok {
+		t.Error("got: survival route; want: removed by the reload")
+	}
+	if addr, _ := proxy.subdomainRoute("creative"); addr != "creative:25566" {
+		t.Errorf("got: %v; want: creative:25566", addr)
+	}
+	if len(routes) != 2 || routes["creative"] != "creative:25565" {
+		t.Errorf("got: %v; want: the routes before the reload", routes)
+	}
+}
@@ -0,0 +1,59 @@
+package infrared
+
+import (
+	"log"
+	"net"
+)
+
+// asTCPConn returns the TCP connection that c wraps, if it is one
+func asTCPConn(c Conn) (*net.TCPConn, bool) {
+	wrapped, ok := c.(*conn)
+	if !ok {
+		return nil, false
+	}
+
+	if tc, ok := wrapped.Conn.(tlsConn); ok {
+		tcpConn, ok := tc.raw.(*net.TCPConn)
+		return tcpConn, ok
+	}
+
+	tcpConn, ok := wrapped.Conn.(*net.TCPConn)
+	return tcpConn, ok
+}
+
+// setTCPOptions applies the TCP keep-alive and no-delay options of the
+// gateway to c. Connections that are no TCP connections are left as they are.
+func (gateway *Gateway) setTCPOptions(c Conn) error {
+	tcpConn, ok := asTCPConn(c)
+	if !ok {
+		return nil
+	}
+
+	if err := tcpConn.SetNoDelay(!gateway.DisableTCPNoDelay); err != nil {
+		return err
+	}
+
+	switch {
+	case gateway.TCPKeepAlive < 0:
+		return tcpConn.SetKeepAlive(false)
+	case gateway.TCPKeepAlive > 0:
+		if err := tcpConn.SetKeepAlive(true); err != nil {
+			return err
+		}
+		return tcpConn.SetKeepAlivePeriod(gateway.TCPKeepAlive)
+	}
+	// Go enables keep-alives with its default period on its own
+	return nil
+}
+
+// setTCPOptions applies the TCP options of the gateway to a connection to
+// a server
+func (proxy *Proxy) setTCPOptions(rconn Conn) {
+	if proxy.gateway == nil {
+		return
+	}
+
+	if err := proxy.gateway.setTCPOptions(rconn); err != nil {
+		log.Printf("[w] Failed to set the TCP options of the connection to %s; error: %s", rconn.RemoteAddr(), err)
+	}
+}
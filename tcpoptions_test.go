@@ -0,0 +1,116 @@
+//go:build linux
+// +build linux
+
+package infrared
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func sockoptInt(t *testing.T, c *net.TCPConn, level, opt int) int {
+	raw, err := c.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var value int
+	var sockErr error
+	if err := raw.Control(func(fd uintptr) {
+		value, sockErr = syscall.GetsockoptInt(int(fd), level, opt)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if sockErr != nil {
+		t.Fatal(sockErr)
+	}
+	return value
+}
+
+func TestGateway_SetTCPOptions(t *testing.T) {
+	tt := []struct {
+		name         string
+		keepAlive    time.Duration
+		disableDelay bool
+		noDelay      int
+		keepAliveOn  int
+		keepIdleSecs int
+	}{
+		{
+			name:        "Defaults",
+			noDelay:     1,
+			keepAliveOn: 1,
+			// The default period of Go
+			keepIdleSecs: 15,
+		},
+		{
+			name:         "KeepAlivePeriod",
+			keepAlive:    42 * time.Second,
+			noDelay:      1,
+			keepAliveOn:  1,
+			keepIdleSecs: 42,
+		},
+		{
+			name:         "Disabled",
+			keepAlive:    -1,
+			disableDelay: true,
+			noDelay:      0,
+			keepAliveOn:  0,
+			keepIdleSecs: -1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			listener, err := Listen("127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer listener.Close()
+
+			c, err := net.Dial("tcp", listener.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer c.Close()
+
+			conn, err := listener.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			gateway := Gateway{TCPKeepAlive: tc.keepAlive, DisableTCPNoDelay: tc.disableDelay}
+			if err := gateway.setTCPOptions(conn); err != nil {
+				t.Fatal(err)
+			}
+
+			tcpConn, _ := asTCPConn(conn)
+			if got := sockoptInt(t, tcpConn, syscall.IPPROTO_TCP, syscall.TCP_NODELAY); got != tc.noDelay {
+				t.Errorf("no delay got: %v; want: %v", got, tc.noDelay)
+			}
+			if got := sockoptInt(t, tcpConn, syscall.SOL_SOCKET, syscall.SO_KEEPALIVE); got != tc.keepAliveOn {
+				t.Errorf("keep-alive got: %v; want: %v", got, tc.keepAliveOn)
+			}
+			if tc.keepIdleSecs < 0 {
+				return
+			}
+			if got := sockoptInt(t, tcpConn, syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE); got != tc.keepIdleSecs {
+				t.Errorf("keep-alive period got: %v; want: %v", got, tc.keepIdleSecs)
+			}
+		})
+	}
+}
+
+func TestGateway_SetTCPOptions_NoTCP(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	gateway := Gateway{TCPKeepAlive: time.Second}
+	if err := gateway.setTCPOptions(wrapConn(c1)); err != nil {
+		t.Errorf("got: %v; want: nil", err)
+	}
+}
@@ -0,0 +1,47 @@
+package infrared
+
+import (
+	"crypto/tls"
+	"net"
+)
+
+// TransportTLS is the transport of connections whose TLS is terminated by the gateway
+const TransportTLS = "tls"
+
+// tlsConn is a TLS connection that keeps the connection it runs on, so
+// that its TCP options can still be set
+type tlsConn struct {
+	*tls.Conn
+	raw net.Conn
+}
+
+// ListenTLS listens on addr and terminates the TLS of every accepted
+// connection with the certificate
+func ListenTLS(addr string, cert tls.Certificate) (Listener, error) {
+	l, err := Listen(addr)
+	if err != nil {
+		return l, err
+	}
+
+	l.Transport = TransportTLS
+	l.TLSConfig = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	return l, nil
+}
+
+// listen opens the listener of addr. If the gateway has a TLS certificate,
+// it is loaded again for every listener, so that a reload picks up a
+// renewed certificate.
+func (gateway *Gateway) listen(addr string) (Listener, error) {
+	if gateway.TLSCertFile == "" && gateway.TLSKeyFile == "" {
+		return Listen(addr)
+	}
+
+	cert, err := tls.LoadX509KeyPair(gateway.TLSCertFile, gateway.TLSKeyFile)
+	if err != nil {
+		return Listener{}, err
+	}
+	return ListenTLS(addr, cert)
+}
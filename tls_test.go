@@ -0,0 +1,113 @@
+package infrared
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate writes a self-signed certificate and its key to dir
+func writeTestCertificate(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "mc.example.com"},
+		DNSNames:     []string{"mc.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestGateway_ListenTLS(t *testing.T) {
+	certFile, keyFile := writeTestCertificate(t, t.TempDir())
+	gateway := Gateway{
+		TLSCertFile: certFile,
+		TLSKeyFile:  keyFile,
+	}
+
+	listener, err := gateway.listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	hs := serverHandshake("mc.example.com", 25565)
+	go func() {
+		client, err := tls.Dial("tcp", listener.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			return
+		}
+		defer client.Close()
+		_ = wrapConn(client).WritePacket(hs)
+		_, _ = client.Read(make([]byte, 1))
+	}()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if conn.Transport() != TransportTLS {
+		t.Errorf("got: %v; want: %v", conn.Transport(), TransportTLS)
+	}
+	if _, ok := asTCPConn(conn); !ok {
+		t.Error("got: no TCP connection; want: the TCP connection under TLS")
+	}
+	if _, ok := rawTCPConn(conn); ok {
+		t.Error("got: TLS connection can be spliced; want: relayed in user space")
+	}
+
+	pk, err := conn.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pk.ID != hs.ID || !bytes.Equal(pk.Data, hs.Data) {
+		t.Errorf("got: %v; want: %v", pk, hs)
+	}
+}
+
+func TestGateway_ListenTLS_MissingCertificate(t *testing.T) {
+	dir := t.TempDir()
+	gateway := Gateway{
+		TLSCertFile: filepath.Join(dir, "cert.pem"),
+		TLSKeyFile:  filepath.Join(dir, "key.pem"),
+	}
+
+	if listener, err := gateway.listen("127.0.0.1:0"); err == nil {
+		listener.Close()
+		t.Error("got: nil; want: error")
+	}
+}
@@ -0,0 +1,98 @@
+package infrared
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/configuration"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+const (
+	defaultTransferPort = 25565
+	// transferCloseTimeout is the time the client gets to close the
+	// connection by itself after it received the transfer packet
+	transferCloseTimeout = 5 * time.Second
+)
+
+func (proxy *Proxy) TransferTo() string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.TransferTo
+}
+
+// canTransfer reports whether the client can be transferred instead of
+// being proxied to the server
+func (proxy *Proxy) canTransfer(hs handshaking.ServerBoundHandshake) bool {
+	return proxy.TransferTo() != "" &&
+		hs.IsLoginRequest() &&
+		hs.ProtocolVersion >= configuration.TransferProtocolVersion
+}
+
+// handleTransfer logs the client in without any server and then sends it a
+// transfer packet that points to the address of TransferTo
+func (proxy *Proxy) handleTransfer(conn Conn, hs handshaking.ServerBoundHandshake, loginStart login.ServerLoginStart, connRemoteAddr net.Addr) error {
+	host, port, err := splitTransferAddr(proxy.TransferTo())
+	if err != nil {
+		return err
+	}
+
+	playerUUID := loginStart.PlayerUUID
+	if !loginStart.HasPlayerUUID {
+		playerUUID = offlinePlayerUUID(string(loginStart.Name))
+	}
+
+	loginSuccess := login.ClientBoundLoginSuccess{
+		UUID:     playerUUID,
+		Username: loginStart.Name,
+	}
+	if err := conn.WritePacket(loginSuccess.Marshal(hs.ProtocolVersion)); err != nil {
+		return err
+	}
+
+	pk, err := conn.ReadPacket()
+	if err != nil {
+		return err
+	}
+
+	if _, err := login.UnmarshalServerBoundLoginAcknowledged(pk); err != nil {
+		return err
+	}
+
+	logWith(connFields("transfer", connRemoteAddr, proxy.UID()), "[i] Transferring %s with username %s to %s:%d", connRemoteAddr, loginStart.Name, host, port)
+	transfer := configuration.ClientBoundTransfer{
+		Host: protocol.String(host),
+		Port: protocol.VarInt(port),
+	}
+	if err := conn.WritePacket(transfer.Marshal()); err != nil {
+		return err
+	}
+
+	// Wait for the client to close the connection, so that the transfer
+	// packet does not get lost by closing it with unread data
+	if err := conn.SetReadDeadline(time.Now().Add(transferCloseTimeout)); err != nil {
+		return err
+	}
+	_, _ = io.Copy(ioutil.Discard, conn)
+	return nil
+}
+
+func splitTransferAddr(addr string) (string, int, error) {
+	host, portString, err := net.SplitHostPort(addr)
+	if err != nil {
+		// The address has no port
+		return addr, defaultTransferPort, nil
+	}
+
+	port, err := strconv.Atoi(portString)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return host, port, nil
+}
@@ -0,0 +1,49 @@
+package infrared
+
+import (
+	"net"
+	"os"
+	"strings"
+)
+
+const (
+	// TransportUnix is the transport of connections on Unix domain sockets
+	TransportUnix = "unix"
+	// unixSocketPrefix marks listener addresses that are Unix domain sockets
+	unixSocketPrefix = "unix://"
+)
+
+// unixSocketPath returns the path of the socket if addr is a Unix domain
+// socket like unix:///run/infrared.sock
+func unixSocketPath(addr string) (string, bool) {
+	if !strings.HasPrefix(addr, unixSocketPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(addr, unixSocketPrefix), true
+}
+
+// ListenUnix listens on the Unix domain socket at path. A socket that was
+// left behind by a process that did not close it is replaced.
+func ListenUnix(path string) (Listener, error) {
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(path); err != nil {
+			return Listener{}, err
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	return Listener{Listener: l, Transport: TransportUnix}, err
+}
+
+// unixPeerAddr is the remote address of clients on Unix domain sockets.
+// They have no IP, but are on the same host, so they are treated like
+// clients on the loopback address by the IP based checks and forwarding.
+var unixPeerAddr = &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1)}
+
+// peerAddr returns the remote address of conn that has an IP
+func peerAddr(conn net.Conn) net.Addr {
+	if _, ok := conn.RemoteAddr().(*net.UnixAddr); ok {
+		return unixPeerAddr
+	}
+	return conn.RemoteAddr()
+}
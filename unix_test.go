@@ -0,0 +1,92 @@
+package infrared
+
+import (
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol/status"
+)
+
+func TestListen_Unix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "infrared.sock")
+
+	// A socket that was left behind is replaced
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	listener, err := Listen(unixSocketPrefix + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	go func() {
+		client, err := net.Dial("unix", path)
+		if err != nil {
+			return
+		}
+		defer client.Close()
+		_, _ = client.Read(make([]byte, 1))
+	}()
+
+	conn, err := listener.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if conn.Transport() != TransportUnix {
+		t.Errorf("got: %v; want: %v", conn.Transport(), TransportUnix)
+	}
+	if ip := remoteIP(peerAddr(conn)); ip != "127.0.0.1" {
+		t.Errorf("got: %v; want: %v", ip, "127.0.0.1")
+	}
+}
+
+func TestGateway_ListenAndServe_Unix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "infrared.sock")
+	cfg := createBasicProxyConfig("mc.example.com", unixSocketPrefix+path, serverAddr(830))
+	cfg.OfflineStatus = statusPKWithVersion("Infrared-test-offline")
+
+	gateway := Gateway{}
+	if err := gateway.ListenAndServe(configsToProxies([]*ProxyConfig{cfg})); err != nil {
+		t.Fatal(err)
+	}
+	defer gateway.Close()
+
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	client := wrapConn(c)
+	if err := client.WritePacket(serverHandshake("mc.example.com", 25565)); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.WritePacket(status.ServerBoundRequest{}.Marshal()); err != nil {
+		t.Fatal(err)
+	}
+
+	pk, err := client.ReadPacket()
+	if err != nil {
+		t.Fatal(err)
+	}
+	response, err := status.UnmarshalClientBoundResponse(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var responseJSON status.ResponseJSON
+	if err := json.Unmarshal([]byte(response.JSONResponse), &responseJSON); err != nil {
+		t.Fatal(err)
+	}
+	if responseJSON.Version.Name != "Infrared-test-offline" {
+		t.Errorf("got: %v; want: %v", responseJSON.Version.Name, "Infrared-test-offline")
+	}
+}
@@ -0,0 +1,116 @@
+package infrared
+
+import (
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+// The actions for clients that request a domain that no proxy has
+const (
+	// UnmatchedActionRespond answers status requests with a canned
+	// response and disconnects logins with a message
+	UnmatchedActionRespond = "respond"
+	// UnmatchedActionDrop closes the connection without an answer, so that
+	// scanners can't tell that Infrared is running
+	UnmatchedActionDrop = "drop"
+	// UnmatchedActionDefaultServer routes the client to the proxy with the
+	// domain name or the UID of Gateway.DefaultServer. It is the action if
+	// none is set, but a default server is.
+	UnmatchedActionDefaultServer = "default_server"
+)
+
+const (
+	unmatchedMOTD    = "Unknown server"
+	unmatchedMessage = "There is no server with this address."
+)
+
+// handleUnmatched handles a client that requested a proxy that does not
+// exist. It returns the proxy that the client should be routed to or nil
+// if the client was already handled.
+func (gateway *Gateway) handleUnmatched(conn Conn, hs handshaking.ServerBoundHandshake, requestedUID, addr string) (*Proxy, error) {
+	action := gateway.UnmatchedAction
+	if action == "" && gateway.DefaultServer != "" {
+		action = UnmatchedActionDefaultServer
+	}
+
+	switch action {
+	case UnmatchedActionDrop:
+		log.Printf("[i] Dropping %s; no proxy with UID %s", conn.RemoteAddr(), requestedUID)
+		return nil, nil
+	case UnmatchedActionDefaultServer:
+		proxy, err := gateway.defaultProxy(addr)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("[i] Routing %s to default proxy %s", conn.RemoteAddr(), proxy.UID())
+		return proxy, nil
+	case UnmatchedActionRespond, "":
+		return nil, gateway.respondUnmatched(conn, hs)
+	default:
+		return nil, errors.New("unknown unmatched action " + gateway.UnmatchedAction)
+	}
+}
+
+// defaultProxy returns the proxy of Gateway.DefaultServer for clients of
+// the listener on addr
+func (gateway *Gateway) defaultProxy(addr string) (*Proxy, error) {
+	defaultUID := gateway.DefaultServer
+	if !strings.Contains(defaultUID, "@") {
+		// A domain name refers to the proxy on the same listener
+		defaultUID = proxyUID(defaultUID, addr)
+	}
+	v, ok := gateway.Proxies.Load(defaultUID)
+	if !ok {
+		return nil, errors.New("no default proxy with uid " + defaultUID)
+	}
+	return v.(*Proxy), nil
+}
+
+// respondUnmatched answers the client like a server that does not exist
+func (gateway *Gateway) respondUnmatched(conn Conn, hs handshaking.ServerBoundHandshake) error {
+	// Read the handshake that was only peeked
+	if _, err := conn.ReadPacket(); err != nil {
+		return err
+	}
+
+	// Read the status request or the login start
+	if _, err := conn.ReadPacket(); err != nil {
+		return err
+	}
+
+	if hs.IsLoginRequest() {
+		message := gateway.UnmatchedMessage
+		if message == "" {
+			message = unmatchedMessage
+		}
+		return conn.WritePacket(disconnectPacket(message))
+	}
+
+	motd := gateway.UnmatchedMOTD
+	if motd == "" {
+		motd = unmatchedMOTD
+	}
+	status := StatusConfig{
+		VersionName:    "Infrared",
+		ProtocolNumber: int(hs.ProtocolVersion),
+		MOTD:           motd,
+	}
+	responsePk, err := status.StatusResponsePacket()
+	if err != nil {
+		return err
+	}
+
+	if err := conn.WritePacket(responsePk); err != nil {
+		return err
+	}
+
+	pingPk, err := conn.ReadPacket()
+	if err != nil {
+		return err
+	}
+
+	return conn.WritePacket(pingPk)
+}
@@ -0,0 +1,118 @@
+package infrared
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+	"github.com/haveachin/infrared/protocol/status"
+)
+
+func TestGateway_RespondUnmatched(t *testing.T) {
+	tt := []struct {
+		name        string
+		gateway     *Gateway
+		wantMOTD    string
+		wantMessage string
+	}{
+		{
+			name:        "Default",
+			gateway:     &Gateway{},
+			wantMOTD:    unmatchedMOTD,
+			wantMessage: unmatchedMessage,
+		},
+		{
+			name: "Custom",
+			gateway: &Gateway{
+				UnmatchedMOTD:    "A Minecraft Server",
+				UnmatchedMessage: "You are not whitelisted on this server!",
+			},
+			wantMOTD:    "A Minecraft Server",
+			wantMessage: "You are not whitelisted on this server!",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name+"Status", func(t *testing.T) {
+			c1, c2 := net.Pipe()
+			defer c1.Close()
+			defer c2.Close()
+
+			hs := handshaking.ServerBoundHandshake{
+				ProtocolVersion: 757,
+				NextState:       handshaking.ServerBoundHandshakeStatusState,
+			}
+			errCh := make(chan error, 1)
+			go func() { errCh <- tc.gateway.respondUnmatched(wrapConn(c2), hs) }()
+
+			client := wrapConn(c1)
+			for _, pk := range []protocol.Packet{hs.Marshal(), status.ServerBoundRequest{}.Marshal()} {
+				if err := client.WritePacket(pk); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			pk, err := client.ReadPacket()
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := status.UnmarshalClientBoundResponse(pk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var responseJSON status.ResponseJSON
+			if err := json.Unmarshal([]byte(response.JSONResponse), &responseJSON); err != nil {
+				t.Fatal(err)
+			}
+			if responseJSON.Description.Text != tc.wantMOTD {
+				t.Errorf("got: %v; want: %v", responseJSON.Description.Text, tc.wantMOTD)
+			}
+
+			if err := client.WritePacket(status.ServerBoundPing{Payload: 1}.Marshal()); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.ReadPacket(); err != nil {
+				t.Fatal(err)
+			}
+			if err := <-errCh; err != nil {
+				t.Error(err)
+			}
+		})
+
+		t.Run(tc.name+"Login", func(t *testing.T) {
+			c1, c2 := net.Pipe()
+			defer c1.Close()
+			defer c2.Close()
+
+			hs := handshaking.ServerBoundHandshake{
+				ProtocolVersion: 757,
+				NextState:       handshaking.ServerBoundHandshakeLoginState,
+			}
+			errCh := make(chan error, 1)
+			go func() { errCh <- tc.gateway.respondUnmatched(wrapConn(c2), hs) }()
+
+			client := wrapConn(c1)
+			for _, pk := range []protocol.Packet{hs.Marshal(), protocol.MarshalPacket(login.ServerBoundLoginStartPacketID, protocol.String("Steve"))} {
+				if err := client.WritePacket(pk); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			pk, err := client.ReadPacket()
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := disconnectPacket(tc.wantMessage)
+			if !bytes.Equal(pk.Data, want.Data) {
+				t.Errorf("got: %s; want: %s", pk.Data, want.Data)
+			}
+			if err := <-errCh; err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package infrared
+
+import (
+	"net"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+const (
+	// defaultMaxUsernameLength is the longest username that vanilla
+	// clients can log in with
+	defaultMaxUsernameLength      = 16
+	defaultInvalidUsernameMessage = "Invalid username."
+)
+
+func (proxy *Proxy) MaxUsernameLength() int {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	if proxy.Config.MaxUsernameLength <= 0 {
+		return defaultMaxUsernameLength
+	}
+	return proxy.Config.MaxUsernameLength
+}
+
+func (proxy *Proxy) RelaxedUsernames() bool {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.RelaxedUsernames
+}
+
+func (proxy *Proxy) InvalidUsernameMessage() string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	if proxy.Config.InvalidUsernameMessage == "" {
+		return defaultInvalidUsernameMessage
+	}
+	return proxy.Config.InvalidUsernameMessage
+}
+
+// isValidUsername reports whether username is at most maxLength characters
+// long and only consists of letters, digits and underscores. If relaxed is
+// set, every printable character except spaces is allowed, like cracked
+// servers often do.
+func isValidUsername(username string, maxLength int, relaxed bool) bool {
+	if username == "" || !utf8.ValidString(username) || utf8.RuneCountInString(username) > maxLength {
+		return false
+	}
+
+	for _, r := range username {
+		if relaxed {
+			if !unicode.IsPrint(r) || unicode.IsSpace(r) {
+				return false
+			}
+			continue
+		}
+
+		if !isStrictUsernameRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isStrictUsernameRune(r rune) bool {
+	return r >= 'a' && r <= 'z' ||
+		r >= 'A' && r <= 'Z' ||
+		r >= '0' && r <= '9' ||
+		r == '_'
+}
+
+// rejectByUsername disconnects logins with a username that vanilla servers
+// would not accept, before they can reach the server. Malformed usernames
+// are a common way of exploit clients to crash servers. It reports whether
+// the client was rejected.
+func (proxy *Proxy) rejectByUsername(conn Conn, hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr, username string) (bool, error) {
+	if !hs.IsLoginRequest() {
+		return false, nil
+	}
+
+	if isValidUsername(username, proxy.MaxUsernameLength(), proxy.RelaxedUsernames()) {
+		return false, nil
+	}
+
+	logWith(connFields("reject", connRemoteAddr, proxy.UID()), "[i] Rejecting %s with invalid username %q on %s", connRemoteAddr, username, proxy.UID())
+	metrics.IncInvalidUsernames(proxy.DomainName())
+	return true, conn.WritePacket(disconnectPacket(proxy.InvalidUsernameMessage()))
+}
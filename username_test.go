@@ -0,0 +1,183 @@
+package infrared
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+func TestIsValidUsername(t *testing.T) {
+	tt := []struct {
+		name      string
+		username  string
+		maxLength int
+		relaxed   bool
+		valid     bool
+	}{
+		{
+			name:      "Vanilla",
+			username:  "Steve_123",
+			maxLength: 16,
+			valid:     true,
+		},
+		{
+			name:      "OneCharacter",
+			username:  "a",
+			maxLength: 16,
+			valid:     true,
+		},
+		{
+			name:      "MaxLength",
+			username:  strings.Repeat("a", 16),
+			maxLength: 16,
+			valid:     true,
+		},
+		{
+			name:      "TooLong",
+			username:  strings.Repeat("a", 17),
+			maxLength: 16,
+		},
+		{
+			name:      "Empty",
+			username:  "",
+			maxLength: 16,
+		},
+		{
+			name:      "Space",
+			username:  "Ste ve",
+			maxLength: 16,
+		},
+		{
+			name:      "Symbol",
+			username:  "Steve!",
+			maxLength: 16,
+		},
+		{
+			name:      "ControlCharacter",
+			username:  "Steve\x00",
+			maxLength: 16,
+		},
+		{
+			name:      "InvalidUTF8",
+			username:  "Steve\xff",
+			maxLength: 16,
+			relaxed:   true,
+		},
+		{
+			name:      "RelaxedSymbols",
+			username:  "Stéve.-!",
+			maxLength: 16,
+			relaxed:   true,
+			valid:     true,
+		},
+		{
+			name:      "RelaxedMaxLengthInCharacters",
+			username:  strings.Repeat("é", 16),
+			maxLength: 16,
+			relaxed:   true,
+			valid:     true,
+		},
+		{
+			name:      "RelaxedTooLong",
+			username:  strings.Repeat("é", 17),
+			maxLength: 16,
+			relaxed:   true,
+		},
+		{
+			name:      "RelaxedSpace",
+			username:  "Ste ve",
+			maxLength: 16,
+			relaxed:   true,
+		},
+		{
+			name:      "RelaxedControlCharacter",
+			username:  "Steve\n",
+			maxLength: 16,
+			relaxed:   true,
+		},
+		{
+			name:      "LongerMaxLength",
+			username:  strings.Repeat("a", 20),
+			maxLength: 20,
+			valid:     true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isValidUsername(tc.username, tc.maxLength, tc.relaxed); got != tc.valid {
+				t.Errorf("got: %v; want: %v", got, tc.valid)
+			}
+		})
+	}
+}
+
+func TestProxy_RejectByUsername(t *testing.T) {
+	proxy := &Proxy{
+		Config: &ProxyConfig{},
+	}
+
+	tt := []struct {
+		name     string
+		username string
+		state    protocol.Byte
+		rejected bool
+	}{
+		{
+			name:     "Valid",
+			username: "Steve",
+			state:    handshaking.ServerBoundHandshakeLoginState,
+		},
+		{
+			name:     "Invalid",
+			username: strings.Repeat("a", 32),
+			state:    handshaking.ServerBoundHandshakeLoginState,
+			rejected: true,
+		},
+		{
+			name:  "StatusRequest",
+			state: handshaking.ServerBoundHandshakeStatusState,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hs := handshaking.ServerBoundHandshake{
+				NextState: tc.state,
+			}
+
+			c1, c2 := net.Pipe()
+			defer c1.Close()
+			defer c2.Close()
+
+			received := make(chan protocol.Packet, 1)
+			go func() {
+				pk, err := wrapConn(c1).ReadPacket()
+				if err == nil {
+					received <- pk
+				}
+			}()
+
+			rejected, err := proxy.rejectByUsername(wrapConn(c2), hs, c2.RemoteAddr(), tc.username)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rejected != tc.rejected {
+				t.Fatalf("got: %v; want: %v", rejected, tc.rejected)
+			}
+
+			if !tc.rejected {
+				return
+			}
+
+			want := disconnectPacket(defaultInvalidUsernameMessage)
+			if pk := <-received; !bytes.Equal(pk.Data, want.Data) {
+				t.Errorf("got: %s; want: %s", pk.Data, want.Data)
+			}
+		})
+	}
+}
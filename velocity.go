@@ -0,0 +1,114 @@
+package infrared
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"log"
+	"net"
+	"time"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+const (
+	velocityPlayerInfoChannel = "velocity:player_info"
+	// velocityDefaultForwardingVersion is the version of the player info
+	// without a chat signing key, which every server in modern forwarding
+	// mode accepts
+	velocityDefaultForwardingVersion = 1
+	// velocityForwardingTimeout is the time that the server gets to request
+	// the player info after it received the login start
+	velocityForwardingTimeout = 5 * time.Second
+)
+
+func (proxy *Proxy) VelocityForwarding() VelocityForwardingConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.VelocityForwarding
+}
+
+// velocityPlayerInfo returns the signed player info of Velocity's modern
+// forwarding. The properties, which carry the skin, are only known if
+// Infrared authenticated the player.
+func velocityPlayerInfo(secret string, connRemoteAddr net.Addr, profile gameProfile) []byte {
+	var payload []byte
+	fields := []protocol.FieldEncoder{
+		protocol.VarInt(velocityDefaultForwardingVersion),
+		protocol.String(remoteIP(connRemoteAddr)),
+		profile.ID,
+		protocol.String(profile.Name),
+		protocol.VarInt(len(profile.Properties)),
+	}
+	for _, property := range profile.Properties {
+		fields = append(fields,
+			protocol.String(property.Name),
+			protocol.String(property.Value),
+			protocol.Boolean(property.Signature != ""),
+		)
+		if property.Signature != "" {
+			fields = append(fields, protocol.String(property.Signature))
+		}
+	}
+	for _, field := range fields {
+		payload = append(payload, field.Encode()...)
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return append(mac.Sum(nil), payload...)
+}
+
+// answerVelocityForwarding waits for the server to request the player info
+// of the client and answers it. The login packets before the request are
+// relayed to the client. It returns early if the server continues the login
+// without a request, since it is not in modern forwarding mode then.
+func answerVelocityForwarding(rconn, conn Conn, secret string, connRemoteAddr net.Addr, profile gameProfile) error {
+	if err := rconn.SetReadDeadline(time.Now().Add(velocityForwardingTimeout)); err != nil {
+		return err
+	}
+	defer rconn.SetReadDeadline(time.Time{})
+
+	for i := 0; i < maxCompressionPeekPackets; i++ {
+		pk, err := rconn.PeekPacket()
+		if err != nil {
+			return err
+		}
+
+		if pk.ID != login.ClientBoundLoginPluginRequestPacketID {
+			log.Printf("[w] %s did not request the player info of %s; is it in modern forwarding mode?", rconn.RemoteAddr(), connRemoteAddr)
+			return nil
+		}
+
+		request, err := login.UnmarshalClientBoundLoginPluginRequest(pk)
+		if err != nil {
+			return err
+		}
+
+		if request.Channel == velocityPlayerInfoChannel {
+			if _, err := rconn.ReadPacket(); err != nil {
+				return err
+			}
+
+			response := login.ServerBoundLoginPluginResponse{
+				MessageID:  request.MessageID,
+				Successful: true,
+				Data:       velocityPlayerInfo(secret, connRemoteAddr, profile),
+			}
+			return rconn.WritePacket(response.Marshal())
+		}
+
+		// Other login plugin requests are answered by the client
+		data, err := protocol.ReadPacketBytes(rconn.Reader())
+		if err != nil {
+			return err
+		}
+		n, err := conn.Write(append(protocol.VarInt(len(data)).Encode(), data...))
+		countRelayed(rconn, conn, int64(n))
+		if err != nil {
+			return err
+		}
+	}
+	return errors.New("server did not request the player info")
+}
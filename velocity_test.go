@@ -0,0 +1,133 @@
+package infrared
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"net"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+func TestVelocityPlayerInfo(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.IPv4(203, 0, 113, 7), Port: 51234}
+	data := velocityPlayerInfo("secret", addr, offlineProfile("Steve"))
+	signature, payload := data[:sha256.Size], data[sha256.Size:]
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		t.Error("got: invalid signature; want: valid")
+	}
+
+	var version protocol.VarInt
+	var ip, username protocol.String
+	var uuid protocol.UUID
+	var properties protocol.VarInt
+	pk := protocol.Packet{Data: payload}
+	if err := pk.Scan(&version, &ip, &uuid, &username, &properties); err != nil {
+		t.Fatal(err)
+	}
+
+	if version != velocityDefaultForwardingVersion {
+		t.Errorf("got: %v; want: %v", version, velocityDefaultForwardingVersion)
+	}
+	if ip != "203.0.113.7" {
+		t.Errorf("got: %v; want: 203.0.113.7", ip)
+	}
+	if uuid != offlinePlayerUUID("Steve") {
+		t.Errorf("got: %v; want: %v", uuid, offlinePlayerUUID("Steve"))
+	}
+	if username != "Steve" {
+		t.Errorf("got: %v; want: Steve", username)
+	}
+	if properties != 0 {
+		t.Errorf("got: %v; want: 0", properties)
+	}
+}
+
+func TestVelocityPlayerInfo_Properties(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.IPv4(203, 0, 113, 7), Port: 51234}
+	profile := offlineProfile("Steve")
+	profile.Properties = []gameProfileProperty{
+		{Name: "textures", Value: "e30=", Signature: "c2ln"},
+		{Name: "unsigned", Value: "dmFsdWU="},
+	}
+
+	data := velocityPlayerInfo("secret", addr, profile)
+
+	var version protocol.VarInt
+	var ip, username protocol.String
+	var uuid protocol.UUID
+	var properties protocol.VarInt
+	var name, value, signature, unsignedName, unsignedValue protocol.String
+	var signed, unsignedSigned protocol.Boolean
+	pk := protocol.Packet{Data: data[sha256.Size:]}
+	if err := pk.Scan(
+		&version, &ip, &uuid, &username, &properties,
+		&name, &value, &signed, &signature,
+		&unsignedName, &unsignedValue, &unsignedSigned,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	if properties != 2 {
+		t.Errorf("got: %v; want: 2", properties)
+	}
+	if name != "textures" || value != "e30=" || !signed || signature != "c2ln" {
+		t.Errorf("got: %v %v %v %v; want: textures e30= true c2ln", name, value, signed, signature)
+	}
+	if unsignedName != "unsigned" || unsignedValue != "dmFsdWU=" || unsignedSigned {
+		t.Errorf("got: %v %v %v; want: unsigned dmFsdWU= false", unsignedName, unsignedValue, unsignedSigned)
+	}
+}
+
+func TestAnswerVelocityForwarding(t *testing.T) {
+	server, rc := net.Pipe()
+	c, client := net.Pipe()
+	defer server.Close()
+	defer rc.Close()
+	defer c.Close()
+	defer client.Close()
+
+	otherRequest := uncompressedFrame(protocol.MarshalPacket(0x04, protocol.VarInt(1), protocol.String("fabric:query")))
+	playerInfoRequest := uncompressedFrame(protocol.MarshalPacket(0x04, protocol.VarInt(2), protocol.String(velocityPlayerInfoChannel), protocol.Byte(4)))
+	go func() {
+		_, _ = server.Write(append(otherRequest, playerInfoRequest...))
+	}()
+
+	relayedCh := make(chan []byte, 1)
+	go func() {
+		b := make([]byte, len(otherRequest))
+		_, _ = client.Read(b)
+		relayedCh <- b
+	}()
+
+	responseCh := make(chan protocol.Packet, 1)
+	go func() {
+		pk, err := wrapConn(server).ReadPacket()
+		if err == nil {
+			responseCh <- pk
+		}
+	}()
+
+	addr := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 51234}
+	if err := answerVelocityForwarding(wrapConn(rc), wrapConn(c), "secret", addr, offlineProfile("Steve")); err != nil {
+		t.Fatal(err)
+	}
+
+	if relayed := <-relayedCh; !bytes.Equal(relayed, otherRequest) {
+		t.Errorf("got: %v; want: %v", relayed, otherRequest)
+	}
+
+	want := login.ServerBoundLoginPluginResponse{
+		MessageID:  2,
+		Successful: true,
+		Data:       velocityPlayerInfo("secret", addr, offlineProfile("Steve")),
+	}.Marshal()
+	if pk := <-responseCh; pk.ID != want.ID || !bytes.Equal(pk.Data, want.Data) {
+		t.Errorf("got: %v; want: %v", pk, want)
+	}
+}
@@ -0,0 +1,66 @@
+package infrared
+
+import (
+	"net"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+const (
+	defaultOutdatedClientMessage = "Outdated client! Please use a newer version."
+	defaultOutdatedServerMessage = "Outdated server! Please use an older version."
+)
+
+func (proxy *Proxy) MinProtocol() int {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.MinProtocol
+}
+
+func (proxy *Proxy) MaxProtocol() int {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.MaxProtocol
+}
+
+func (proxy *Proxy) OutdatedClientMessage() string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	if proxy.Config.OutdatedClientMessage == "" {
+		return defaultOutdatedClientMessage
+	}
+	return proxy.Config.OutdatedClientMessage
+}
+
+func (proxy *Proxy) OutdatedServerMessage() string {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	if proxy.Config.OutdatedServerMessage == "" {
+		return defaultOutdatedServerMessage
+	}
+	return proxy.Config.OutdatedServerMessage
+}
+
+// rejectByVersion disconnects logins whose protocol version is outside of
+// the accepted range like vanilla servers do. It reports whether the client
+// was rejected. Status requests always pass, so that clients see the version
+// of the server.
+func (proxy *Proxy) rejectByVersion(conn Conn, hs handshaking.ServerBoundHandshake, connRemoteAddr net.Addr) (bool, error) {
+	if !hs.IsLoginRequest() {
+		return false, nil
+	}
+
+	version := int(hs.ProtocolVersion)
+	var message string
+	switch {
+	case proxy.MinProtocol() > 0 && version < proxy.MinProtocol():
+		message = proxy.OutdatedClientMessage()
+	case proxy.MaxProtocol() > 0 && version > proxy.MaxProtocol():
+		message = proxy.OutdatedServerMessage()
+	default:
+		return false, nil
+	}
+
+	logWith(connFields("reject", connRemoteAddr, proxy.UID()), "[i] Rejecting %s with protocol version %d on %s", connRemoteAddr, version, proxy.UID())
+	return true, conn.WritePacket(disconnectPacket(message))
+}
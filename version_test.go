@@ -0,0 +1,90 @@
+package infrared
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+func TestProxy_RejectByVersion(t *testing.T) {
+	proxy := &Proxy{
+		Config: &ProxyConfig{
+			MinProtocol: 340,
+			MaxProtocol: 758,
+		},
+	}
+
+	tt := []struct {
+		name     string
+		version  protocol.VarInt
+		state    protocol.Byte
+		rejected bool
+		message  string
+	}{
+		{
+			name:     "OutdatedClient",
+			version:  47,
+			state:    handshaking.ServerBoundHandshakeLoginState,
+			rejected: true,
+			message:  defaultOutdatedClientMessage,
+		},
+		{
+			name:     "OutdatedServer",
+			version:  759,
+			state:    handshaking.ServerBoundHandshakeLoginState,
+			rejected: true,
+			message:  defaultOutdatedServerMessage,
+		},
+		{
+			name:    "InRange",
+			version: 758,
+			state:   handshaking.ServerBoundHandshakeLoginState,
+		},
+		{
+			name:    "StatusRequest",
+			version: 47,
+			state:   handshaking.ServerBoundHandshakeStatusState,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hs := handshaking.ServerBoundHandshake{
+				ProtocolVersion: tc.version,
+				NextState:       tc.state,
+			}
+
+			c1, c2 := net.Pipe()
+			defer c1.Close()
+			defer c2.Close()
+
+			received := make(chan protocol.Packet, 1)
+			go func() {
+				pk, err := wrapConn(c1).ReadPacket()
+				if err == nil {
+					received <- pk
+				}
+			}()
+
+			rejected, err := proxy.rejectByVersion(wrapConn(c2), hs, c2.RemoteAddr())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rejected != tc.rejected {
+				t.Fatalf("got: %v; want: %v", rejected, tc.rejected)
+			}
+
+			if !tc.rejected {
+				return
+			}
+
+			want := disconnectPacket(tc.message)
+			if pk := <-received; !bytes.Equal(pk.Data, want.Data) {
+				t.Errorf("got: %s; want: %s", pk.Data, want.Data)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package infrared
+
+import (
+	"fmt"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+func (proxy *Proxy) VersionRoutes() []VersionRouteConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.VersionRoutes
+}
+
+// contains reports whether the protocol version is within the range of the
+// route. A range without a second version has no upper bound.
+func (route VersionRouteConfig) contains(version int) bool {
+	switch len(route.ProtocolRange) {
+	case 1:
+		return version >= route.ProtocolRange[0]
+	case 2:
+		return version >= route.ProtocolRange[0] && version <= route.ProtocolRange[1]
+	default:
+		return false
+	}
+}
+
+// name identifies the route in sessions and the status cache
+func (route VersionRouteConfig) name() string {
+	if len(route.ProtocolRange) == 1 {
+		return fmt.Sprintf("protocol:%d-", route.ProtocolRange[0])
+	}
+	return fmt.Sprintf("protocol:%d-%d", route.ProtocolRange[0], route.ProtocolRange[1])
+}
+
+// versionRouteTo returns the address of the server of the first version
+// route that contains the protocol version of the client and the name of
+// that route. Both are empty if there is none.
+func (proxy *Proxy) versionRouteTo(hs handshaking.ServerBoundHandshake) (string, string) {
+	version := int(hs.ProtocolVersion)
+	for _, route := range proxy.VersionRoutes() {
+		if route.ProxyTo != "" && route.contains(version) {
+			return route.ProxyTo, route.name()
+		}
+	}
+	return "", ""
+}
@@ -0,0 +1,132 @@
+package infrared
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+	"github.com/haveachin/infrared/protocol/login"
+)
+
+func TestProxy_VersionRouteTo(t *testing.T) {
+	tt := []struct {
+		name      string
+		version   int
+		wantAddr  string
+		wantRoute string
+	}{
+		{
+			name:      "OldClient",
+			version:   47,
+			wantAddr:  "legacy:25565",
+			wantRoute: "protocol:47-340",
+		},
+		{
+			name:      "UpperBound",
+			version:   340,
+			wantAddr:  "legacy:25565",
+			wantRoute: "protocol:47-340",
+		},
+		{
+			name:    "BetweenRanges",
+			version: 578,
+		},
+		{
+			name:      "NewClient",
+			version:   764,
+			wantAddr:  "modern:25565",
+			wantRoute: "protocol:763-",
+		},
+		{
+			name:    "TooOld",
+			version: 5,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{
+				Config: &ProxyConfig{
+					ProxyTo: "hub:25565",
+					VersionRoutes: []VersionRouteConfig{
+						{ProtocolRange: []int{47, 340}, ProxyTo: "legacy:25565"},
+						{ProtocolRange: []int{763}, ProxyTo: "modern:25565"},
+					},
+				},
+			}
+			hs := handshaking.ServerBoundHandshake{
+				ProtocolVersion: protocol.VarInt(tc.version),
+			}
+
+			addr, route := proxy.versionRouteTo(hs)
+			if addr != tc.wantAddr {
+				t.Errorf("got: %v; want: %v", addr, tc.wantAddr)
+			}
+			if route != tc.wantRoute {
+				t.Errorf("got: %v; want: %v", route, tc.wantRoute)
+			}
+		})
+	}
+}
+
+func TestProxy_BackendsFor_VersionRoutes(t *testing.T) {
+	tt := []struct {
+		name      string
+		address   string
+		version   int
+		wantAddrs []string
+		wantRoute string
+	}{
+		{
+			name:      "VersionRoute",
+			address:   "hub.example.com",
+			version:   47,
+			wantAddrs: []string{"legacy:25565"},
+			wantRoute: "protocol:47-340",
+		},
+		{
+			name:      "SubdomainRouteFirst",
+			address:   "creative.hub.example.com",
+			version:   47,
+			wantAddrs: []string{"creative:25565"},
+			wantRoute: "creative",
+		},
+		{
+			name:      "NoRoute",
+			address:   "hub.example.com",
+			version:   757,
+			wantAddrs: []string{"hub:25565"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{
+				Config: &ProxyConfig{
+					DomainName: "hub.example.com",
+					ProxyTo:    "hub:25565",
+					SubdomainRoutes: map[string]string{
+						"creative": "creative:25565",
+					},
+					VersionRoutes: []VersionRouteConfig{
+						{ProtocolRange: []int{47, 340}, ProxyTo: "legacy:25565"},
+					},
+				},
+			}
+			hs := handshaking.ServerBoundHandshake{
+				ProtocolVersion: protocol.VarInt(tc.version),
+				ServerAddress:   protocol.String(tc.address),
+				NextState:       handshaking.ServerBoundHandshakeLoginState,
+			}
+
+			addrs, route := proxy.backendsFor(hs, nil, login.ServerLoginStart{})
+			if !reflect.DeepEqual(addrs, tc.wantAddrs) {
+				t.Errorf("got: %v; want: %v", addrs, tc.wantAddrs)
+			}
+			if route != tc.wantRoute {
+				t.Errorf("got: %v; want: %v", route, tc.wantRoute)
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package infrared
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultWaitForBackendInterval = time.Second
+	defaultWaitForBackendMOTD     = "Server is starting... {{elapsed}}s"
+	motdElapsedPlaceholder        = "{{elapsed}}"
+)
+
+// WaitForBackend returns how logins wait for the server while it is offline
+func (proxy *Proxy) WaitForBackend() WaitForBackendConfig {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.WaitForBackend
+}
+
+func (cfg WaitForBackendConfig) maxWait() time.Duration {
+	return time.Millisecond * time.Duration(cfg.MaxWait)
+}
+
+func (cfg WaitForBackendConfig) interval() time.Duration {
+	if cfg.Interval <= 0 {
+		return defaultWaitForBackendInterval
+	}
+	return time.Millisecond * time.Duration(cfg.Interval)
+}
+
+func (cfg WaitForBackendConfig) motd() string {
+	if cfg.MOTD == "" {
+		return defaultWaitForBackendMOTD
+	}
+	return cfg.MOTD
+}
+
+// beginWaiting registers a login that waits for the server
+func (proxy *Proxy) beginWaiting() {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.waiting == 0 {
+		proxy.waitingSince = time.Now()
+	}
+	proxy.waiting++
+}
+
+func (proxy *Proxy) endWaiting() {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	proxy.waiting--
+	if proxy.waiting == 0 {
+		proxy.waitingSince = time.Time{}
+	}
+}
+
+// startingSince returns since when logins wait for the server and whether
+// any does
+func (proxy *Proxy) startingSince() (time.Time, bool) {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	return proxy.waitingSince, proxy.waiting > 0
+}
+
+// waitForBackend starts the process of the server and dials the backends
+// every interval until one of them answers or the max wait is over. The
+// client stays in the login state meanwhile.
+func (proxy *Proxy) waitForBackend(backends []string) (Conn, string, error) {
+	cfg := proxy.WaitForBackend()
+	if err := proxy.startProcessIfNotRunning(); err != nil {
+		return nil, "", err
+	}
+
+	proxy.beginWaiting()
+	defer proxy.endWaiting()
+
+	deadline := time.Now().Add(cfg.maxWait())
+	for {
+		time.Sleep(cfg.interval())
+		rconn, addr, err := proxy.dialBackend(backends)
+		if err == nil {
+			return rconn, addr, nil
+		}
+
+		if !time.Now().Before(deadline) {
+			log.Printf("[i] %s did not start within %s", addr, cfg.maxWait())
+			return nil, addr, err
+		}
+	}
+}
+
+// handleStartingStatus answers the status request with the offline status
+// and the MOTD of waitForBackend, which shows how long the logins wait
+func (proxy *Proxy) handleStartingStatus(conn Conn, since time.Time) error {
+	proxyUID := proxy.UID()
+	proxy.Config.RLock()
+	cfg := proxy.Config.OfflineStatus
+	motd := proxy.Config.WaitForBackend.motd()
+	proxy.Config.RUnlock()
+
+	elapsed := strconv.Itoa(int(time.Since(since).Seconds()))
+	cfg.MOTD = strings.Replace(motd, motdElapsedPlaceholder, elapsed, -1)
+	cfg.cachedPacket = nil
+
+	responsePk, err := cfg.withPlaceholders(proxyUID, nil).StatusResponsePacket()
+	if err != nil {
+		return err
+	}
+	return writeStatus(conn, responsePk, proxy.StrictProtocol())
+}
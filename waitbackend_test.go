@@ -0,0 +1,150 @@
+package infrared
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/haveachin/infrared/protocol/status"
+)
+
+func TestProxy_WaitForBackend(t *testing.T) {
+	tt := []struct {
+		name      string
+		maxWait   int
+		connected bool
+	}{
+		{
+			name:      "Started",
+			maxWait:   1000,
+			connected: true,
+		},
+		{
+			name:      "TimedOut",
+			maxWait:   20,
+			connected: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Reserve an address that is not bound yet, like the one of a starting server
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			addr := l.Addr().String()
+			l.Close()
+
+			started := make(chan net.Listener, 1)
+			go func() {
+				time.Sleep(100 * time.Millisecond)
+				l, err := net.Listen("tcp", addr)
+				if err != nil {
+					started <- nil
+					return
+				}
+				started <- l
+			}()
+			defer func() {
+				if l := <-started; l != nil {
+					l.Close()
+				}
+			}()
+
+			proxy := &Proxy{
+				Config: &ProxyConfig{
+					WaitForBackend: WaitForBackendConfig{
+						MaxWait:  tc.maxWait,
+						Interval: 20,
+					},
+				},
+			}
+			rconn, _, err := proxy.waitForBackend([]string{addr})
+			if connected := err == nil; connected != tc.connected {
+				t.Fatalf("got: %v; want connected: %v", err, tc.connected)
+			}
+			if rconn != nil {
+				rconn.Close()
+			}
+
+			if _, waiting := proxy.startingSince(); waiting {
+				t.Errorf("got: %v; want: %v", waiting, false)
+			}
+		})
+	}
+}
+
+func TestProxy_HandleStartingStatus(t *testing.T) {
+	tt := []struct {
+		name     string
+		motd     string
+		wantMOTD string
+	}{
+		{
+			name:     "DefaultMOTD",
+			wantMOTD: "Server is starting... 15s",
+		},
+		{
+			name:     "MOTD",
+			motd:     "{{proxy_uid}} is starting ({{elapsed}}s)",
+			wantMOTD: "mc.example.com@:25565 is starting (15s)",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			proxy := &Proxy{
+				Config: &ProxyConfig{
+					DomainName:    "mc.example.com",
+					ListenTo:      ":25565",
+					OfflineStatus: statusPKWithVersion("Infrared-test-offline"),
+					WaitForBackend: WaitForBackendConfig{
+						MaxWait: 60000,
+						MOTD:    tc.motd,
+					},
+				},
+			}
+
+			c1, c2 := net.Pipe()
+			defer c1.Close()
+			defer c2.Close()
+
+			errCh := make(chan error, 1)
+			since := time.Now().Add(-15 * time.Second)
+			go func() { errCh <- proxy.handleStartingStatus(wrapConn(c2), since) }()
+
+			client := wrapConn(c1)
+			if err := client.WritePacket(status.ServerBoundRequest{}.Marshal()); err != nil {
+				t.Fatal(err)
+			}
+
+			pk, err := client.ReadPacket()
+			if err != nil {
+				t.Fatal(err)
+			}
+			response, err := status.UnmarshalClientBoundResponse(pk)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var responseJSON status.ResponseJSON
+			if err := json.Unmarshal([]byte(response.JSONResponse), &responseJSON); err != nil {
+				t.Fatal(err)
+			}
+			if responseJSON.Description.Text != tc.wantMOTD {
+				t.Errorf("got: %v; want: %v", responseJSON.Description.Text, tc.wantMOTD)
+			}
+
+			if err := client.WritePacket(status.ServerBoundPing{Payload: 1}.Marshal()); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.ReadPacket(); err != nil {
+				t.Fatal(err)
+			}
+			if err := <-errCh; err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
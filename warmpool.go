@@ -0,0 +1,217 @@
+package infrared
+
+import (
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+const (
+	// warmConnMaxIdle is the time after which a pooled connection is
+	// replaced, well before servers drop connections without a handshake
+	warmConnMaxIdle = 10 * time.Second
+	// warmPoolRefillInterval is the time between two refills of the pool
+	warmPoolRefillInterval = time.Second
+	// warmConnCheckTimeout is the time a pooled connection gets to show
+	// that the server closed it
+	warmConnCheckTimeout = time.Millisecond
+)
+
+func (proxy *Proxy) WarmPoolSize() int {
+	proxy.Config.RLock()
+	defer proxy.Config.RUnlock()
+	return proxy.Config.WarmPoolSize
+}
+
+// warmConn is a connection to a server that was dialed ahead of a login
+type warmConn struct {
+	conn     Conn
+	addr     string
+	dialedAt time.Time
+}
+
+// warmPool holds connections to the preferred backend of a proxy, so that
+// logins don't have to wait for the dial. It is safe for concurrent use.
+type warmPool struct {
+	mu    sync.Mutex
+	conns []warmConn
+	stop  chan struct{}
+	taken chan struct{}
+}
+
+// take returns a pooled connection to addr. Connections that are too old,
+// to another backend or closed by the server are discarded.
+func (pool *warmPool) take(addr string) (Conn, bool) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	for len(pool.conns) > 0 {
+		wc := pool.conns[0]
+		pool.conns = pool.conns[1:]
+		if wc.addr == addr && time.Since(wc.dialedAt) < warmConnMaxIdle && isWarmConnAlive(wc.conn) {
+			return wc.conn, true
+		}
+		wc.conn.Close()
+	}
+	return nil, false
+}
+
+func (pool *warmPool) put(wc warmConn) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.conns = append(pool.conns, wc)
+}
+
+// expire closes all connections that are too old or not to addr and
+// returns the number of remaining ones
+func (pool *warmPool) expire(addr string) int {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	conns := pool.conns[:0]
+	for _, wc := range pool.conns {
+		if wc.addr == addr && time.Since(wc.dialedAt) < warmConnMaxIdle {
+			conns = append(conns, wc)
+			continue
+		}
+		wc.conn.Close()
+	}
+	pool.conns = conns
+	return len(conns)
+}
+
+func (pool *warmPool) closeAll() {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	for _, wc := range pool.conns {
+		wc.conn.Close()
+	}
+	pool.conns = nil
+}
+
+// isWarmConnAlive reports whether the server did not close or write to the
+// connection, since it has to wait for the handshake
+func isWarmConnAlive(c Conn) bool {
+	if err := c.SetReadDeadline(time.Now().Add(warmConnCheckTimeout)); err != nil {
+		return false
+	}
+	_, err := c.Reader().Peek(1)
+	if err := c.SetReadDeadline(time.Time{}); err != nil {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// preferredBackend returns the first backend that is up. It does not use
+// the backend selection, since a selection like round robin changes with
+// every call.
+func (proxy *Proxy) preferredBackend() string {
+	backends := failoverSelector{}.Select(proxy.Backends(), proxy.backendHealth.IsUp)
+	if len(backends) == 0 {
+		return ""
+	}
+	return backends[0]
+}
+
+// connectBackend hands a pooled connection to logins if it is to the first
+// of the backends and dials them otherwise
+func (proxy *Proxy) connectBackend(hs handshaking.ServerBoundHandshake, backends []string) (Conn, string, error) {
+	if hs.IsLoginRequest() && len(backends) > 0 && proxy.WarmPoolSize() > 0 {
+		if rconn, ok := proxy.warmPool.take(backends[0]); ok {
+			proxy.refillWarmPool()
+			return rconn, backends[0], nil
+		}
+	}
+	return proxy.dialBackendWithRetries(backends)
+}
+
+// refillWarmPool makes the pool refill right away instead of waiting
+// for the next refill interval
+func (proxy *Proxy) refillWarmPool() {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.warmPool.taken == nil {
+		return
+	}
+
+	select {
+	case proxy.warmPool.taken <- struct{}{}:
+	default:
+	}
+}
+
+// startWarmPool keeps the pool filled until stopWarmPool is called. The
+// size is read on every refill, so that changes apply without restarting
+// the proxy.
+func (proxy *Proxy) startWarmPool() {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.warmPool.stop != nil {
+		return
+	}
+
+	stop := make(chan struct{})
+	taken := make(chan struct{}, 1)
+	proxy.warmPool.stop = stop
+	proxy.warmPool.taken = taken
+	go func() {
+		for {
+			proxy.fillWarmPool()
+
+			select {
+			case <-stop:
+				proxy.warmPool.closeAll()
+				return
+			case <-taken:
+			case <-time.After(warmPoolRefillInterval):
+			}
+		}
+	}()
+}
+
+func (proxy *Proxy) stopWarmPool() {
+	proxy.mu.Lock()
+	defer proxy.mu.Unlock()
+	if proxy.warmPool.stop == nil {
+		return
+	}
+
+	close(proxy.warmPool.stop)
+	proxy.warmPool.stop = nil
+	proxy.warmPool.taken = nil
+}
+
+// fillWarmPool dials the preferred backend until the pool is full. Backends
+// that are down are not dialed until they are up again.
+func (proxy *Proxy) fillWarmPool() {
+	size := proxy.WarmPoolSize()
+	addr := proxy.preferredBackend()
+	if size <= 0 || addr == "" || !proxy.backendHealth.IsUp(addr) {
+		proxy.warmPool.closeAll()
+		return
+	}
+
+	dialer, err := proxy.Dialer()
+	if err != nil {
+		return
+	}
+
+	for n := proxy.warmPool.expire(addr); n < size; n++ {
+		rconn, err := dialer.Dial(addr)
+		if err != nil {
+			proxy.backendHealth.MarkDown(addr, backendRetryInterval)
+			return
+		}
+		proxy.setTCPOptions(rconn)
+		proxy.warmPool.put(warmConn{
+			conn:     rconn,
+			addr:     addr,
+			dialedAt: time.Now(),
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package infrared
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+func TestProxy_ConnectBackend_WarmPool(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	accepted := make(chan net.Conn, 4)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	addr := l.Addr().String()
+	proxy := &Proxy{Config: &ProxyConfig{
+		ProxyTo:      addr,
+		Timeout:      1000,
+		WarmPoolSize: 1,
+	}}
+	proxy.fillWarmPool()
+
+	var serverConn net.Conn
+	select {
+	case serverConn = <-accepted:
+	case <-time.After(time.Second):
+		t.Fatal("got: no pooled connection; want: one")
+	}
+
+	login := handshaking.ServerBoundHandshake{NextState: handshaking.ServerBoundHandshakeLoginState}
+	rconn, proxyTo, err := proxy.connectBackend(login, []string{addr})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rconn.Close()
+
+	if proxyTo != addr {
+		t.Errorf("got: %s; want: %s", proxyTo, addr)
+	}
+	if rconn.LocalAddr().String() != serverConn.RemoteAddr().String() {
+		t.Errorf("got: %s; want: pooled connection %s", rconn.LocalAddr(), serverConn.RemoteAddr())
+	}
+}
+
+func TestWarmPool_Take(t *testing.T) {
+	tt := []struct {
+		name     string
+		addr     string
+		dialedAt time.Time
+		closed   bool
+		want     bool
+	}{
+		{
+			name:     "Alive",
+			addr:     "a:25565",
+			dialedAt: time.Now(),
+			want:     true,
+		},
+		{
+			name:     "OtherBackend",
+			addr:     "b:25565",
+			dialedAt: time.Now(),
+		},
+		{
+			name:     "Stale",
+			addr:     "a:25565",
+			dialedAt: time.Now().Add(-warmConnMaxIdle),
+		},
+		{
+			name:     "ClosedByServer",
+			addr:     "a:25565",
+			dialedAt: time.Now(),
+			closed:   true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			c, s := net.Pipe()
+			defer c.Close()
+			if tc.closed {
+				s.Close()
+			} else {
+				defer s.Close()
+			}
+
+			var pool warmPool
+			pool.put(warmConn{conn: wrapConn(c), addr: tc.addr, dialedAt: tc.dialedAt})
+
+			_, got := pool.take("a:25565")
+			if got != tc.want {
+				t.Errorf("got: %v; want: %v", got, tc.want)
+			}
+			if len(pool.conns) != 0 {
+				t.Errorf("got: %d pooled connections; want: 0", len(pool.conns))
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package infrared
+
+import (
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+// wildcardDomain is the domain name of a proxy that matches every domain
+// that no other proxy matches
+const wildcardDomain = "*"
+
+// wildcardDomains returns the wildcard domain names that match the server
+// address from the most to the least specific one, e.g.
+// a.play.example.com is matched by *.play.example.com, *.example.com,
+// *.com and *
+func wildcardDomains(serverAddr string) []string {
+	var domains []string
+	_, rest, ok := splitSubdomain(serverAddr)
+	for ok {
+		domains = append(domains, wildcardDomain+"."+rest)
+		_, rest, ok = splitSubdomain(rest)
+	}
+	return append(domains, wildcardDomain)
+}
+
+// loadWildcardProxy returns the proxy with the most specific wildcard domain
+// name that matches the requested address and its UID. Like exact domain
+// names, the ones with a port take precedence over the ones without.
+func (gateway *Gateway) loadWildcardProxy(hs handshaking.ServerBoundHandshake, addr string) (*Proxy, string, bool) {
+	port := strconv.Itoa(int(hs.ServerPort))
+	for _, domain := range wildcardDomains(strings.ToLower(hs.ParseServerAddress())) {
+		uids := []string{
+			proxyUID(net.JoinHostPort(domain, port), addr),
+			proxyUID(domain, addr),
+		}
+		for _, uid := range uids {
+			if v, ok := gateway.Proxies.Load(uid); ok {
+				return v.(*Proxy), uid, true
+			}
+		}
+	}
+	return nil, "", false
+}
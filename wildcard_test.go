@@ -0,0 +1,134 @@
+package infrared
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/haveachin/infrared/protocol"
+	"github.com/haveachin/infrared/protocol/handshaking"
+)
+
+func TestWildcardDomains(t *testing.T) {
+	got := wildcardDomains("a.play.example.com")
+	want := []string{"*.play.example.com", "*.example.com", "*.com", "*"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got: %v; want: %v", got, want)
+	}
+}
+
+func TestGateway_LoadWildcardProxy(t *testing.T) {
+	const addr = ":25565"
+	domains := []string{
+		"*",
+		"*.example.com",
+		"*.play.example.com",
+		"*.play.example.com:25566",
+		"lobby.play.example.com",
+	}
+
+	var gateway Gateway
+	for _, domain := range domains {
+		proxy := &Proxy{Config: &ProxyConfig{DomainName: domain, ListenTo: addr}}
+		gateway.Proxies.Store(proxy.UID(), proxy)
+	}
+
+	tt := []struct {
+		name    string
+		address string
+		port    protocol.UnsignedShort
+		want    string
+	}{
+		{
+			name:    "SingleLabelWildcard",
+			address: "survival.play.example.com",
+			port:    25565,
+			want:    "*.play.example.com",
+		},
+		{
+			name:    "MostSpecificWildcard",
+			address: "a.b.play.example.com",
+			port:    25565,
+			want:    "*.play.example.com",
+		},
+		{
+			name:    "LessSpecificWildcard",
+			address: "hub.example.com",
+			port:    25565,
+			want:    "*.example.com",
+		},
+		{
+			name:    "WildcardWithPort",
+			address: "survival.play.example.com",
+			port:    25566,
+			want:    "*.play.example.com:25566",
+		},
+		{
+			name:    "CaseInsensitive",
+			address: "Survival.PLAY.example.com",
+			port:    25565,
+			want:    "*.play.example.com",
+		},
+		{
+			name:    "FullWildcard",
+			address: "example.org",
+			port:    25565,
+			want:    "*",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			hs := handshaking.ServerBoundHandshake{
+				ServerAddress: protocol.String(tc.address),
+				ServerPort:    tc.port,
+			}
+
+			proxy, uid, ok := gateway.loadWildcardProxy(hs, addr)
+			if !ok {
+				t.Fatal("got: no proxy; want: proxy")
+			}
+			if got := proxy.DomainName(); got != tc.want {
+				t.Errorf("got: %v; want: %v", got, tc.want)
+			}
+			if uid != proxy.UID() {
+				t.Errorf("got: %v; want: %v", uid, proxy.UID())
+			}
+		})
+	}
+}
+
+func TestGateway_MatchProxy_Forge(t *testing.T) {
+	const addr = ":25565"
+	var gateway Gateway
+	for _, domain := range []string{"mc.example.com", "*.example.com"} {
+		proxy := &Proxy{Config: &ProxyConfig{DomainName: domain, ListenTo: addr}}
+		gateway.Proxies.Store(proxy.UID(), proxy)
+	}
+
+	tt := []struct {
+		address string
+		want    string
+	}{
+		{address: "mc.example.com\x00FML\x00", want: "mc.example.com"},
+		{address: "mc.example.com\x00FML2\x00", want: "mc.example.com"},
+		{address: "mc.example.com.\x00FML3\x00", want: "mc.example.com"},
+		{address: "lobby.example.com\x00FML2\x00", want: "*.example.com"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.address, func(t *testing.T) {
+			hs := handshaking.ServerBoundHandshake{
+				ServerAddress: protocol.String(tc.address),
+				ServerPort:    25565,
+			}
+
+			proxy, _ := gateway.matchProxy(hs, addr)
+			if proxy == nil {
+				t.Fatal("got: no proxy; want: proxy")
+			}
+			if got := proxy.DomainName(); got != tc.want {
+				t.Errorf("got: %v; want: %v", got, tc.want)
+			}
+		})
+	}
+}